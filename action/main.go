@@ -11,6 +11,7 @@ import (
 	"log"
 	"time"
 
+	"github.com/nleiva/clus2019/inventory"
 	xr "github.com/nleiva/xrgrpc"
 )
 
@@ -27,6 +28,9 @@ func main() {
 	enc := flag.String("enc", "json", "Encoding: 'json' or 'cli'")
 	// Action to issue; defaults to "ping4.json"
 	act := flag.String("act", "../input/action/ping6.json", "Command to execute")
+	// Target devices; defaults to "router2" from "inventory.json"
+	var tg inventory.Target
+	tg.AddFlags(flag.CommandLine)
 	flag.Parse()
 
 	file, err := ioutil.ReadFile(*act)
@@ -35,20 +39,22 @@ func main() {
 	}
 	cli := string(file)
 
+	devices, err := tg.Devices()
+	if err != nil {
+		log.Fatalf("could not select a device, %v", err)
+	}
+	for _, d := range devices {
+		action(d, *enc, cli)
+	}
+}
+
+func action(d inventory.Device, enc, cli string) {
 	// ID for the transaction.
 	var id int64 = 1
 	var output string
 
-	// Manually specify target parameters.
-	router, err := xr.BuildRouter(
-		xr.WithUsername("cisco"),
-		xr.WithPassword("cisco"),
-		//xr.WithHost("[2001:420:2cff:1204::5502:1]:57344"),
-		//xr.WithCert("../input/certificate/router1.pem"),
-		xr.WithHost("[2001:420:2cff:1204::5502:2]:57344"),
-		xr.WithCert("../input/certificate/router2.pem"),
-		xr.WithTimeout(20),
-	)
+	// Target parameters come from the inventory.
+	router, err := d.Router(xr.WithTimeout(20))
 	if err != nil {
 		log.Fatalf("could not build a router, %v", err)
 	}
@@ -61,13 +67,13 @@ func main() {
 	defer conn.Close()
 
 	// Return show command output based on encoding selected
-	switch *enc {
+	switch enc {
 	case "json":
 		output, err = xr.ActionJSON(ctx, conn, cli, id)
 	//case "cli":
 	//	output, err = xr.ActionCLI(ctx, conn, cli, id)
 	default:
-		log.Fatalf("don't recognize encoding: %v\n", *enc)
+		log.Fatalf("don't recognize encoding: %v\n", enc)
 	}
 	if err != nil {
 		log.Fatalf("couldn't get an output: %v\n", err)
@@ -0,0 +1,240 @@
+/*
+Package backup keeps timestamped config snapshots per device in a directory,
+skipping snapshots identical to the previous one and pruning old ones.
+*/
+package backup
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Snapshot kinds, also used as file extensions.
+const (
+	// JSON snapshots hold the YANG JSON config from GetConfig.
+	JSON = "json"
+	// CLI snapshots hold the output of "show running-config".
+	CLI = "cfg"
+)
+
+const stampLayout = "20060102T150405Z"
+
+// Snapshot is a saved config.
+type Snapshot struct {
+	Device string
+	Kind   string
+	Time   time.Time
+	// Hash is the SHA-256 of the content, in hex.
+	Hash string
+	File string
+
+	// seq orders the snapshots taken in the same second: the first has
+	// none, the next ones 1, 2 and so on, in their file names.
+	seq int
+}
+
+// Store saves snapshots under Dir, one directory per device.
+type Store struct {
+	Dir string
+}
+
+// Normalize prepares a config for storage, so the same config always
+// hashes the same: JSON is indented, CLI loses the banner and timestamps.
+func Normalize(kind string, data []byte) ([]byte, error) {
+	if kind == CLI {
+		return []byte(CleanConfig(string(data)) + "\n"), nil
+	}
+	var out bytes.Buffer
+	if err := json.Indent(&out, bytes.TrimSpace(data), "", "  "); err != nil {
+		return nil, errors.Wrap(err, "could not pretty-print the config")
+	}
+	out.WriteByte('\n')
+	return out.Bytes(), nil
+}
+
+// CleanConfig drops the banner and timestamps "show running-config" prints
+// before the config itself, so snapshots only differ on actual changes.
+func CleanConfig(cfg string) string {
+	lines := strings.Split(strings.TrimRight(cfg, "\n"), "\n")
+	for i, l := range lines {
+		if strings.HasPrefix(l, "!!") {
+			lines = lines[i:]
+			break
+		}
+	}
+	out := lines[:0]
+	for _, l := range lines {
+		if strings.HasPrefix(l, "!! Last configuration change") {
+			continue
+		}
+		out = append(out, l)
+	}
+	return strings.Join(out, "\n")
+}
+
+func hash(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// Save stores data for device unless it's identical to the latest snapshot
+// of the same kind. It returns the stored or latest snapshot and whether it
+// was new.
+func (s *Store) Save(device, kind string, data []byte, t time.Time) (Snapshot, bool, error) {
+	data, err := Normalize(kind, data)
+	if err != nil {
+		return Snapshot{}, false, err
+	}
+	h := hash(data)
+	snaps, err := s.List(device, kind)
+	if err != nil {
+		return Snapshot{}, false, err
+	}
+	if n := len(snaps); n > 0 && snaps[n-1].Hash == h {
+		return snaps[n-1], false, nil
+	}
+	dir := filepath.Join(s.Dir, device)
+	if err = os.MkdirAll(dir, 0755); err != nil {
+		return Snapshot{}, false, errors.Wrapf(err, "could not create directory %s", dir)
+	}
+	t = t.UTC()
+	stamp := t.Format(stampLayout)
+	snap := Snapshot{
+		Device: device,
+		Kind:   kind,
+		Time:   t,
+		Hash:   h,
+	}
+	// File names have a second's resolution, so a snapshot taken in the
+	// same second as others comes after them.
+	for _, o := range snaps {
+		if o.Time.Format(stampLayout) == stamp && o.seq >= snap.seq {
+			snap.seq = o.seq + 1
+		}
+	}
+	name := stamp + "-" + h[:12] + "." + kind
+	if snap.seq > 0 {
+		name = stamp + "-" + strconv.Itoa(snap.seq) + "-" + h[:12] + "." + kind
+	}
+	snap.File = filepath.Join(dir, name)
+	if err = ioutil.WriteFile(snap.File, data, 0644); err != nil {
+		return Snapshot{}, false, errors.Wrapf(err, "could not write file %s", snap.File)
+	}
+	return snap, true, nil
+}
+
+// List returns the snapshots of kind for device, oldest first.
+func (s *Store) List(device, kind string) ([]Snapshot, error) {
+	files, err := filepath.Glob(filepath.Join(s.Dir, device, "*."+kind))
+	if err != nil {
+		return nil, errors.Wrap(err, "could not list snapshots")
+	}
+	var out []Snapshot
+	for _, f := range files {
+		snap, err := parse(f)
+		if err != nil {
+			continue
+		}
+		snap.Device = device
+		out = append(out, snap)
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		a, b := out[i], out[j]
+		switch {
+		case !a.Time.Equal(b.Time):
+			return a.Time.Before(b.Time)
+		case a.seq != b.seq:
+			return a.seq < b.seq
+		}
+		return a.File < b.File
+	})
+	return out, nil
+}
+
+// parse recovers a Snapshot from its file name,
+// <stamp>[-<seq>]-<hash>.<kind>; the full hash is computed from the
+// content.
+func parse(file string) (Snapshot, error) {
+	base := filepath.Base(file)
+	ext := filepath.Ext(base)
+	parts := strings.Split(strings.TrimSuffix(base, ext), "-")
+	if len(parts) != 2 && len(parts) != 3 {
+		return Snapshot{}, errors.Errorf("%s is not a snapshot", file)
+	}
+	t, err := time.Parse(stampLayout, parts[0])
+	if err != nil {
+		return Snapshot{}, errors.Wrapf(err, "%s is not a snapshot", file)
+	}
+	seq := 0
+	if len(parts) == 3 {
+		if seq, err = strconv.Atoi(parts[1]); err != nil || seq < 1 {
+			return Snapshot{}, errors.Errorf("%s is not a snapshot", file)
+		}
+	}
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return Snapshot{}, errors.Wrapf(err, "could not read file %s", file)
+	}
+	return Snapshot{Kind: strings.TrimPrefix(ext, "."), Time: t, Hash: hash(data), File: file, seq: seq}, nil
+}
+
+// Latest returns the newest snapshot of kind for device.
+func (s *Store) Latest(device, kind string) (Snapshot, error) {
+	snaps, err := s.List(device, kind)
+	if err != nil {
+		return Snapshot{}, err
+	}
+	if len(snaps) == 0 {
+		return Snapshot{}, errors.Errorf("no %s snapshots for %s", kind, device)
+	}
+	return snaps[len(snaps)-1], nil
+}
+
+// Read returns the content of a snapshot.
+func (s *Store) Read(snap Snapshot) ([]byte, error) {
+	b, err := ioutil.ReadFile(snap.File)
+	return b, errors.Wrapf(err, "could not read snapshot %s", snap.File)
+}
+
+// Retention says which snapshots to keep. Snapshots beyond the newest Keep,
+// or older than MaxAge, are removed; zero disables either limit. The newest
+// snapshot is always kept.
+type Retention struct {
+	Keep   int
+	MaxAge time.Duration
+}
+
+// Prune removes the snapshots of kind for device that fall outside r and
+// returns them.
+func (s *Store) Prune(device, kind string, r Retention, now time.Time) ([]Snapshot, error) {
+	snaps, err := s.List(device, kind)
+	if err != nil {
+		return nil, err
+	}
+	var removed []Snapshot
+	// Walk newest first; i is the number of newer snapshots.
+	for i := 1; i < len(snaps); i++ {
+		snap := snaps[len(snaps)-1-i]
+		tooMany := r.Keep > 0 && i >= r.Keep
+		tooOld := r.MaxAge > 0 && now.Sub(snap.Time) > r.MaxAge
+		if !tooMany && !tooOld {
+			continue
+		}
+		if err = os.Remove(snap.File); err != nil {
+			return removed, errors.Wrapf(err, "could not remove snapshot %s", snap.File)
+		}
+		removed = append(removed, snap)
+	}
+	return removed, nil
+}
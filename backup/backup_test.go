@@ -0,0 +1,229 @@
+package backup
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// testStore returns a Store in a directory the caller removes.
+func testStore(t *testing.T) *Store {
+	t.Helper()
+	dir, err := ioutil.TempDir("", "backup")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &Store{Dir: dir}
+}
+
+// save saves each config for device r1, step apart from start.
+func save(t *testing.T, s *Store, start time.Time, step time.Duration, configs ...string) []Snapshot {
+	t.Helper()
+	var out []Snapshot
+	for i, c := range configs {
+		snap, _, err := s.Save("r1", JSON, []byte(c), start.Add(time.Duration(i)*step))
+		if err != nil {
+			t.Fatal(err)
+		}
+		out = append(out, snap)
+	}
+	return out
+}
+
+// contents returns the content of each snapshot.
+func contents(t *testing.T, s *Store, snaps []Snapshot) []string {
+	t.Helper()
+	var out []string
+	for _, snap := range snaps {
+		b, err := s.Read(snap)
+		if err != nil {
+			t.Fatal(err)
+		}
+		out = append(out, string(b))
+	}
+	return out
+}
+
+var start = time.Date(2019, 6, 10, 14, 43, 28, 0, time.UTC)
+
+func TestSave(t *testing.T) {
+	s := testStore(t)
+	defer os.RemoveAll(s.Dir)
+
+	first, isNew, err := s.Save("r1", JSON, []byte(`{"a":1}`), start)
+	if err != nil || !isNew {
+		t.Fatalf("Save() = %v, %v, want a new snapshot", isNew, err)
+	}
+	if want := filepath.Join(s.Dir, "r1", "20190610T144328Z-"+first.Hash[:12]+".json"); first.File != want {
+		t.Errorf("snapshot in %s, want %s", first.File, want)
+	}
+	// The same config, formatted differently, isn't saved again.
+	again, isNew, err := s.Save("r1", JSON, []byte("{\n\"a\": 1}\n"), start.Add(time.Hour))
+	if err != nil || isNew || again.File != first.File {
+		t.Errorf("Save() of the same config = %s, %v, %v, want %s, false", again.File, isNew, err, first.File)
+	}
+	// A change is.
+	if _, isNew, err = s.Save("r1", JSON, []byte(`{"a":2}`), start.Add(2*time.Hour)); err != nil || !isNew {
+		t.Errorf("Save() of a change = %v, %v, want a new snapshot", isNew, err)
+	}
+	// Going back to the first config is a change too.
+	if _, isNew, err = s.Save("r1", JSON, []byte(`{"a":1}`), start.Add(3*time.Hour)); err != nil || !isNew {
+		t.Errorf("Save() of the first config again = %v, %v, want a new snapshot", isNew, err)
+	}
+	snaps, err := s.List("r1", JSON)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"{\n  \"a\": 1\n}\n", "{\n  \"a\": 2\n}\n", "{\n  \"a\": 1\n}\n"}
+	if got := contents(t, s, snaps); !reflect.DeepEqual(got, want) {
+		t.Errorf("snapshots %q, want %q", got, want)
+	}
+	if _, _, err = s.Save("r1", JSON, []byte(`{"a":`), start); err == nil {
+		t.Error("Save() of invalid JSON succeeded")
+	}
+}
+
+func TestSameSecond(t *testing.T) {
+	s := testStore(t)
+	defer os.RemoveAll(s.Dir)
+
+	// The hashes of these don't sort in the order they're saved in.
+	configs := []string{`{"n":1}`, `{"n":2}`, `{"n":3}`, `{"n":4}`, `{"n":5}`}
+	saved := save(t, s, start, 0, configs...)
+	snaps, err := s.List("r1", JSON)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, snap := range snaps {
+		got = append(got, snap.File)
+	}
+	var want []string
+	for _, snap := range saved {
+		want = append(want, snap.File)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("List() = %q, want %q", got, want)
+	}
+	latest, err := s.Latest("r1", JSON)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if latest.File != saved[len(saved)-1].File {
+		t.Errorf("Latest() = %s, want %s", latest.File, saved[len(saved)-1].File)
+	}
+	// The newest is kept.
+	if _, err = s.Prune("r1", JSON, Retention{Keep: 1}, start); err != nil {
+		t.Fatal(err)
+	}
+	if snaps, err = s.List("r1", JSON); err != nil || len(snaps) != 1 || snaps[0].File != latest.File {
+		t.Errorf("after Prune(), List() = %v, %v, want only %s", snaps, err, latest.File)
+	}
+}
+
+func TestLatest(t *testing.T) {
+	s := testStore(t)
+	defer os.RemoveAll(s.Dir)
+
+	if _, err := s.Latest("r1", JSON); err == nil {
+		t.Error("Latest() with no snapshots succeeded")
+	}
+	save(t, s, start, time.Minute, `{"a":1}`, `{"a":2}`)
+	// Other kinds, devices and files are left alone.
+	if _, _, err := s.Save("r1", CLI, []byte("!! IOS XR\nhostname r1\n"), start.Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := s.Save("r2", JSON, []byte(`{"a":3}`), start.Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(s.Dir, "r1", "notes.json"), []byte("{}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	latest, err := s.Latest("r1", JSON)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := contents(t, s, []Snapshot{latest}); got[0] != "{\n  \"a\": 2\n}\n" {
+		t.Errorf("Latest() = %q, want the second snapshot", got[0])
+	}
+	if !latest.Time.Equal(start.Add(time.Minute)) || latest.Device != "r1" || latest.Kind != JSON {
+		t.Errorf("Latest() = %+v, want r1 json at %v", latest, start.Add(time.Minute))
+	}
+}
+
+func TestPrune(t *testing.T) {
+	tests := []struct {
+		name string
+		r    Retention
+		// now is in hours after the first of the five snapshots, an
+		// hour apart.
+		now  int
+		kept []string
+	}{
+		{name: "no limits", now: 100, kept: []string{"1", "2", "3", "4", "5"}},
+		{name: "keep", r: Retention{Keep: 2}, now: 4, kept: []string{"4", "5"}},
+		{name: "max age", r: Retention{MaxAge: 90 * time.Minute}, now: 4, kept: []string{"4", "5"}},
+		// Whichever limit removes more wins.
+		{name: "both", r: Retention{Keep: 3, MaxAge: 150 * time.Minute}, now: 4, kept: []string{"3", "4", "5"}},
+		{name: "both, age first", r: Retention{Keep: 3, MaxAge: 30 * time.Minute}, now: 4, kept: []string{"5"}},
+		// The newest is kept, however old.
+		{name: "all too old", r: Retention{MaxAge: time.Hour}, now: 100, kept: []string{"5"}},
+	}
+	for _, tt := range tests {
+		s := testStore(t)
+		save(t, s, start, time.Hour, `"1"`, `"2"`, `"3"`, `"4"`, `"5"`)
+		removed, err := s.Prune("r1", JSON, tt.r, start.Add(time.Duration(tt.now)*time.Hour))
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		snaps, err := s.List("r1", JSON)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var kept []string
+		for _, c := range contents(t, s, snaps) {
+			kept = append(kept, c[1:2])
+		}
+		if !reflect.DeepEqual(kept, tt.kept) {
+			t.Errorf("%s: kept %v, want %v", tt.name, kept, tt.kept)
+		}
+		if len(removed)+len(kept) != 5 {
+			t.Errorf("%s: Prune() returned %d snapshots, want %d", tt.name, len(removed), 5-len(kept))
+		}
+		os.RemoveAll(s.Dir)
+	}
+}
+
+func TestCleanConfig(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{
+			in:   "Mon Jun 10 14:43:28.123 UTC\nBuilding configuration...\n!! IOS XR Configuration 6.5.2\n!! Last configuration change at Mon Jun 10 14:40:01 2019 by admin\n!\nhostname r1\nend\n",
+			want: "!! IOS XR Configuration 6.5.2\n!\nhostname r1\nend",
+		},
+		// Without a "!!" line, there's no banner to drop.
+		{in: "hostname r1\nend\n", want: "hostname r1\nend"},
+		{in: "", want: ""},
+	}
+	for _, tt := range tests {
+		if got := CleanConfig(tt.in); got != tt.want {
+			t.Errorf("CleanConfig(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+	// So two outputs of the same config are one snapshot.
+	s := testStore(t)
+	defer os.RemoveAll(s.Dir)
+	a := "Mon Jun 10 14:43:28.123 UTC\n!! IOS XR\n!! Last configuration change at Mon Jun 10 14:40:01 2019 by admin\nhostname r1\n"
+	b := "Tue Jun 11 02:00:00.456 UTC\n!! IOS XR\n!! Last configuration change at Mon Jun 10 14:40:01 2019 by admin\nhostname r1\n"
+	if _, _, err := s.Save("r1", CLI, []byte(a), start); err != nil {
+		t.Fatal(err)
+	}
+	if _, isNew, err := s.Save("r1", CLI, []byte(b), start.Add(time.Hour)); err != nil || isNew {
+		t.Errorf("Save() of the same CLI config = %v, %v, want no new snapshot", isNew, err)
+	}
+}
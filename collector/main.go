@@ -0,0 +1,304 @@
+/*
+collector subscribes to the telemetry streams of many routers at once and
+serves the numeric leaves of their messages as Prometheus metrics on
+/metrics, with the keys of each entry as labels, for a Prometheus server to
+scrape and Grafana to graph.
+
+Every device gets a session per subscription, its own from the inventory or
+-subs, kept up until the collector is interrupted: a session that fails or
+ends is set up again, after a pause that doubles with each failure in a
+row.
+
+	./collector -group all -subs COUNTERS,LLDP -enc gpb -listen :9273
+*/
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"time"
+
+	proto "github.com/golang/protobuf/proto"
+	"github.com/nleiva/clus2019/gpb"
+	"github.com/nleiva/clus2019/inventory"
+	"github.com/nleiva/clus2019/mdt"
+	"github.com/nleiva/clus2019/record"
+	xr "github.com/nleiva/xrgrpc"
+	"github.com/nleiva/xrgrpc/proto/telemetry"
+	"github.com/pkg/errors"
+)
+
+func main() {
+	// Subs options; for the devices without subscriptions in the inventory
+	p := flag.String("subs", "LLDP", "Telemetry Subscriptions, comma-separated")
+	// Encoding option; JSON has no keys to label metrics with
+	enc := flag.String("enc", "gpbkv", "Encoding: 'gpb' or 'gpbkv'")
+	protos := flag.String("proto", "", "Directory of .proto files for more encoding paths")
+	listen := flag.String("listen", ":9273", "Address to serve /metrics on")
+	prefix := flag.String("prefix", "xr", "Prefix of the metric names")
+	expire := flag.Duration("expire", 0, "Drop series not updated for this long; 0 keeps them")
+	// Pauses before setting up a session again
+	backoff := flag.Duration("backoff", time.Second, "Pause after the first failure of a session")
+	maxBackoff := flag.Duration("max-backoff", 2*time.Minute, "Longest pause between attempts to set up a session")
+	replay := flag.String("replay", "", "Serve the messages saved in this file instead of subscribing")
+	speed := flag.Float64("speed", 1, "Pace of -replay: 1 as recorded, 10 ten times faster, 0 without pauses")
+	// Target devices; defaults to every device in "inventory.json"
+	var tg inventory.Target
+	tg.AddFlags(flag.CommandLine)
+	flag.Parse()
+	selectAll(&tg)
+
+	mape := map[string]int64{
+		"gpb":   2,
+		"gpbkv": 3,
+	}
+	e, ok := mape[*enc]
+	if !ok {
+		log.Fatalf("encoding option '%v' not supported", *enc)
+	}
+
+	if *protos != "" {
+		n, err := gpb.Default.Load(*protos)
+		if err != nil {
+			log.Fatalf("could not load the messages of more encoding paths: %v", err)
+		}
+		log.Printf("loaded the messages of %d encoding paths from %s", n, *protos)
+	}
+
+	prom := mdt.NewPrometheus(*prefix, *expire)
+	http.Handle("/metrics", prom)
+	go func() {
+		log.Fatal(http.ListenAndServe(*listen, nil))
+	}()
+	log.Printf("serving metrics on %s/metrics", *listen)
+	h := &handler{prom: prom, unknown: make(map[string]bool)}
+
+	// Sessions run until the collector is interrupted, with no deadline.
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, os.Interrupt)
+	go func() {
+		<-c
+		log.Printf("stopping the collector")
+		cancel()
+	}()
+
+	if *replay != "" {
+		// Nothing is sent on the error channel of a replay.
+		ch, _, err := record.Replay(ctx, *replay, *speed)
+		if err != nil {
+			log.Fatalf("could not replay the messages: %v", err)
+		}
+		for b := range ch {
+			h.handle(*replay, b)
+		}
+		if ctx.Err() != nil {
+			return
+		}
+		// A replay leaves its metrics for scraping until interrupted.
+		log.Printf("replayed %s, serving its metrics until interrupted", *replay)
+		<-ctx.Done()
+		return
+	}
+
+	devices, err := tg.Devices()
+	if err != nil {
+		log.Fatalf("could not select a device, %v", err)
+	}
+	var wg sync.WaitGroup
+	for _, d := range devices {
+		subs := d.Subscriptions
+		if len(subs) == 0 {
+			subs = strings.Split(*p, ",")
+		}
+		for i, sub := range subs {
+			s := &session{
+				device: d,
+				subs:   strings.TrimSpace(sub),
+				enc:    e,
+				id:     int64(i + 1),
+				min:    *backoff,
+				max:    *maxBackoff,
+				h:      h,
+			}
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				s.run(ctx)
+			}()
+		}
+	}
+	log.Printf("collecting from %d devices", len(devices))
+	wg.Wait()
+}
+
+// selectAll targets every device unless -device or -group were given.
+func selectAll(tg *inventory.Target) {
+	set := false
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == "device" || f.Name == "group" {
+			set = true
+		}
+	})
+	if !set {
+		tg.Group = "all"
+	}
+}
+
+// handler decodes the messages of every session into the metrics.
+type handler struct {
+	prom *mdt.Prometheus
+
+	mu      sync.Mutex
+	unknown map[string]bool
+}
+
+// handle decodes a message from a router or a recording, from.
+func (h *handler) handle(from string, b []byte) {
+	message := new(telemetry.Telemetry)
+	if err := proto.Unmarshal(b, message); err != nil {
+		log.Printf("could not unmarshal a message from %v: %v", from, err)
+		return
+	}
+	rows, err := mdt.Flatten(message, gpb.Default)
+	switch {
+	case errors.Cause(err) == gpb.ErrUnknownPath:
+		// Report each path once, rather than for every message.
+		path := message.GetEncodingPath()
+		h.mu.Lock()
+		seen := h.unknown[path]
+		h.unknown[path] = true
+		h.mu.Unlock()
+		if !seen {
+			log.Printf("no messages for path %v, load its .proto file with -proto", path)
+		}
+		return
+	case err != nil:
+		log.Printf("could not decode a message from %v: %v", from, err)
+		return
+	}
+	h.prom.Write(rows)
+}
+
+// session is a subscription to a device.
+type session struct {
+	device inventory.Device
+	subs   string
+	enc    int64
+	// id is the ID of the subscription request.
+	id int64
+	// min and max bound the pause before setting the session up again.
+	min, max time.Duration
+	h        *handler
+	// attempts counts the times the session was set up.
+	attempts uint64
+	// sleep pauses between attempts; a timer unless a test says
+	// otherwise.
+	sleep func(ctx context.Context, d time.Duration) bool
+}
+
+// run keeps the session up until ctx is done, pausing between attempts
+// as backoff says.
+func (s *session) run(ctx context.Context) {
+	sleep := s.sleep
+	if sleep == nil {
+		sleep = pause
+	}
+	var wait time.Duration
+	for {
+		n, err := s.subscribe(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		wait = backoff(wait, n, s.min, s.max)
+		log.Printf("subscription %s to %s ended after %d messages: %v; trying again in %v", s.subs, s.device.Name, n, err, wait)
+		if !sleep(ctx, wait) {
+			return
+		}
+	}
+}
+
+// backoff returns the pause after an attempt that got n messages, given
+// the previous pause, none for the first attempt. The pause doubles with
+// each failure in a row up to max, and starts over from min once an
+// attempt gets messages through.
+func backoff(prev time.Duration, n int, min, max time.Duration) time.Duration {
+	if n > 0 || prev == 0 {
+		return min
+	}
+	if prev *= 2; prev > max {
+		prev = max
+	}
+	return prev
+}
+
+// pause waits for d, and reports whether ctx is still live.
+func pause(ctx context.Context, d time.Duration) bool {
+	select {
+	case <-ctx.Done():
+		return false
+	case <-time.After(d):
+		return true
+	}
+}
+
+// subscribe sets up the session and handles its messages until it fails
+// or ends, and returns how many it got.
+func (s *session) subscribe(ctx context.Context) (int, error) {
+	s.attempts++
+	s.status(false)
+	defer s.status(false)
+	router, err := s.device.Router()
+	if err != nil {
+		return 0, errors.Wrap(err, "could not build a router")
+	}
+	conn, _, err := xr.Connect(*router)
+	if err != nil {
+		return 0, errors.Wrapf(err, "could not setup a client connection to %s", router.Host)
+	}
+	defer conn.Close()
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	ch, ech, err := xr.GetSubscription(ctx, conn, s.subs, s.id, s.enc)
+	if err != nil {
+		return 0, errors.Wrap(err, "could not setup Telemetry Subscription")
+	}
+	log.Printf("subscribed to %s on %s", s.subs, s.device.Name)
+
+	n := 0
+	for {
+		select {
+		case b, ok := <-ch:
+			if !ok {
+				return n, errors.New("the router ended the subscription")
+			}
+			n++
+			s.h.handle(s.device.Name, b)
+			s.status(true)
+		case err := <-ech:
+			return n, err
+		case <-ctx.Done():
+			return n, ctx.Err()
+		}
+	}
+}
+
+// status serves the state of the session as metrics, labeled with the
+// device and the subscription: collector_up is 1 while the session gets
+// messages, and collector_attempts counts the times it was set up.
+func (s *session) status(up bool) {
+	s.h.prom.Write([]mdt.Row{{
+		Path:   "collector",
+		Node:   s.device.Name,
+		Keys:   map[string]string{"subscription": s.subs},
+		Fields: map[string]interface{}{"up": up, "attempts": s.attempts},
+	}})
+}
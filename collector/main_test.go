@@ -0,0 +1,217 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"io/ioutil"
+	"net"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nleiva/clus2019/inventory"
+	"github.com/nleiva/clus2019/mdt"
+	"github.com/nleiva/clus2019/xrmock"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/status"
+)
+
+func TestBackoff(t *testing.T) {
+	const min, max = time.Second, 5 * time.Second
+	tests := []struct {
+		prev time.Duration
+		n    int
+		want time.Duration
+	}{
+		// The first attempt, whatever it got.
+		{prev: 0, n: 0, want: min},
+		{prev: 0, n: 10, want: min},
+		{prev: min, n: 0, want: 2 * min},
+		{prev: 2 * min, n: 0, want: 4 * min},
+		{prev: 4 * min, n: 0, want: max},
+		{prev: max, n: 0, want: max},
+		// Messages got through: start over.
+		{prev: max, n: 1, want: min},
+		{prev: 2 * min, n: 3, want: min},
+	}
+	for _, tt := range tests {
+		if got := backoff(tt.prev, tt.n, min, max); got != tt.want {
+			t.Errorf("backoff(%v, %d) = %v, want %v", tt.prev, tt.n, got, tt.want)
+		}
+	}
+}
+
+// dropper lets through the number of messages its plan gives each
+// subscription, in turn, then drops the stream; 0 fails it right away.
+type dropper struct {
+	plan []int
+	// wait is called before a stream that got messages is dropped.
+	wait func()
+
+	mu   sync.Mutex
+	subs int
+}
+
+func (d *dropper) intercept(srv interface{}, ss grpc.ServerStream, _ *grpc.StreamServerInfo, h grpc.StreamHandler) error {
+	d.mu.Lock()
+	n := 0
+	if d.subs < len(d.plan) {
+		n = d.plan[d.subs]
+	}
+	d.subs++
+	d.mu.Unlock()
+	if n == 0 {
+		return status.Error(codes.Unavailable, "no subscription")
+	}
+	return h(srv, &limitStream{ServerStream: ss, left: n, d: d})
+}
+
+type limitStream struct {
+	grpc.ServerStream
+	left int
+	d    *dropper
+}
+
+func (s *limitStream) SendMsg(m interface{}) error {
+	if s.left == 0 {
+		s.d.wait()
+		return status.Error(codes.Unavailable, "stream dropped")
+	}
+	s.left--
+	return s.ServerStream.SendMsg(m)
+}
+
+// serve starts the mock router, looping its recordings, behind d, and
+// returns the device to reach it and a function that stops it.
+func serve(t *testing.T, d *dropper) (inventory.Device, func()) {
+	t.Helper()
+	cert, key, err := xrmock.SelfSigned()
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir, err := ioutil.TempDir("", "collector")
+	if err != nil {
+		t.Fatal(err)
+	}
+	file := filepath.Join(dir, "mock.pem")
+	if err = ioutil.WriteFile(file, cert, 0600); err != nil {
+		t.Fatal(err)
+	}
+	tc, err := tls.X509KeyPair(cert, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := grpc.NewServer(grpc.Creds(credentials.NewServerTLSFromCert(&tc)), grpc.StreamInterceptor(d.intercept))
+	mock := &xrmock.Server{
+		Telemetry: "../input/mock/telemetry",
+		Loop:      true,
+		Username:  "cisco",
+		Password:  "cisco",
+	}
+	mock.Register(srv)
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go srv.Serve(lis)
+	dev := inventory.Device{
+		Name:     "mock",
+		Host:     lis.Addr().String(),
+		Cert:     file,
+		Username: "cisco",
+		Password: "cisco",
+		Timeout:  5,
+	}
+	return dev, func() {
+		srv.Stop()
+		os.RemoveAll(dir)
+	}
+}
+
+// metric returns the value of the series of name for the session.
+func metric(p *mdt.Prometheus, name string) string {
+	rec := httptest.NewRecorder()
+	p.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	prefix := name + `{router="mock",subscription="LLDP"} `
+	for _, line := range strings.Split(rec.Body.String(), "\n") {
+		if strings.HasPrefix(line, prefix) {
+			return strings.TrimPrefix(line, prefix)
+		}
+	}
+	return ""
+}
+
+func TestSession(t *testing.T) {
+	prom := mdt.NewPrometheus("xr", 0)
+	// Two failures, a stream that gets messages through and is dropped,
+	// and two failures again.
+	d := &dropper{plan: []int{0, 0, 3, 0, 0}}
+	// The session is up while it gets messages.
+	d.wait = func() {
+		deadline := time.Now().Add(5 * time.Second)
+		for metric(prom, "xr_collector_up") != "1" {
+			if time.Now().After(deadline) {
+				t.Error("collector_up isn't 1 while the stream is up")
+				return
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+	dev, stop := serve(t, d)
+	defer stop()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var waits []time.Duration
+	s := &session{
+		device: dev,
+		subs:   "LLDP",
+		enc:    3,
+		id:     1,
+		min:    time.Second,
+		max:    3 * time.Second,
+		h:      &handler{prom: prom, unknown: make(map[string]bool)},
+		sleep: func(_ context.Context, wait time.Duration) bool {
+			waits = append(waits, wait)
+			// Between attempts, the session is down and counted.
+			if up, n := metric(prom, "xr_collector_up"), metric(prom, "xr_collector_attempts"); up != "0" || n != strconv.Itoa(len(waits)) {
+				t.Errorf("after attempt %d: collector_up %s, collector_attempts %s", len(waits), up, n)
+			}
+			if len(waits) == len(d.plan) {
+				cancel()
+				return false
+			}
+			return true
+		},
+	}
+	done := make(chan struct{})
+	go func() {
+		s.run(ctx)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(20 * time.Second):
+		t.Fatal("the session is still running")
+	}
+
+	// The pause doubles with each failure, up to max, and starts over
+	// once messages got through.
+	want := []time.Duration{time.Second, 2 * time.Second, time.Second, 2 * time.Second, 3 * time.Second}
+	if !reflect.DeepEqual(waits, want) {
+		t.Errorf("paused for %v, want %v", waits, want)
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.subs != len(d.plan) {
+		t.Errorf("subscribed %d times, want %d", d.subs, len(d.plan))
+	}
+}
@@ -3,10 +3,12 @@ package main
 import (
 	"flag"
 	"fmt"
-	"io/ioutil"
 	"log"
 	"time"
 
+	"github.com/nleiva/clus2019/inventory"
+	"github.com/nleiva/clus2019/payload"
+	"github.com/nleiva/clus2019/yang"
 	xr "github.com/nleiva/xrgrpc"
 )
 
@@ -21,22 +23,53 @@ func main() {
 	defer timeTrack(time.Now())
 
 	// YANG config; defaults to "yangconfig.json"
-	ypath := flag.String("ypath", "../input/yangdelocconfig.json", "YANG path arguments")
+	ypath := flag.String("ypath", "../input/yangdelocconfig.json", "YANG config file or template (*.tmpl)")
+	// Template variables, e.g. "vars.json"
+	vars := flag.String("vars", "", "Variables file to render -ypath with")
+	// Print the payload instead of sending it
+	dryRun := flag.Bool("dry-run", false, "Show the payload for each device without sending it")
+	// YANG modules to validate the payload with; defaults to "../yang"
+	var yf yang.Flags
+	yf.AddFlags(flag.CommandLine)
+	// Target devices; defaults to "router2" from "inventory.json"
+	var tg inventory.Target
+	tg.AddFlags(flag.CommandLine)
 	flag.Parse()
 
+	devices, err := tg.Devices()
+	if err != nil {
+		log.Fatalf("could not select a device, %v", err)
+	}
+
+	// Get YANG config file to delete
+	src, err := payload.Open(*ypath, *vars)
+	if err != nil {
+		log.Fatalf("could not load the payload: %v\n", err)
+	}
+	schema, err := yf.Load(log.Printf)
+	if err != nil {
+		log.Fatalf("could not load the YANG modules: %v\n", err)
+	}
+	src.Check(schema, yang.Delete)
+	for _, d := range devices {
+		js, err := src.For(d)
+		if err != nil {
+			log.Fatalf("could not render the payload for %s: %v\n", d.Name, err)
+		}
+		if *dryRun {
+			fmt.Printf("\npayload for %s\n%s\n", d.Name, js)
+			continue
+		}
+		deleteConfig(d, string(js))
+	}
+}
+
+func deleteConfig(d inventory.Device, js string) {
 	// ID for the transaction.
 	var id int64 = 1
 
-	// Manually specify target parameters.
-	router, err := xr.BuildRouter(
-		xr.WithUsername("cisco"),
-		xr.WithPassword("cisco"),
-		//xr.WithHost("[2001:420:2cff:1204::5502:1]:57344"),
-		//xr.WithCert("../input/certificate/router1.pem"),
-		xr.WithHost("[2001:420:2cff:1204::5502:2]:57344"),
-		xr.WithCert("../input/certificate/router2.pem"),
-		xr.WithTimeout(5),
-	)
+	// Target parameters come from the inventory.
+	router, err := d.Router(xr.WithTimeout(5))
 	if err != nil {
 		log.Fatalf("could not build a router, %v", err)
 	}
@@ -48,14 +81,8 @@ func main() {
 	}
 	defer conn.Close()
 
-	// Get YANG config file to delete
-	js, err := ioutil.ReadFile(*ypath)
-	if err != nil {
-		log.Fatalf("could not read file: %v: %v\n", *ypath, err)
-	}
-
 	// Delete 'js' config on target
-	ri, err := xr.DeleteConfig(ctx, conn, string(js), id)
+	ri, err := xr.DeleteConfig(ctx, conn, js, id)
 	if err != nil {
 		log.Fatalf("failed to delete config from %s, %v", router.Host, err)
 	} else {
@@ -0,0 +1,326 @@
+/*
+Package dialout receives the model-driven telemetry routers dial out with,
+over TCP or gRPC MdtDialout, and sends it the way they do, for the mock
+router.
+
+Over TCP, IOS XR puts a 12-byte header in front of each message, with
+big-endian numbers: the type of the message (1 for data), its
+encapsulation (1 for GPB, 2 for JSON), the version of the header (1),
+flags (1 for a zlib-compressed message) and the length of the message.
+Over gRPC, messages come in the data of MdtDialoutArgs. Either way, a
+message is a telemetry.Telemetry, encoded, as a subscription gets them.
+*/
+package dialout
+
+import (
+	"bufio"
+	"bytes"
+	"compress/zlib"
+	"context"
+	"encoding/binary"
+	"io"
+	"io/ioutil"
+	"net"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/peer"
+)
+
+// The fields of the TCP header IOS XR sends.
+const (
+	headerLen  = 12
+	typeData   = 1
+	encapGPB   = 1
+	hdrVersion = 1
+	flagZlib   = 1
+)
+
+// maxMessage bounds the length of a message, so a corrupt header ends the
+// session instead of allocating gigabytes.
+const maxMessage = 64 << 20
+
+// Listen accepts dial-out sessions on addrs, each tcp://host:port or
+// grpc://host:port, and sends their messages on the first channel until
+// ctx is done, when it's closed. The channels are those of
+// xrgrpc.GetSubscription, so dial-out telemetry goes through the same code
+// as a subscription. A session that fails only ends, as routers dial
+// again, and is reported to logf, as is every session that starts; an
+// error on the second channel means a listener failed.
+func Listen(ctx context.Context, addrs []string, logf func(string, ...interface{})) (chan []byte, chan error, error) {
+	r := &receiver{ctx: ctx, ch: make(chan []byte), ech: make(chan error, len(addrs)), logf: logf}
+	var stops []func()
+	stopAll := func() {
+		for _, stop := range stops {
+			stop()
+		}
+	}
+	for _, a := range addrs {
+		a := a
+		scheme, hostport := split(a)
+		lis, err := net.Listen("tcp", hostport)
+		if err != nil {
+			stopAll()
+			return nil, nil, errors.Wrapf(err, "could not listen on %s", a)
+		}
+		switch scheme {
+		case "tcp":
+			stops = append(stops, func() { lis.Close() })
+			r.wg.Add(1)
+			go r.acceptTCP(lis)
+		case "grpc":
+			srv := grpc.NewServer(grpc.MaxRecvMsgSize(maxMessage))
+			RegisterGRPCMdtDialoutServer(srv, r)
+			stops = append(stops, srv.Stop)
+			r.wg.Add(1)
+			go func() {
+				defer r.wg.Done()
+				if err := srv.Serve(lis); err != nil && ctx.Err() == nil {
+					r.ech <- errors.Wrapf(err, "could not serve on %s", a)
+				}
+			}()
+		default:
+			lis.Close()
+			stopAll()
+			return nil, nil, errors.Errorf("dial-out address %s isn't tcp://host:port or grpc://host:port", a)
+		}
+	}
+	go func() {
+		<-ctx.Done()
+		stopAll()
+		r.wg.Wait()
+		close(r.ch)
+	}()
+	return r.ch, r.ech, nil
+}
+
+// split splits scheme://host:port.
+func split(addr string) (string, string) {
+	if i := strings.Index(addr, "://"); i >= 0 {
+		return addr[:i], addr[i+3:]
+	}
+	return "", addr
+}
+
+// receiver passes on the messages of the sessions of Listen.
+type receiver struct {
+	ctx  context.Context
+	ch   chan []byte
+	ech  chan error
+	logf func(string, ...interface{})
+	// wg counts the listeners and sessions, for ch to be closed after
+	// the last of them.
+	wg sync.WaitGroup
+}
+
+// send passes b on, unless ctx is done first.
+func (r *receiver) send(b []byte) bool {
+	select {
+	case r.ch <- b:
+		return true
+	case <-r.ctx.Done():
+		return false
+	}
+}
+
+// ended reports the end of a session, with how many messages it had.
+func (r *receiver) ended(from string, n int, err error) {
+	if r.ctx.Err() != nil {
+		return
+	}
+	if err == nil || err == io.EOF {
+		err = errors.New("the router closed it")
+	}
+	r.logf("dial-out session from %s ended after %d messages: %v", from, n, err)
+}
+
+func (r *receiver) acceptTCP(lis net.Listener) {
+	defer r.wg.Done()
+	for {
+		conn, err := lis.Accept()
+		if err != nil {
+			if r.ctx.Err() == nil {
+				r.ech <- errors.Wrapf(err, "could not accept on %s", lis.Addr())
+			}
+			return
+		}
+		r.wg.Add(1)
+		go func() {
+			defer r.wg.Done()
+			done := make(chan struct{})
+			defer close(done)
+			go func() {
+				select {
+				case <-r.ctx.Done():
+					conn.Close()
+				case <-done:
+				}
+			}()
+			from := conn.RemoteAddr().String()
+			r.logf("dial-out session from %s over TCP", from)
+			n, err := r.readTCP(conn)
+			conn.Close()
+			r.ended(from, n, err)
+		}()
+	}
+}
+
+// readTCP passes on the messages of a TCP session until it ends, and
+// returns how many there were.
+func (r *receiver) readTCP(conn net.Conn) (int, error) {
+	br := bufio.NewReader(conn)
+	var hdr [headerLen]byte
+	for n := 0; ; n++ {
+		if _, err := io.ReadFull(br, hdr[:]); err != nil {
+			return n, err
+		}
+		typ := binary.BigEndian.Uint16(hdr[0:])
+		encap := binary.BigEndian.Uint16(hdr[2:])
+		flags := binary.BigEndian.Uint16(hdr[6:])
+		l := binary.BigEndian.Uint32(hdr[8:])
+		switch {
+		case typ != typeData:
+			return n, errors.Errorf("message type %d isn't data", typ)
+		case encap != encapGPB:
+			return n, errors.Errorf("encapsulation %d not supported, only GPB", encap)
+		case l > maxMessage:
+			return n, errors.Errorf("message of %d bytes is too long", l)
+		}
+		b := make([]byte, l)
+		if _, err := io.ReadFull(br, b); err != nil {
+			return n, errors.Wrap(err, "truncated message")
+		}
+		if flags&flagZlib != 0 {
+			var err error
+			if b, err = inflate(b); err != nil {
+				return n, err
+			}
+		}
+		if !r.send(b) {
+			return n, r.ctx.Err()
+		}
+	}
+}
+
+func inflate(b []byte) ([]byte, error) {
+	zr, err := zlib.NewReader(bytes.NewReader(b))
+	if err != nil {
+		return nil, errors.Wrap(err, "could not decompress a message")
+	}
+	defer zr.Close()
+	out, err := ioutil.ReadAll(io.LimitReader(zr, maxMessage))
+	return out, errors.Wrap(err, "could not decompress a message")
+}
+
+// MdtDialout implements GRPCMdtDialoutServer.
+func (r *receiver) MdtDialout(stream GRPCMdtDialout_MdtDialoutServer) error {
+	r.wg.Add(1)
+	defer r.wg.Done()
+	from := "unknown"
+	if p, ok := peer.FromContext(stream.Context()); ok {
+		from = p.Addr.String()
+	}
+	r.logf("dial-out session from %s over gRPC", from)
+	n := 0
+	for {
+		args, err := stream.Recv()
+		if err == nil && args.GetErrors() != "" {
+			err = errors.Errorf("error triggered by remote host: %s", args.GetErrors())
+		}
+		if err != nil {
+			r.ended(from, n, err)
+			return nil
+		}
+		if len(args.GetData()) == 0 {
+			continue
+		}
+		if !r.send(args.GetData()) {
+			return r.ctx.Err()
+		}
+		n++
+	}
+}
+
+// Conn is the router's end of a dial-out session.
+type Conn interface {
+	// Send sends a message, an encoded telemetry.Telemetry.
+	Send(b []byte) error
+	// Close ends the session once the collector has what was sent.
+	Close() error
+}
+
+// Dial starts a dial-out session to the collector at addr,
+// tcp://host:port or grpc://host:port, as a router would. gRPC sessions
+// are without TLS, as no-tls in the router's destination group.
+func Dial(ctx context.Context, addr string) (Conn, error) {
+	scheme, hostport := split(addr)
+	switch scheme {
+	case "tcp":
+		var d net.Dialer
+		conn, err := d.DialContext(ctx, "tcp", hostport)
+		if err != nil {
+			return nil, errors.Wrapf(err, "could not dial out to %s", addr)
+		}
+		return &tcpConn{conn}, nil
+	case "grpc":
+		cc, err := grpc.DialContext(ctx, hostport, grpc.WithInsecure(), grpc.WithBlock())
+		if err != nil {
+			return nil, errors.Wrapf(err, "could not dial out to %s", addr)
+		}
+		stream, err := NewGRPCMdtDialoutClient(cc).MdtDialout(context.Background())
+		if err != nil {
+			cc.Close()
+			return nil, errors.Wrapf(err, "could not dial out to %s", addr)
+		}
+		return &grpcConn{cc: cc, stream: stream}, nil
+	}
+	return nil, errors.Errorf("dial-out address %s isn't tcp://host:port or grpc://host:port", addr)
+}
+
+type tcpConn struct {
+	conn net.Conn
+}
+
+func (c *tcpConn) Send(b []byte) error {
+	msg := make([]byte, headerLen+len(b))
+	binary.BigEndian.PutUint16(msg[0:], typeData)
+	binary.BigEndian.PutUint16(msg[2:], encapGPB)
+	binary.BigEndian.PutUint16(msg[4:], hdrVersion)
+	binary.BigEndian.PutUint32(msg[8:], uint32(len(b)))
+	copy(msg[headerLen:], b)
+	_, err := c.conn.Write(msg)
+	return errors.Wrap(err, "could not send a message")
+}
+
+func (c *tcpConn) Close() error {
+	return c.conn.Close()
+}
+
+type grpcConn struct {
+	cc     *grpc.ClientConn
+	stream GRPCMdtDialout_MdtDialoutClient
+	id     int64
+}
+
+func (c *grpcConn) Send(b []byte) error {
+	c.id++
+	return errors.Wrap(c.stream.Send(&MdtDialoutArgs{ReqId: c.id, Data: b}), "could not send a message")
+}
+
+// Close waits for the collector to end the stream, so it has every
+// message before the connection goes.
+func (c *grpcConn) Close() error {
+	err := c.stream.CloseSend()
+	for err == nil {
+		_, err = c.stream.Recv()
+	}
+	if err == io.EOF {
+		err = nil
+	}
+	if cerr := c.cc.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
@@ -0,0 +1,175 @@
+package dialout
+
+import (
+	"bytes"
+	"compress/zlib"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// frame is a message with the TCP header, as IOS XR sends it.
+func frame(typ, encap, flags uint16, b []byte) []byte {
+	hdr := make([]byte, headerLen)
+	binary.BigEndian.PutUint16(hdr[0:], typ)
+	binary.BigEndian.PutUint16(hdr[2:], encap)
+	binary.BigEndian.PutUint16(hdr[4:], hdrVersion)
+	binary.BigEndian.PutUint16(hdr[6:], flags)
+	binary.BigEndian.PutUint32(hdr[8:], uint32(len(b)))
+	return append(hdr, b...)
+}
+
+func deflate(b []byte) []byte {
+	var buf bytes.Buffer
+	zw := zlib.NewWriter(&buf)
+	zw.Write(b)
+	zw.Close()
+	return buf.Bytes()
+}
+
+func join(bs ...[]byte) []byte {
+	return bytes.Join(bs, nil)
+}
+
+func TestReadTCP(t *testing.T) {
+	tests := []struct {
+		name string
+		in   []byte
+		want [][]byte
+		err  string
+	}{
+		{
+			name: "messages",
+			in:   join(frame(typeData, encapGPB, 0, []byte("one")), frame(typeData, encapGPB, 0, []byte("two"))),
+			want: [][]byte{[]byte("one"), []byte("two")},
+			err:  "EOF",
+		},
+		{
+			name: "zlib",
+			in:   frame(typeData, encapGPB, flagZlib, deflate([]byte("compressed"))),
+			want: [][]byte{[]byte("compressed")},
+			err:  "EOF",
+		},
+		{
+			// The messages before a bad header are passed on.
+			name: "type",
+			in:   join(frame(typeData, encapGPB, 0, []byte("one")), frame(2, encapGPB, 0, nil)),
+			want: [][]byte{[]byte("one")},
+			err:  "message type 2 isn't data",
+		},
+		{
+			name: "json",
+			in:   frame(typeData, 2, 0, []byte("{}")),
+			err:  "encapsulation 2 not supported, only GPB",
+		},
+		{
+			name: "short header",
+			in:   frame(typeData, encapGPB, 0, nil)[:8],
+			err:  "unexpected EOF",
+		},
+		{
+			name: "length",
+			in:   join(frame(typeData, encapGPB, 0, nil)[:8], []byte{0x10, 0, 0, 0}),
+			err:  "message of 268435456 bytes is too long",
+		},
+		{
+			name: "truncated",
+			in:   frame(typeData, encapGPB, 0, []byte("one"))[:headerLen+1],
+			err:  "truncated message: unexpected EOF",
+		},
+		{
+			name: "not zlib",
+			in:   frame(typeData, encapGPB, flagZlib, []byte("plain")),
+			err:  "could not decompress a message",
+		},
+	}
+	for _, tt := range tests {
+		r := &receiver{ctx: context.Background(), ch: make(chan []byte, 10)}
+		client, server := net.Pipe()
+		go func() {
+			client.Write(tt.in)
+			client.Close()
+		}()
+		n, err := r.readTCP(server)
+		server.Close()
+		close(r.ch)
+		var got [][]byte
+		for b := range r.ch {
+			got = append(got, b)
+		}
+		if n != len(tt.want) || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: readTCP() passed on %d, %q, want %q", tt.name, n, got, tt.want)
+		}
+		if err == nil || !strings.HasPrefix(err.Error(), tt.err) {
+			t.Errorf("%s: readTCP() = %v, want %s", tt.name, err, tt.err)
+		}
+	}
+}
+
+func TestSend(t *testing.T) {
+	client, server := net.Pipe()
+	go func() {
+		(&tcpConn{client}).Send([]byte("one"))
+		client.Close()
+	}()
+	var buf bytes.Buffer
+	io.Copy(&buf, server)
+	if got, want := buf.Bytes(), frame(typeData, encapGPB, 0, []byte("one")); !bytes.Equal(got, want) {
+		t.Errorf("Send() wrote % x, want % x", got, want)
+	}
+}
+
+func TestListen(t *testing.T) {
+	for _, scheme := range []string{"tcp", "grpc"} {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		logf := func(format string, args ...interface{}) {
+			t.Logf("%s: %s", scheme, fmt.Sprintf(format, args...))
+		}
+		// Dial needs the port Listen has, so pick a free one first.
+		lis, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		addr := scheme + "://" + lis.Addr().String()
+		lis.Close()
+		ch, _, err := Listen(ctx, []string{addr}, logf)
+		if err != nil {
+			t.Fatalf("%s: %v", scheme, err)
+		}
+		c, err := Dial(ctx, addr)
+		if err != nil {
+			t.Fatalf("%s: %v", scheme, err)
+		}
+		go func() {
+			for _, b := range []string{"one", "two"} {
+				c.Send([]byte(b))
+			}
+		}()
+		var got []string
+		for len(got) < 2 {
+			b, ok := <-ch
+			if !ok {
+				break
+			}
+			got = append(got, string(b))
+		}
+		if want := []string{"one", "two"}; !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: received %q, want %q", scheme, got, want)
+		}
+		cancel()
+		c.Close()
+		// ch is closed once the listener and sessions are gone.
+		for range ch {
+		}
+	}
+
+	if _, _, err := Listen(context.Background(), []string{"udp://127.0.0.1:0"}, nil); err == nil || !strings.Contains(err.Error(), "isn't tcp://host:port") {
+		t.Errorf("Listen() = %v, want a bad address", err)
+	}
+}
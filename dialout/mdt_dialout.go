@@ -0,0 +1,151 @@
+package dialout
+
+import (
+	"context"
+
+	proto "github.com/golang/protobuf/proto"
+	"google.golang.org/grpc"
+)
+
+// The message and service of mdt_dialout.proto, the gRPC dial-out of IOS
+// XR. They're written by hand, not generated: the build doesn't need
+// protoc. They follow the layout of protoc-gen-go and its grpc plugin, so
+// the proto package marshals them from the struct tags, and
+// TestMessages checks the tags against mdt_dialout.proto.
+
+// MdtDialoutArgs carries a telemetry message, encoded as the
+// telemetry.Telemetry of the subscription, or the errors of the session.
+type MdtDialoutArgs struct {
+	ReqId                int64    `protobuf:"varint,1,opt,name=ReqId,proto3" json:"ReqId,omitempty"`
+	Data                 []byte   `protobuf:"bytes,2,opt,name=data,proto3" json:"data,omitempty"`
+	Errors               string   `protobuf:"bytes,3,opt,name=errors,proto3" json:"errors,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *MdtDialoutArgs) Reset()         { *m = MdtDialoutArgs{} }
+func (m *MdtDialoutArgs) String() string { return proto.CompactTextString(m) }
+func (*MdtDialoutArgs) ProtoMessage()    {}
+
+func (m *MdtDialoutArgs) GetReqId() int64 {
+	if m != nil {
+		return m.ReqId
+	}
+	return 0
+}
+
+func (m *MdtDialoutArgs) GetData() []byte {
+	if m != nil {
+		return m.Data
+	}
+	return nil
+}
+
+func (m *MdtDialoutArgs) GetErrors() string {
+	if m != nil {
+		return m.Errors
+	}
+	return ""
+}
+
+// GRPCMdtDialoutClient is the client API for the gRPCMdtDialout service,
+// the router's end.
+type GRPCMdtDialoutClient interface {
+	MdtDialout(ctx context.Context, opts ...grpc.CallOption) (GRPCMdtDialout_MdtDialoutClient, error)
+}
+
+type gRPCMdtDialoutClient struct {
+	cc *grpc.ClientConn
+}
+
+// NewGRPCMdtDialoutClient returns a client for the gRPCMdtDialout service
+// on cc.
+func NewGRPCMdtDialoutClient(cc *grpc.ClientConn) GRPCMdtDialoutClient {
+	return &gRPCMdtDialoutClient{cc}
+}
+
+func (c *gRPCMdtDialoutClient) MdtDialout(ctx context.Context, opts ...grpc.CallOption) (GRPCMdtDialout_MdtDialoutClient, error) {
+	stream, err := c.cc.NewStream(ctx, &_GRPCMdtDialout_serviceDesc.Streams[0], "/mdt_dialout.gRPCMdtDialout/MdtDialout", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &gRPCMdtDialoutMdtDialoutClient{stream}
+	return x, nil
+}
+
+// GRPCMdtDialout_MdtDialoutClient sends telemetry to the collector.
+type GRPCMdtDialout_MdtDialoutClient interface {
+	Send(*MdtDialoutArgs) error
+	Recv() (*MdtDialoutArgs, error)
+	grpc.ClientStream
+}
+
+type gRPCMdtDialoutMdtDialoutClient struct {
+	grpc.ClientStream
+}
+
+func (x *gRPCMdtDialoutMdtDialoutClient) Send(m *MdtDialoutArgs) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *gRPCMdtDialoutMdtDialoutClient) Recv() (*MdtDialoutArgs, error) {
+	m := new(MdtDialoutArgs)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// GRPCMdtDialoutServer is the server API for the gRPCMdtDialout service,
+// the collector's end.
+type GRPCMdtDialoutServer interface {
+	MdtDialout(GRPCMdtDialout_MdtDialoutServer) error
+}
+
+// RegisterGRPCMdtDialoutServer registers srv on s.
+func RegisterGRPCMdtDialoutServer(s *grpc.Server, srv GRPCMdtDialoutServer) {
+	s.RegisterService(&_GRPCMdtDialout_serviceDesc, srv)
+}
+
+func _GRPCMdtDialout_MdtDialout_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(GRPCMdtDialoutServer).MdtDialout(&gRPCMdtDialoutMdtDialoutServer{stream})
+}
+
+// GRPCMdtDialout_MdtDialoutServer receives the telemetry of a router.
+type GRPCMdtDialout_MdtDialoutServer interface {
+	Send(*MdtDialoutArgs) error
+	Recv() (*MdtDialoutArgs, error)
+	grpc.ServerStream
+}
+
+type gRPCMdtDialoutMdtDialoutServer struct {
+	grpc.ServerStream
+}
+
+func (x *gRPCMdtDialoutMdtDialoutServer) Send(m *MdtDialoutArgs) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *gRPCMdtDialoutMdtDialoutServer) Recv() (*MdtDialoutArgs, error) {
+	m := new(MdtDialoutArgs)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+var _GRPCMdtDialout_serviceDesc = grpc.ServiceDesc{
+	ServiceName: "mdt_dialout.gRPCMdtDialout",
+	HandlerType: (*GRPCMdtDialoutServer)(nil),
+	Methods:     []grpc.MethodDesc{},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "MdtDialout",
+			Handler:       _GRPCMdtDialout_MdtDialout_Handler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
+	},
+	Metadata: "mdt_dialout.proto",
+}
@@ -0,0 +1,45 @@
+package dialout
+
+import (
+	"reflect"
+	"testing"
+
+	proto "github.com/golang/protobuf/proto"
+	"github.com/nleiva/clus2019/gpb"
+)
+
+// TestMessages checks the hand-written message against mdt_dialout.proto.
+func TestMessages(t *testing.T) {
+	f, err := gpb.ParseFile("mdt_dialout.proto")
+	if err != nil {
+		t.Fatal(err)
+	}
+	types := map[string]reflect.Type{
+		"mdt_dialout.MdtDialoutArgs": reflect.TypeOf(MdtDialoutArgs{}),
+	}
+	if len(f.Messages) != len(types) {
+		t.Errorf("mdt_dialout.proto has %d messages, want %d", len(f.Messages), len(types))
+	}
+	for _, d := range f.Messages {
+		typ, ok := types[d.FullName]
+		if !ok {
+			t.Errorf("no message %s", d.FullName)
+			continue
+		}
+		props := proto.GetProperties(typ)
+		for _, fd := range d.Fields {
+			var p *proto.Properties
+			for _, pp := range props.Prop {
+				if pp.OrigName == fd.Name {
+					p = pp
+				}
+			}
+			switch {
+			case p == nil:
+				t.Errorf("%s has no field %s", d.FullName, fd.Name)
+			case p.Tag != fd.Number || p.Repeated != fd.Repeated:
+				t.Errorf("%s.%s is field %d (repeated %v), want %d (repeated %v)", d.FullName, fd.Name, p.Tag, p.Repeated, fd.Number, fd.Repeated)
+			}
+		}
+	}
+}
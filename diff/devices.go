@@ -0,0 +1,82 @@
+package diff
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/nleiva/clus2019/inventory"
+	"github.com/nleiva/clus2019/runner"
+	"github.com/pkg/errors"
+)
+
+// Exit codes, as in diff(1).
+const (
+	ExitNoDrift = 0
+	ExitDrift   = 1
+	ExitTrouble = 2
+)
+
+// Drift counts the outcome of Devices.
+type Drift struct {
+	// Devices is the number of devices compared.
+	Devices int
+	// Drifted is the number whose running config doesn't match.
+	Drifted int
+	// Failed is the number that couldn't be compared.
+	Failed int
+}
+
+// Code returns the exit code for d: trouble wins over drift.
+func (d Drift) Code() int {
+	switch {
+	case d.Failed > 0:
+		return ExitTrouble
+	case d.Drifted > 0:
+		return ExitDrift
+	}
+	return ExitNoDrift
+}
+
+// Err describes the devices that drifted or failed, if any.
+func (d Drift) Err() error {
+	switch {
+	case d.Failed > 0:
+		return errors.Errorf("%d of %d devices failed, drift on %d", d.Failed, d.Devices, d.Drifted)
+	case d.Drifted > 0:
+		return errors.Errorf("drift on %d of %d devices", d.Drifted, d.Devices)
+	}
+	return nil
+}
+
+// Devices gets the running config of the top level containers of intended
+// from each device with r, compares it with intended and writes the
+// changes to w. A device that fails is reported with the others, rather
+// than stopping the comparison.
+func Devices(w io.Writer, r *runner.Runner, ds []inventory.Device, intended []byte, o Options) (Drift, error) {
+	d := Drift{Devices: len(ds)}
+	paths, err := Paths(intended)
+	if err != nil {
+		return d, err
+	}
+	for _, res := range r.Run(ds, runner.GetConfig(paths)) {
+		if res.Err != nil {
+			fmt.Fprintf(w, "\ncould not get the config from %s, %v\n", res.Host, res.Err)
+			d.Failed++
+			continue
+		}
+		cs, err := JSON([]byte(res.Output), intended, o)
+		if err != nil {
+			fmt.Fprintf(w, "\ncould not compare the config from %s, %v\n", res.Host, err)
+			d.Failed++
+			continue
+		}
+		if len(cs) == 0 {
+			fmt.Fprintf(w, "\nno drift on %s\n", res.Host)
+			continue
+		}
+		d.Drifted++
+		fmt.Fprintf(w, "\ndrift on %s: %s\n", res.Host, Summary(cs))
+		Print(w, cs)
+	}
+	return d, nil
+}
@@ -0,0 +1,42 @@
+package diff
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/nleiva/clus2019/runner"
+)
+
+func TestDrift(t *testing.T) {
+	tests := []struct {
+		d    Drift
+		code int
+		err  string
+	}{
+		{d: Drift{Devices: 2}, code: ExitNoDrift},
+		{d: Drift{Devices: 2, Drifted: 1}, code: ExitDrift, err: "drift on 1 of 2 devices"},
+		// A device that couldn't be checked isn't taken for one without
+		// drift, whatever the others did.
+		{d: Drift{Devices: 2, Failed: 1}, code: ExitTrouble, err: "1 of 2 devices failed, drift on 0"},
+		{d: Drift{Devices: 2, Drifted: 1, Failed: 1}, code: ExitTrouble, err: "1 of 2 devices failed, drift on 1"},
+	}
+	for _, tt := range tests {
+		if got := tt.d.Code(); got != tt.code {
+			t.Errorf("%+v: Code() = %d, want %d", tt.d, got, tt.code)
+		}
+		err := tt.d.Err()
+		if (err == nil) != (tt.err == "") || err != nil && err.Error() != tt.err {
+			t.Errorf("%+v: Err() = %v, want %q", tt.d, err, tt.err)
+		}
+	}
+}
+
+func TestDevicesInvalid(t *testing.T) {
+	var b bytes.Buffer
+	if _, err := Devices(&b, &runner.Runner{}, nil, []byte(`{"a":`), Options{}); err == nil {
+		t.Error("Devices() with invalid intended config succeeded")
+	}
+	if b.Len() != 0 {
+		t.Errorf("Devices() wrote %q", b.String())
+	}
+}
@@ -0,0 +1,429 @@
+package diff
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Kind is the type of a structural Change.
+type Kind int
+
+const (
+	// Added leaves are in the intended config but not in the running one.
+	Added Kind = iota
+	// Removed leaves are in the running config but not in the intended one.
+	Removed
+	// Changed leaves are in both with different values.
+	Changed
+)
+
+func (k Kind) String() string {
+	switch k {
+	case Added:
+		return "+"
+	case Removed:
+		return "-"
+	default:
+		return "~"
+	}
+}
+
+// Change is a leaf that differs between two YANG JSON documents. Path
+// identifies list entries by their keys, e.g.
+// "openconfig-interfaces:interfaces/interface[name=Loopback201]/config/enabled".
+type Change struct {
+	Kind Kind
+	Path string
+	Old  interface{}
+	New  interface{}
+}
+
+func (c Change) String() string {
+	switch c.Kind {
+	case Added:
+		return fmt.Sprintf("+ %s: %s", c.Path, show(c.New))
+	case Removed:
+		return fmt.Sprintf("- %s: %s", c.Path, show(c.Old))
+	default:
+		return fmt.Sprintf("~ %s: %s -> %s", c.Path, show(c.Old), show(c.New))
+	}
+}
+
+func show(v interface{}) string {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(b)
+}
+
+// DefaultKeys are the leaf names tried, in order, to match list entries.
+var DefaultKeys = []string{"name", "index", "ip", "id", "prefix", "sequence-id", "address", "key"}
+
+// Options control how documents are compared.
+type Options struct {
+	// Keys are the leaf names that identify list entries; DefaultKeys
+	// when empty. Lists with no common key are compared by position.
+	Keys []string
+	// Strict reports config only present in the running document as
+	// Removed. Otherwise the intended document is treated as a merge and
+	// anything it doesn't mention is ignored.
+	Strict bool
+}
+
+// Decode parses a YANG JSON document, unwrapping the "data" object
+// GetConfig returns. An empty document, as GetConfig returns when none of
+// the paths are configured, is an empty object.
+func Decode(b []byte) (map[string]interface{}, error) {
+	if len(bytes.TrimSpace(b)) == 0 {
+		return map[string]interface{}{}, nil
+	}
+	d := json.NewDecoder(bytes.NewReader(b))
+	d.UseNumber()
+	var doc map[string]interface{}
+	if err := d.Decode(&doc); err != nil {
+		return nil, errors.Wrap(err, "could not parse the JSON document")
+	}
+	if data, ok := doc["data"].(map[string]interface{}); ok && len(doc) == 1 {
+		return data, nil
+	}
+	if doc == nil {
+		doc = map[string]interface{}{}
+	}
+	return doc, nil
+}
+
+// Paths builds the GetConfig argument that retrieves the top level
+// containers of a YANG JSON document, as in "yangocpaths.json".
+func Paths(doc []byte) (string, error) {
+	d, err := Decode(doc)
+	if err != nil {
+		return "", err
+	}
+	paths := make(map[string][]interface{}, len(d))
+	for k := range d {
+		paths[k] = []interface{}{nil}
+	}
+	b, err := json.Marshal(paths)
+	return string(b), errors.Wrap(err, "could not marshall into JSON")
+}
+
+// JSON compares a running and an intended YANG JSON document.
+func JSON(running, intended []byte, o Options) ([]Change, error) {
+	r, err := Decode(running)
+	if err != nil {
+		return nil, errors.Wrap(err, "running config")
+	}
+	i, err := Decode(intended)
+	if err != nil {
+		return nil, errors.Wrap(err, "intended config")
+	}
+	return Trees(r, i, o), nil
+}
+
+// Trees compares two decoded documents.
+func Trees(running, intended map[string]interface{}, o Options) []Change {
+	if len(o.Keys) == 0 {
+		o.Keys = DefaultKeys
+	}
+	c := &comparer{opts: o}
+	c.object("", running, intended)
+	return c.changes
+}
+
+type comparer struct {
+	opts    Options
+	changes []Change
+}
+
+func join(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "/" + name
+}
+
+func (c *comparer) add(k Kind, path string, old, new interface{}) {
+	c.changes = append(c.changes, Change{Kind: k, Path: path, Old: old, New: new})
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	ks := make([]string, 0, len(m))
+	for k := range m {
+		ks = append(ks, k)
+	}
+	sort.Strings(ks)
+	return ks
+}
+
+func (c *comparer) object(path string, r, i map[string]interface{}) {
+	for _, k := range sortedKeys(i) {
+		rv, ok := r[k]
+		if !ok {
+			c.leaves(Added, join(path, k), i[k])
+			continue
+		}
+		c.value(join(path, k), rv, i[k])
+	}
+	if !c.opts.Strict {
+		return
+	}
+	for _, k := range sortedKeys(r) {
+		if _, ok := i[k]; !ok {
+			c.leaves(Removed, join(path, k), r[k])
+		}
+	}
+}
+
+func (c *comparer) value(path string, r, i interface{}) {
+	switch iv := i.(type) {
+	case map[string]interface{}:
+		if rv, ok := r.(map[string]interface{}); ok {
+			c.object(path, rv, iv)
+			return
+		}
+	case []interface{}:
+		if rv, ok := r.([]interface{}); ok {
+			c.list(path, rv, iv)
+			return
+		}
+	default:
+		if !isContainer(r) {
+			if scalar(r) != scalar(i) {
+				c.add(Changed, path, r, i)
+			}
+			return
+		}
+	}
+	// Different shapes, e.g. a leaf that became a container.
+	c.add(Changed, path, r, i)
+}
+
+func isContainer(v interface{}) bool {
+	switch v.(type) {
+	case map[string]interface{}, []interface{}:
+		return true
+	}
+	return false
+}
+
+// scalar normalizes a leaf value, so 64-bit integers encoded as strings
+// (RFC 7951) match their numeric form.
+func scalar(v interface{}) string {
+	switch t := v.(type) {
+	case nil:
+		return "null"
+	case json.Number:
+		return t.String()
+	case string:
+		return t
+	default:
+		return fmt.Sprint(t)
+	}
+}
+
+// leaves reports every leaf under v with kind k.
+func (c *comparer) leaves(k Kind, path string, v interface{}) {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		if len(t) == 0 {
+			c.addLeaf(k, path, t)
+		}
+		for _, n := range sortedKeys(t) {
+			c.leaves(k, join(path, n), t[n])
+		}
+	case []interface{}:
+		if key := c.listKey(t, nil); key != "" {
+			for _, e := range t {
+				m := e.(map[string]interface{})
+				c.leaves(k, entryPath(path, key, m[key]), m)
+			}
+			return
+		}
+		c.addLeaf(k, path, t)
+	default:
+		c.addLeaf(k, path, t)
+	}
+}
+
+func (c *comparer) addLeaf(k Kind, path string, v interface{}) {
+	if k == Added {
+		c.add(k, path, nil, v)
+	} else {
+		c.add(k, path, v, nil)
+	}
+}
+
+func entryPath(path, key string, val interface{}) string {
+	return fmt.Sprintf("%s[%s=%s]", path, key, scalar(val))
+}
+
+// listKey returns the first candidate key every entry of a and b has, or
+// "" if the lists aren't lists of objects with a common key.
+func (c *comparer) listKey(a, b []interface{}) string {
+	if len(a)+len(b) == 0 {
+		return ""
+	}
+	for _, key := range c.opts.Keys {
+		ok := true
+		for _, l := range [][]interface{}{a, b} {
+			for _, e := range l {
+				m, isObj := e.(map[string]interface{})
+				if !isObj {
+					return ""
+				}
+				if v, has := m[key]; !has || isContainer(v) {
+					ok = false
+				}
+			}
+		}
+		if ok {
+			return key
+		}
+	}
+	return ""
+}
+
+// index groups list entries by key. Entries that share a key are merged,
+// as a device would do with them.
+func index(l []interface{}, key string) ([]string, map[string]map[string]interface{}) {
+	var order []string
+	idx := make(map[string]map[string]interface{})
+	for _, e := range l {
+		m := e.(map[string]interface{})
+		k := scalar(m[key])
+		if prev, ok := idx[k]; ok {
+			idx[k] = merge(prev, m)
+			continue
+		}
+		order = append(order, k)
+		idx[k] = m
+	}
+	return order, idx
+}
+
+// merge deep-merges b into a copy of a.
+func merge(a, b map[string]interface{}) map[string]interface{} {
+	out := make(map[string]interface{}, len(a))
+	for k, v := range a {
+		out[k] = v
+	}
+	for k, bv := range b {
+		am, aok := out[k].(map[string]interface{})
+		bm, bok := bv.(map[string]interface{})
+		if aok && bok {
+			out[k] = merge(am, bm)
+			continue
+		}
+		al, aok := out[k].([]interface{})
+		bl, bok := bv.([]interface{})
+		if aok && bok {
+			out[k] = append(append([]interface{}{}, al...), bl...)
+			continue
+		}
+		out[k] = bv
+	}
+	return out
+}
+
+func (c *comparer) list(path string, r, i []interface{}) {
+	key := c.listKey(r, i)
+	if key == "" {
+		c.positional(path, r, i)
+		return
+	}
+	rOrder, rIdx := index(r, key)
+	iOrder, iIdx := index(i, key)
+	for _, k := range iOrder {
+		ep := entryPath(path, key, iIdx[k][key])
+		re, ok := rIdx[k]
+		if !ok {
+			c.leaves(Added, ep, iIdx[k])
+			continue
+		}
+		c.object(ep, re, iIdx[k])
+	}
+	if !c.opts.Strict {
+		return
+	}
+	for _, k := range rOrder {
+		if _, ok := iIdx[k]; !ok {
+			c.leaves(Removed, entryPath(path, key, rIdx[k][key]), rIdx[k])
+		}
+	}
+}
+
+// positional compares lists without keys. Lists of leaves (leaf-lists) are
+// compared as sets; anything else entry by entry.
+func (c *comparer) positional(path string, r, i []interface{}) {
+	if !anyContainer(r) && !anyContainer(i) {
+		rs := make(map[string]bool, len(r))
+		for _, v := range r {
+			rs[scalar(v)] = true
+		}
+		is := make(map[string]bool, len(i))
+		for _, v := range i {
+			is[scalar(v)] = true
+			if !rs[scalar(v)] {
+				c.add(Added, path, nil, v)
+			}
+		}
+		if c.opts.Strict {
+			for _, v := range r {
+				if !is[scalar(v)] {
+					c.add(Removed, path, v, nil)
+				}
+			}
+		}
+		return
+	}
+	for n := range i {
+		ep := fmt.Sprintf("%s[%d]", path, n)
+		if n >= len(r) {
+			c.leaves(Added, ep, i[n])
+			continue
+		}
+		c.value(ep, r[n], i[n])
+	}
+	if c.opts.Strict {
+		for n := len(i); n < len(r); n++ {
+			c.leaves(Removed, fmt.Sprintf("%s[%d]", path, n), r[n])
+		}
+	}
+}
+
+func anyContainer(l []interface{}) bool {
+	for _, v := range l {
+		if isContainer(v) {
+			return true
+		}
+	}
+	return false
+}
+
+// Print writes one line per change and reports whether there was any.
+func Print(w io.Writer, cs []Change) bool {
+	for _, c := range cs {
+		fmt.Fprintln(w, c)
+	}
+	return len(cs) > 0
+}
+
+// Summary counts changes by kind, e.g. "2 added, 0 removed, 1 changed".
+func Summary(cs []Change) string {
+	var n [3]int
+	for _, c := range cs {
+		n[c.Kind]++
+	}
+	return strings.Join([]string{
+		fmt.Sprintf("%d added", n[Added]),
+		fmt.Sprintf("%d removed", n[Removed]),
+		fmt.Sprintf("%d changed", n[Changed]),
+	}, ", ")
+}
@@ -0,0 +1,198 @@
+package diff
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDecode(t *testing.T) {
+	tests := []struct {
+		in   string
+		want map[string]interface{}
+		err  bool
+	}{
+		{in: "", want: map[string]interface{}{}},
+		{in: " \n\t", want: map[string]interface{}{}},
+		{in: "{}", want: map[string]interface{}{}},
+		{in: "null", want: map[string]interface{}{}},
+		{in: `{"data": {"a:b": {"c": true}}}`, want: map[string]interface{}{"a:b": map[string]interface{}{"c": true}}},
+		// Only a lone "data" is GetConfig's wrapper.
+		{in: `{"data": {"c": true}, "d": 1}`, want: map[string]interface{}{"data": map[string]interface{}{"c": true}, "d": json.Number("1")}},
+		{in: `{"a:b": `, err: true},
+		{in: `[]`, err: true},
+	}
+	for _, tt := range tests {
+		got, err := Decode([]byte(tt.in))
+		if tt.err {
+			if err == nil {
+				t.Errorf("Decode(%q) succeeded, want an error", tt.in)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Decode(%q): %v", tt.in, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Decode(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestJSONMissing(t *testing.T) {
+	// GetConfig returns nothing when the intended config isn't there.
+	intended := `{"openconfig-interfaces:interfaces": {"interface": [{"name": "Loopback1", "config": {"name": "Loopback1", "enabled": true}}]}}`
+	cs, err := JSON([]byte(""), []byte(intended), Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		`+ openconfig-interfaces:interfaces/interface[name=Loopback1]/config/enabled: true`,
+		`+ openconfig-interfaces:interfaces/interface[name=Loopback1]/config/name: "Loopback1"`,
+		`+ openconfig-interfaces:interfaces/interface[name=Loopback1]/name: "Loopback1"`,
+	}
+	if got := strs(cs); !reflect.DeepEqual(got, want) {
+		t.Errorf("JSON() = %q, want %q", got, want)
+	}
+}
+
+func strs(cs []Change) []string {
+	out := make([]string, len(cs))
+	for i, c := range cs {
+		out[i] = c.String()
+	}
+	return out
+}
+
+func TestJSON(t *testing.T) {
+	tests := []struct {
+		name     string
+		running  string
+		intended string
+		opts     Options
+		want     []string
+	}{
+		{
+			name:     "same",
+			running:  `{"data": {"m:c": {"a": 1, "b": "x"}}}`,
+			intended: `{"m:c": {"a": 1, "b": "x"}}`,
+		},
+		{
+			name:     "changed",
+			running:  `{"m:c": {"a": 1}}`,
+			intended: `{"m:c": {"a": 2}}`,
+			want:     []string{`~ m:c/a: 1 -> 2`},
+		},
+		{
+			// 64-bit integers may come as strings (RFC 7951).
+			name:     "int64 as string",
+			running:  `{"m:c": {"a": "18446744073709551615"}}`,
+			intended: `{"m:c": {"a": 18446744073709551615}}`,
+		},
+		{
+			// Without Strict, the intended config is a merge.
+			name:     "merge",
+			running:  `{"m:c": {"a": 1, "b": 2}, "m:d": {}}`,
+			intended: `{"m:c": {"a": 1}}`,
+		},
+		{
+			name:     "strict",
+			running:  `{"m:c": {"a": 1, "b": 2}, "m:d": {}}`,
+			intended: `{"m:c": {"a": 1}}`,
+			opts:     Options{Strict: true},
+			want:     []string{`- m:c/b: 2`, `- m:d: {}`},
+		},
+		{
+			// Entries match by key, whatever their order.
+			name:     "list",
+			running:  `{"m:l": [{"name": "b", "v": 2}, {"name": "a", "v": 1}, {"name": "c"}]}`,
+			intended: `{"m:l": [{"name": "a", "v": 1}, {"name": "b", "v": 3}, {"name": "d", "v": 4}]}`,
+			opts:     Options{Strict: true},
+			want: []string{
+				`~ m:l[name=b]/v: 2 -> 3`,
+				`+ m:l[name=d]/name: "d"`,
+				`+ m:l[name=d]/v: 4`,
+				`- m:l[name=c]/name: "c"`,
+			},
+		},
+		{
+			// Entries that share a key are merged, as a router would.
+			name:     "duplicate keys",
+			running:  `{"m:l": [{"name": "a", "v": 1, "w": 2}]}`,
+			intended: `{"m:l": [{"name": "a", "v": 1}, {"name": "a", "w": 3}]}`,
+			want:     []string{`~ m:l[name=a]/w: 2 -> 3`},
+		},
+		{
+			name:     "keys option",
+			running:  `{"m:l": [{"name": "x", "seq": 10}]}`,
+			intended: `{"m:l": [{"name": "y", "seq": 10}]}`,
+			opts:     Options{Keys: []string{"seq"}},
+			want:     []string{`~ m:l[seq=10]/name: "x" -> "y"`},
+		},
+		{
+			// Leaf-lists are sets.
+			name:     "leaf-list",
+			running:  `{"m:c": {"ll": ["a", "b"]}}`,
+			intended: `{"m:c": {"ll": ["c", "b"]}}`,
+			opts:     Options{Strict: true},
+			want:     []string{`+ m:c/ll: "c"`, `- m:c/ll: "a"`},
+		},
+		{
+			// Lists without a common key are compared by position.
+			name:     "positional",
+			running:  `{"m:l": [{"v": 1}, {"v": 2}, {"v": 3}]}`,
+			intended: `{"m:l": [{"v": 1}, {"v": 5}]}`,
+			opts:     Options{Strict: true},
+			want:     []string{`~ m:l[1]/v: 2 -> 5`, `- m:l[2]/v: 3`},
+		},
+		{
+			name:     "shape",
+			running:  `{"m:c": {"a": 1}}`,
+			intended: `{"m:c": {"a": {"b": 1}}}`,
+			want:     []string{`~ m:c/a: 1 -> {"b":1}`},
+		},
+	}
+	for _, tt := range tests {
+		cs, err := JSON([]byte(tt.running), []byte(tt.intended), tt.opts)
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if got := strs(cs); strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+			t.Errorf("%s: JSON() = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestJSONInvalid(t *testing.T) {
+	if _, err := JSON([]byte(`{`), []byte(`{}`), Options{}); err == nil || !strings.Contains(err.Error(), "running config") {
+		t.Errorf("JSON() = %v, want an error about the running config", err)
+	}
+	if _, err := JSON([]byte(`{}`), []byte(`[`), Options{}); err == nil || !strings.Contains(err.Error(), "intended config") {
+		t.Errorf("JSON() = %v, want an error about the intended config", err)
+	}
+}
+
+func TestPaths(t *testing.T) {
+	got, err := Paths([]byte(`{"a:b": {"c": 1}, "d:e": []}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"a:b":[null],"d:e":[null]}`; got != want {
+		t.Errorf("Paths() = %s, want %s", got, want)
+	}
+}
+
+func TestSummary(t *testing.T) {
+	cs := []Change{{Kind: Added}, {Kind: Added}, {Kind: Changed}}
+	if got, want := Summary(cs), "2 added, 0 removed, 1 changed"; got != want {
+		t.Errorf("Summary() = %q, want %q", got, want)
+	}
+	var b bytes.Buffer
+	if Print(&b, nil) || b.Len() != 0 {
+		t.Errorf("Print() of no changes wrote %q", b.String())
+	}
+}
@@ -0,0 +1,178 @@
+/*
+Package diff compares router configurations, either as text (e.g. the output of
+"show running-config") or as YANG JSON documents.
+*/
+package diff
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Op is the kind of change a Line represents.
+type Op int
+
+const (
+	// Equal lines are in both inputs.
+	Equal Op = iota
+	// Delete lines are only in the first input.
+	Delete
+	// Insert lines are only in the second input.
+	Insert
+)
+
+// Line is a line of a text diff.
+type Line struct {
+	Op   Op
+	Text string
+}
+
+// Lines returns the shortest edit script that turns a into b, using Myers'
+// algorithm.
+func Lines(a, b []string) []Line {
+	n, m := len(a), len(b)
+	max := n + m
+	if max == 0 {
+		return nil
+	}
+	// v[k+max] holds the furthest x reached on diagonal k; trace keeps a
+	// copy per edit distance to walk the path back.
+	v := make([]int, 2*max+1)
+	var trace [][]int
+	for d := 0; d <= max; d++ {
+		trace = append(trace, append([]int(nil), v...))
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[k-1+max] < v[k+1+max]) {
+				x = v[k+1+max]
+			} else {
+				x = v[k-1+max] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[k+max] = x
+			if x >= n && y >= m {
+				return backtrack(a, b, trace, d)
+			}
+		}
+	}
+	return nil
+}
+
+func backtrack(a, b []string, trace [][]int, d int) []Line {
+	max := len(a) + len(b)
+	x, y := len(a), len(b)
+	var out []Line
+	for ; d >= 0; d-- {
+		v := trace[d]
+		k := x - y
+		var pk int
+		if k == -d || (k != d && v[k-1+max] < v[k+1+max]) {
+			pk = k + 1
+		} else {
+			pk = k - 1
+		}
+		px := v[pk+max]
+		py := px - pk
+		for x > px && y > py {
+			x--
+			y--
+			out = append(out, Line{Equal, a[x]})
+		}
+		if d == 0 {
+			break
+		}
+		if x == px {
+			y--
+			out = append(out, Line{Insert, b[y]})
+		} else {
+			x--
+			out = append(out, Line{Delete, a[x]})
+		}
+		x, y = px, py
+	}
+	for i, j := 0, len(out)-1; i < j; i, j = i+1, j-1 {
+		out[i], out[j] = out[j], out[i]
+	}
+	return out
+}
+
+// SplitLines splits text into lines, ignoring a trailing newline.
+func SplitLines(text string) []string {
+	text = strings.TrimRight(text, "\n")
+	if text == "" {
+		return nil
+	}
+	return strings.Split(text, "\n")
+}
+
+// HasChanges reports whether the diff has any insertion or deletion.
+func HasChanges(ls []Line) bool {
+	for _, l := range ls {
+		if l.Op != Equal {
+			return true
+		}
+	}
+	return false
+}
+
+// Unified writes the diff between a and b in unified format, with context
+// lines around each change. It reports whether there was any change.
+func Unified(w io.Writer, a, b []string, context int) bool {
+	ls := Lines(a, b)
+	if !HasChanges(ls) {
+		return false
+	}
+	// Line numbers in a and b where each element of ls starts.
+	ai := make([]int, len(ls)+1)
+	bi := make([]int, len(ls)+1)
+	for i, l := range ls {
+		ai[i+1], bi[i+1] = ai[i], bi[i]
+		if l.Op != Insert {
+			ai[i+1]++
+		}
+		if l.Op != Delete {
+			bi[i+1]++
+		}
+	}
+	for i := 0; i < len(ls); {
+		if ls[i].Op == Equal {
+			i++
+			continue
+		}
+		// Grow the hunk while changes are less than 2*context apart.
+		start := i - context
+		if start < 0 {
+			start = 0
+		}
+		end := i
+		for j := i; j < len(ls); j++ {
+			if ls[j].Op != Equal {
+				end = j + 1
+			} else if j-end >= 2*context {
+				break
+			}
+		}
+		end += context
+		if end > len(ls) {
+			end = len(ls)
+		}
+		fmt.Fprintf(w, "@@ -%d,%d +%d,%d @@\n", ai[start]+1, ai[end]-ai[start], bi[start]+1, bi[end]-bi[start])
+		for _, l := range ls[start:end] {
+			switch l.Op {
+			case Equal:
+				fmt.Fprintf(w, " %s\n", l.Text)
+			case Delete:
+				fmt.Fprintf(w, "-%s\n", l.Text)
+			case Insert:
+				fmt.Fprintf(w, "+%s\n", l.Text)
+			}
+		}
+		i = end
+	}
+	return true
+}
@@ -0,0 +1,92 @@
+package diff
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestLines(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want []Line
+	}{
+		{a: "", b: ""},
+		{a: "x\ny", b: "x\ny", want: []Line{{Equal, "x"}, {Equal, "y"}}},
+		{a: "", b: "x", want: []Line{{Insert, "x"}}},
+		{a: "x", b: "", want: []Line{{Delete, "x"}}},
+		{
+			a:    "a\nb\nc",
+			b:    "a\nc\nd",
+			want: []Line{{Equal, "a"}, {Delete, "b"}, {Equal, "c"}, {Insert, "d"}},
+		},
+	}
+	for _, tt := range tests {
+		got := Lines(SplitLines(tt.a), SplitLines(tt.b))
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Lines(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+		if HasChanges(got) != (tt.a != tt.b) {
+			t.Errorf("HasChanges(Lines(%q, %q)) = %v", tt.a, tt.b, HasChanges(got))
+		}
+	}
+}
+
+func TestSplitLines(t *testing.T) {
+	tests := []struct {
+		in   string
+		want []string
+	}{
+		{in: ""},
+		{in: "\n"},
+		{in: "a\nb\n", want: []string{"a", "b"}},
+		{in: "a\n\nb", want: []string{"a", "", "b"}},
+	}
+	for _, tt := range tests {
+		if got := SplitLines(tt.in); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("SplitLines(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestUnified(t *testing.T) {
+	a := SplitLines("1\n2\n3\n4\n5\n6\n7\n8\n9\n10")
+	tests := []struct {
+		name    string
+		b       string
+		context int
+		want    string
+	}{
+		{name: "same", b: "1\n2\n3\n4\n5\n6\n7\n8\n9\n10", context: 3},
+		{
+			name:    "one change",
+			b:       "1\n2\n3\n4\nfive\n6\n7\n8\n9\n10",
+			context: 1,
+			want:    "@@ -4,3 +4,3 @@\n 4\n-5\n+five\n 6\n",
+		},
+		{
+			// Changes far apart get a hunk each.
+			name:    "two hunks",
+			b:       "one\n2\n3\n4\n5\n6\n7\n8\n9\nten",
+			context: 1,
+			want:    "@@ -1,2 +1,2 @@\n-1\n+one\n 2\n@@ -9,2 +9,2 @@\n 9\n-10\n+ten\n",
+		},
+		{
+			// And close ones share it.
+			name:    "one hunk",
+			b:       "1\n2\nthree\n4\nfive\n6\n7\n8\n9\n10",
+			context: 1,
+			want:    "@@ -2,5 +2,5 @@\n 2\n-3\n+three\n 4\n-5\n+five\n 6\n",
+		},
+	}
+	for _, tt := range tests {
+		var b bytes.Buffer
+		changed := Unified(&b, a, SplitLines(tt.b), tt.context)
+		if changed != (tt.want != "") {
+			t.Errorf("%s: Unified() = %v", tt.name, changed)
+		}
+		if b.String() != tt.want {
+			t.Errorf("%s: Unified() wrote\n%s\nwant\n%s", tt.name, b.String(), tt.want)
+		}
+	}
+}
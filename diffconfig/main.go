@@ -0,0 +1,70 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/nleiva/clus2019/diff"
+	"github.com/nleiva/clus2019/inventory"
+	"github.com/nleiva/clus2019/runner"
+	xr "github.com/nleiva/xrgrpc"
+)
+
+func timeTrack(start time.Time) {
+	elapsed := time.Since(start)
+	log.Printf("This process took %s\n", elapsed)
+}
+
+func main() {
+	// Intended YANG config; defaults to "yangocconfig.json"
+	ypath := flag.String("ypath", "../input/yangocconfig.json", "Intended YANG config")
+	// Compare as a replace instead of a merge
+	strict := flag.Bool("strict", false, "Also report config only present on the router")
+	// Leaves that identify list entries
+	keys := flag.String("keys", strings.Join(diff.DefaultKeys, ","), "List keys to match entries by, in order of preference")
+	// Number of devices to query in parallel; defaults to 10
+	workers := flag.Int("workers", 10, "Devices to query concurrently")
+	// Per-device timeout in seconds; defaults to the inventory value or 5
+	timeout := flag.Int("timeout", 0, "Per-device timeout in seconds")
+	// Target devices; defaults to "router2" from "inventory.json"
+	var tg inventory.Target
+	tg.AddFlags(flag.CommandLine)
+	flag.Parse()
+
+	opts := diff.Options{Keys: strings.Split(*keys, ","), Strict: *strict}
+	os.Exit(run(tg, *ypath, opts, *workers, *timeout))
+}
+
+func run(tg inventory.Target, ypath string, opts diff.Options, workers, timeout int) int {
+	// To time this process
+	defer timeTrack(time.Now())
+
+	devices, err := tg.Devices()
+	if err != nil {
+		log.Printf("could not select a device, %v", err)
+		return diff.ExitTrouble
+	}
+	intended, err := ioutil.ReadFile(ypath)
+	if err != nil {
+		log.Printf("could not read file: %v: %v\n", ypath, err)
+		return diff.ExitTrouble
+	}
+
+	r := runner.Runner{
+		Workers:  workers,
+		Timeout:  timeout,
+		Defaults: []xr.RouterOption{xr.WithTimeout(5)},
+	}
+	d, err := diff.Devices(os.Stdout, &r, devices, intended, opts)
+	if err != nil {
+		log.Printf("could not parse %v: %v\n", ypath, err)
+		return diff.ExitTrouble
+	}
+	fmt.Println()
+	return d.Code()
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// event is the outcome of checking one intended file on one device.
+type event struct {
+	Time     time.Time `json:"time"`
+	Device   string    `json:"device"`
+	File     string    `json:"file"`
+	Revision string    `json:"revision,omitempty"`
+	Drift    bool      `json:"drift"`
+	Summary  string    `json:"summary,omitempty"`
+	Changes  []string  `json:"changes,omitempty"`
+	// Remediation is the operation sent to fix the drift, if any.
+	Remediation string `json:"remediation,omitempty"`
+	Error       string `json:"error,omitempty"`
+}
+
+// store keeps the latest result per device and file, plus a bounded log of
+// the drift and error events.
+type store struct {
+	mu     sync.Mutex
+	size   int
+	events []event
+	latest map[string]event
+}
+
+func newStore(size int) *store {
+	return &store{size: size, latest: make(map[string]event)}
+}
+
+func (s *store) add(e event) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.latest[e.Device+"/"+e.File] = e
+	if !e.Drift && e.Error == "" {
+		return
+	}
+	s.events = append(s.events, e)
+	if len(s.events) > s.size {
+		s.events = s.events[len(s.events)-s.size:]
+	}
+}
+
+func (s *store) status() []event {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make([]event, 0, len(s.latest))
+	for _, e := range s.latest {
+		out = append(out, e)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Device != out[j].Device {
+			return out[i].Device < out[j].Device
+		}
+		return out[i].File < out[j].File
+	})
+	return out
+}
+
+func (s *store) log() []event {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]event(nil), s.events...)
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.Encode(v)
+}
+
+// handler exposes the latest status on /status and the drift events on
+// /events.
+func (s *store) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/status", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, s.status())
+	})
+	mux.HandleFunc("/events", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, s.log())
+	})
+	return mux
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/nleiva/clus2019/inventory"
+	"github.com/pkg/errors"
+)
+
+// intended is a YANG JSON file that describes the state a device must be in.
+type intended struct {
+	// Name is the path relative to the intended directory.
+	Name string
+	JS   []byte
+}
+
+// intendedFor returns the files that apply to d: everything in "all", in
+// a directory named after one of its groups, or in one named after the
+// device itself.
+func intendedFor(dir string, d inventory.Device) ([]intended, error) {
+	scopes := append([]string{"all"}, d.Groups...)
+	scopes = append(scopes, d.Name)
+	var out []intended
+	for _, s := range scopes {
+		files, err := filepath.Glob(filepath.Join(dir, s, "*.json"))
+		if err != nil {
+			return nil, errors.Wrapf(err, "could not list %s", s)
+		}
+		sort.Strings(files)
+		for _, f := range files {
+			js, err := ioutil.ReadFile(f)
+			if err != nil {
+				return nil, errors.Wrapf(err, "could not read file %s", f)
+			}
+			rel, err := filepath.Rel(dir, f)
+			if err != nil {
+				rel = f
+			}
+			out = append(out, intended{Name: rel, JS: js})
+		}
+	}
+	return out, nil
+}
+
+// revision returns the git commit the intended directory is at, or "" if
+// it isn't tracked by git.
+func revision(dir string) string {
+	cmd := exec.Command("git", "-C", dir, "rev-parse", "--short", "HEAD")
+	out, err := cmd.Output()
+	if err != nil {
+		return ""
+	}
+	rev := strings.TrimSpace(string(out))
+	// Flag uncommitted changes, since then the revision isn't the whole story.
+	st, err := exec.Command("git", "-C", dir, "status", "--porcelain", ".").Output()
+	if err == nil && len(strings.TrimSpace(string(st))) > 0 {
+		rev += "-dirty"
+	}
+	return rev
+}
+
+func isDir(dir string) bool {
+	fi, err := os.Stat(dir)
+	return err == nil && fi.IsDir()
+}
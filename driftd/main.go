@@ -0,0 +1,221 @@
+/*
+driftd periodically compares the running config of each device with the
+intended YANG JSON files in a directory, logs any drift and optionally fixes it.
+*/
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"time"
+
+	"github.com/nleiva/clus2019/diff"
+	"github.com/nleiva/clus2019/inventory"
+	"github.com/nleiva/clus2019/runner"
+	xr "github.com/nleiva/xrgrpc"
+	"google.golang.org/grpc"
+)
+
+func main() {
+	// Intended config; defaults to "../input/intended"
+	dir := flag.String("intended", "../input/intended", "Directory with the intended YANG JSON files")
+	interval := flag.Duration("interval", 5*time.Minute, "Time between checks")
+	once := flag.Bool("once", false, "Run a single check and exit; the exit code is 1 if there is drift and 2 if a check failed")
+	remediate := flag.String("remediate", "", "Fix drift with 'merge' or 'replace'; empty only reports it")
+	strict := flag.Bool("strict", false, "Also report config only present on the router")
+	listen := flag.String("listen", ":8080", "Address to serve /status and /events on; empty disables it")
+	// Number of devices to query in parallel; defaults to 10
+	workers := flag.Int("workers", 10, "Devices to query concurrently")
+	// Target devices; defaults to every device in "inventory.json"
+	var tg inventory.Target
+	tg.AddFlags(flag.CommandLine)
+	flag.Parse()
+	selectAll(&tg)
+
+	if *remediate != "" && *remediate != "merge" && *remediate != "replace" {
+		log.Fatalf("remediation '%v' not supported", *remediate)
+	}
+	if !isDir(*dir) {
+		log.Fatalf("intended config directory %s not found", *dir)
+	}
+	c := &checker{
+		dir: *dir,
+		// A replace removes what the files don't declare, so report it.
+		opts:      diff.Options{Strict: *strict || *remediate == "replace"},
+		remediate: *remediate,
+		runner: runner.Runner{
+			Workers:  *workers,
+			Defaults: []xr.RouterOption{xr.WithTimeout(30)},
+		},
+		store: newStore(1000),
+	}
+
+	if *once {
+		drift, failed := c.check(tg)
+		switch {
+		case failed:
+			os.Exit(2)
+		case drift:
+			os.Exit(1)
+		}
+		return
+	}
+
+	if *listen != "" {
+		go func() {
+			log.Printf("serving drift status on %s\n", *listen)
+			log.Fatal(http.ListenAndServe(*listen, c.store.handler()))
+		}()
+	}
+
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, os.Interrupt)
+	tick := time.NewTicker(*interval)
+	defer tick.Stop()
+	for {
+		c.check(tg)
+		select {
+		case <-sig:
+			log.Printf("stopping drift detection\n")
+			return
+		case <-tick.C:
+		}
+	}
+}
+
+// selectAll targets every device unless -device or -group were given.
+func selectAll(tg *inventory.Target) {
+	set := false
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == "device" || f.Name == "group" {
+			set = true
+		}
+	})
+	if !set {
+		tg.Group = "all"
+	}
+}
+
+// checker compares devices with their intended config.
+type checker struct {
+	dir       string
+	opts      diff.Options
+	remediate string
+	runner    runner.Runner
+	store     *store
+}
+
+// check compares every device with its intended files once and reports
+// whether any drift is left, and whether any device or file couldn't be
+// checked, so it may have drift too.
+func (c *checker) check(tg inventory.Target) (drift, failed bool) {
+	devices, err := tg.Devices()
+	if err != nil {
+		log.Printf("could not select a device, %v", err)
+		return false, true
+	}
+	rev := revision(c.dir)
+
+	var mu sync.Mutex
+	results := c.runner.RunEach(devices, func(d inventory.Device) runner.Task {
+		return func(ctx context.Context, conn *grpc.ClientConn, id int64) (string, error) {
+			files, err := intendedFor(c.dir, d)
+			if err != nil {
+				return "", err
+			}
+			for _, f := range files {
+				e := c.checkFile(ctx, conn, id, d, f)
+				e.Revision = rev
+				c.record(e)
+				mu.Lock()
+				drift = drift || e.Drift && e.Remediation == ""
+				failed = failed || e.Error != ""
+				mu.Unlock()
+			}
+			return fmt.Sprintf("%d files checked", len(files)), nil
+		}
+	})
+	for _, res := range results {
+		if res.Err != nil {
+			c.record(event{Time: time.Now(), Device: res.Device, Revision: rev, Error: res.Err.Error()})
+			failed = true
+		}
+	}
+	return drift, failed
+}
+
+// checkFile compares one intended file with the running config and fixes
+// the drift if remediation is on.
+func (c *checker) checkFile(ctx context.Context, conn *grpc.ClientConn, id int64, d inventory.Device, f intended) event {
+	e := event{Time: time.Now(), Device: d.Name, File: f.Name}
+	paths, err := diff.Paths(f.JS)
+	if err != nil {
+		e.Error = err.Error()
+		return e
+	}
+	out, err := xr.GetConfig(ctx, conn, paths, id)
+	if err != nil {
+		e.Error = fmt.Sprintf("could not get the config, %v", err)
+		return e
+	}
+	cs, err := diff.JSON([]byte(out), f.JS, c.opts)
+	if err != nil {
+		e.Error = err.Error()
+		return e
+	}
+	if len(cs) == 0 {
+		return e
+	}
+	e.Drift = true
+	e.Summary = diff.Summary(cs)
+	e.Changes = changes(cs)
+
+	switch c.remediate {
+	case "merge":
+		_, err = xr.MergeConfig(ctx, conn, string(f.JS), id)
+	case "replace":
+		_, err = xr.ReplaceConfig(ctx, conn, string(f.JS), id)
+	default:
+		return e
+	}
+	if err != nil {
+		e.Error = fmt.Sprintf("could not remediate with %s, %v", c.remediate, err)
+		return e
+	}
+	e.Remediation = c.remediate
+	return e
+}
+
+func (c *checker) record(e event) {
+	c.store.add(e)
+	file := e.File
+	if file == "" {
+		file = "-"
+	}
+	if e.Drift {
+		log.Printf("drift on %s %s (revision %s): %s\n", e.Device, file, e.Revision, e.Summary)
+		for _, ch := range e.Changes {
+			log.Printf("  %s\n", ch)
+		}
+		if e.Remediation != "" {
+			log.Printf("%s %s remediated with %s\n", e.Device, file, e.Remediation)
+		}
+	}
+	if e.Error != "" {
+		log.Printf("%s %s: %s\n", e.Device, file, e.Error)
+	}
+}
+
+func changes(cs []diff.Change) []string {
+	out := make([]string, len(cs))
+	for i, ch := range cs {
+		out[i] = ch.String()
+	}
+	return out
+}
@@ -0,0 +1,927 @@
+package e2e
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/nleiva/clus2019/record"
+)
+
+// lldpPath and countersPath are the encoding paths of the recordings
+// under input/mock/telemetry.
+const (
+	lldpPath     = "Cisco-IOS-XR-ethernet-lldp-oper:lldp/nodes/node/neighbors/details/detail"
+	countersPath = "Cisco-IOS-XR-infra-statsd-oper:infra-statistics/interfaces/interface/latest/generic-counters"
+)
+
+// lldpTopic is where lldpPath is published.
+const lldpTopic = "telemetry.Cisco-IOS-XR-ethernet-lldp-oper.lldp.nodes.node.neighbors.details.detail"
+
+// testCase is a command run and what to expect of it.
+type testCase struct {
+	name string
+	// cmd is the directory of the command, which it runs from.
+	cmd string
+	// args follow -inv, unless noInv is set; "{dir}" stands for the
+	// scratch directory, "{influx}" for the InfluxDB stand-in, "{bus}" for
+	// the NATS one and "{addr}" for a free address.
+	args  []string
+	noInv bool
+	// server is a command to start first, listening on "{addr}", and to
+	// stop after cmd. Its output goes with cmd's.
+	server *server
+	// fail says the command should exit with an error, and code, if
+	// set, with which exit code.
+	fail bool
+	code int
+	// stream says the command runs until it's stopped, at timeout, so
+	// only its output counts.
+	stream  bool
+	timeout time.Duration
+	// fetch is a path to get over HTTP from the command while it runs,
+	// at "{addr}" in args, until the reply or the output has each text
+	// want asks for. The reply is checked with the output, and the
+	// command stopped.
+	fetch string
+	// dialout has the mock send a recording to the command, as a router
+	// dialing out, again until the output has each text want asks for.
+	// The command is then stopped.
+	dialout *dial
+	// want and reject are text the output has to have, or not have.
+	want   []string
+	reject []string
+	// check runs after the command, to look at the mock.
+	check func(*env) error
+}
+
+// dial is a dial-out session of the mock.
+type dial struct {
+	// addr is where the mock dials, with "{addr}" as in args.
+	addr string
+	// subs and enc pick the recording.
+	subs string
+	enc  int64
+}
+
+// server is a command a case runs against.
+type server struct {
+	cmd  string
+	args []string
+}
+
+// interfaceJSON asks for one interface of openconfig-interfaces.
+func interfaceJSON(name string) string {
+	return fmt.Sprintf(`{"openconfig-interfaces:interfaces":{"interface":[{"name":%q}]}}`, name)
+}
+
+// hasInterface checks whether the mock's config has interface name, and
+// that its config has the text in want.
+func hasInterface(name string, present bool, want ...string) func(*env) error {
+	return func(e *env) error {
+		out, err := e.mock.Config.Get(interfaceJSON(name))
+		if err != nil {
+			return err
+		}
+		switch {
+		case present && out == "":
+			return fmt.Errorf("%s isn't in the mock's config", name)
+		case !present && out != "":
+			return fmt.Errorf("%s is still in the mock's config:\n%s", name, out)
+		}
+		for _, w := range want {
+			if !strings.Contains(out, w) {
+				return fmt.Errorf("config of %s doesn't have %q:\n%s", name, w, out)
+			}
+		}
+		return nil
+	}
+}
+
+// hasCLI checks the mock's CLI config has line.
+func hasCLI(line string) func(*env) error {
+	return func(e *env) error {
+		if !strings.Contains(e.mock.CLI(), line) {
+			return fmt.Errorf("CLI config doesn't have %q:\n%s", line, e.mock.CLI())
+		}
+		return nil
+	}
+}
+
+// sameFrames checks the recording in file, under the scratch directory,
+// has the messages of the mock's recording orig.
+func sameFrames(file, orig string) func(*env) error {
+	return func(e *env) error {
+		got, err := record.ReadFile(strings.Replace(file, "{dir}", e.dir, -1))
+		if err != nil {
+			return err
+		}
+		want, err := record.ReadFile(filepath.Join(e.mock.Telemetry, orig))
+		if err != nil {
+			return err
+		}
+		if len(got) != len(want) {
+			return fmt.Errorf("recorded %d messages, expected %d", len(got), len(want))
+		}
+		for i := range got {
+			if !bytes.Equal(got[i].Data, want[i].Data) {
+				return fmt.Errorf("message %d differs from the one sent", i)
+			}
+		}
+		return nil
+	}
+}
+
+// influxLines checks the InfluxDB stand-in got the lines in want for
+// database db.
+func influxLines(db string, want ...string) func(*env) error {
+	return func(e *env) error {
+		got := e.influx.received(db)
+		for _, w := range want {
+			if !strings.Contains(got, w) {
+				return fmt.Errorf("InfluxDB didn't get %q\n%s", w, got)
+			}
+		}
+		return nil
+	}
+}
+
+// published checks the NATS stand-in got n messages on subject, each with
+// the text in want.
+func published(subject string, n int, want ...string) func(*env) error {
+	return func(e *env) error {
+		msgs := e.bus.published(subject)
+		if len(msgs) != n {
+			return fmt.Errorf("%d messages published on %s, expected %d", len(msgs), subject, n)
+		}
+		for _, m := range msgs {
+			for _, w := range want {
+				if !strings.Contains(m, w) {
+					return fmt.Errorf("message published on %s doesn't have %q\n%s", subject, w, m)
+				}
+			}
+		}
+		return nil
+	}
+}
+
+// spooled checks the spool in dir, under the scratch directory, has n
+// messages waiting.
+func spooled(dir string, n int) func(*env) error {
+	return func(e *env) error {
+		file := filepath.Join(strings.Replace(dir, "{dir}", e.dir, -1), "pending.jsonl")
+		b, err := ioutil.ReadFile(file)
+		if os.IsNotExist(err) && n == 0 {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if got := bytes.Count(b, []byte("\n")); got != n {
+			return fmt.Errorf("%d messages spooled, expected %d\n%s", got, n, b)
+		}
+		return nil
+	}
+}
+
+// gumiServer serves the users in the scratch directory from store on
+// "{addr}".
+func gumiServer(store string) *server {
+	return &server{cmd: "gumiserver", args: []string{"-listen", "{addr}", "-store", store, "-file", "{dir}/users.json"}}
+}
+
+// hasStatic checks the mock's config has a default route to nextHop.
+func hasStatic(nextHop string) func(*env) error {
+	return func(e *env) error {
+		out, err := e.mock.Config.Get(`{"Cisco-IOS-XR-ip-static-cfg:router-static":[null]}`)
+		if err != nil {
+			return err
+		}
+		if !strings.Contains(out, nextHop) {
+			return fmt.Errorf("static routes don't have %s:\n%s", nextHop, out)
+		}
+		return nil
+	}
+}
+
+// cases run in order, and later ones build on the config earlier ones
+// leave in the mock.
+func cases() []testCase {
+	return []testCase{
+		{
+			name: "getconfig",
+			cmd:  "getconfig",
+			args: []string{"-device", "mock"},
+			want: []string{"config from 127.0.0.1", `"name": "Loopback0"`, "1 devices, 0 failed"},
+		},
+		{
+			name: "getconfig/group",
+			cmd:  "getconfig",
+			args: []string{"-group", "mock"},
+			want: []string{"mock2", "2 devices, 0 failed"},
+		},
+		{
+			// Nothing of static.json is configured on the mock, so
+			// GetConfig returns nothing and every leaf is drift.
+			name:   "diffconfig/missing",
+			cmd:    "diffconfig",
+			args:   []string{"-device", "mock", "-ypath", "../input/mock/intended/all/static.json"},
+			fail:   true,
+			code:   1,
+			want:   []string{"drift on 127.0.0.1", "3 added, 0 removed, 0 changed", "[prefix=0.0.0.0]"},
+			reject: []string{"could not"},
+		},
+		{
+			// A check that fails isn't taken for one without drift.
+			name:   "driftd/failed",
+			cmd:    "driftd",
+			args:   []string{"-once", "-device", "badauth", "-listen", ""},
+			fail:   true,
+			code:   2,
+			want:   []string{"badauth all/loopback201.json: could not get the config"},
+			reject: []string{"drift on"},
+		},
+		{
+			name: "driftd/missing",
+			cmd:  "driftd",
+			args: []string{"-once", "-device", "mock", "-listen", "", "-intended", "../input/mock/intended"},
+			fail: true,
+			code: 1,
+			want: []string{"drift on mock all/static.json", "3 added, 0 removed, 0 changed"},
+		},
+		{
+			name:  "driftd/remediate",
+			cmd:   "driftd",
+			args:  []string{"-once", "-device", "mock", "-listen", "", "-intended", "../input/mock/intended", "-remediate", "merge"},
+			want:  []string{"drift on mock all/static.json", "mock all/static.json remediated with merge"},
+			check: hasStatic("192.0.2.254"),
+		},
+		{
+			name: "diffconfig/no-drift",
+			cmd:  "diffconfig",
+			args: []string{"-device", "mock", "-ypath", "../input/mock/intended/all/static.json"},
+			want: []string{"no drift on 127.0.0.1"},
+		},
+		{
+			// A device that can't be checked isn't taken for drift.
+			name:   "diffconfig/bad-auth",
+			cmd:    "diffconfig",
+			args:   []string{"-device", "badauth", "-ypath", "../input/mock/intended/all/static.json"},
+			fail:   true,
+			code:   2,
+			want:   []string{"could not get the config from 127.0.0.1"},
+			reject: []string{"drift on"},
+		},
+		{
+			name:  "driftd/status",
+			cmd:   "driftd",
+			args:  []string{"-device", "mock", "-intended", "../input/mock/intended", "-listen", "{addr}"},
+			fetch: "/status",
+			want:  []string{`"device": "mock"`, `"file": "all/static.json"`, `"drift": false`},
+		},
+		{
+			name: "validate",
+			cmd:  "validate",
+			args: []string{"../e2e/testdata/users.json"},
+			want: []string{"users.json: valid"},
+		},
+		{
+			name: "validate/invalid",
+			cmd:  "validate",
+			args: []string{"../e2e/testdata/users-invalid.json"},
+			fail: true,
+			code: 1,
+			want: []string{"/test:user/id: 70000 is out of range for uint16"},
+		},
+		{
+			// Nodes of modules that aren't loaded are only reported.
+			name: "validate/unloaded",
+			cmd:  "validate",
+			args: []string{"../input/yangocconfig.json"},
+			want: []string{"not checked: /openconfig-interfaces:interfaces: module openconfig-interfaces isn't loaded", "yangocconfig.json: valid"},
+		},
+		{
+			name: "validate/strict",
+			cmd:  "validate",
+			args: []string{"-strict", "../input/yangocconfig.json"},
+			fail: true,
+			code: 1,
+			want: []string{"module openconfig-interfaces isn't loaded"},
+		},
+		{
+			name: "validate/template",
+			cmd:  "validate",
+			args: []string{"-ypath", "../input/yangocconfig.json.tmpl", "-vars", "../input/mock/vars.json", "-device", "mock"},
+			want: []string{"yangocconfig.json.tmpl for mock: not checked", "yangocconfig.json.tmpl for mock: valid"},
+		},
+		{
+			// vars.json has no addresses for mock2.
+			name: "validate/template-vars",
+			cmd:  "validate",
+			args: []string{"-ypath", "../input/yangocconfig.json.tmpl", "-vars", "../input/mock/vars.json", "-group", "mock"},
+			fail: true,
+			code: 2,
+			want: []string{"for mock: valid", `could not render ../input/yangocconfig.json.tmpl for mock2`},
+		},
+		{
+			name: "validate/no-modules",
+			cmd:  "validate",
+			args: []string{"-yang", "{dir}", "../e2e/testdata/users.json"},
+			fail: true,
+			code: 2,
+			want: []string{"could not load the YANG modules"},
+		},
+		{
+			name:  "mergeconfig/dry-run",
+			cmd:   "mergeconfig",
+			args:  []string{"-device", "mock", "-dry-run"},
+			want:  []string{"payload for mock", "Loopback201"},
+			check: hasInterface("Loopback201", false),
+		},
+		{
+			name:  "mergeconfig/strict",
+			cmd:   "mergeconfig",
+			args:  []string{"-device", "mock", "-strict"},
+			fail:  true,
+			want:  []string{"module openconfig-interfaces isn't loaded"},
+			check: hasInterface("Loopback201", false),
+		},
+		{
+			name:  "mergeconfig",
+			cmd:   "mergeconfig",
+			args:  []string{"-device", "mock"},
+			want:  []string{"module openconfig-interfaces isn't loaded, so /openconfig-interfaces:interfaces wasn't checked", "config merged on", "Request ID: 1, Response ID: 1"},
+			check: hasInterface("Loopback201", true, "LOOP: Test interface 201", "203.0.113.201"),
+		},
+		{
+			name:  "mergeconfig/template",
+			cmd:   "mergeconfig",
+			args:  []string{"-device", "mock", "-ypath", "../input/yangocconfig.json.tmpl", "-vars", "../input/mock/vars.json"},
+			want:  []string{"config merged on", "Request ID: 1, Response ID: 1"},
+			check: hasInterface("Loopback301", true, "LOOP: Mock interface 301", "203.0.113.31", "2001:db8::30:1"),
+		},
+		{
+			name: "getconfig/after-merge",
+			cmd:  "getconfig",
+			args: []string{"-device", "mock"},
+			want: []string{"Loopback0", "Loopback201", "LOOP: Test interface 201"},
+		},
+		{
+			name:  "deleteconfig",
+			cmd:   "deleteconfig",
+			args:  []string{"-device", "mock"},
+			want:  []string{"config deleted on", "Request ID: 1, Response ID: 1"},
+			check: hasInterface("Loopback201", false),
+		},
+		{
+			name: "replaceconfig",
+			cmd:  "replaceconfig",
+			args: []string{"-device", "mock"},
+			want: []string{"config replaced on", "Request ID: 1, Response ID: 1"},
+			check: func(e *env) error {
+				// Replace leaves only the interfaces in the payload.
+				if err := hasInterface("Loopback201", true)(e); err != nil {
+					return err
+				}
+				return hasInterface("Loopback0", false)(e)
+			},
+		},
+		{
+			name:  "setconfig",
+			cmd:   "setconfig",
+			args:  []string{"-device", "mock", "-cli", "interface Loopback9 description e2e"},
+			want:  []string{"config applied to"},
+			check: hasCLI("interface Loopback9 description e2e"),
+		},
+		{
+			name: "setconfig/stage",
+			cmd:  "setconfig",
+			args: []string{"-device", "mock", "-journal", "{dir}/journal", "stage", "-cli", "interface Loopback10 description staged"},
+			want: []string{"change staged for mock", "+interface Loopback10 description staged"},
+			check: func(e *env) error {
+				if strings.Contains(e.mock.CLI(), "Loopback10") {
+					return fmt.Errorf("staged change is already on the mock")
+				}
+				return nil
+			},
+		},
+		{
+			name:  "setconfig/commit",
+			cmd:   "setconfig",
+			args:  []string{"-device", "mock", "-journal", "{dir}/journal", "commit", "-label", "e2e", "-comment", "staged loopback"},
+			want:  []string{"commit 1 applied to"},
+			check: hasCLI("interface Loopback10 description staged"),
+		},
+		{
+			// The label and comment are kept in the journal.
+			name: "setconfig/log",
+			cmd:  "setconfig",
+			args: []string{"-device", "mock", "-journal", "{dir}/journal", "log"},
+			want: []string{"committed  e2e    staged loopback"},
+		},
+		{
+			// replaceconfig left Loopback0 out, so merging it back adds it.
+			name:   "setconfig/stage-merge",
+			cmd:    "setconfig",
+			args:   []string{"-device", "mock", "-journal", "{dir}/journal", "stage", "-ypath", "../input/mock/config.json"},
+			want:   []string{"change staged for mock", "+ openconfig-interfaces:interfaces/interface[name=Loopback0]/config/name"},
+			reject: []string{"- openconfig", "no changes"},
+		},
+		{
+			name: "showcmd",
+			cmd:  "showcmd",
+			args: []string{"-device", "mock"},
+			want: []string{"output from 127.0.0.1", "listening-port", "1 devices, 0 failed"},
+		},
+		{
+			name: "showcmd/running-config",
+			cmd:  "showcmd",
+			args: []string{"-device", "mock", "-cli", "show running-config"},
+			want: []string{"hostname mock", "interface Loopback9 description e2e"},
+		},
+		{
+			name: "showcmd/unknown",
+			cmd:  "showcmd",
+			args: []string{"-device", "mock", "-cli", "show bogus"},
+			fail: true,
+			want: []string{"no canned output", "1 devices, 1 failed"},
+		},
+		{
+			name: "action",
+			cmd:  "action",
+			args: []string{"-device", "mock"},
+			want: []string{"output from 127.0.0.1", "ping-response"},
+		},
+		{
+			name: "action/unknown",
+			cmd:  "action",
+			args: []string{"-device", "mock", "-act", "../input/action/crypto.json", "-enc", "cli"},
+			fail: true,
+			want: []string{"don't recognize encoding"},
+		},
+		{
+			// The mock doesn't serve the service layer API, so setroute
+			// can only show it reports the failure instead of hanging.
+			name: "setroute",
+			cmd:  "setroute",
+			args: []string{"-device", "mock"},
+			fail: true,
+		},
+		{
+			name: "xrctl/get",
+			cmd:  "xrctl",
+			args: []string{"-device", "mock", "get"},
+			want: []string{"config from 127.0.0.1", "Loopback201", "1 devices, 0 failed"},
+		},
+		{
+			name: "xrctl/json",
+			cmd:  "xrctl",
+			args: []string{"-group", "mock", "-o", "json", "show", "-cli", "show running-config"},
+			want: []string{`"device": "mock"`, `"device": "mock2"`, "hostname mock"},
+		},
+		{
+			// Loopback0 went with replaceconfig.
+			name:  "xrctl/merge",
+			cmd:   "xrctl",
+			args:  []string{"-device", "mock", "merge", "-ypath", "../input/mock/config.json"},
+			want:  []string{"config merged on", "Request ID: 1, Response ID: 1"},
+			check: hasInterface("Loopback0", true),
+		},
+		{
+			name: "xrctl/diff",
+			cmd:  "xrctl",
+			args: []string{"-device", "mock", "diff", "-ypath", "../input/mock/config.json"},
+			want: []string{"no drift on 127.0.0.1"},
+		},
+		{
+			name: "xrctl/diff-drift",
+			cmd:  "xrctl",
+			args: []string{"-device", "mock", "diff", "-ypath", "../e2e/testdata/drift.json"},
+			fail: true,
+			want: []string{"drift on 127.0.0.1", "0 added, 0 removed, 1 changed", "drift on 1 of 1 devices"},
+		},
+		{
+			name: "xrctl/backup",
+			cmd:  "xrctl",
+			args: []string{"-device", "mock", "backup", "-dir", "{dir}/backups", "-cli"},
+			want: []string{"backup of 127.0.0.1", "backups/mock/", ".json\n", ".cfg\n", "1 devices, 0 failed"},
+		},
+		{
+			name:  "xrctl/merge-drift",
+			cmd:   "xrctl",
+			args:  []string{"-device", "mock", "merge", "-ypath", "../e2e/testdata/drift.json"},
+			want:  []string{"config merged on"},
+			check: hasInterface("Loopback0", true, "PEER: changed"),
+		},
+		{
+			// The latest snapshot undoes the merge.
+			name:  "xrctl/restore",
+			cmd:   "xrctl",
+			args:  []string{"-device", "mock", "restore", "-dir", "{dir}/backups", "-yes"},
+			want:  []string{"restore plan for 127.0.0.1", `"PEER: changed"`, "config restored on 127.0.0.1", "remaining differences:\nnone"},
+			check: hasInterface("Loopback0", true, "PEER: router-id"),
+		},
+		{
+			name: "xrctl/restore-clean",
+			cmd:  "xrctl",
+			args: []string{"-device", "mock", "restore", "-dir", "{dir}/backups", "-yes"},
+			want: []string{"nothing to restore"},
+		},
+		{
+			name:    "xrctl/subscribe",
+			cmd:     "xrctl",
+			args:    []string{"-device", "mock", "subscribe"},
+			stream:  true,
+			timeout: 5 * time.Second,
+			want:    []string{"Path: " + lldpPath, `"NodeIdStr": "mock"`, "router3"},
+		},
+		{
+			name: "xrctl/unknown",
+			cmd:  "xrctl",
+			args: []string{"bogus"},
+			fail: true,
+			code: 2,
+			want: []string{`unknown command "bogus"`},
+		},
+		{
+			name:    "telemetry",
+			cmd:     "telemetry",
+			args:    []string{"-device", "mock"},
+			stream:  true,
+			timeout: 5 * time.Second,
+			want:    []string{"Path: " + lldpPath, "router1"},
+		},
+		{
+			name:    "telemetrykv",
+			cmd:     "telemetrykv",
+			args:    []string{"-device", "mock"},
+			stream:  true,
+			timeout: 5 * time.Second,
+			want:    []string{"Path: " + lldpPath, "system-name: router3", "hold-time: 120"},
+		},
+		{
+			name:    "telemetrygpb",
+			cmd:     "telemetrygpb",
+			args:    []string{"-device", "mock"},
+			stream:  true,
+			timeout: 5 * time.Second,
+			want:    []string{"Path: " + lldpPath, "Decoded Keys", "router1"},
+		},
+		{
+			name:    "telemetrygpb/unknown-path",
+			cmd:     "telemetrygpb",
+			args:    []string{"-device", "mock", "-subs", "COUNTERS"},
+			stream:  true,
+			timeout: 5 * time.Second,
+			want:    []string{"no messages for path Cisco-IOS-XR-infra-statsd-oper"},
+			reject:  []string{"Decoded"},
+		},
+		{
+			name:    "telemetrygpb/proto",
+			cmd:     "telemetrygpb",
+			args:    []string{"-device", "mock", "-subs", "COUNTERS", "-proto", "../input/mock/proto"},
+			stream:  true,
+			timeout: 5 * time.Second,
+			want:    []string{`"interface_name": "Loopback0"`, `"packets_received": 1000`, `"level": "WARNING"`},
+			reject:  []string{"no messages for path"},
+		},
+		{
+			name:    "telemetry/record",
+			cmd:     "telemetry",
+			args:    []string{"-device", "mock", "-record", "{dir}/lldp.rec"},
+			stream:  true,
+			timeout: 5 * time.Second,
+			want:    []string{"Path: " + lldpPath},
+			check:   sameFrames("{dir}/lldp.rec", "LLDP.gpbkv.rec"),
+		},
+		{
+			// A capture replays through any decoder of its encoding,
+			// without a router.
+			name:   "telemetrykv/replay",
+			cmd:    "telemetrykv",
+			args:   []string{"-replay", "{dir}/lldp.rec", "-speed", "0"},
+			want:   []string{"Path: " + lldpPath, "system-name: router3"},
+			reject: []string{"timed out"},
+		},
+		{
+			// COUNTERS.gpbkv.rec has a value of every type.
+			name: "telemetrykv/values",
+			cmd:  "telemetrykv",
+			args: []string{"-replay", "../input/mock/telemetry/COUNTERS.gpbkv.rec", "-speed", "0"},
+			want: []string{
+				"packets-received: 1000\n",
+				"input-drops: 0\n",
+				"carrier-delta: -1\n",
+				"clock-offset: -250\n",
+				"input-load: 0.125\n",
+				"output-load: 0.25\n",
+				"is-up: true\n",
+				"mac-address: 00:8a:96:46:6c:d9\n",
+				"ip-address: 203.0.113.1\n",
+				"ipv6-address: 2001:db8::1\n",
+				"checksum: 0xbeef\n",
+				// A value with children
+				"state: im-state-up\n   transitions: 2\n",
+			},
+			reject: []string{"unknown type", "[190 239]"},
+		},
+		{
+			// Lines replace the printout, with integers marked as such
+			// and the time the router sent each message.
+			name: "telemetrygpb/influx",
+			cmd:  "telemetrygpb",
+			args: []string{"-replay", "../input/mock/telemetry/COUNTERS.gpb.rec", "-speed", "0", "-proto", "../input/mock/proto", "-influx", "-"},
+			want: []string{
+				countersPath + ",interface_name=Loopback0,source=mock,subscription=COUNTERS bytes_received=384001i,",
+			},
+			reject: []string{"Decoded Keys"},
+		},
+		{
+			name:    "telemetrykv/dialout-tcp",
+			cmd:     "telemetrykv",
+			args:    []string{"-dialout", "tcp://{addr}"},
+			dialout: &dial{addr: "tcp://{addr}", subs: "LLDP", enc: 3},
+			want:    []string{"over TCP", "Path: " + lldpPath, "system-name: router3"},
+		},
+		{
+			// Sessions over gRPC and TCP go to the same output.
+			name:    "telemetrygpb/dialout-grpc",
+			cmd:     "telemetrygpb",
+			args:    []string{"-dialout", "grpc://{addr},tcp://127.0.0.1:0"},
+			dialout: &dial{addr: "grpc://{addr}", subs: "LLDP", enc: 2},
+			want:    []string{"over gRPC", "Path: " + lldpPath, "Decoded Keys", "router1"},
+			reject:  []string{"failed"},
+		},
+		{
+			name: "telemetry/dialout-bad",
+			cmd:  "telemetry",
+			args: []string{"-dialout", "udp://{addr}"},
+			fail: true,
+			want: []string{"isn't tcp://host:port or grpc://host:port"},
+		},
+		{
+			// The stand-in fails the first post, so the batch is retried.
+			name: "telemetrykv/influx-http",
+			cmd:  "telemetrykv",
+			args: []string{"-replay", "../input/mock/telemetry/COUNTERS.gpbkv.rec", "-speed", "0", "-influx", "{influx}/write?db=kv"},
+			check: influxLines("kv",
+				`,interface-name=HundredGigE0/0/0/0,source=mock,subscription=COUNTERS `,
+				`packets-received=3000i`,
+				`mac-address="00:8a:96:46:6c:d9"`,
+				`is-up=true`,
+			),
+			reject: []string{"could not write"},
+		},
+		{
+			// With the bus down, messages wait on disk.
+			name:   "telemetry/publish-down",
+			cmd:    "telemetry",
+			args:   []string{"-replay", "../input/mock/telemetry/LLDP.gpbkv.rec", "-speed", "0", "-publish", "nats://{addr}", "-spool", "{dir}/spool"},
+			want:   []string{"3 messages left in"},
+			reject: []string{"could not publish", "Path:"},
+			check:  spooled("{dir}/spool", 3),
+		},
+		{
+			// Once it's back, they go ahead of the new ones.
+			name:   "telemetry/publish",
+			cmd:    "telemetry",
+			args:   []string{"-replay", "../input/mock/telemetry/LLDP.gpbkv.rec", "-speed", "0", "-publish", "nats://{bus}", "-spool", "{dir}/spool"},
+			reject: []string{"could not", "Path:"},
+			check: func(e *env) error {
+				if err := published(lldpTopic, 6, `"encoding_path":"`+lldpPath+`"`)(e); err != nil {
+					return err
+				}
+				return spooled("{dir}/spool", 0)(e)
+			},
+		},
+		{
+			// -influx and -publish both get every message.
+			name:   "telemetry/influx-publish",
+			cmd:    "telemetry",
+			args:   []string{"-replay", "../input/mock/telemetry/LLDP.gpbkv.rec", "-speed", "0", "-influx", "{influx}/write?db=lldp", "-publish", "nats://{bus}"},
+			reject: []string{"could not", "Path:"},
+			check: func(e *env) error {
+				if err := published(lldpTopic, 9, `"encoding_path":"`+lldpPath+`"`)(e); err != nil {
+					return err
+				}
+				return influxLines("lldp", lldpPath+",", "source=mock")(e)
+			},
+		},
+		{
+			// The stand-in fails the first post, so the rows are spooled
+			// and sent as the command ends.
+			name:   "telemetrygpb/publish-webhook",
+			cmd:    "telemetrygpb",
+			args:   []string{"-replay", "../input/mock/telemetry/COUNTERS.gpb.rec", "-speed", "0", "-proto", "../input/mock/proto", "-publish", "{influx}/write?db=hook", "-spool", "{dir}/hook"},
+			reject: []string{"could not", "Decoded Keys"},
+			check: func(e *env) error {
+				if err := influxLines("hook", `"keys":{"interface_name":"Loopback0"}`, `"packets_received":3001`)(e); err != nil {
+					return err
+				}
+				return spooled("{dir}/hook", 0)(e)
+			},
+		},
+		{
+			// Each device gets its own subscriptions, and the mock's
+			// streams end after their recording, so the sessions are
+			// set up again.
+			name:    "collector/devices",
+			cmd:     "collector",
+			args:    []string{"-group", "mock", "-subs", "COUNTERS", "-backoff", "100ms", "-listen", "{addr}"},
+			fetch:   "/metrics",
+			timeout: 10 * time.Second,
+			want: []string{
+				`xr_generic_counters_packets_received{interface_name="HundredGigE0/0/0/0",router="mock"} 3000`,
+				`xr_detail_lldp_neighbor_hold_time{device_id="router3",`,
+				`xr_collector_up{router="mock",subscription="COUNTERS"} `,
+				`xr_collector_up{router="mock2",subscription="LLDP"} `,
+				// Sessions that got messages start the pause over.
+				"subscription COUNTERS to mock ended after 3 messages: the router ended the subscription; trying again in 100ms",
+				"subscription LLDP to mock2 ended after 3 messages: the router ended the subscription; trying again in 100ms",
+			},
+			reject: []string{"subscribed to LLDP on mock\n", "subscribed to COUNTERS on mock2"},
+		},
+		{
+			// A device that fails doesn't hold up the others.
+			name:    "collector/failing",
+			cmd:     "collector",
+			args:    []string{"-group", "all", "-subs", "COUNTERS", "-backoff", "100ms", "-listen", "{addr}"},
+			fetch:   "/metrics",
+			timeout: 10 * time.Second,
+			want: []string{
+				`xr_collector_up{router="badauth",subscription="COUNTERS"} 0`,
+				`xr_collector_attempts{router="badauth",subscription="COUNTERS"} 3`,
+				`xr_generic_counters_packets_received{interface_name="HundredGigE0/0/0/0",router="mock"} 3000`,
+				// The pause doubles while it keeps failing.
+				"subscription COUNTERS to badauth ended after 0 messages",
+				"trying again in 100ms",
+				"trying again in 200ms",
+			},
+		},
+		{
+			name:  "collector/gpbkv",
+			cmd:   "collector",
+			args:  []string{"-replay", "../input/mock/telemetry/COUNTERS.gpbkv.rec", "-speed", "0", "-listen", "{addr}"},
+			fetch: "/metrics",
+			want: []string{
+				"# TYPE xr_generic_counters_packets_received untyped\n",
+				`xr_generic_counters_packets_received{interface_name="HundredGigE0/0/0/0",router="mock"} 3000` + "\n",
+				`xr_generic_counters_carrier_delta{interface_name="HundredGigE0/0/0/0",router="mock"} -1` + "\n",
+				`xr_generic_counters_input_load{interface_name="HundredGigE0/0/0/0",router="mock"} 0.125` + "\n",
+				`xr_generic_counters_is_up{interface_name="HundredGigE0/0/0/0",router="mock"} 1` + "\n",
+				`xr_generic_counters_state_transitions{interface_name="HundredGigE0/0/0/0",router="mock"} 2` + "\n",
+			},
+			// Strings aren't metrics.
+			reject: []string{"mac_address", "xr_generic_counters_state{"},
+		},
+		{
+			name:  "collector/gpb",
+			cmd:   "collector",
+			args:  []string{"-replay", "../input/mock/telemetry/COUNTERS.gpb.rec", "-speed", "0", "-enc", "gpb", "-proto", "../input/mock/proto", "-prefix", "lab", "-listen", "{addr}"},
+			fetch: "/metrics",
+			want: []string{
+				`lab_generic_counters_packets_received{interface_name="Loopback0",router="mock"} 3001` + "\n",
+				`lab_generic_counters_bytes_sent{interface_name="HundredGigE0/0/0/0",router="mock"} 345600` + "\n",
+			},
+		},
+		{
+			name:  "collector/lldp",
+			cmd:   "collector",
+			args:  []string{"-replay", "../input/mock/telemetry/LLDP.gpb.rec", "-speed", "0", "-enc", "gpb", "-listen", "{addr}"},
+			fetch: "/metrics",
+			want: []string{
+				`xr_detail_lldp_neighbor_hold_time{device_id="router1",interface_name="HundredGigE0/0/0/0",node_name="0/RP0/CPU0",router="`,
+			},
+		},
+		{
+			name: "telemetrykv/replay-missing",
+			cmd:  "telemetrykv",
+			args: []string{"-replay", "{dir}/missing.rec"},
+			fail: true,
+			want: []string{"could not replay the messages"},
+		},
+		{
+			name:    "telemetry/no-recording",
+			cmd:     "telemetry",
+			args:    []string{"-device", "mock", "-subs", "BGP"},
+			stream:  true,
+			timeout: 5 * time.Second,
+			want:    []string{"BGP has no gpbkv recording"},
+			reject:  []string{"Path:"},
+		},
+		{
+			name:   "getconfig/bad-cert",
+			cmd:    "getconfig",
+			args:   []string{"-device", "badcert"},
+			fail:   true,
+			want:   []string{"1 devices, 1 failed"},
+			reject: []string{"config from"},
+		},
+		{
+			name: "getconfig/bad-auth",
+			cmd:  "getconfig",
+			args: []string{"-device", "badauth"},
+			fail: true,
+			want: []string{"invalid username or password", "1 devices, 1 failed"},
+		},
+		{
+			name: "mergeconfig/bad-auth",
+			cmd:  "mergeconfig",
+			args: []string{"-device", "badauth"},
+			fail: true,
+			want: []string{"invalid username or password"},
+		},
+		{
+			name: "getconfig/timeout",
+			cmd:  "getconfig",
+			args: []string{"-device", "slow"},
+			fail: true,
+			// How gRPC words the deadline depends on when it hits, so
+			// only the failure counts.
+			want:   []string{"1 devices, 1 failed"},
+			reject: []string{"config from"},
+		},
+		{
+			name:   "showcmd/timeout",
+			cmd:    "showcmd",
+			args:   []string{"-device", "slow"},
+			fail:   true,
+			want:   []string{"1 devices, 1 failed"},
+			reject: []string{"output from"},
+		},
+		{
+			name:   "gumiclient/all",
+			cmd:    "gumiclient",
+			args:   []string{"-server", "{addr}", "all"},
+			noInv:  true,
+			server: gumiServer("memory"),
+			want:   []string{`{"name":"nleiva","email":"nleiva@example.com","id":1}`, `"name":"jdoe"`},
+		},
+		{
+			name:   "gumiclient/id",
+			cmd:    "gumiclient",
+			args:   []string{"-server", "{addr}", "id", "2"},
+			noInv:  true,
+			server: gumiServer("memory"),
+			want:   []string{`"name":"jdoe"`},
+			reject: []string{"nleiva"},
+		},
+		{
+			// The server checks users against yang/user.yang.
+			name:   "gumiclient/invalid",
+			cmd:    "gumiclient",
+			args:   []string{"-server", "{addr}", "create", "alice", "alice@example.com", "70000"},
+			noInv:  true,
+			server: gumiServer("memory"),
+			fail:   true,
+			want:   []string{"Create failed", "InvalidArgument"},
+		},
+		{
+			name:   "gumiclient/duplicate",
+			cmd:    "gumiclient",
+			args:   []string{"-server", "{addr}", "create", "alice", "alice@example.com", "1"},
+			noInv:  true,
+			server: gumiServer("memory"),
+			fail:   true,
+			want:   []string{"Create failed", "AlreadyExists"},
+		},
+		{
+			name:   "gumiclient/create",
+			cmd:    "gumiclient",
+			args:   []string{"-server", "{addr}", "create", "alice", "alice@example.com", "3"},
+			noInv:  true,
+			server: gumiServer("file"),
+			want:   []string{`{"name":"alice","email":"alice@example.com","id":3}`},
+		},
+		{
+			// The file store kept alice for the next server.
+			name:   "gumiclient/stored",
+			cmd:    "gumiclient",
+			args:   []string{"-server", "{addr}", "name", "alice"},
+			noInv:  true,
+			server: gumiServer("file"),
+			want:   []string{`"id":3`},
+		},
+		{
+			name:   "gumiclient/missing",
+			cmd:    "gumiclient",
+			args:   []string{"-server", "{addr}", "name", "bob"},
+			noInv:  true,
+			server: gumiServer("file"),
+			fail:   true,
+			want:   []string{"GetByName failed", "NotFound"},
+		},
+		{
+			name:  "gumiclient/down",
+			cmd:   "gumiclient",
+			args:  []string{"-server", "{addr}", "-timeout", "1", "all"},
+			noInv: true,
+			fail:  true,
+			want:  []string{"GetAll failed"},
+		},
+	}
+}
@@ -0,0 +1,12 @@
+/*
+Package e2e runs the commands of this repo against a mock router served from
+the test process, and checks their output, the request and response IDs they
+report, what they leave in the mock's config, and how they fail on a bad
+certificate, bad credentials and a router too slow to answer.
+
+	go test ./e2e [-run TestCommands/regexp] [-v]
+
+It builds the commands it runs, so it needs the Go toolchain; -short skips
+it.
+*/
+package e2e
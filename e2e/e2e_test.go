@@ -0,0 +1,564 @@
+package e2e
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nleiva/clus2019/xrmock"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+)
+
+var (
+	// Repo root; defaults to "..", as the commands' own defaults assume
+	root = flag.String("root", "..", "Root of the repo")
+	keep = flag.Bool("keep", false, "Keep the scratch directory with the binaries and inventory")
+)
+
+// slowBy is how long the slow router waits before answering, longer than
+// the timeout of its inventory entry.
+const slowBy = 3 * time.Second
+
+// suite is the env the cases run in; nil with -short.
+var suite *env
+
+func TestMain(m *testing.M) {
+	flag.Parse()
+	os.Exit(setup(m))
+}
+
+// setup serves the mocks for the cases, runs them and cleans up after
+// them.
+func setup(m *testing.M) int {
+	if testing.Short() {
+		return m.Run()
+	}
+	root, err := filepath.Abs(*root)
+	if err != nil {
+		log.Printf("could not find the repo: %v", err)
+		return 2
+	}
+	dir, err := ioutil.TempDir("", "e2e")
+	if err != nil {
+		log.Printf("could not create a scratch directory: %v", err)
+		return 2
+	}
+	if *keep {
+		log.Printf("scratch directory is %s", dir)
+	} else {
+		defer os.RemoveAll(dir)
+	}
+	e := &env{root: root, bin: filepath.Join(dir, "bin"), dir: dir, built: make(map[string]bool)}
+	stop, err := e.serve()
+	if err != nil {
+		log.Print(err)
+		return 2
+	}
+	defer stop()
+	suite = e
+	return m.Run()
+}
+
+// TestCommands runs the cases in order, as subtests named after them.
+func TestCommands(t *testing.T) {
+	if suite == nil {
+		t.Skip("skipping the commands in short mode")
+	}
+	for _, c := range cases() {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			if err := suite.runCase(t, c); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
+// env is what the cases run in: the mock, the binaries and an inventory
+// pointing at the mock.
+type env struct {
+	root   string
+	bin    string
+	dir    string
+	inv    string
+	mock   *xrmock.Server
+	influx *influxServer
+	bus    *busServer
+
+	// built are the commands built so far.
+	built map[string]bool
+}
+
+// build compiles a command the first time a case needs it, so -run only
+// builds what the cases it picks run.
+func (e *env) build(cmd string) error {
+	if e.built[cmd] {
+		return nil
+	}
+	c := exec.Command("go", "build", "-o", filepath.Join(e.bin, cmd), "./"+cmd)
+	c.Dir = e.root
+	if out, err := c.CombinedOutput(); err != nil {
+		return fmt.Errorf("could not build %s: %v\n%s", cmd, err, out)
+	}
+	e.built[cmd] = true
+	return nil
+}
+
+// serve starts the mock router twice, once answering right away and once
+// after slowBy, with the InfluxDB and NATS stand-ins, writes their
+// certificate, an inventory for them and the users of the gUMI cases, and
+// returns a function that stops them.
+func (e *env) serve() (func(), error) {
+	mock, err := e.newMock()
+	if err != nil {
+		return nil, err
+	}
+	e.mock = mock
+
+	cert, key, err := xrmock.SelfSigned()
+	if err != nil {
+		return nil, err
+	}
+	// A second certificate, for a router other than the one the
+	// inventory expects.
+	other, _, err := xrmock.SelfSigned()
+	if err != nil {
+		return nil, err
+	}
+	// The users the gUMI server's file store starts with.
+	users, err := ioutil.ReadFile(filepath.Join(e.root, "input", "users.json"))
+	if err != nil {
+		return nil, fmt.Errorf("could not read the users: %v", err)
+	}
+	files := map[string][]byte{"mock.pem": cert, "other.pem": other, "users.json": users}
+	for name, b := range files {
+		if err = ioutil.WriteFile(filepath.Join(e.dir, name), b, 0600); err != nil {
+			return nil, fmt.Errorf("could not write %s: %v", name, err)
+		}
+	}
+	tc, err := tls.X509KeyPair(cert, key)
+	if err != nil {
+		return nil, fmt.Errorf("could not load the certificate: %v", err)
+	}
+	creds := grpc.Creds(credentials.NewServerTLSFromCert(&tc))
+
+	fast := grpc.NewServer(creds)
+	mock.Register(fast)
+	slow := grpc.NewServer(creds,
+		grpc.UnaryInterceptor(func(ctx context.Context, req interface{}, _ *grpc.UnaryServerInfo, h grpc.UnaryHandler) (interface{}, error) {
+			time.Sleep(slowBy)
+			return h(ctx, req)
+		}),
+		grpc.StreamInterceptor(func(srv interface{}, ss grpc.ServerStream, _ *grpc.StreamServerInfo, h grpc.StreamHandler) error {
+			time.Sleep(slowBy)
+			return h(srv, ss)
+		}))
+	mock.Register(slow)
+
+	var addrs []string
+	for _, srv := range []*grpc.Server{fast, slow} {
+		lis, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			return nil, fmt.Errorf("could not listen: %v", err)
+		}
+		go srv.Serve(lis)
+		addrs = append(addrs, lis.Addr().String())
+	}
+	e.influx = &influxServer{}
+	hs := httptest.NewServer(e.influx)
+	e.influx.url = hs.URL
+	if e.bus, err = newBus(); err != nil {
+		fast.Stop()
+		slow.Stop()
+		hs.Close()
+		return nil, err
+	}
+
+	stop := func() {
+		fast.Stop()
+		slow.Stop()
+		hs.Close()
+		e.bus.Close()
+	}
+	if err = e.writeInventory(addrs[0], addrs[1]); err != nil {
+		stop()
+		return nil, err
+	}
+	return stop, nil
+}
+
+// newMock returns a mock router with the sample config, canned answers
+// and recordings under input/mock, replaying them without pauses.
+func (e *env) newMock() (*xrmock.Server, error) {
+	in := filepath.Join(e.root, "input", "mock")
+	s := &xrmock.Server{
+		Telemetry: filepath.Join(in, "telemetry"),
+		Username:  "cisco",
+		Password:  "cisco",
+	}
+	var err error
+	if s.Config, err = xrmock.LoadDatastore(filepath.Join(in, "config.json")); err != nil {
+		return nil, err
+	}
+	if s.Canned, err = xrmock.LoadCanned(filepath.Join(in, "canned.json")); err != nil {
+		return nil, err
+	}
+	cli, err := ioutil.ReadFile(filepath.Join(in, "running.cfg"))
+	if err != nil {
+		return nil, fmt.Errorf("could not read the CLI config: %v", err)
+	}
+	s.SetCLI(string(cli))
+	return s, nil
+}
+
+// writeInventory describes the mock as the devices the cases target.
+func (e *env) writeInventory(addr, slowAddr string) error {
+	inv := map[string]interface{}{
+		"defaults": map[string]interface{}{
+			"username": "cisco",
+			"password": "cisco",
+			"cert":     "mock.pem",
+			"timeout":  5,
+		},
+		"devices": []map[string]interface{}{
+			{"name": "mock", "host": addr, "groups": []string{"mock"}},
+			{"name": "mock2", "host": addr, "groups": []string{"mock"}, "subscriptions": []string{"LLDP"}},
+			{"name": "badcert", "host": addr, "cert": "other.pem"},
+			{"name": "badauth", "host": addr, "password": "wrong"},
+			{"name": "slow", "host": slowAddr, "timeout": 1},
+		},
+	}
+	b, err := json.MarshalIndent(inv, "", "    ")
+	if err != nil {
+		return err
+	}
+	e.inv = filepath.Join(e.dir, "inventory.json")
+	return ioutil.WriteFile(e.inv, b, 0644)
+}
+
+// runCase runs a command from its own directory, so its defaults find the
+// files under input, and checks the outcome.
+func (e *env) runCase(t *testing.T, c testCase) error {
+	if err := e.build(c.cmd); err != nil {
+		return err
+	}
+	timeout := c.timeout
+	if timeout == 0 {
+		timeout = 15 * time.Second
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	// {addr} is a free address, for the command to listen on or to find
+	// nothing listening on.
+	addr, err := freeAddr()
+	if err != nil {
+		return err
+	}
+	expand := func(args []string) []string {
+		var out []string
+		for _, a := range args {
+			a = strings.Replace(a, "{dir}", e.dir, -1)
+			a = strings.Replace(a, "{influx}", e.influx.url, -1)
+			a = strings.Replace(a, "{bus}", e.bus.addr, -1)
+			out = append(out, strings.Replace(a, "{addr}", addr, -1))
+		}
+		return out
+	}
+	var out syncBuffer
+	if c.server != nil {
+		stop, err := e.start(c.server.cmd, expand(c.server.args), addr, &out)
+		if err != nil {
+			return err
+		}
+		defer stop()
+	}
+	args := expand(c.args)
+	if !c.noInv {
+		args = append([]string{"-inv", e.inv}, args...)
+	}
+	cmd := exec.CommandContext(ctx, filepath.Join(e.bin, c.cmd), args...)
+	cmd.Dir = filepath.Join(e.root, c.cmd)
+	cmd.Stdout, cmd.Stderr = &out, &out
+	err = cmd.Start()
+	if err == nil && c.dialout != nil {
+		dialOut(ctx, e.mock, strings.Replace(c.dialout.addr, "{addr}", addr, -1), c.dialout, c.want, &out)
+		cancel()
+		cmd.Wait()
+	} else if err == nil && c.fetch != "" {
+		body := fetch(ctx, "http://"+addr+c.fetch, c.want, &out)
+		cancel()
+		cmd.Wait()
+		out.WriteString("\n" + body)
+	} else if err == nil {
+		err = cmd.Wait()
+	}
+	output := out.String()
+	t.Logf("%s %s\n%s", c.cmd, strings.Join(c.args, " "), output)
+
+	switch {
+	case c.stream || c.fetch != "" || c.dialout != nil:
+		// Streams run until they're stopped, or fail as they end.
+	case ctx.Err() != nil:
+		return fmt.Errorf("%s didn't finish within %v\n%s", c.cmd, timeout, output)
+	case err != nil && !c.fail:
+		return fmt.Errorf("%s failed: %v\n%s", c.cmd, err, output)
+	case err == nil && c.fail:
+		return fmt.Errorf("%s succeeded, expected it to fail\n%s", c.cmd, output)
+	case c.code != 0 && cmd.ProcessState.ExitCode() != c.code:
+		return fmt.Errorf("%s exited with %d, expected %d\n%s", c.cmd, cmd.ProcessState.ExitCode(), c.code, output)
+	}
+	for _, w := range c.want {
+		if !strings.Contains(output, w) {
+			return fmt.Errorf("output doesn't have %q\n%s", w, output)
+		}
+	}
+	for _, w := range c.reject {
+		if strings.Contains(output, w) {
+			return fmt.Errorf("output has %q\n%s", w, output)
+		}
+	}
+	if c.check != nil {
+		if err = c.check(e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// start runs a server a case needs, from its own directory, until the
+// function it returns is called. The server is up once addr takes
+// connections.
+func (e *env) start(name string, args []string, addr string, out io.Writer) (func(), error) {
+	if err := e.build(name); err != nil {
+		return nil, err
+	}
+	cmd := exec.Command(filepath.Join(e.bin, name), args...)
+	cmd.Dir = filepath.Join(e.root, name)
+	cmd.Stdout, cmd.Stderr = out, out
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+	stop := func() {
+		cmd.Process.Kill()
+		cmd.Wait()
+	}
+	for deadline := time.Now().Add(5 * time.Second); ; {
+		conn, err := net.Dial("tcp", addr)
+		if err == nil {
+			conn.Close()
+			return stop, nil
+		}
+		if time.Now().After(deadline) {
+			stop()
+			return nil, fmt.Errorf("%s isn't listening on %s: %v", name, addr, err)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+// freeAddr returns a local address nothing listens on.
+func freeAddr() (string, error) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return "", fmt.Errorf("could not find a free port: %v", err)
+	}
+	defer lis.Close()
+	return lis.Addr().String(), nil
+}
+
+// fetch gets url until the reply, with the output so far, has every text
+// in want, or ctx is done, and returns the last reply.
+func fetch(ctx context.Context, url string, want []string, out *syncBuffer) string {
+	var body string
+	for {
+		if resp, err := http.Get(url); err == nil {
+			b, _ := ioutil.ReadAll(resp.Body)
+			resp.Body.Close()
+			body = string(b)
+			all, output := true, out.String()
+			for _, w := range want {
+				all = all && (strings.Contains(body, w) || strings.Contains(output, w))
+			}
+			if all {
+				return body
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return body
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+}
+
+// dialOut has the mock send the recording of d to addr until the output
+// has every text in want, or ctx is done. Sessions fail until the command
+// listens, and are dialed again.
+func dialOut(ctx context.Context, mock *xrmock.Server, addr string, d *dial, want []string, out *syncBuffer) {
+	for {
+		mock.DialOut(ctx, addr, d.subs, d.enc)
+		all, output := true, out.String()
+		for _, w := range want {
+			all = all && strings.Contains(output, w)
+		}
+		if all {
+			return
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+}
+
+// syncBuffer is a bytes.Buffer a command can write to while it's read.
+type syncBuffer struct {
+	mu sync.Mutex
+	b  bytes.Buffer
+}
+
+func (s *syncBuffer) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.b.Write(p)
+}
+
+func (s *syncBuffer) WriteString(p string) (int, error) {
+	return s.Write([]byte(p))
+}
+
+func (s *syncBuffer) String() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.b.String()
+}
+
+// influxServer stands in for the write endpoint of InfluxDB. It keeps the
+// lines posted for each database, and fails the first post to each with
+// 503, as a server that's starting up, for the writers to retry.
+type influxServer struct {
+	url string
+
+	mu    sync.Mutex
+	lines map[string]*bytes.Buffer
+}
+
+func (s *influxServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	b, _ := ioutil.ReadAll(r.Body)
+	db := r.URL.Query().Get("db")
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.lines == nil {
+		s.lines = make(map[string]*bytes.Buffer)
+	}
+	buf, ok := s.lines[db]
+	if !ok {
+		s.lines[db] = new(bytes.Buffer)
+		http.Error(w, "starting up", http.StatusServiceUnavailable)
+		return
+	}
+	buf.Write(b)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// received returns the lines posted for database db.
+func (s *influxServer) received(db string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if buf, ok := s.lines[db]; ok {
+		return buf.String()
+	}
+	return ""
+}
+
+// busServer stands in for a NATS server. It speaks enough of the protocol
+// for a publisher, and keeps the messages published on each subject.
+type busServer struct {
+	addr string
+	lis  net.Listener
+
+	mu   sync.Mutex
+	msgs map[string][]string
+}
+
+func newBus() (*busServer, error) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, fmt.Errorf("could not listen: %v", err)
+	}
+	b := &busServer{addr: lis.Addr().String(), lis: lis, msgs: make(map[string][]string)}
+	go func() {
+		for {
+			conn, err := lis.Accept()
+			if err != nil {
+				return
+			}
+			go b.serve(conn)
+		}
+	}()
+	return b, nil
+}
+
+// serve takes PUB and answers PING, until the publisher goes away.
+func (b *busServer) serve(conn net.Conn) {
+	defer conn.Close()
+	fmt.Fprintf(conn, "INFO {\"server_id\":\"e2e\",\"max_payload\":1048576}\r\n")
+	r := bufio.NewReader(conn)
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		f := strings.Fields(line)
+		switch {
+		case len(f) == 0:
+		case f[0] == "PING":
+			fmt.Fprintf(conn, "PONG\r\n")
+		case f[0] == "PUB" && len(f) == 3:
+			n, err := strconv.Atoi(f[2])
+			if err != nil {
+				fmt.Fprintf(conn, "-ERR 'Unknown Protocol Operation'\r\n")
+				return
+			}
+			msg := make([]byte, n+2)
+			if _, err = io.ReadFull(r, msg); err != nil {
+				return
+			}
+			b.mu.Lock()
+			b.msgs[f[1]] = append(b.msgs[f[1]], string(msg[:n]))
+			b.mu.Unlock()
+		}
+	}
+}
+
+// published returns the messages published on subject.
+func (b *busServer) published(subject string) []string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]string(nil), b.msgs[subject]...)
+}
+
+func (b *busServer) Close() error {
+	return b.lis.Close()
+}
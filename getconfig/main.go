@@ -5,8 +5,11 @@ import (
 	"fmt"
 	"io/ioutil"
 	"log"
+	"os"
 	"time"
 
+	"github.com/nleiva/clus2019/inventory"
+	"github.com/nleiva/clus2019/runner"
 	xr "github.com/nleiva/xrgrpc"
 )
 
@@ -17,46 +20,54 @@ func timeTrack(start time.Time) {
 }
 
 func main() {
-	// To time this process
-	defer timeTrack(time.Now())
-
 	// YANG path arguments; defaults to "yangocpaths.json"
 	ypath := flag.String("ypath", "../input/yangocpaths.json", "YANG path arguments")
+	// Number of devices to query in parallel; defaults to 10
+	workers := flag.Int("workers", 10, "Devices to query concurrently")
+	// Per-device timeout in seconds; defaults to the inventory value or 5
+	timeout := flag.Int("timeout", 0, "Per-device timeout in seconds")
+	// Target devices; defaults to "router2" from "inventory.json"
+	var tg inventory.Target
+	tg.AddFlags(flag.CommandLine)
 	flag.Parse()
 
-	// ID for the transaction.
-	var id int64 = 1
-	var output string
-
-	// Manually specify target parameters.
-	router, err := xr.BuildRouter(
-		xr.WithUsername("cisco"),
-		xr.WithPassword("cisco"),
-		//xr.WithHost("[2001:420:2cff:1204::5502:1]:57344"),
-		//xr.WithCert("../input/certificate/router1.pem"),
-		xr.WithHost("[2001:420:2cff:1204::5502:2]:57344"),
-		xr.WithCert("../input/certificate/router2.pem"),
-		xr.WithTimeout(5),
-	)
-	if err != nil {
-		log.Fatalf("could not build a router, %v", err)
+	if code := run(tg, *ypath, *workers, *timeout); code != 0 {
+		os.Exit(code)
 	}
+}
 
-	// Setup a connection to the target.
-	conn, ctx, err := xr.Connect(*router)
+func run(tg inventory.Target, ypath string, workers, timeout int) int {
+	// To time this process
+	defer timeTrack(time.Now())
+
+	devices, err := tg.Devices()
 	if err != nil {
-		log.Fatalf("could not setup a client connection to %s, %v", router.Host, err)
+		log.Fatalf("could not select a device, %v", err)
 	}
-	defer conn.Close()
 
 	// Get config for the YANG paths specified on 'js'
-	js, err := ioutil.ReadFile(*ypath)
+	js, err := ioutil.ReadFile(ypath)
 	if err != nil {
-		log.Fatalf("could not read file: %v: %v\n", *ypath, err)
+		log.Fatalf("could not read file: %v: %v\n", ypath, err)
 	}
-	output, err = xr.GetConfig(ctx, conn, string(js), id)
-	if err != nil {
-		log.Fatalf("could not get the config from %s, %v", router.Host, err)
+
+	r := runner.Runner{
+		Workers:  workers,
+		Timeout:  timeout,
+		Defaults: []xr.RouterOption{xr.WithTimeout(5)},
+	}
+	results := r.Run(devices, runner.GetConfig(string(js)))
+	for _, res := range results {
+		if res.Err != nil {
+			continue
+		}
+		fmt.Printf("\nconfig from %s\n %s\n", res.Host, res.Output)
+	}
+	fmt.Println()
+	runner.Report(os.Stdout, results)
+
+	if runner.Failed(results) > 0 {
+		return 1
 	}
-	fmt.Printf("\nconfig from %s\n %s\n", router.Host, output)
+	return 0
 }
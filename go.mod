@@ -9,4 +9,5 @@ require (
 	github.com/pkg/errors v0.8.0
 	golang.org/x/net v0.0.0-20190313220215-9f648a60d977
 	google.golang.org/grpc v1.16.0
+	gopkg.in/yaml.v2 v2.2.1
 )
@@ -0,0 +1,14 @@
+package gpb
+
+import (
+	lldp "github.com/nleiva/xrgrpc/proto/telemetry/lldp65x"
+)
+
+// The messages compiled in. Only the LLDP neighbor details path is: it's
+// the one the examples subscribe to, with -subs LLDP, and lldp65x is the
+// xrgrpc package the module pins that has its messages. Every other path
+// takes its .proto files, loaded with Load.
+func init() {
+	Default.Register("Cisco-IOS-XR-ethernet-lldp-oper:lldp/nodes/node/neighbors/details/detail",
+		&lldp.LldpNeighborEntry_KEYS{}, &lldp.LldpNeighborEntry{})
+}
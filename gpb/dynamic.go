@@ -0,0 +1,236 @@
+package gpb
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"math"
+
+	"github.com/pkg/errors"
+)
+
+// Wire types of the protobuf encoding.
+const (
+	wireVarint  = 0
+	wireFixed64 = 1
+	wireBytes   = 2
+	wireFixed32 = 5
+)
+
+// scalars maps the scalar types to their wire type.
+var scalars = map[string]int{
+	"double":   wireFixed64,
+	"float":    wireFixed32,
+	"int32":    wireVarint,
+	"int64":    wireVarint,
+	"uint32":   wireVarint,
+	"uint64":   wireVarint,
+	"sint32":   wireVarint,
+	"sint64":   wireVarint,
+	"bool":     wireVarint,
+	"fixed32":  wireFixed32,
+	"fixed64":  wireFixed64,
+	"sfixed32": wireFixed32,
+	"sfixed64": wireFixed64,
+	"string":   wireBytes,
+	"bytes":    wireBytes,
+}
+
+// Object is a message decoded with a Descriptor. It marshals to JSON with
+// its fields in the order of the .proto file, named as there, like the
+// messages protoc-gen-go generates.
+type Object struct {
+	d      *Descriptor
+	values map[*Field]interface{}
+}
+
+// Get returns the value of the field called name, a []interface{} for a
+// repeated field, or nil if it wasn't in the message.
+func (o *Object) Get(name string) interface{} {
+	for _, f := range o.d.Fields {
+		if f.Name == name {
+			return o.values[f]
+		}
+	}
+	return nil
+}
+
+// MarshalJSON implements json.Marshaler.
+func (o *Object) MarshalJSON() ([]byte, error) {
+	var b bytes.Buffer
+	b.WriteByte('{')
+	for _, f := range o.d.Fields {
+		v, ok := o.values[f]
+		if !ok {
+			continue
+		}
+		if b.Len() > 1 {
+			b.WriteByte(',')
+		}
+		name, _ := json.Marshal(f.Name)
+		b.Write(name)
+		b.WriteByte(':')
+		js, err := json.Marshal(v)
+		if err != nil {
+			return nil, errors.Wrapf(err, "could not marshal field %s", f.Name)
+		}
+		b.Write(js)
+	}
+	b.WriteByte('}')
+	return b.Bytes(), nil
+}
+
+// Decode unmarshals b as the message d describes, into an *Object. Fields
+// d doesn't have are skipped, as a newer router may send them.
+func (d *Descriptor) Decode(b []byte) (interface{}, error) {
+	o, err := d.decode(b)
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not unmarshal %s", d.FullName)
+	}
+	return o, nil
+}
+
+func (d *Descriptor) decode(b []byte) (*Object, error) {
+	o := &Object{d: d, values: make(map[*Field]interface{})}
+	for len(b) > 0 {
+		tag, n := binary.Uvarint(b)
+		if n <= 0 {
+			return nil, errors.New("invalid tag")
+		}
+		b = b[n:]
+		num, wire := int(tag>>3), int(tag&7)
+		raw, rest, err := field(b, wire)
+		if err != nil {
+			return nil, errors.Wrapf(err, "field %d", num)
+		}
+		b = rest
+		f, ok := d.numbers[num]
+		if !ok {
+			continue
+		}
+		if err = o.set(f, wire, raw); err != nil {
+			return nil, errors.Wrapf(err, "field %s", f.Name)
+		}
+	}
+	return o, nil
+}
+
+// field splits the value of a field with wire type wire off b. Varints
+// and fixed values are returned as their bytes, and length-delimited ones
+// without the length.
+func field(b []byte, wire int) ([]byte, []byte, error) {
+	switch wire {
+	case wireVarint:
+		_, n := binary.Uvarint(b)
+		if n <= 0 {
+			return nil, nil, errors.New("invalid varint")
+		}
+		return b[:n], b[n:], nil
+	case wireFixed64:
+		if len(b) < 8 {
+			return nil, nil, errors.New("truncated fixed64")
+		}
+		return b[:8], b[8:], nil
+	case wireFixed32:
+		if len(b) < 4 {
+			return nil, nil, errors.New("truncated fixed32")
+		}
+		return b[:4], b[4:], nil
+	case wireBytes:
+		l, n := binary.Uvarint(b)
+		if n <= 0 || l > uint64(len(b)-n) {
+			return nil, nil, errors.New("truncated length-delimited value")
+		}
+		end := n + int(l)
+		return b[n:end], b[end:], nil
+	}
+	return nil, nil, errors.Errorf("unsupported wire type %d", wire)
+}
+
+// set stores the value of f in raw, unpacking repeated scalars sent
+// packed.
+func (o *Object) set(f *Field, wire int, raw []byte) error {
+	want := wireBytes
+	if st, ok := scalars[f.Type]; ok {
+		want = st
+	} else if f.enum != nil {
+		want = wireVarint
+	}
+	if wire == wireBytes && want != wireBytes && f.Repeated {
+		for len(raw) > 0 {
+			v, rest, err := field(raw, want)
+			if err != nil {
+				return err
+			}
+			if err = o.set(f, want, v); err != nil {
+				return err
+			}
+			raw = rest
+		}
+		return nil
+	}
+	if wire != want {
+		return errors.Errorf("wire type %d, expected %d for %s", wire, want, f.Type)
+	}
+	v, err := f.value(raw)
+	if err != nil {
+		return err
+	}
+	if !f.Repeated {
+		o.values[f] = v
+		return nil
+	}
+	list, _ := o.values[f].([]interface{})
+	o.values[f] = append(list, v)
+	return nil
+}
+
+// value converts the bytes of a single value of f.
+func (f *Field) value(raw []byte) (interface{}, error) {
+	switch {
+	case f.message != nil:
+		return f.message.decode(raw)
+	case f.enum != nil:
+		u, _ := binary.Uvarint(raw)
+		if name, ok := f.enum.Values[int32(u)]; ok {
+			return name, nil
+		}
+		return int32(u), nil
+	}
+	switch f.Type {
+	case "string":
+		return string(raw), nil
+	case "bytes":
+		return append([]byte(nil), raw...), nil
+	case "double":
+		return math.Float64frombits(binary.LittleEndian.Uint64(raw)), nil
+	case "float":
+		return math.Float32frombits(binary.LittleEndian.Uint32(raw)), nil
+	case "fixed64":
+		return binary.LittleEndian.Uint64(raw), nil
+	case "sfixed64":
+		return int64(binary.LittleEndian.Uint64(raw)), nil
+	case "fixed32":
+		return binary.LittleEndian.Uint32(raw), nil
+	case "sfixed32":
+		return int32(binary.LittleEndian.Uint32(raw)), nil
+	}
+	u, _ := binary.Uvarint(raw)
+	switch f.Type {
+	case "int32":
+		return int32(u), nil
+	case "int64":
+		return int64(u), nil
+	case "uint32":
+		return uint32(u), nil
+	case "sint32":
+		return int32(uint32(u)>>1) ^ -int32(u&1), nil
+	case "sint64":
+		return int64(u>>1) ^ -int64(u&1), nil
+	case "bool":
+		return u != 0, nil
+	case "uint64":
+		return u, nil
+	}
+	return nil, errors.Errorf("type %s isn't resolved", f.Type)
+}
@@ -0,0 +1,181 @@
+package gpb
+
+import (
+	"encoding/json"
+	"math"
+	"strings"
+	"testing"
+
+	proto "github.com/golang/protobuf/proto"
+)
+
+const scalarsProto = `syntax = "proto3";
+package s;
+
+message all {
+    double d = 1;
+    float f = 2;
+    int32 i32 = 3;
+    int64 i64 = 4;
+    uint32 u32 = 5;
+    uint64 u64 = 6;
+    sint32 s32 = 7;
+    sint64 s64 = 8;
+    bool b = 9;
+    fixed32 x32 = 10;
+    fixed64 x64 = 11;
+    sfixed32 sx32 = 12;
+    sfixed64 sx64 = 13;
+    string s = 14;
+    bytes raw = 15;
+    color c = 16;
+    repeated uint32 packed = 17;
+    repeated sub subs = 18;
+}
+
+message sub {
+    string name = 1;
+}
+
+enum color {
+    RED = 0;
+    GREEN = 1;
+}
+`
+
+// testMessage returns the message called name in scalarsProto.
+func testMessage(t *testing.T, name string) *Descriptor {
+	t.Helper()
+	f, err := Parse("s.proto", []byte(scalarsProto))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = Resolve([]*File{f}); err != nil {
+		t.Fatal(err)
+	}
+	for _, m := range f.Messages {
+		if m.FullName == name {
+			return m
+		}
+	}
+	t.Fatalf("no message %s", name)
+	return nil
+}
+
+// tag writes the key of field num with wire type wire.
+func tag(b *proto.Buffer, num, wire int) {
+	b.EncodeVarint(uint64(num)<<3 | uint64(wire))
+}
+
+func TestDecode(t *testing.T) {
+	d := testMessage(t, "s.all")
+	b := proto.NewBuffer(nil)
+	tag(b, 1, wireFixed64)
+	b.EncodeFixed64(math.Float64bits(0.5))
+	tag(b, 2, wireFixed32)
+	b.EncodeFixed32(uint64(math.Float32bits(0.25)))
+	tag(b, 3, wireVarint)
+	b.EncodeVarint(uint64(1<<64 - 1)) // -1, sign-extended to 64 bits
+	tag(b, 4, wireVarint)
+	b.EncodeVarint(uint64(1<<64 - 2)) // -2
+	tag(b, 5, wireVarint)
+	b.EncodeVarint(4000000000)
+	tag(b, 6, wireVarint)
+	b.EncodeVarint(1 << 40)
+	tag(b, 7, wireVarint)
+	b.EncodeZigzag32(uint64(-3 & 0xffffffff))
+	tag(b, 8, wireVarint)
+	b.EncodeZigzag64(uint64(1<<64 - 4)) // -4
+	tag(b, 9, wireVarint)
+	b.EncodeVarint(1)
+	tag(b, 10, wireFixed32)
+	b.EncodeFixed32(7)
+	tag(b, 11, wireFixed64)
+	b.EncodeFixed64(8)
+	tag(b, 12, wireFixed32)
+	b.EncodeFixed32(uint64(0xfffffff7)) // -9
+	tag(b, 13, wireFixed64)
+	b.EncodeFixed64(uint64(1<<64 - 10)) // -10
+	tag(b, 14, wireBytes)
+	b.EncodeStringBytes("hi")
+	tag(b, 15, wireBytes)
+	b.EncodeRawBytes([]byte{0xbe, 0xef})
+	tag(b, 16, wireVarint)
+	b.EncodeVarint(1)
+	// A repeated scalar, packed and not.
+	tag(b, 17, wireBytes)
+	b.EncodeRawBytes([]byte{1, 2})
+	tag(b, 17, wireVarint)
+	b.EncodeVarint(3)
+	// A field the descriptor doesn't have is skipped.
+	tag(b, 99, wireBytes)
+	b.EncodeStringBytes("new")
+	for _, name := range []string{"a", "b"} {
+		sub := proto.NewBuffer(nil)
+		tag(sub, 1, wireBytes)
+		sub.EncodeStringBytes(name)
+		tag(b, 18, wireBytes)
+		b.EncodeRawBytes(sub.Bytes())
+	}
+
+	v, err := d.Decode(b.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	js, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"d":0.5,"f":0.25,"i32":-1,"i64":-2,"u32":4000000000,"u64":1099511627776,` +
+		`"s32":-3,"s64":-4,"b":true,"x32":7,"x64":8,"sx32":-9,"sx64":-10,"s":"hi","raw":"vu8=",` +
+		`"c":"GREEN","packed":[1,2,3],"subs":[{"name":"a"},{"name":"b"}]}`
+	if string(js) != want {
+		t.Errorf("Decode() = %s\nwant %s", js, want)
+	}
+	if got := v.(*Object).Get("s"); got != "hi" {
+		t.Errorf(`Get("s") = %v, want "hi"`, got)
+	}
+	if got := v.(*Object).Get("missing"); got != nil {
+		t.Errorf(`Get("missing") = %v, want nil`, got)
+	}
+}
+
+func TestDecodeEnum(t *testing.T) {
+	d := testMessage(t, "s.all")
+	b := proto.NewBuffer(nil)
+	// A value the router knows and the .proto file doesn't.
+	tag(b, 16, wireVarint)
+	b.EncodeVarint(7)
+	v, err := d.Decode(b.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if js, _ := json.Marshal(v); string(js) != `{"c":7}` {
+		t.Errorf("Decode() = %s, want {\"c\":7}", js)
+	}
+}
+
+func TestDecodeErrors(t *testing.T) {
+	d := testMessage(t, "s.all")
+	tests := []struct {
+		name string
+		in   []byte
+		want string
+	}{
+		{name: "tag", in: []byte{0x80}, want: "invalid tag"},
+		{name: "varint", in: []byte{3 << 3, 0x80}, want: "field 3: invalid varint"},
+		{name: "fixed64", in: []byte{1<<3 | wireFixed64, 1, 2}, want: "field 1: truncated fixed64"},
+		{name: "fixed32", in: []byte{2<<3 | wireFixed32, 1}, want: "field 2: truncated fixed32"},
+		{name: "length", in: []byte{14<<3 | wireBytes, 5, 'a'}, want: "field 14: truncated length-delimited value"},
+		{name: "wire type", in: []byte{9<<3 | 3}, want: "field 9: unsupported wire type 3"},
+		{name: "mismatch", in: []byte{14<<3 | wireVarint, 1}, want: "field s: wire type 0, expected 2 for string"},
+		// 0x92 0x01 is the key of subs.
+		{name: "nested", in: []byte{0x92, 0x01, 2, 1<<3 | wireBytes, 5}, want: "field subs: field 1: truncated length-delimited value"},
+	}
+	for _, tt := range tests {
+		_, err := d.Decode(tt.in)
+		if err == nil || !strings.HasPrefix(err.Error(), "could not unmarshal s.all: "+tt.want) {
+			t.Errorf("%s: Decode() = %v, want %s", tt.name, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,141 @@
+/*
+Package gpb decodes the rows of compact GPB telemetry, whose keys and
+content are messages that depend on the encoding path of the subscription.
+
+A Registry knows the messages of each encoding path, either compiled in,
+as Default has those of the LLDP neighbor details from
+github.com/nleiva/xrgrpc/proto/telemetry/lldp65x, or read from .proto files
+at run time, so a new sensor path decodes without a rebuild.
+*/
+package gpb
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+
+	proto "github.com/golang/protobuf/proto"
+	"github.com/pkg/errors"
+)
+
+// Message is a message a row can hold.
+type Message interface {
+	// Name is the name of the message, for error messages.
+	Name() string
+	// Decode unmarshals b into a value encoding/json can marshal.
+	Decode(b []byte) (interface{}, error)
+}
+
+// Entry holds the messages of the keys and content of the rows of an
+// encoding path.
+type Entry struct {
+	Keys    Message
+	Content Message
+}
+
+// Registry maps encoding paths to the messages of their rows. It's safe
+// for concurrent use.
+type Registry struct {
+	mu    sync.RWMutex
+	paths map[string]Entry
+}
+
+// NewRegistry returns an empty Registry.
+func NewRegistry() *Registry {
+	return &Registry{paths: make(map[string]Entry)}
+}
+
+// ErrUnknownPath is the cause of the errors about an encoding path a
+// Registry has no messages for.
+var ErrUnknownPath = errors.New("no messages for the encoding path")
+
+// Default holds the messages compiled into xrgrpc.
+var Default = NewRegistry()
+
+// Package returns the proto package IOS XR generates the messages of an
+// encoding path in: the path lower-cased, with '-' as '_' and ':' and '/'
+// as '.'. As in Cisco-IOS-XR-ethernet-lldp-oper:lldp/nodes/node, whose
+// messages are in cisco_ios_xr_ethernet_lldp_oper.lldp.nodes.node.
+func Package(path string) string {
+	r := strings.NewReplacer("-", "_", ":", ".", "/", ".")
+	return r.Replace(strings.ToLower(strings.Trim(path, "/")))
+}
+
+// Add sets the messages of the encoding paths whose messages are in pkg,
+// replacing any it had.
+func (r *Registry) Add(pkg string, e Entry) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.paths[pkg] = e
+}
+
+// Register sets the compiled messages of path, as in
+//
+//	Register(path, &lldp.LldpNeighborEntry_KEYS{}, &lldp.LldpNeighborEntry{})
+func (r *Registry) Register(path string, keys, content proto.Message) {
+	r.Add(Package(path), Entry{Keys: compiled(keys), Content: compiled(content)})
+}
+
+// Lookup returns the messages of path.
+func (r *Registry) Lookup(path string) (Entry, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	e, ok := r.paths[Package(path)]
+	return e, ok
+}
+
+// Load reads the .proto files in dir and its subdirectories, and adds the
+// messages of every package with a <name>_KEYS and a <name> message, the
+// way IOS XR generates them for an encoding path. They replace the
+// compiled messages of the same path. It returns how many paths it added.
+func (r *Registry) Load(dir string) (int, error) {
+	var files []*File
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() || filepath.Ext(path) != ".proto" {
+			return nil
+		}
+		f, err := ParseFile(path)
+		if err != nil {
+			return err
+		}
+		files = append(files, f)
+		return nil
+	})
+	if err != nil {
+		return 0, errors.Wrapf(err, "could not load the .proto files in %s", dir)
+	}
+	entries, err := Resolve(files)
+	if err != nil {
+		return 0, errors.Wrapf(err, "could not load the .proto files in %s", dir)
+	}
+	for pkg, e := range entries {
+		r.Add(pkg, e)
+	}
+	return len(entries), nil
+}
+
+// compiledMessage is a message generated by protoc-gen-go.
+type compiledMessage struct {
+	t reflect.Type
+}
+
+func compiled(m proto.Message) Message {
+	return compiledMessage{t: reflect.TypeOf(m).Elem()}
+}
+
+func (m compiledMessage) Name() string {
+	return m.t.String()
+}
+
+func (m compiledMessage) Decode(b []byte) (interface{}, error) {
+	v := reflect.New(m.t).Interface().(proto.Message)
+	if err := proto.Unmarshal(b, v); err != nil {
+		return nil, errors.Wrapf(err, "could not unmarshal %s", m.Name())
+	}
+	return v, nil
+}
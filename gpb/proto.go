@@ -0,0 +1,559 @@
+package gpb
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// File is what a .proto file declares. Only what decoding needs is kept:
+// services, options and imports are skipped, as types are looked up in
+// every file loaded together.
+type File struct {
+	Name     string
+	Package  string
+	Messages []*Descriptor
+	Enums    []*Enum
+}
+
+// Descriptor describes a message. It's a Message whose Decode returns an
+// *Object.
+type Descriptor struct {
+	// FullName is the name with its package and enclosing messages.
+	FullName string
+	Fields   []*Field
+	// Top says it isn't nested in another message.
+	Top bool
+	// Reserved holds the reserved field numbers, as ranges with both ends
+	// included.
+	Reserved [][2]int
+
+	numbers map[int]*Field
+	// scope is where the types of its fields are looked up first.
+	scope string
+}
+
+// Field is a field of a message.
+type Field struct {
+	Name     string
+	Number   int
+	Repeated bool
+	// Type is a scalar type, or the name of a message or enum as written.
+	Type string
+	Line int
+
+	message *Descriptor
+	enum    *Enum
+}
+
+// Enum describes an enum, whose values decode to their names.
+type Enum struct {
+	FullName string
+	Values   map[int32]string
+}
+
+// ParseFile parses the .proto file in file.
+func ParseFile(file string) (*File, error) {
+	b, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not read file %s", file)
+	}
+	return Parse(file, b)
+}
+
+// Parse parses a .proto file; name is only used in errors.
+func Parse(name string, in []byte) (*File, error) {
+	toks, err := lex(name, in)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{file: name, toks: toks}
+	f := &File{Name: name}
+	for !p.eof() {
+		t := p.next()
+		switch {
+		case t.is(";"):
+		case t.is("syntax"):
+			if err = p.expect("="); err != nil {
+				return nil, err
+			}
+			s := p.next()
+			if !s.quoted || s.text != "proto3" && s.text != "proto2" {
+				return nil, p.errorf(s, "unknown syntax %q", s.text)
+			}
+			err = p.expect(";")
+		case t.is("package"):
+			f.Package, err = p.ident()
+			if err == nil {
+				err = p.expect(";")
+			}
+		case t.is("import"):
+			if n := p.peek(); n.is("public") || n.is("weak") {
+				p.next()
+			}
+			if s := p.next(); !s.quoted {
+				return nil, p.errorf(s, "expected a file name after import, got %q", s.text)
+			}
+			err = p.expect(";")
+		case t.is("option"):
+			err = p.skipStatement()
+		case t.is("message"):
+			err = p.message(f, f.Package, true)
+		case t.is("enum"):
+			err = p.enum(f, f.Package)
+		case t.is("service") || t.is("extend"):
+			err = p.skipBlock()
+		default:
+			return nil, p.errorf(t, "unexpected %q", t.text)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return f, nil
+}
+
+// Resolve links the fields of the messages in files to the messages and
+// enums they name, and returns the entries of the packages holding the
+// messages of an encoding path: <name>_KEYS for the keys and <name> for
+// the content, at the top of the package.
+func Resolve(files []*File) (map[string]Entry, error) {
+	messages := make(map[string]*Descriptor)
+	enums := make(map[string]*Enum)
+	for _, f := range files {
+		for _, m := range f.Messages {
+			if _, ok := messages[m.FullName]; ok {
+				return nil, errors.Errorf("%s: message %s is declared twice", f.Name, m.FullName)
+			}
+			messages[m.FullName] = m
+		}
+		for _, e := range f.Enums {
+			enums[e.FullName] = e
+		}
+	}
+	for _, f := range files {
+		for _, m := range f.Messages {
+			for _, fd := range m.Fields {
+				if _, ok := scalars[fd.Type]; ok {
+					continue
+				}
+				name := lookup(m.scope, fd.Type, func(n string) bool {
+					return messages[n] != nil || enums[n] != nil
+				})
+				if name == "" {
+					return nil, errors.Errorf("%s:%d: unknown type %s of field %s", f.Name, fd.Line, fd.Type, fd.Name)
+				}
+				fd.message, fd.enum = messages[name], enums[name]
+			}
+		}
+	}
+	entries := make(map[string]Entry)
+	for _, f := range files {
+		for _, m := range f.Messages {
+			if !m.Top || !strings.HasSuffix(m.FullName, "_KEYS") {
+				continue
+			}
+			content, ok := messages[strings.TrimSuffix(m.FullName, "_KEYS")]
+			if !ok {
+				continue
+			}
+			if _, ok = entries[f.Package]; ok {
+				return nil, errors.Errorf("%s: package %s has more than one _KEYS message", f.Name, f.Package)
+			}
+			entries[f.Package] = Entry{Keys: m, Content: content}
+		}
+	}
+	return entries, nil
+}
+
+// lookup finds name the way protoc does: in scope, then in each scope
+// enclosing it, unless it starts with a '.'.
+func lookup(scope, name string, known func(string) bool) string {
+	if strings.HasPrefix(name, ".") {
+		if known(name[1:]) {
+			return name[1:]
+		}
+		return ""
+	}
+	for {
+		full := name
+		if scope != "" {
+			full = scope + "." + name
+		}
+		if known(full) {
+			return full
+		}
+		if scope == "" {
+			return ""
+		}
+		i := strings.LastIndex(scope, ".")
+		if i < 0 {
+			scope = ""
+		} else {
+			scope = scope[:i]
+		}
+	}
+}
+
+// Name returns the full name of the message.
+func (d *Descriptor) Name() string {
+	return d.FullName
+}
+
+type parser struct {
+	file string
+	toks []token
+	pos  int
+}
+
+func (p *parser) errorf(t token, format string, args ...interface{}) error {
+	return errors.Errorf("%s:%d: %s", p.file, t.line, fmt.Sprintf(format, args...))
+}
+
+func (p *parser) eof() bool {
+	return p.pos >= len(p.toks)
+}
+
+// next returns the next token; past the end, an empty one on the last
+// line.
+func (p *parser) next() token {
+	t := p.peek()
+	if !p.eof() {
+		p.pos++
+	}
+	return t
+}
+
+func (p *parser) peek() token {
+	if p.eof() {
+		t := token{}
+		if len(p.toks) > 0 {
+			t.line = p.toks[len(p.toks)-1].line
+		}
+		return t
+	}
+	return p.toks[p.pos]
+}
+
+func (p *parser) expect(punct string) error {
+	if t := p.next(); !t.is(punct) {
+		return p.errorf(t, "expected %q, got %q", punct, t.text)
+	}
+	return nil
+}
+
+func (p *parser) ident() (string, error) {
+	t := p.next()
+	if t.quoted || !isIdent(t.text) {
+		return "", p.errorf(t, "expected a name, got %q", t.text)
+	}
+	return t.text, nil
+}
+
+// skipStatement moves past the next ';' outside braces.
+func (p *parser) skipStatement() error {
+	depth := 0
+	for !p.eof() {
+		t := p.next()
+		switch {
+		case t.is("{"):
+			depth++
+		case t.is("}"):
+			depth--
+		case t.is(";") && depth == 0:
+			return nil
+		}
+	}
+	return p.errorf(p.peek(), "missing ';'")
+}
+
+// skipBlock moves past the next block in braces.
+func (p *parser) skipBlock() error {
+	for !p.eof() && !p.peek().is("{") {
+		p.next()
+	}
+	depth := 0
+	for !p.eof() {
+		t := p.next()
+		switch {
+		case t.is("{"):
+			depth++
+		case t.is("}"):
+			depth--
+			if depth == 0 {
+				return nil
+			}
+		}
+	}
+	return p.errorf(p.peek(), "missing '}'")
+}
+
+func (p *parser) message(f *File, scope string, top bool) error {
+	name, err := p.ident()
+	if err != nil {
+		return err
+	}
+	full := qualify(scope, name)
+	m := &Descriptor{FullName: full, Top: top, numbers: make(map[int]*Field), scope: full}
+	f.Messages = append(f.Messages, m)
+	if err = p.expect("{"); err != nil {
+		return err
+	}
+	return p.body(f, m, false)
+}
+
+// body reads the fields and nested types of m up to the closing '}'; in a
+// oneof, only fields.
+func (p *parser) body(f *File, m *Descriptor, oneof bool) error {
+	for {
+		t := p.next()
+		var err error
+		switch {
+		case t.text == "" && p.eof():
+			return p.errorf(t, "missing '}' for %s", m.FullName)
+		case t.is("}"):
+			return nil
+		case t.is(";"):
+		case t.is("option"):
+			err = p.skipStatement()
+		case !oneof && t.is("reserved"):
+			err = p.reserved(m)
+		case !oneof && t.is("extensions"):
+			err = p.skipStatement()
+		case !oneof && t.is("message"):
+			err = p.message(f, m.FullName, false)
+		case !oneof && t.is("enum"):
+			err = p.enum(f, m.FullName)
+		case !oneof && t.is("extend"):
+			err = p.skipBlock()
+		case !oneof && t.is("oneof"):
+			if _, err = p.ident(); err == nil {
+				if err = p.expect("{"); err == nil {
+					err = p.body(f, m, true)
+				}
+			}
+		case t.is("map") && p.peek().is("<"):
+			return p.errorf(t, "map fields aren't supported")
+		case t.is("group"):
+			return p.errorf(t, "groups aren't supported")
+		default:
+			err = p.field(m, t)
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// field reads a field declaration starting with t.
+func (p *parser) field(m *Descriptor, t token) error {
+	fd := &Field{Line: t.line}
+	switch {
+	case t.is("repeated"):
+		fd.Repeated = true
+		t = p.next()
+	case t.is("optional") || t.is("required"):
+		t = p.next()
+	}
+	if t.quoted || !isIdent(t.text) {
+		return p.errorf(t, "expected a field type, got %q", t.text)
+	}
+	fd.Type = t.text
+	var err error
+	if fd.Name, err = p.ident(); err != nil {
+		return err
+	}
+	if err = p.expect("="); err != nil {
+		return err
+	}
+	n := p.next()
+	if fd.Number, err = strconv.Atoi(n.text); err != nil || fd.Number <= 0 {
+		return p.errorf(n, "invalid number %q for field %s", n.text, fd.Name)
+	}
+	if _, ok := m.numbers[fd.Number]; ok {
+		return p.errorf(n, "field number %d of %s is used twice", fd.Number, m.FullName)
+	}
+	if p.peek().is("[") {
+		for !p.eof() && !p.next().is("]") {
+		}
+	}
+	if err = p.expect(";"); err != nil {
+		return err
+	}
+	m.Fields = append(m.Fields, fd)
+	m.numbers[fd.Number] = fd
+	return nil
+}
+
+// maxField is the highest field number, which "max" stands for.
+const maxField = 1<<29 - 1
+
+// reserved reads the numbers of a reserved statement into m; reserved
+// names are skipped.
+func (p *parser) reserved(m *Descriptor) error {
+	for {
+		t := p.next()
+		switch {
+		case t.text == "" && p.eof():
+			return p.errorf(t, "missing ';'")
+		case t.is(";"):
+			return nil
+		case t.is(",") || t.quoted:
+			continue
+		}
+		from, err := strconv.Atoi(t.text)
+		if err != nil || from <= 0 {
+			return p.errorf(t, "invalid reserved number %q", t.text)
+		}
+		to := from
+		if p.peek().is("to") {
+			p.next()
+			n := p.next()
+			if n.is("max") {
+				to = maxField
+			} else if to, err = strconv.Atoi(n.text); err != nil || to < from {
+				return p.errorf(n, "invalid reserved range %d to %s", from, n.text)
+			}
+		}
+		m.Reserved = append(m.Reserved, [2]int{from, to})
+	}
+}
+
+func (p *parser) enum(f *File, scope string) error {
+	name, err := p.ident()
+	if err != nil {
+		return err
+	}
+	e := &Enum{FullName: qualify(scope, name), Values: make(map[int32]string)}
+	f.Enums = append(f.Enums, e)
+	if err = p.expect("{"); err != nil {
+		return err
+	}
+	for {
+		t := p.next()
+		switch {
+		case t.text == "" && p.eof():
+			return p.errorf(t, "missing '}' for %s", e.FullName)
+		case t.is("}"):
+			return nil
+		case t.is(";"):
+			continue
+		case t.is("option") || t.is("reserved"):
+			if err = p.skipStatement(); err != nil {
+				return err
+			}
+			continue
+		}
+		if err = p.expect("="); err != nil {
+			return err
+		}
+		n := p.next()
+		v, err := strconv.ParseInt(n.text, 0, 32)
+		if err != nil {
+			return p.errorf(n, "invalid value %q for %s", n.text, t.text)
+		}
+		// The first name of an alias wins.
+		if _, ok := e.Values[int32(v)]; !ok {
+			e.Values[int32(v)] = t.text
+		}
+		if err = p.skipStatement(); err != nil {
+			return err
+		}
+	}
+}
+
+func qualify(scope, name string) string {
+	if scope == "" {
+		return name
+	}
+	return scope + "." + name
+}
+
+func isIdent(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i, c := range s {
+		switch {
+		case c == '_' || c == '.' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z':
+		case c >= '0' && c <= '9' && i > 0:
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// token is a lexed token; quoted strings are never taken for punctuation
+// or keywords.
+type token struct {
+	text   string
+	quoted bool
+	line   int
+}
+
+func (t token) is(s string) bool {
+	return !t.quoted && t.text == s
+}
+
+// lex splits a .proto file into punctuation, quoted strings and words:
+// names, keywords and numbers.
+func lex(name string, in []byte) ([]token, error) {
+	var toks []token
+	line := 1
+	for pos := 0; pos < len(in); {
+		c := in[pos]
+		switch {
+		case c == '\n':
+			line++
+			pos++
+		case c == ' ' || c == '\t' || c == '\r':
+			pos++
+		case bytes.HasPrefix(in[pos:], []byte("//")):
+			for pos < len(in) && in[pos] != '\n' {
+				pos++
+			}
+		case bytes.HasPrefix(in[pos:], []byte("/*")):
+			end := bytes.Index(in[pos+2:], []byte("*/"))
+			if end < 0 {
+				return nil, errors.Errorf("%s:%d: unterminated comment", name, line)
+			}
+			line += bytes.Count(in[pos:pos+2+end], []byte("\n"))
+			pos += end + 4
+		case strings.IndexByte("{}[]<>()=;,", c) >= 0:
+			toks = append(toks, token{text: string(c), line: line})
+			pos++
+		case c == '"' || c == '\'':
+			end := pos + 1
+			for end < len(in) && in[end] != c && in[end] != '\n' {
+				if in[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end >= len(in) || in[end] != c {
+				return nil, errors.Errorf("%s:%d: unterminated string", name, line)
+			}
+			toks = append(toks, token{text: string(in[pos+1 : end]), quoted: true, line: line})
+			pos = end + 1
+		default:
+			start := pos
+			for pos < len(in) && !isSpace(in[pos]) && strings.IndexByte("{}[]<>()=;,\"'/", in[pos]) < 0 {
+				pos++
+			}
+			if pos == start {
+				return nil, errors.Errorf("%s:%d: unexpected %q", name, line, c)
+			}
+			toks = append(toks, token{text: string(in[start:pos]), line: line})
+		}
+	}
+	return toks, nil
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\r' || c == '\n'
+}
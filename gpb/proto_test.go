@@ -0,0 +1,213 @@
+package gpb
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const testProto = `// A comment
+syntax = "proto3";
+package t.p;
+
+import public "other.proto";
+option go_package = "p";
+
+/* Keys
+   of the path */
+message row_KEYS {
+    string name = 1 [json_name = "n"];
+}
+
+message row {
+    option deprecated = true;
+    reserved 2, 3, 9 to 11, "old";
+    uint64 count = 1;
+    repeated sint32 deltas = 4;
+    item first = 5;
+    .t.p.row.item last = 6;
+    oneof choice {
+        string label = 7;
+        level lvl = 8;
+    }
+
+    message item {
+        string text = 1;
+    }
+}
+
+enum level {
+    option allow_alias = true;
+    LOW = 0;
+    NONE = 0;
+    HIGH = 0x10;
+}
+
+service s {
+    rpc Get (row_KEYS) returns (row) {}
+}
+`
+
+func TestParse(t *testing.T) {
+	f, err := Parse("t.proto", []byte(testProto))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.Package != "t.p" {
+		t.Errorf("package %q, want t.p", f.Package)
+	}
+	var msgs []string
+	for _, m := range f.Messages {
+		var fields []string
+		for _, fd := range m.Fields {
+			s := fd.Type + " " + fd.Name
+			if fd.Repeated {
+				s = "repeated " + s
+			}
+			fields = append(fields, s)
+		}
+		msgs = append(msgs, m.FullName+"{"+strings.Join(fields, ", ")+"}")
+	}
+	want := []string{
+		"t.p.row_KEYS{string name}",
+		"t.p.row{uint64 count, repeated sint32 deltas, item first, .t.p.row.item last, string label, level lvl}",
+		"t.p.row.item{string text}",
+	}
+	if !reflect.DeepEqual(msgs, want) {
+		t.Errorf("messages %q, want %q", msgs, want)
+	}
+	if !f.Messages[1].Top || f.Messages[2].Top {
+		t.Errorf("Top = %v, %v, want true, false", f.Messages[1].Top, f.Messages[2].Top)
+	}
+	if len(f.Enums) != 1 || f.Enums[0].FullName != "t.p.level" {
+		t.Fatalf("enums %v, want t.p.level", f.Enums)
+	}
+	// The first name of an alias wins.
+	if got, want := f.Enums[0].Values, map[int32]string{0: "LOW", 16: "HIGH"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("enum values %v, want %v", got, want)
+	}
+	if got, want := f.Messages[1].Reserved, [][2]int{{2, 2}, {3, 3}, {9, 11}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("reserved %v, want %v", got, want)
+	}
+	if fd := f.Messages[1].Fields[1]; fd.Number != 4 || fd.Line != 18 {
+		t.Errorf("deltas is field %d on line %d, want 4 on line 18", fd.Number, fd.Line)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{in: `syntax = "proto4";`, want: `t.proto:1: unknown syntax "proto4"`},
+		{in: "message m {\n  string s = 1;", want: "t.proto:2: missing '}' for m"},
+		{in: "message m { string s = 0; }", want: `t.proto:1: invalid number "0" for field s`},
+		{in: "message m { string s = 1; int32 i = 1; }", want: "t.proto:1: field number 1 of m is used twice"},
+		{in: "message m { map<string, string> tags = 1; }", want: "t.proto:1: map fields aren't supported"},
+		{in: "message m { string s = 1 }", want: `t.proto:1: expected ";", got "}"`},
+		{in: "message m { reserved 3 to 2; }", want: `t.proto:1: invalid reserved range 3 to 2`},
+		{in: "enum e { A = x; }", want: `t.proto:1: invalid value "x" for A`},
+		{in: "message m {}\n}", want: `t.proto:2: unexpected "}"`},
+		{in: "package \"p\";", want: `t.proto:1: expected a name, got "p"`},
+		{in: "message m {\n string s = 1; /* open", want: "t.proto:2: unterminated comment"},
+		{in: "option o = \"open;\n", want: "t.proto:1: unterminated string"},
+	}
+	for _, tt := range tests {
+		_, err := Parse("t.proto", []byte(tt.in))
+		if err == nil || err.Error() != tt.want {
+			t.Errorf("Parse(%q) = %v, want %s", tt.in, err, tt.want)
+		}
+	}
+}
+
+func TestResolve(t *testing.T) {
+	f, err := Parse("t.proto", []byte(testProto))
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries, err := Resolve([]*File{f})
+	if err != nil {
+		t.Fatal(err)
+	}
+	e, ok := entries["t.p"]
+	if !ok || e.Keys.Name() != "t.p.row_KEYS" || e.Content.Name() != "t.p.row" {
+		t.Fatalf("entries %v, want row_KEYS and row in t.p", entries)
+	}
+	row := e.Content.(*Descriptor)
+	// "item" is found in the scope of row, and ".t.p.row.item" from the
+	// top.
+	for _, fd := range row.Fields[2:4] {
+		if fd.message == nil || fd.message.FullName != "t.p.row.item" {
+			t.Errorf("field %s of type %s resolves to %v", fd.Name, fd.Type, fd.message)
+		}
+	}
+	if fd := row.Fields[5]; fd.enum == nil || fd.enum.FullName != "t.p.level" {
+		t.Errorf("field lvl resolves to %v, want enum t.p.level", fd.enum)
+	}
+}
+
+func TestResolveErrors(t *testing.T) {
+	tests := []struct {
+		files []string
+		want  string
+	}{
+		{
+			files: []string{"package p;\nmessage m {\n  other o = 1;\n}"},
+			want:  "0.proto:3: unknown type other of field o",
+		},
+		{
+			files: []string{"package p; message m {}", "package p; message m {}"},
+			want:  "1.proto: message p.m is declared twice",
+		},
+		{
+			files: []string{"package p; message a_KEYS {} message a {} message b_KEYS {} message b {}"},
+			want:  "0.proto: package p has more than one _KEYS message",
+		},
+	}
+	for _, tt := range tests {
+		var files []*File
+		for i, src := range tt.files {
+			f, err := Parse(string('0'+rune(i))+".proto", []byte(src))
+			if err != nil {
+				t.Fatal(err)
+			}
+			files = append(files, f)
+		}
+		if _, err := Resolve(files); err == nil || err.Error() != tt.want {
+			t.Errorf("Resolve(%q) = %v, want %s", tt.files, err, tt.want)
+		}
+	}
+}
+
+func TestPackage(t *testing.T) {
+	tests := map[string]string{
+		"Cisco-IOS-XR-ethernet-lldp-oper:lldp/nodes/node/neighbors/details/detail": "cisco_ios_xr_ethernet_lldp_oper.lldp.nodes.node.neighbors.details.detail",
+		"/Cisco-IOS-XR-infra-statsd-oper:infra-statistics/":                        "cisco_ios_xr_infra_statsd_oper.infra_statistics",
+	}
+	for path, want := range tests {
+		if got := Package(path); got != want {
+			t.Errorf("Package(%q) = %q, want %q", path, got, want)
+		}
+	}
+}
+
+func TestLoad(t *testing.T) {
+	r := NewRegistry()
+	n, err := r.Load("../input/mock/proto")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 1 {
+		t.Errorf("Load() = %d paths, want 1", n)
+	}
+	e, ok := r.Lookup("Cisco-IOS-XR-infra-statsd-oper:infra-statistics/interfaces/interface/latest/generic-counters")
+	if !ok || e.Content.Name() != "cisco_ios_xr_infra_statsd_oper.infra_statistics.interfaces.interface.latest.generic_counters.ifstatsbag_generic" {
+		t.Errorf("Lookup() = %v, %v", e, ok)
+	}
+	if _, ok = r.Lookup("Cisco-IOS-XR-ethernet-lldp-oper:lldp"); ok {
+		t.Errorf("Lookup() found a path that wasn't loaded")
+	}
+	if _, err = r.Load("missing"); err == nil {
+		t.Errorf("Load() of a missing directory succeeded")
+	}
+}
@@ -0,0 +1,133 @@
+package gumi
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/nleiva/clus2019/yang"
+	"github.com/pkg/errors"
+)
+
+// Errors a Server's writes return, wrapped with the details.
+var (
+	// ErrInvalid is for users the model doesn't allow.
+	ErrInvalid = errors.New("invalid user")
+	// ErrExists is for users whose name or id are taken.
+	ErrExists = errors.New("user already exists")
+)
+
+// unique are the sets of leaves no two users may share. The model has a
+// single user container, but the service keeps many users: name tells
+// them apart, and id has to be unique too.
+var unique = [][]string{{"id"}}
+
+// Rules check users against the user container of yang/user.yang, for the
+// types and ranges of its leaves, and against each other for the leaves
+// in unique.
+type Rules struct {
+	schema *yang.Schema
+	user   *yang.Node
+}
+
+// LoadRules reads the YANG modules in dir and finds the user container in
+// module "test".
+func LoadRules(dir string) (*Rules, error) {
+	schema, err := yang.Load(dir)
+	if err != nil {
+		return nil, err
+	}
+	if schema == nil {
+		return nil, errors.Errorf("no YANG modules in %s", dir)
+	}
+	m := schema.Module("test")
+	if m == nil {
+		return nil, errors.Errorf("module test isn't in %s", dir)
+	}
+	for _, n := range m.Nodes() {
+		if n.Name == "user" && n.Kind == yang.Container {
+			return &Rules{schema: schema, user: n}, nil
+		}
+	}
+	return nil, errors.New("module test has no user container")
+}
+
+// Check says whether u may be written next to the users in others. A
+// user in others with the same name is the one u replaces. Nil Rules only
+// need a name.
+func (r *Rules) Check(u *User, others []*User) error {
+	if u.Name == "" {
+		return errors.Wrap(ErrInvalid, "name is missing")
+	}
+	if r == nil {
+		return nil
+	}
+	entry, err := leaves(u)
+	if err != nil {
+		return err
+	}
+	js, err := json.Marshal(map[string]interface{}{
+		r.user.Module + ":" + r.user.Name: entry,
+	})
+	if err != nil {
+		return errors.Wrap(err, "could not encode the user")
+	}
+	if err = r.schema.Check(js, yang.Replace); err != nil {
+		if errs, ok := err.(yang.Errors); ok {
+			return errors.Wrap(ErrInvalid, errs.Error())
+		}
+		return err
+	}
+
+	for _, o := range others {
+		if o.Name == u.Name {
+			continue
+		}
+		other, err := leaves(o)
+		if err != nil {
+			return err
+		}
+		for _, set := range unique {
+			if same(entry, other, set) {
+				return errors.Wrapf(ErrExists, "%s %s is taken by %s", strings.Join(set, ", "), values(entry, set), o.Name)
+			}
+		}
+	}
+	return nil
+}
+
+// leaves returns the leaves of u by their YANG names, which its JSON
+// names follow. proto3 can't tell unset from zero, so zero values are
+// left out.
+func leaves(u *User) (map[string]interface{}, error) {
+	b, err := json.Marshal(u)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not encode the user")
+	}
+	var m map[string]interface{}
+	if err = json.Unmarshal(b, &m); err != nil {
+		return nil, errors.Wrap(err, "could not decode the user")
+	}
+	return m, nil
+}
+
+// same says whether a and b have the same values for every leaf in set.
+// As in RFC 7950, entries missing any of them don't clash.
+func same(a, b map[string]interface{}, set []string) bool {
+	for _, l := range set {
+		va, oka := a[l]
+		vb, okb := b[l]
+		if !oka || !okb || va != vb {
+			return false
+		}
+	}
+	return len(set) > 0
+}
+
+func values(m map[string]interface{}, set []string) string {
+	vs := make([]string, len(set))
+	for i, l := range set {
+		vs[i] = fmt.Sprint(m[l])
+	}
+	return strings.Join(vs, ", ")
+}
@@ -0,0 +1,216 @@
+package gumi
+
+import (
+	"context"
+	"io"
+	"sync"
+
+	"github.com/pkg/errors"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// watchBuffer is how many events a watcher may fall behind before it's
+// dropped.
+const watchBuffer = 64
+
+// Server implements GUMIServer with the users in Store.
+type Server struct {
+	Store Store
+	// Rules check users before they're written; nil only needs a name.
+	Rules *Rules
+
+	// mu makes each write, with its checks, happen on its own, and guards
+	// watchers.
+	mu       sync.Mutex
+	watchers map[*watcher]bool
+}
+
+// toStatus turns a Store or Rules error into a gRPC status.
+func toStatus(err error) error {
+	switch errors.Cause(err) {
+	case nil:
+		return nil
+	case ErrNotFound:
+		return status.Error(codes.NotFound, err.Error())
+	case ErrInvalid:
+		return status.Error(codes.InvalidArgument, err.Error())
+	case ErrExists:
+		return status.Error(codes.AlreadyExists, err.Error())
+	}
+	return status.Error(codes.Internal, err.Error())
+}
+
+// GetByID returns the user with the requested id.
+func (s *Server) GetByID(ctx context.Context, in *GetByIDRequest) (*User, error) {
+	u, err := s.Store.ByID(in.Id)
+	return u, toStatus(err)
+}
+
+// GetByName returns the user with the requested name.
+func (s *Server) GetByName(ctx context.Context, in *GetByNameRequest) (*User, error) {
+	u, err := s.Store.ByName(in.Name)
+	return u, toStatus(err)
+}
+
+// GetAll streams every user, by id.
+func (s *Server) GetAll(in *GetAllRequest, stream GUMI_GetAllServer) error {
+	users, err := s.Store.All()
+	if err != nil {
+		return toStatus(err)
+	}
+	for _, u := range users {
+		if err = stream.Send(u); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Create adds a new user.
+func (s *Server) Create(ctx context.Context, in *User) (*User, error) {
+	return s.write(in, UserEvent_TYPE_CREATED)
+}
+
+// Update replaces the user with the same name.
+func (s *Server) Update(ctx context.Context, in *User) (*User, error) {
+	return s.write(in, UserEvent_TYPE_UPDATED)
+}
+
+// write checks u and stores it, for a new user or an existing one.
+func (s *Server) write(u *User, t UserEvent_Type) (*User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	users, err := s.Store.All()
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	found := false
+	for _, o := range users {
+		found = found || o.Name == u.Name
+	}
+	switch {
+	case t == UserEvent_TYPE_CREATED && found:
+		return nil, toStatus(errors.Wrapf(ErrExists, "name %s is taken", u.Name))
+	case t == UserEvent_TYPE_UPDATED && !found:
+		return nil, toStatus(errors.Wrapf(ErrNotFound, "no user %s", u.Name))
+	}
+	if err = s.Rules.Check(u, users); err != nil {
+		return nil, toStatus(err)
+	}
+	if err = s.Store.Put(u); err != nil {
+		return nil, toStatus(err)
+	}
+	s.notify(t, u)
+	return clone(u), nil
+}
+
+// Delete removes the requested user and returns it.
+func (s *Server) Delete(ctx context.Context, in *DeleteRequest) (*User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	u, err := s.Store.Delete(in.Name)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	s.notify(UserEvent_TYPE_DELETED, u)
+	return u, nil
+}
+
+// watcher is a Watch stream: the names it asked for, where its events go
+// and whether it fell behind.
+type watcher struct {
+	mu    sync.Mutex
+	all   bool
+	names map[string]bool
+
+	events chan *UserEvent
+	behind chan struct{}
+	once   sync.Once
+}
+
+func (w *watcher) add(name string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if name == "" {
+		w.all = true
+		return
+	}
+	w.names[name] = true
+}
+
+func (w *watcher) wants(name string) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.all || w.names[name]
+}
+
+// notify hands an event to the watchers that want it. Those that aren't
+// keeping up are told so instead of holding the write up. Callers hold
+// s.mu.
+func (s *Server) notify(t UserEvent_Type, u *User) {
+	for w := range s.watchers {
+		if !w.wants(u.Name) {
+			continue
+		}
+		select {
+		case w.events <- &UserEvent{Type: t, User: clone(u)}:
+		default:
+			w.once.Do(func() { close(w.behind) })
+		}
+	}
+}
+
+// Watch streams the changes to the users named in the requests, or to all
+// of them after a request without a name. Each request adds to what's
+// watched. Only changes made after a request are sent; GetAll has the
+// users as they are. The stream ends when the client closes its side.
+func (s *Server) Watch(stream GUMI_WatchServer) error {
+	w := &watcher{
+		names:  make(map[string]bool),
+		events: make(chan *UserEvent, watchBuffer),
+		behind: make(chan struct{}),
+	}
+	s.mu.Lock()
+	if s.watchers == nil {
+		s.watchers = make(map[*watcher]bool)
+	}
+	s.watchers[w] = true
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		delete(s.watchers, w)
+		s.mu.Unlock()
+	}()
+
+	// The channel has room for the one error that ends the loop, so the
+	// goroutine never outlives the stream.
+	errc := make(chan error, 1)
+	go func() {
+		for {
+			in, err := stream.Recv()
+			if err != nil {
+				errc <- err
+				return
+			}
+			w.add(in.Name)
+		}
+	}()
+	for {
+		select {
+		case ev := <-w.events:
+			if err := stream.Send(ev); err != nil {
+				return err
+			}
+		case err := <-errc:
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		case <-w.behind:
+			return status.Errorf(codes.ResourceExhausted, "watcher fell more than %d events behind", watchBuffer)
+		case <-stream.Context().Done():
+			return stream.Context().Err()
+		}
+	}
+}
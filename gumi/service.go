@@ -0,0 +1,336 @@
+package gumi
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+)
+
+// The gUMI service of yang/user.proto, written by hand as protoc-gen-go's
+// grpc plugin would declare it.
+
+// GUMIClient is the client API for the gUMI service.
+type GUMIClient interface {
+	GetByID(ctx context.Context, in *GetByIDRequest, opts ...grpc.CallOption) (*User, error)
+	GetByName(ctx context.Context, in *GetByNameRequest, opts ...grpc.CallOption) (*User, error)
+	GetAll(ctx context.Context, in *GetAllRequest, opts ...grpc.CallOption) (GUMI_GetAllClient, error)
+	Create(ctx context.Context, in *User, opts ...grpc.CallOption) (*User, error)
+	Update(ctx context.Context, in *User, opts ...grpc.CallOption) (*User, error)
+	Delete(ctx context.Context, in *DeleteRequest, opts ...grpc.CallOption) (*User, error)
+	Watch(ctx context.Context, opts ...grpc.CallOption) (GUMI_WatchClient, error)
+}
+
+type gUMIClient struct {
+	cc *grpc.ClientConn
+}
+
+// NewGUMIClient returns a client for the gUMI service on cc.
+func NewGUMIClient(cc *grpc.ClientConn) GUMIClient {
+	return &gUMIClient{cc}
+}
+
+func (c *gUMIClient) GetByID(ctx context.Context, in *GetByIDRequest, opts ...grpc.CallOption) (*User, error) {
+	out := new(User)
+	err := c.cc.Invoke(ctx, "/test.gUMI/GetByID", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *gUMIClient) GetByName(ctx context.Context, in *GetByNameRequest, opts ...grpc.CallOption) (*User, error) {
+	out := new(User)
+	err := c.cc.Invoke(ctx, "/test.gUMI/GetByName", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *gUMIClient) GetAll(ctx context.Context, in *GetAllRequest, opts ...grpc.CallOption) (GUMI_GetAllClient, error) {
+	stream, err := c.cc.NewStream(ctx, &_GUMI_serviceDesc.Streams[0], "/test.gUMI/GetAll", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &gUMIGetAllClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// GUMI_GetAllClient receives the users streamed by GetAll.
+type GUMI_GetAllClient interface {
+	Recv() (*User, error)
+	grpc.ClientStream
+}
+
+type gUMIGetAllClient struct {
+	grpc.ClientStream
+}
+
+func (x *gUMIGetAllClient) Recv() (*User, error) {
+	m := new(User)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *gUMIClient) Create(ctx context.Context, in *User, opts ...grpc.CallOption) (*User, error) {
+	out := new(User)
+	err := c.cc.Invoke(ctx, "/test.gUMI/Create", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *gUMIClient) Update(ctx context.Context, in *User, opts ...grpc.CallOption) (*User, error) {
+	out := new(User)
+	err := c.cc.Invoke(ctx, "/test.gUMI/Update", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *gUMIClient) Delete(ctx context.Context, in *DeleteRequest, opts ...grpc.CallOption) (*User, error) {
+	out := new(User)
+	err := c.cc.Invoke(ctx, "/test.gUMI/Delete", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *gUMIClient) Watch(ctx context.Context, opts ...grpc.CallOption) (GUMI_WatchClient, error) {
+	stream, err := c.cc.NewStream(ctx, &_GUMI_serviceDesc.Streams[1], "/test.gUMI/Watch", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &gUMIWatchClient{stream}
+	return x, nil
+}
+
+// GUMI_WatchClient sends what to watch and receives the changes.
+type GUMI_WatchClient interface {
+	Send(*WatchRequest) error
+	Recv() (*UserEvent, error)
+	grpc.ClientStream
+}
+
+type gUMIWatchClient struct {
+	grpc.ClientStream
+}
+
+func (x *gUMIWatchClient) Send(m *WatchRequest) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *gUMIWatchClient) Recv() (*UserEvent, error) {
+	m := new(UserEvent)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// GUMIServer is the server API for the gUMI service.
+type GUMIServer interface {
+	GetByID(context.Context, *GetByIDRequest) (*User, error)
+	GetByName(context.Context, *GetByNameRequest) (*User, error)
+	GetAll(*GetAllRequest, GUMI_GetAllServer) error
+	Create(context.Context, *User) (*User, error)
+	Update(context.Context, *User) (*User, error)
+	Delete(context.Context, *DeleteRequest) (*User, error)
+	Watch(GUMI_WatchServer) error
+}
+
+// RegisterGUMIServer registers srv with s.
+func RegisterGUMIServer(s *grpc.Server, srv GUMIServer) {
+	s.RegisterService(&_GUMI_serviceDesc, srv)
+}
+
+func _GUMI_GetByID_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetByIDRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GUMIServer).GetByID(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/test.gUMI/GetByID",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GUMIServer).GetByID(ctx, req.(*GetByIDRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _GUMI_GetByName_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetByNameRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GUMIServer).GetByName(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/test.gUMI/GetByName",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GUMIServer).GetByName(ctx, req.(*GetByNameRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _GUMI_GetAll_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(GetAllRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(GUMIServer).GetAll(m, &gUMIGetAllServer{stream})
+}
+
+// GUMI_GetAllServer sends the users for GetAll.
+type GUMI_GetAllServer interface {
+	Send(*User) error
+	grpc.ServerStream
+}
+
+type gUMIGetAllServer struct {
+	grpc.ServerStream
+}
+
+func (x *gUMIGetAllServer) Send(m *User) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _GUMI_Create_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(User)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GUMIServer).Create(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/test.gUMI/Create",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GUMIServer).Create(ctx, req.(*User))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _GUMI_Update_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(User)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GUMIServer).Update(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/test.gUMI/Update",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GUMIServer).Update(ctx, req.(*User))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _GUMI_Delete_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GUMIServer).Delete(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/test.gUMI/Delete",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GUMIServer).Delete(ctx, req.(*DeleteRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _GUMI_Watch_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(GUMIServer).Watch(&gUMIWatchServer{stream})
+}
+
+// GUMI_WatchServer receives what to watch and sends the changes.
+type GUMI_WatchServer interface {
+	Send(*UserEvent) error
+	Recv() (*WatchRequest, error)
+	grpc.ServerStream
+}
+
+type gUMIWatchServer struct {
+	grpc.ServerStream
+}
+
+func (x *gUMIWatchServer) Send(m *UserEvent) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *gUMIWatchServer) Recv() (*WatchRequest, error) {
+	m := new(WatchRequest)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+var _GUMI_serviceDesc = grpc.ServiceDesc{
+	ServiceName: "test.gUMI",
+	HandlerType: (*GUMIServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "GetByID",
+			Handler:    _GUMI_GetByID_Handler,
+		},
+		{
+			MethodName: "GetByName",
+			Handler:    _GUMI_GetByName_Handler,
+		},
+		{
+			MethodName: "Create",
+			Handler:    _GUMI_Create_Handler,
+		},
+		{
+			MethodName: "Update",
+			Handler:    _GUMI_Update_Handler,
+		},
+		{
+			MethodName: "Delete",
+			Handler:    _GUMI_Delete_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "GetAll",
+			Handler:       _GUMI_GetAll_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "Watch",
+			Handler:       _GUMI_Watch_Handler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
+	},
+	Metadata: "yang/user.proto",
+}
@@ -0,0 +1,261 @@
+/*
+Package gumi serves the user model of yang/user.yang over the gUMI service
+of yang/user.proto, from a pluggable Store.
+*/
+package gumi
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// ErrNotFound is returned by a Store for users it doesn't have.
+var ErrNotFound = errors.New("user not found")
+
+// Store holds the users a Server serves.
+type Store interface {
+	ByID(id uint32) (*User, error)
+	ByName(name string) (*User, error)
+	// All returns every user, sorted by id.
+	All() ([]*User, error)
+	// Put adds u, or replaces the user with its name.
+	Put(u *User) error
+	// Delete removes the user called name and returns it.
+	Delete(name string) (*User, error)
+}
+
+// clone returns a copy of u, so callers can't change what a Store holds.
+func clone(u *User) *User {
+	return &User{Name: u.Name, Email: u.Email, Id: u.Id}
+}
+
+// MemStore keeps users in memory, by name.
+type MemStore struct {
+	mu    sync.RWMutex
+	users map[string]*User
+}
+
+// NewMemStore returns a MemStore holding users.
+func NewMemStore(users ...*User) *MemStore {
+	s := &MemStore{users: make(map[string]*User)}
+	for _, u := range users {
+		s.users[u.Name] = clone(u)
+	}
+	return s
+}
+
+// ByID returns the user with id.
+func (s *MemStore) ByID(id uint32) (*User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, u := range s.users {
+		if u.Id == id {
+			return clone(u), nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+// ByName returns the user called name.
+func (s *MemStore) ByName(name string) (*User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	u, ok := s.users[name]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return clone(u), nil
+}
+
+// All returns every user, sorted by id.
+func (s *MemStore) All() ([]*User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	out := make([]*User, 0, len(s.users))
+	for _, u := range s.users {
+		out = append(out, clone(u))
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Id < out[j].Id })
+	return out, nil
+}
+
+// Put adds u, or replaces the user with its name.
+func (s *MemStore) Put(u *User) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.users[u.Name] = clone(u)
+	return nil
+}
+
+// Delete removes the user called name and returns it.
+func (s *MemStore) Delete(name string) (*User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	u, ok := s.users[name]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	delete(s.users, name)
+	return u, nil
+}
+
+// ReadUsers reads a JSON array of users from file.
+func ReadUsers(file string) ([]*User, error) {
+	b, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not read file %s", file)
+	}
+	var users []*User
+	if err = json.Unmarshal(b, &users); err != nil {
+		return nil, errors.Wrapf(err, "could not parse users in %s", file)
+	}
+	return users, nil
+}
+
+// WriteUsers writes users to file as a JSON array. It writes a temporary
+// file next to it first, so readers never see half a file.
+func WriteUsers(file string, users []*User) error {
+	b, err := json.MarshalIndent(users, "", "    ")
+	if err != nil {
+		return errors.Wrap(err, "could not encode the users")
+	}
+	tmp, err := ioutil.TempFile(filepath.Dir(file), filepath.Base(file)+".*")
+	if err != nil {
+		return errors.Wrapf(err, "could not write file %s", file)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err = tmp.Write(append(b, '\n')); err == nil {
+		err = tmp.Close()
+	} else {
+		tmp.Close()
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), file)
+	}
+	return errors.Wrapf(err, "could not write file %s", file)
+}
+
+// FileStore serves the users in a JSON file, reading it again when it
+// changes. Writes go to the file too.
+type FileStore struct {
+	file string
+	// wmu serializes writes, which read the file before changing it.
+	wmu sync.Mutex
+
+	mu  sync.Mutex
+	mod time.Time
+	mem *MemStore
+}
+
+// OpenFile returns a FileStore for file, which must exist.
+func OpenFile(file string) (*FileStore, error) {
+	s := &FileStore{file: file}
+	if _, err := s.current(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// current returns the users in the file, as of its last change.
+func (s *FileStore) current() (*MemStore, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	fi, err := os.Stat(s.file)
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not read file %s", s.file)
+	}
+	if s.mem != nil && fi.ModTime().Equal(s.mod) {
+		return s.mem, nil
+	}
+	users, err := ReadUsers(s.file)
+	if err != nil {
+		return nil, err
+	}
+	s.mem, s.mod = NewMemStore(users...), fi.ModTime()
+	return s.mem, nil
+}
+
+// ByID returns the user with id.
+func (s *FileStore) ByID(id uint32) (*User, error) {
+	m, err := s.current()
+	if err != nil {
+		return nil, err
+	}
+	return m.ByID(id)
+}
+
+// ByName returns the user called name.
+func (s *FileStore) ByName(name string) (*User, error) {
+	m, err := s.current()
+	if err != nil {
+		return nil, err
+	}
+	return m.ByName(name)
+}
+
+// All returns every user, sorted by id.
+func (s *FileStore) All() ([]*User, error) {
+	m, err := s.current()
+	if err != nil {
+		return nil, err
+	}
+	return m.All()
+}
+
+// Put adds u, or replaces the user with its name, in the file.
+func (s *FileStore) Put(u *User) error {
+	return s.update(func(m *MemStore) error { return m.Put(u) })
+}
+
+// Delete removes the user called name from the file and returns it.
+func (s *FileStore) Delete(name string) (*User, error) {
+	var u *User
+	err := s.update(func(m *MemStore) (err error) {
+		u, err = m.Delete(name)
+		return err
+	})
+	return u, err
+}
+
+// update applies change to a copy of the users in the file and writes the
+// result back. Concurrent updates through this FileStore don't race, but
+// other writers to the file can still lose changes.
+func (s *FileStore) update(change func(*MemStore) error) error {
+	s.wmu.Lock()
+	defer s.wmu.Unlock()
+	m, err := s.current()
+	if err != nil {
+		return err
+	}
+	users, err := m.All()
+	if err != nil {
+		return err
+	}
+	next := NewMemStore(users...)
+	if err = change(next); err != nil {
+		return err
+	}
+	if users, err = next.All(); err != nil {
+		return err
+	}
+	if err = WriteUsers(s.file, users); err != nil {
+		return err
+	}
+	// Serve what was written right away; the mod time may not have moved
+	// if the file changes twice within its resolution.
+	fi, err := os.Stat(s.file)
+	if err != nil {
+		return errors.Wrapf(err, "could not read file %s", s.file)
+	}
+	s.mu.Lock()
+	s.mem, s.mod = next, fi.ModTime()
+	s.mu.Unlock()
+	return nil
+}
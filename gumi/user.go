@@ -0,0 +1,191 @@
+package gumi
+
+import (
+	proto "github.com/golang/protobuf/proto"
+)
+
+// The messages of yang/user.proto. They're written by hand, not generated:
+// the build doesn't need protoc. They follow the layout of protoc-gen-go, so
+// the proto package marshals them from the struct tags, and TestMessages
+// checks the tags against user.proto.
+
+// User is a user of the demo model in yang/user.yang.
+type User struct {
+	Name                 string   `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Email                string   `protobuf:"bytes,2,opt,name=email,proto3" json:"email,omitempty"`
+	Id                   uint32   `protobuf:"varint,3,opt,name=id,proto3" json:"id,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *User) Reset()         { *m = User{} }
+func (m *User) String() string { return proto.CompactTextString(m) }
+func (*User) ProtoMessage()    {}
+
+func (m *User) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+func (m *User) GetEmail() string {
+	if m != nil {
+		return m.Email
+	}
+	return ""
+}
+
+func (m *User) GetId() uint32 {
+	if m != nil {
+		return m.Id
+	}
+	return 0
+}
+
+type GetByIDRequest struct {
+	Id                   uint32   `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *GetByIDRequest) Reset()         { *m = GetByIDRequest{} }
+func (m *GetByIDRequest) String() string { return proto.CompactTextString(m) }
+func (*GetByIDRequest) ProtoMessage()    {}
+
+func (m *GetByIDRequest) GetId() uint32 {
+	if m != nil {
+		return m.Id
+	}
+	return 0
+}
+
+type GetByNameRequest struct {
+	Name                 string   `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *GetByNameRequest) Reset()         { *m = GetByNameRequest{} }
+func (m *GetByNameRequest) String() string { return proto.CompactTextString(m) }
+func (*GetByNameRequest) ProtoMessage()    {}
+
+func (m *GetByNameRequest) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+type GetAllRequest struct {
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *GetAllRequest) Reset()         { *m = GetAllRequest{} }
+func (m *GetAllRequest) String() string { return proto.CompactTextString(m) }
+func (*GetAllRequest) ProtoMessage()    {}
+
+type DeleteRequest struct {
+	Name                 string   `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *DeleteRequest) Reset()         { *m = DeleteRequest{} }
+func (m *DeleteRequest) String() string { return proto.CompactTextString(m) }
+func (*DeleteRequest) ProtoMessage()    {}
+
+func (m *DeleteRequest) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+type WatchRequest struct {
+	Name                 string   `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *WatchRequest) Reset()         { *m = WatchRequest{} }
+func (m *WatchRequest) String() string { return proto.CompactTextString(m) }
+func (*WatchRequest) ProtoMessage()    {}
+
+func (m *WatchRequest) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+type UserEvent_Type int32
+
+const (
+	UserEvent_TYPE_UNSPECIFIED UserEvent_Type = 0
+	UserEvent_TYPE_CREATED     UserEvent_Type = 1
+	UserEvent_TYPE_UPDATED     UserEvent_Type = 2
+	UserEvent_TYPE_DELETED     UserEvent_Type = 3
+)
+
+var UserEvent_Type_name = map[int32]string{
+	0: "TYPE_UNSPECIFIED",
+	1: "TYPE_CREATED",
+	2: "TYPE_UPDATED",
+	3: "TYPE_DELETED",
+}
+
+var UserEvent_Type_value = map[string]int32{
+	"TYPE_UNSPECIFIED": 0,
+	"TYPE_CREATED":     1,
+	"TYPE_UPDATED":     2,
+	"TYPE_DELETED":     3,
+}
+
+func (x UserEvent_Type) String() string {
+	return proto.EnumName(UserEvent_Type_name, int32(x))
+}
+
+type UserEvent struct {
+	Type                 UserEvent_Type `protobuf:"varint,1,opt,name=type,proto3,enum=test.UserEvent_Type" json:"type,omitempty"`
+	User                 *User          `protobuf:"bytes,2,opt,name=user,proto3" json:"user,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}       `json:"-"`
+	XXX_unrecognized     []byte         `json:"-"`
+	XXX_sizecache        int32          `json:"-"`
+}
+
+func (m *UserEvent) Reset()         { *m = UserEvent{} }
+func (m *UserEvent) String() string { return proto.CompactTextString(m) }
+func (*UserEvent) ProtoMessage()    {}
+
+func (m *UserEvent) GetType() UserEvent_Type {
+	if m != nil {
+		return m.Type
+	}
+	return UserEvent_TYPE_UNSPECIFIED
+}
+
+func (m *UserEvent) GetUser() *User {
+	if m != nil {
+		return m.User
+	}
+	return nil
+}
+
+func init() {
+	proto.RegisterEnum("test.UserEvent_Type", UserEvent_Type_name, UserEvent_Type_value)
+	proto.RegisterType((*User)(nil), "test.User")
+	proto.RegisterType((*GetByIDRequest)(nil), "test.GetByIDRequest")
+	proto.RegisterType((*GetByNameRequest)(nil), "test.GetByNameRequest")
+	proto.RegisterType((*GetAllRequest)(nil), "test.GetAllRequest")
+	proto.RegisterType((*DeleteRequest)(nil), "test.DeleteRequest")
+	proto.RegisterType((*WatchRequest)(nil), "test.WatchRequest")
+	proto.RegisterType((*UserEvent)(nil), "test.UserEvent")
+}
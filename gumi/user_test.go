@@ -0,0 +1,51 @@
+package gumi
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	proto "github.com/golang/protobuf/proto"
+	"github.com/nleiva/clus2019/gpb"
+)
+
+// TestMessages checks the hand-written messages against user.proto.
+func TestMessages(t *testing.T) {
+	f, err := gpb.ParseFile("../yang/user.proto")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, d := range f.Messages {
+		typ := proto.MessageType(d.FullName)
+		if typ == nil {
+			t.Errorf("no message %s", d.FullName)
+			continue
+		}
+		props := proto.GetProperties(typ.Elem())
+		for _, fd := range d.Fields {
+			var p *proto.Properties
+			for _, pp := range props.Prop {
+				if pp.OrigName == fd.Name {
+					p = pp
+				}
+			}
+			switch {
+			case p == nil:
+				t.Errorf("%s has no field %s", d.FullName, fd.Name)
+			case p.Tag != fd.Number || p.Repeated != fd.Repeated:
+				t.Errorf("%s.%s is field %d (repeated %v), want %d (repeated %v)", d.FullName, fd.Name, p.Tag, p.Repeated, fd.Number, fd.Repeated)
+			}
+		}
+	}
+	for _, e := range f.Enums {
+		// protoc-gen-go joins the names of nested types with underscores.
+		name := f.Package + "." + strings.Replace(strings.TrimPrefix(e.FullName, f.Package+"."), ".", "_", -1)
+		want := make(map[string]int32)
+		for v, n := range e.Values {
+			want[n] = v
+		}
+		if got := proto.EnumValueMap(name); !reflect.DeepEqual(got, want) {
+			t.Errorf("%s = %v, want %v", name, got, want)
+		}
+	}
+}
@@ -0,0 +1,174 @@
+/*
+gumiclient calls the gUMI service:
+
+	gumiclient [flags] id <id> | name <name> | all
+	gumiclient [flags] create|update <name> <email> <id> | delete <name>
+	gumiclient [flags] watch [name...]
+
+watch prints the changes to the named users, or to all of them, until
+interrupted.
+*/
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nleiva/clus2019/gumi"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+)
+
+func main() {
+	// gUMI server; defaults to "localhost:50051"
+	server := flag.String("server", "localhost:50051", "gUMI server address")
+	// TLS; plain text without a certificate
+	cert := flag.String("cert", "", "CA certificate to verify the server with")
+	// Per-call timeout in seconds
+	timeout := flag.Int("timeout", 5, "Timeout in seconds; watch doesn't have one")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: gumiclient [flags] id <id> | name <name> | all\n")
+		fmt.Fprintf(flag.CommandLine.Output(), "       gumiclient [flags] create|update <name> <email> <id> | delete <name>\n")
+		fmt.Fprintf(flag.CommandLine.Output(), "       gumiclient [flags] watch [name...]\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	opts := []grpc.DialOption{grpc.WithInsecure()}
+	if *cert != "" {
+		creds, err := credentials.NewClientTLSFromFile(*cert, "")
+		if err != nil {
+			log.Fatalf("could not load the TLS credentials: %v", err)
+		}
+		opts = []grpc.DialOption{grpc.WithTransportCredentials(creds)}
+	}
+	conn, err := grpc.Dial(*server, opts...)
+	if err != nil {
+		log.Fatalf("could not setup a client connection to %s, %v", *server, err)
+	}
+	defer conn.Close()
+
+	var ctx context.Context
+	var cancel context.CancelFunc
+	if flag.Arg(0) == "watch" {
+		ctx, cancel = context.WithCancel(context.Background())
+	} else {
+		ctx, cancel = context.WithTimeout(context.Background(), time.Duration(*timeout)*time.Second)
+	}
+	defer cancel()
+	if err = run(ctx, gumi.NewGUMIClient(conn), flag.Args()); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func run(ctx context.Context, c gumi.GUMIClient, args []string) error {
+	if len(args) == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	switch {
+	case args[0] == "id" && len(args) == 2:
+		id, err := strconv.ParseUint(args[1], 10, 32)
+		if err != nil {
+			return fmt.Errorf("invalid id %q", args[1])
+		}
+		u, err := c.GetByID(ctx, &gumi.GetByIDRequest{Id: uint32(id)})
+		if err != nil {
+			return fmt.Errorf("GetByID failed: %v", err)
+		}
+		return show(u)
+	case args[0] == "name" && len(args) == 2:
+		u, err := c.GetByName(ctx, &gumi.GetByNameRequest{Name: args[1]})
+		if err != nil {
+			return fmt.Errorf("GetByName failed: %v", err)
+		}
+		return show(u)
+	case args[0] == "all" && len(args) == 1:
+		stream, err := c.GetAll(ctx, &gumi.GetAllRequest{})
+		if err != nil {
+			return fmt.Errorf("GetAll failed: %v", err)
+		}
+		for {
+			u, err := stream.Recv()
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return fmt.Errorf("GetAll failed: %v", err)
+			}
+			if err = show(u); err != nil {
+				return err
+			}
+		}
+	case (args[0] == "create" || args[0] == "update") && len(args) == 4:
+		id, err := strconv.ParseUint(args[3], 10, 32)
+		if err != nil {
+			return fmt.Errorf("invalid id %q", args[3])
+		}
+		in := &gumi.User{Name: args[1], Email: args[2], Id: uint32(id)}
+		call, write := "Create", c.Create
+		if args[0] == "update" {
+			call, write = "Update", c.Update
+		}
+		u, err := write(ctx, in)
+		if err != nil {
+			return fmt.Errorf("%s failed: %v", call, err)
+		}
+		return show(u)
+	case args[0] == "delete" && len(args) == 2:
+		u, err := c.Delete(ctx, &gumi.DeleteRequest{Name: args[1]})
+		if err != nil {
+			return fmt.Errorf("Delete failed: %v", err)
+		}
+		return show(u)
+	case args[0] == "watch":
+		return watch(ctx, c, args[1:])
+	}
+	flag.Usage()
+	os.Exit(2)
+	return nil
+}
+
+// watch asks for the changes to names, or to every user without names, and
+// prints them as they come.
+func watch(ctx context.Context, c gumi.GUMIClient, names []string) error {
+	stream, err := c.Watch(ctx)
+	if err != nil {
+		return fmt.Errorf("Watch failed: %v", err)
+	}
+	if len(names) == 0 {
+		names = []string{""}
+	}
+	for _, n := range names {
+		if err = stream.Send(&gumi.WatchRequest{Name: n}); err != nil {
+			return fmt.Errorf("Watch failed: %v", err)
+		}
+	}
+	for {
+		ev, err := stream.Recv()
+		if err != nil {
+			return fmt.Errorf("Watch failed: %v", err)
+		}
+		fmt.Printf("%s ", strings.ToLower(strings.TrimPrefix(ev.Type.String(), "TYPE_")))
+		if err = show(ev.User); err != nil {
+			return err
+		}
+	}
+}
+
+func show(u *gumi.User) error {
+	b, err := json.Marshal(u)
+	if err != nil {
+		return fmt.Errorf("could not marshall into JSON: %v", err)
+	}
+	fmt.Println(string(b))
+	return nil
+}
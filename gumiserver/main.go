@@ -0,0 +1,78 @@
+/*
+gumiserver serves the users of yang/user.yang over the gUMI gRPC service
+in yang/user.proto.
+*/
+package main
+
+import (
+	"flag"
+	"log"
+	"net"
+
+	"github.com/nleiva/clus2019/gumi"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+)
+
+func main() {
+	// Address to listen on; defaults to ":50051"
+	listen := flag.String("listen", ":50051", "Address to serve gUMI on")
+	// Where the users live
+	store := flag.String("store", "file", "User store: 'memory' or 'file'")
+	// Users file; defaults to "users.json"
+	file := flag.String("file", "../input/users.json", "JSON users file; the 'file' store serves it, the 'memory' one starts from it")
+	// YANG modules the users are checked against; defaults to "../yang"
+	ydir := flag.String("yang", "../yang", "Directory with yang/user.yang, to check users against before writing them; empty to only need a name")
+	// TLS; plain text without a certificate
+	cert := flag.String("cert", "", "TLS certificate file")
+	key := flag.String("key", "", "TLS key file")
+	flag.Parse()
+
+	var s gumi.Store
+	switch *store {
+	case "memory":
+		var users []*gumi.User
+		if *file != "" {
+			var err error
+			if users, err = gumi.ReadUsers(*file); err != nil {
+				log.Fatalf("could not load the users: %v", err)
+			}
+		}
+		s = gumi.NewMemStore(users...)
+	case "file":
+		fs, err := gumi.OpenFile(*file)
+		if err != nil {
+			log.Fatalf("could not open the user store: %v", err)
+		}
+		s = fs
+	default:
+		log.Fatalf("store '%v' not supported", *store)
+	}
+
+	var rules *gumi.Rules
+	if *ydir != "" {
+		var err error
+		if rules, err = gumi.LoadRules(*ydir); err != nil {
+			log.Fatalf("could not load the user model: %v", err)
+		}
+	}
+
+	var opts []grpc.ServerOption
+	if *cert != "" {
+		creds, err := credentials.NewServerTLSFromFile(*cert, *key)
+		if err != nil {
+			log.Fatalf("could not load the TLS credentials: %v", err)
+		}
+		opts = append(opts, grpc.Creds(creds))
+	}
+	lis, err := net.Listen("tcp", *listen)
+	if err != nil {
+		log.Fatalf("could not listen on %s: %v", *listen, err)
+	}
+	srv := grpc.NewServer(opts...)
+	gumi.RegisterGUMIServer(srv, &gumi.Server{Store: s, Rules: rules})
+	log.Printf("serving gUMI on %s from the %s store", lis.Addr(), *store)
+	if err = srv.Serve(lis); err != nil {
+		log.Fatalf("gUMI server stopped: %v", err)
+	}
+}
@@ -0,0 +1,250 @@
+/*
+Package inventory loads the routers the demo tools talk to from a JSON or
+YAML file, so targets can be switched with flags instead of editing every
+main.go. Files ending in .yaml or .yml are read as YAML, anything else as
+JSON; the keys are the same.
+*/
+package inventory
+
+import (
+	"encoding/json"
+	"flag"
+	"io/ioutil"
+	"net"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	xr "github.com/nleiva/xrgrpc"
+	"github.com/pkg/errors"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// DefaultPort is the gRPC port used when a device doesn't specify one.
+const DefaultPort = 57344
+
+// Device holds the parameters needed to reach a router.
+type Device struct {
+	Name     string   `json:"name" yaml:"name"`
+	Host     string   `json:"host" yaml:"host"`
+	Port     int      `json:"port,omitempty" yaml:"port,omitempty"`
+	Cert     string   `json:"cert,omitempty" yaml:"cert,omitempty"`
+	Username string   `json:"username,omitempty" yaml:"username,omitempty"`
+	Password string   `json:"password,omitempty" yaml:"password,omitempty"`
+	Timeout  int      `json:"timeout,omitempty" yaml:"timeout,omitempty"`
+	Groups   []string `json:"groups,omitempty" yaml:"groups,omitempty"`
+	// Subscriptions are the telemetry subscription IDs configured on
+	// the device, for the tools that stream from many devices.
+	Subscriptions []string `json:"subscriptions,omitempty" yaml:"subscriptions,omitempty"`
+}
+
+// Inventory is the content of an inventory file. Defaults apply to every
+// device that leaves the corresponding field empty.
+type Inventory struct {
+	Defaults Device   `json:"defaults" yaml:"defaults"`
+	Devices  []Device `json:"devices" yaml:"devices"`
+}
+
+// Load reads an inventory file, in YAML if it ends in .yaml or .yml and in
+// JSON otherwise. Relative certificate paths are resolved against the
+// directory the file lives in.
+func Load(file string) (*Inventory, error) {
+	b, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not read file %s", file)
+	}
+	inv := new(Inventory)
+	if isYAML(file) {
+		err = yaml.UnmarshalStrict(b, inv)
+	} else {
+		err = json.Unmarshal(b, inv)
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not parse inventory %s", file)
+	}
+	dir := filepath.Dir(file)
+	seen := make(map[string]bool)
+	for i := range inv.Devices {
+		d := &inv.Devices[i]
+		if d.Name == "" {
+			return nil, errors.Errorf("device #%d in %s has no name", i+1, file)
+		}
+		if seen[d.Name] {
+			return nil, errors.Errorf("device %s is defined more than once in %s", d.Name, file)
+		}
+		seen[d.Name] = true
+		d.merge(inv.Defaults)
+		if d.Host == "" {
+			return nil, errors.Errorf("device %s has no host", d.Name)
+		}
+		if d.Cert != "" && !filepath.IsAbs(d.Cert) {
+			d.Cert = filepath.Join(dir, d.Cert)
+		}
+	}
+	return inv, nil
+}
+
+// isYAML reports whether file is read as YAML, by its extension.
+func isYAML(file string) bool {
+	switch strings.ToLower(filepath.Ext(file)) {
+	case ".yaml", ".yml":
+		return true
+	}
+	return false
+}
+
+// merge fills the empty fields of d with the values from def.
+func (d *Device) merge(def Device) {
+	if d.Port == 0 {
+		d.Port = def.Port
+	}
+	if d.Port == 0 {
+		d.Port = DefaultPort
+	}
+	if d.Cert == "" {
+		d.Cert = def.Cert
+	}
+	if d.Username == "" {
+		d.Username = def.Username
+	}
+	if d.Password == "" {
+		d.Password = def.Password
+	}
+	if d.Timeout == 0 {
+		d.Timeout = def.Timeout
+	}
+	if len(d.Groups) == 0 {
+		d.Groups = def.Groups
+	}
+	if len(d.Subscriptions) == 0 {
+		d.Subscriptions = def.Subscriptions
+	}
+}
+
+// Device returns the device called name.
+func (inv *Inventory) Device(name string) (Device, error) {
+	for _, d := range inv.Devices {
+		if d.Name == name {
+			return d, nil
+		}
+	}
+	return Device{}, errors.Errorf("device %s not found in inventory", name)
+}
+
+// Group returns all devices that belong to group, in file order. The
+// group "all" matches every device.
+func (inv *Inventory) Group(group string) ([]Device, error) {
+	var ds []Device
+	for _, d := range inv.Devices {
+		if group == "all" || d.InGroup(group) {
+			ds = append(ds, d)
+		}
+	}
+	if len(ds) == 0 {
+		return nil, errors.Errorf("group %s has no devices", group)
+	}
+	return ds, nil
+}
+
+// Groups lists the group names used in the inventory.
+func (inv *Inventory) Groups() []string {
+	set := make(map[string]bool)
+	for _, d := range inv.Devices {
+		for _, g := range d.Groups {
+			set[g] = true
+		}
+	}
+	gs := make([]string, 0, len(set))
+	for g := range set {
+		gs = append(gs, g)
+	}
+	sort.Strings(gs)
+	return gs
+}
+
+// InGroup reports whether the device is tagged with group.
+func (d Device) InGroup(group string) bool {
+	for _, g := range d.Groups {
+		if g == group {
+			return true
+		}
+	}
+	return false
+}
+
+// Addr returns the host:port string xrgrpc dials, e.g. "[2001:db8::1]:57344".
+func (d Device) Addr() string {
+	if _, _, err := net.SplitHostPort(d.Host); err == nil {
+		return d.Host
+	}
+	return net.JoinHostPort(d.Host, strconv.Itoa(d.Port))
+}
+
+// Options returns the xrgrpc options for the device. Tool defaults in def
+// come first, so the values in the inventory take precedence.
+func (d Device) Options(def ...xr.RouterOption) []xr.RouterOption {
+	opts := append([]xr.RouterOption{}, def...)
+	opts = append(opts,
+		xr.WithUsername(d.Username),
+		xr.WithPassword(d.Password),
+		xr.WithHost(d.Addr()),
+		xr.WithCert(d.Cert),
+	)
+	if d.Timeout != 0 {
+		opts = append(opts, xr.WithTimeout(d.Timeout))
+	}
+	return opts
+}
+
+// Router builds the xrgrpc target for the device.
+func (d Device) Router(def ...xr.RouterOption) (*xr.CiscoGrpcClient, error) {
+	router, err := xr.BuildRouter(d.Options(def...)...)
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not build router %s", d.Name)
+	}
+	return router, nil
+}
+
+// Target holds the device selection flags shared by every tool.
+type Target struct {
+	File   string
+	Device string
+	Group  string
+}
+
+// AddFlags registers the -inv, -device and -group flags on fs.
+func (t *Target) AddFlags(fs *flag.FlagSet) {
+	fs.StringVar(&t.File, "inv", "../input/inventory.json", "Inventory file, JSON or YAML (.yaml, .yml)")
+	fs.StringVar(&t.Device, "device", "router2", "Device to target")
+	fs.StringVar(&t.Group, "group", "", "Device group to target; overrides -device")
+}
+
+// Devices loads the inventory and returns the selected devices.
+func (t *Target) Devices() ([]Device, error) {
+	inv, err := Load(t.File)
+	if err != nil {
+		return nil, err
+	}
+	if t.Group != "" {
+		return inv.Group(t.Group)
+	}
+	d, err := inv.Device(t.Device)
+	if err != nil {
+		return nil, err
+	}
+	return []Device{d}, nil
+}
+
+// One returns the selected device, failing if the selection matches
+// more than one.
+func (t *Target) One() (Device, error) {
+	ds, err := t.Devices()
+	if err != nil {
+		return Device{}, err
+	}
+	if len(ds) > 1 {
+		return Device{}, errors.Errorf("group %s has %d devices, this tool needs exactly one", t.Group, len(ds))
+	}
+	return ds[0], nil
+}
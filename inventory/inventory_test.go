@@ -0,0 +1,96 @@
+package inventory
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+const invJSON = `{
+    "defaults": {"port": 57344, "username": "cisco", "password": "cisco"},
+    "devices": [
+        {"name": "router1", "host": "2001:db8::1", "cert": "router1.pem", "groups": ["lab"]},
+        {"name": "router2", "host": "192.0.2.2:57400", "password": "other", "subscriptions": ["LLDP"]}
+    ]
+}`
+
+const invYAML = `
+defaults:
+  port: 57344
+  username: cisco
+  password: cisco
+devices:
+  - name: router1
+    host: 2001:db8::1
+    cert: router1.pem
+    groups: [lab]
+  - name: router2
+    host: 192.0.2.2:57400
+    password: other
+    subscriptions: [LLDP]
+`
+
+func TestLoad(t *testing.T) {
+	dir, err := ioutil.TempDir("", "inventory")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	want := []Device{
+		{Name: "router1", Host: "2001:db8::1", Port: 57344, Cert: filepath.Join(dir, "router1.pem"), Username: "cisco", Password: "cisco", Groups: []string{"lab"}},
+		{Name: "router2", Host: "192.0.2.2:57400", Port: 57344, Username: "cisco", Password: "other", Subscriptions: []string{"LLDP"}},
+	}
+	tests := []struct {
+		file    string
+		content string
+		err     bool
+	}{
+		{file: "inventory.json", content: invJSON},
+		{file: "inventory.yaml", content: invYAML},
+		{file: "inventory.YML", content: invYAML},
+		// YAML is only read from a YAML file.
+		{file: "yaml.json", content: invYAML, err: true},
+		{file: "typo.yaml", content: invYAML + "    grups: [lab]\n", err: true},
+		{file: "noname.yaml", content: "devices:\n  - host: 192.0.2.1\n", err: true},
+		{file: "twice.json", content: `{"devices": [{"name": "a", "host": "h"}, {"name": "a", "host": "h"}]}`, err: true},
+		{file: "nohost.json", content: `{"devices": [{"name": "a"}]}`, err: true},
+	}
+	for _, tt := range tests {
+		file := filepath.Join(dir, tt.file)
+		if err := ioutil.WriteFile(file, []byte(tt.content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		inv, err := Load(file)
+		if tt.err {
+			if err == nil {
+				t.Errorf("Load(%s) succeeded, want an error", tt.file)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Load(%s): %v", tt.file, err)
+			continue
+		}
+		if !reflect.DeepEqual(inv.Devices, want) {
+			t.Errorf("Load(%s) devices = %+v, want %+v", tt.file, inv.Devices, want)
+		}
+	}
+}
+
+func TestAddr(t *testing.T) {
+	tests := []struct {
+		d    Device
+		want string
+	}{
+		{Device{Host: "2001:db8::1", Port: 57344}, "[2001:db8::1]:57344"},
+		{Device{Host: "192.0.2.1", Port: 57400}, "192.0.2.1:57400"},
+		{Device{Host: "[2001:db8::1]:57500", Port: 57344}, "[2001:db8::1]:57500"},
+	}
+	for _, tt := range tests {
+		if got := tt.d.Addr(); got != tt.want {
+			t.Errorf("Addr() of %s = %s, want %s", tt.d.Host, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,261 @@
+package mdt
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Influx writes rows in the InfluxDB line protocol, a line per row: the
+// encoding path is the measurement, the keys of the row and the router
+// and subscription it came from are tags, its leaves are fields, and the
+// time the router sent the message is the timestamp.
+//
+//	Cisco-IOS-XR-infra-statsd-oper:...,interface-name=Hu0/0/0/0,source=r1,subscription=COUNTERS packets-received=3000i 1560185400000000000
+//
+// Lines go to a file as they're written, and to an HTTP write endpoint in
+// batches.
+type Influx struct {
+	// Batch is how many lines are posted at once.
+	Batch int
+	// Interval posts a batch that isn't full this long after its first
+	// line, from a timer, so lines don't wait for more rows.
+	Interval time.Duration
+	// Retries is how many more times a batch is posted when the endpoint
+	// is unreachable or fails, waiting Backoff and then twice as long
+	// each time.
+	Retries int
+	Backoff time.Duration
+
+	w      io.Writer
+	c      io.Closer
+	url    string
+	client *http.Client
+
+	// mu guards the lines waiting to be sent.
+	mu    sync.Mutex
+	buf   bytes.Buffer
+	lines int
+	timer *time.Timer
+	// err is why the last batch the timer sent failed, for the next call
+	// to return.
+	err error
+	// send keeps batches in order. Rows are still taken while one is
+	// retried, as it isn't held with mu.
+	send sync.Mutex
+}
+
+// OpenInflux returns an Influx writing to dest: "-" for stdout, an
+// http:// or https:// URL for the write endpoint of a server, as in
+// http://localhost:8086/write?db=telemetry, or else a file, which is
+// appended to.
+func OpenInflux(dest string) (*Influx, error) {
+	i := &Influx{
+		Batch:    1000,
+		Interval: 5 * time.Second,
+		Retries:  3,
+		Backoff:  time.Second,
+	}
+	switch {
+	case dest == "-":
+		i.w = os.Stdout
+	case strings.HasPrefix(dest, "http://") || strings.HasPrefix(dest, "https://"):
+		i.url = dest
+		i.client = &http.Client{Timeout: 10 * time.Second}
+	default:
+		f, err := os.OpenFile(dest, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+		if err != nil {
+			return nil, errors.Wrap(err, "could not open the InfluxDB output")
+		}
+		i.w, i.c = f, f
+	}
+	return i, nil
+}
+
+// Write converts rows to lines. Rows without fields are skipped, as a line
+// needs one. It returns the error of a batch the timer sent, if that
+// failed.
+func (i *Influx) Write(rows []Row) error {
+	i.mu.Lock()
+	for _, r := range rows {
+		if AppendLine(&i.buf, r) {
+			i.lines++
+		}
+	}
+	if i.url != "" && i.lines < i.Batch {
+		if i.lines > 0 && i.timer == nil {
+			i.timer = time.AfterFunc(i.Interval, i.expire)
+		}
+		err := i.err
+		i.err = nil
+		i.mu.Unlock()
+		return err
+	}
+	i.mu.Unlock()
+	return i.Flush()
+}
+
+// Flush writes the lines of a batch that isn't full.
+func (i *Influx) Flush() error {
+	i.send.Lock()
+	defer i.send.Unlock()
+	b, n, err := i.take()
+	if n > 0 {
+		if werr := i.write(b, n); err == nil {
+			err = werr
+		}
+	}
+	return err
+}
+
+// Close flushes the lines left and closes the file written to.
+func (i *Influx) Close() error {
+	err := i.Flush()
+	if i.c != nil {
+		if cerr := i.c.Close(); err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
+
+// expire sends the batch when its Interval is up.
+func (i *Influx) expire() {
+	if err := i.Flush(); err != nil {
+		i.mu.Lock()
+		i.err = err
+		i.mu.Unlock()
+	}
+}
+
+// take empties the buffer, and returns the lines in it, how many there
+// are, and the error the timer left.
+func (i *Influx) take() ([]byte, int, error) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	if i.timer != nil {
+		i.timer.Stop()
+		i.timer = nil
+	}
+	b := append([]byte(nil), i.buf.Bytes()...)
+	n, err := i.lines, i.err
+	i.buf.Reset()
+	i.lines, i.err = 0, nil
+	return b, n, err
+}
+
+// write sends n lines. A batch that can't be posted is dropped, so a
+// server that stays down doesn't hold up the session.
+func (i *Influx) write(b []byte, n int) error {
+	if i.url == "" {
+		_, err := i.w.Write(b)
+		return errors.Wrap(err, "could not write the lines")
+	}
+	wait := i.Backoff
+	for try := 0; ; try++ {
+		retry, err := i.post(b)
+		if err == nil {
+			return nil
+		}
+		if !retry || try >= i.Retries {
+			return errors.Wrapf(err, "could not post %d lines to %s", n, i.url)
+		}
+		time.Sleep(wait)
+		wait *= 2
+	}
+}
+
+// post sends a batch, and says whether a failure is worth retrying: the
+// server was unreachable or busy, rather than rejecting the lines.
+func (i *Influx) post(b []byte) (bool, error) {
+	resp, err := i.client.Post(i.url, "text/plain; charset=utf-8", bytes.NewReader(b))
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close()
+	msg, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 512))
+	if resp.StatusCode/100 == 2 {
+		return false, nil
+	}
+	err = errors.Errorf("%s: %s", resp.Status, bytes.TrimSpace(msg))
+	return resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests, err
+}
+
+// AppendLine writes r to b as a line, and returns false without writing
+// anything if r has no field the line protocol can hold.
+func AppendLine(b *bytes.Buffer, r Row) bool {
+	fields := make([]string, 0, len(r.Fields))
+	for k, v := range r.Fields {
+		if f, ok := fieldValue(v); ok {
+			fields = append(fields, escapeKey(k)+"="+f)
+		}
+	}
+	if len(fields) == 0 {
+		return false
+	}
+	sort.Strings(fields)
+
+	tags := map[string]string{"source": r.Node, "subscription": r.Subscription}
+	for k, v := range r.Keys {
+		tags[k] = v
+	}
+	names := make([]string, 0, len(tags))
+	for k, v := range tags {
+		// Empty tags aren't allowed.
+		if v != "" {
+			names = append(names, k)
+		}
+	}
+	sort.Strings(names)
+
+	b.WriteString(strings.NewReplacer(",", `\,`, " ", `\ `, "\n", `\n`).Replace(r.Path))
+	for _, k := range names {
+		b.WriteString("," + escapeKey(k) + "=" + escapeKey(tags[k]))
+	}
+	b.WriteString(" " + strings.Join(fields, ","))
+	if !r.Time.IsZero() {
+		b.WriteString(" " + strconv.FormatInt(r.Time.UnixNano(), 10))
+	}
+	b.WriteByte('\n')
+	return true
+}
+
+// fieldValue renders a field, with integers marked as such. Unsigned
+// ones go as integers too when they fit, for servers without the unsigned
+// type.
+func fieldValue(v interface{}) (string, bool) {
+	switch t := v.(type) {
+	case uint64:
+		if t > math.MaxInt64 {
+			return strconv.FormatUint(t, 10) + "u", true
+		}
+		return strconv.FormatUint(t, 10) + "i", true
+	case int64:
+		return strconv.FormatInt(t, 10) + "i", true
+	case float64:
+		if math.IsNaN(t) || math.IsInf(t, 0) {
+			return "", false
+		}
+		return strconv.FormatFloat(t, 'f', -1, 64), true
+	case bool:
+		return strconv.FormatBool(t), true
+	case string:
+		return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(t) + `"`, true
+	}
+	return "", false
+}
+
+// escapeKey escapes tag keys and values and field keys.
+func escapeKey(s string) string {
+	return strings.NewReplacer(",", `\,`, "=", `\=`, " ", `\ `, "\n", `\n`).Replace(s)
+}
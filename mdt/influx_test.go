@@ -0,0 +1,166 @@
+package mdt
+
+import (
+	"bytes"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// server is an InfluxDB write endpoint that fails the first posts.
+type server struct {
+	mu    sync.Mutex
+	fail  int
+	lines []string
+}
+
+func (s *server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	b, _ := ioutil.ReadAll(r.Body)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.fail > 0 {
+		s.fail--
+		http.Error(w, "busy", http.StatusServiceUnavailable)
+		return
+	}
+	s.lines = append(s.lines, strings.Split(strings.TrimSpace(string(b)), "\n")...)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *server) received() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.lines...)
+}
+
+func testRow(n int64) Row {
+	return Row{Path: "p", Node: "r1", Fields: map[string]interface{}{"n": n}}
+}
+
+func TestInfluxInterval(t *testing.T) {
+	s := &server{}
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+	i, err := OpenInflux(ts.URL + "/write?db=test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	i.Interval = 50 * time.Millisecond
+	if err = i.Write([]Row{testRow(1)}); err != nil {
+		t.Fatal(err)
+	}
+	// The batch isn't full and no more rows come: the timer sends it.
+	deadline := time.Now().Add(2 * time.Second)
+	for len(s.received()) == 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if got, want := s.received(), []string{"p,source=r1 n=1i"}; strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("posted %q, want %q", got, want)
+	}
+	if err = i.Close(); err != nil {
+		t.Errorf("Close: %v", err)
+	}
+}
+
+func TestInfluxRetry(t *testing.T) {
+	s := &server{fail: 2}
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+	i, err := OpenInflux(ts.URL + "/write?db=test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	i.Batch, i.Backoff = 2, 100*time.Millisecond
+
+	done := make(chan error)
+	go func() {
+		// A full batch is posted right away, and retried.
+		done <- i.Write([]Row{testRow(1), testRow(2)})
+	}()
+	time.Sleep(20 * time.Millisecond)
+	// Rows are taken while the batch waits to be retried.
+	start := time.Now()
+	if err = i.Write([]Row{testRow(3)}); err != nil {
+		t.Fatal(err)
+	}
+	if d := time.Since(start); d > 50*time.Millisecond {
+		t.Errorf("Write took %v while a batch was retried", d)
+	}
+	if err = <-done; err != nil {
+		t.Fatalf("the batch wasn't retried: %v", err)
+	}
+	if err = i.Close(); err != nil {
+		t.Fatal(err)
+	}
+	want := "p,source=r1 n=1i\np,source=r1 n=2i\np,source=r1 n=3i"
+	if got := strings.Join(s.received(), "\n"); got != want {
+		t.Errorf("posted %q, want %q", got, want)
+	}
+}
+
+func TestInfluxRejected(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "unable to parse", http.StatusBadRequest)
+	}))
+	defer ts.Close()
+	i, err := OpenInflux(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	i.Batch = 1
+	// A 400 isn't retried, and the batch is dropped.
+	if err = i.Write([]Row{testRow(1)}); err == nil || !strings.Contains(err.Error(), "unable to parse") {
+		t.Errorf("Write() = %v, want the server's error", err)
+	}
+	if err = i.Close(); err != nil {
+		t.Errorf("Close() = %v, want nil", err)
+	}
+}
+
+func TestAppendLine(t *testing.T) {
+	tests := []struct {
+		name string
+		row  Row
+		want string
+	}{
+		{
+			name: "types",
+			row: Row{Path: "p", Node: "r1", Subscription: "S", Time: time.Unix(1, 5), Fields: map[string]interface{}{
+				"u": uint64(1), "big": uint64(math.MaxUint64), "i": int64(-2), "f": 0.5, "b": true, "s": "x",
+			}},
+			want: `p,source=r1,subscription=S b=true,big=18446744073709551615u,f=0.5,i=-2i,s="x",u=1i 1000000005` + "\n",
+		},
+		{
+			// Empty tags are left out, and there's no time without one.
+			name: "keys",
+			row:  Row{Path: "p", Node: "r1", Keys: map[string]string{"if": "Gi0/0/0/0", "vrf": ""}, Fields: map[string]interface{}{"n": int64(1)}},
+			want: "p,if=Gi0/0/0/0,source=r1 n=1i\n",
+		},
+		{
+			name: "escaping",
+			row: Row{Path: "a b,c=d", Node: "r 1", Keys: map[string]string{"k=,": "v 1,\nx"}, Fields: map[string]interface{}{
+				"f g": `say "hi" \ bye` + "\n",
+			}},
+			want: `a\ b\,c=d,k\=\,=v\ 1\,\nx,source=r\ 1 f\ g="say \"hi\" \\ bye\n"` + "\n",
+		},
+		{
+			// Without fields there's no line.
+			name: "no fields",
+			row:  Row{Path: "p", Node: "r1", Fields: map[string]interface{}{"nan": math.NaN(), "inf": math.Inf(1), "other": []int{1}}},
+		},
+	}
+	for _, tt := range tests {
+		var b bytes.Buffer
+		if ok := AppendLine(&b, tt.row); ok != (tt.want != "") {
+			t.Errorf("%s: AppendLine() = %v", tt.name, ok)
+		}
+		if b.String() != tt.want {
+			t.Errorf("%s: AppendLine() wrote %q, want %q", tt.name, b.String(), tt.want)
+		}
+	}
+}
@@ -0,0 +1,245 @@
+/*
+Package mdt turns model-driven telemetry messages into rows: the keys that
+identify an entry of the encoding path, and the leaves of its content, for
+tools that store or graph them rather than print them.
+*/
+package mdt
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nleiva/clus2019/gpb"
+	"github.com/nleiva/xrgrpc/proto/telemetry"
+	"github.com/pkg/errors"
+)
+
+// Row is an entry of a telemetry message.
+type Row struct {
+	// Path is the encoding path of the subscription.
+	Path string
+	// Node is the router that sent it.
+	Node         string
+	Subscription string
+	// Time is when the router sent the message the row is in.
+	Time time.Time
+	// Keys identify the entry, as in interface_name.
+	Keys map[string]string
+	// Fields are the leaves of the content, with the names of the
+	// containers they're in joined by '/', as in
+	// lldp_neighbor/hold_time. Values are uint64, int64, float64, bool
+	// or string.
+	Fields map[string]interface{}
+}
+
+// Flatten returns the rows of a self-describing (GPB-KV) or compact GPB
+// message, decoding compact rows with the messages in reg. A leaf that
+// shows up more than once in an entry, as in a list, keeps its last value.
+func Flatten(m *telemetry.Telemetry, reg *gpb.Registry) ([]Row, error) {
+	base := Row{
+		Path:         m.GetEncodingPath(),
+		Node:         m.GetNodeIdStr(),
+		Subscription: m.GetSubscriptionIdStr(),
+		Time:         msTime(m.GetMsgTimestamp()),
+	}
+	switch {
+	case len(m.GetDataGpbkv()) > 0:
+		return flattenKV(base, m.GetDataGpbkv()), nil
+	case m.GetDataGpb() != nil:
+		return flattenGPB(base, m.GetDataGpb().GetRow(), reg)
+	}
+	return nil, nil
+}
+
+// msTime converts a timestamp in milliseconds, as routers send them.
+func msTime(ms uint64) time.Time {
+	return time.Unix(0, int64(ms)*int64(time.Millisecond))
+}
+
+func newRow(base Row) Row {
+	r := base
+	r.Keys = make(map[string]string)
+	r.Fields = make(map[string]interface{})
+	return r
+}
+
+// flattenKV reads rows as IOS XR sends them self-describing: a field per
+// entry, with its timestamp and a "keys" and a "content" field.
+func flattenKV(base Row, entries []*telemetry.TelemetryField) []Row {
+	var rows []Row
+	for _, e := range entries {
+		r := newRow(base)
+		for _, f := range e.GetFields() {
+			switch f.GetName() {
+			case "keys":
+				leavesKV(f.GetFields(), "", func(name string, v interface{}) {
+					r.Keys[name] = fmt.Sprint(v)
+				})
+			case "content":
+				leavesKV(f.GetFields(), "", func(name string, v interface{}) {
+					r.Fields[name] = v
+				})
+			default:
+				// Entries without keys have their leaves right there.
+				leavesKV([]*telemetry.TelemetryField{f}, "", func(name string, v interface{}) {
+					r.Fields[name] = v
+				})
+			}
+		}
+		rows = append(rows, r)
+	}
+	return rows
+}
+
+// leavesKV calls add for every field with a value, named after it and the
+// fields it's in.
+func leavesKV(fields []*telemetry.TelemetryField, prefix string, add func(string, interface{})) {
+	for _, f := range fields {
+		name := prefix + f.GetName()
+		if v, ok := Value(f); ok {
+			add(name, v)
+		}
+		if len(f.GetFields()) > 0 {
+			leavesKV(f.GetFields(), name+"/", add)
+		}
+	}
+}
+
+// Value returns the value of a self-describing field as a uint64, int64,
+// float64, bool or string, with bytes as FormatBytes renders them. It
+// returns false for a field without one, or of a type it doesn't know.
+func Value(f *telemetry.TelemetryField) (interface{}, bool) {
+	switch v := f.GetValueByType().(type) {
+	case *telemetry.TelemetryField_StringValue:
+		return v.StringValue, true
+	case *telemetry.TelemetryField_BoolValue:
+		return v.BoolValue, true
+	case *telemetry.TelemetryField_Uint32Value:
+		return uint64(v.Uint32Value), true
+	case *telemetry.TelemetryField_Uint64Value:
+		return v.Uint64Value, true
+	case *telemetry.TelemetryField_Sint32Value:
+		return int64(v.Sint32Value), true
+	case *telemetry.TelemetryField_Sint64Value:
+		return v.Sint64Value, true
+	case *telemetry.TelemetryField_DoubleValue:
+		return v.DoubleValue, true
+	case *telemetry.TelemetryField_FloatValue:
+		return float64(v.FloatValue), true
+	case *telemetry.TelemetryField_BytesValue:
+		return FormatBytes(f.GetName(), v.BytesValue), true
+	}
+	return nil, false
+}
+
+// FormatBytes renders bytes as an IP or MAC address when their length
+// fits and name, the name of their field, says it's an address, and in
+// hex otherwise.
+func FormatBytes(name string, b []byte) string {
+	words := make(map[string]bool)
+	for _, w := range strings.FieldsFunc(strings.ToLower(name), func(r rune) bool {
+		return r == '-' || r == '_'
+	}) {
+		words[w] = true
+	}
+	addr := words["address"] || words["addr"]
+	switch {
+	case len(b) == 0:
+		return ""
+	case (len(b) == net.IPv4len || len(b) == net.IPv6len) &&
+		(addr || words["ip"] || words["ipv4"] || words["ipv6"] || words["prefix"] || words["nexthop"] || words["hop"]):
+		return net.IP(b).String()
+	case len(b) == 6 && (addr || words["mac"]):
+		return net.HardwareAddr(b).String()
+	}
+	return "0x" + hex.EncodeToString(b)
+}
+
+// flattenGPB decodes compact rows with the messages of their path.
+func flattenGPB(base Row, entries []*telemetry.TelemetryRowGPB, reg *gpb.Registry) ([]Row, error) {
+	msgs, ok := reg.Lookup(base.Path)
+	if !ok {
+		return nil, errors.Wrap(gpb.ErrUnknownPath, base.Path)
+	}
+	var rows []Row
+	for _, e := range entries {
+		r := newRow(base)
+		keys, err := decodeJSON(msgs.Keys, e.GetKeys())
+		if err != nil {
+			return nil, err
+		}
+		leavesJSON(keys, "", func(name string, v interface{}) {
+			r.Keys[name] = fmt.Sprint(v)
+		})
+		content, err := decodeJSON(msgs.Content, e.GetContent())
+		if err != nil {
+			return nil, err
+		}
+		leavesJSON(content, "", func(name string, v interface{}) {
+			r.Fields[name] = v
+		})
+		rows = append(rows, r)
+	}
+	return rows, nil
+}
+
+// decodeJSON decodes b with m, and reads it back from JSON, the one form
+// compiled and loaded messages have in common.
+func decodeJSON(m gpb.Message, b []byte) (interface{}, error) {
+	v, err := m.Decode(b)
+	if err != nil {
+		return nil, err
+	}
+	js, err := json.Marshal(v)
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not marshal %s", m.Name())
+	}
+	d := json.NewDecoder(bytes.NewReader(js))
+	d.UseNumber()
+	var out interface{}
+	err = d.Decode(&out)
+	return out, errors.Wrapf(err, "could not read %s back", m.Name())
+}
+
+// leavesJSON calls add for every leaf of v, named after it and the objects
+// it's in; the entries of a list share their names.
+func leavesJSON(v interface{}, name string, add func(string, interface{})) {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		prefix := name
+		if prefix != "" {
+			prefix += "/"
+		}
+		for k, c := range t {
+			leavesJSON(c, prefix+k, add)
+		}
+	case []interface{}:
+		for _, c := range t {
+			leavesJSON(c, name, add)
+		}
+	case json.Number:
+		add(name, number(t))
+	case nil:
+	default:
+		add(name, t)
+	}
+}
+
+// number returns n as the narrowest of uint64, int64 and float64 that
+// holds it.
+func number(n json.Number) interface{} {
+	if u, err := strconv.ParseUint(string(n), 10, 64); err == nil {
+		return u
+	}
+	if i, err := n.Int64(); err == nil {
+		return i
+	}
+	f, _ := n.Float64()
+	return f
+}
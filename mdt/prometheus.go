@@ -0,0 +1,181 @@
+package mdt
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Prometheus keeps the latest value of the numeric fields of the rows
+// written to it, and serves them in the Prometheus text format. A field is
+// a metric named after the last element of the encoding path and the
+// field, as in xr_generic_counters_packets_received, labeled with the keys
+// of its row and the router that sent it.
+type Prometheus struct {
+	// Prefix starts the name of every metric.
+	Prefix string
+	// Expire drops a series that hasn't been updated for as long, as when
+	// an interface goes away; 0 keeps them.
+	Expire time.Duration
+
+	mu     sync.Mutex
+	series map[string]*series
+}
+
+type series struct {
+	name    string
+	help    string
+	labels  string
+	value   float64
+	updated time.Time
+}
+
+// NewPrometheus returns a Prometheus with metrics named prefix_... that
+// expire after expire.
+func NewPrometheus(prefix string, expire time.Duration) *Prometheus {
+	return &Prometheus{Prefix: prefix, Expire: expire, series: make(map[string]*series)}
+}
+
+// Write updates the series of the fields of rows. Strings are skipped, as
+// Prometheus has no use for them, and booleans are 1 or 0.
+func (p *Prometheus) Write(rows []Row) error {
+	now := time.Now()
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, r := range rows {
+		labels := labelSet(r)
+		base := metricName(p.Prefix, lastElem(r.Path))
+		for field, v := range r.Fields {
+			f, ok := float(v)
+			if !ok {
+				continue
+			}
+			name := base + "_" + metricName("", field)
+			p.series[name+labels] = &series{
+				name:    name,
+				help:    r.Path + " " + field,
+				labels:  labels,
+				value:   f,
+				updated: now,
+			}
+		}
+	}
+	return nil
+}
+
+// ServeHTTP serves the metrics, for a Prometheus server to scrape.
+func (p *Prometheus) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	w.Write(p.expose(time.Now()))
+}
+
+// expose writes the series in the text format, grouped by metric, and
+// drops the expired ones.
+func (p *Prometheus) expose(now time.Time) []byte {
+	p.mu.Lock()
+	var out []*series
+	for k, s := range p.series {
+		if p.Expire > 0 && now.Sub(s.updated) > p.Expire {
+			delete(p.series, k)
+			continue
+		}
+		out = append(out, s)
+	}
+	p.mu.Unlock()
+
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].name != out[j].name {
+			return out[i].name < out[j].name
+		}
+		return out[i].labels < out[j].labels
+	})
+	var b bytes.Buffer
+	for i, s := range out {
+		if i == 0 || out[i-1].name != s.name {
+			fmt.Fprintf(&b, "# HELP %s %s\n", s.name, escapeHelp(s.help))
+			fmt.Fprintf(&b, "# TYPE %s untyped\n", s.name)
+		}
+		fmt.Fprintf(&b, "%s%s %s\n", s.name, s.labels, strconv.FormatFloat(s.value, 'g', -1, 64))
+	}
+	return b.Bytes()
+}
+
+// float returns a numeric field as a float64.
+func float(v interface{}) (float64, bool) {
+	switch t := v.(type) {
+	case uint64:
+		return float64(t), true
+	case int64:
+		return float64(t), true
+	case float64:
+		return t, true
+	case bool:
+		if t {
+			return 1, true
+		}
+		return 0, true
+	}
+	return 0, false
+}
+
+// labelSet renders the keys of r and its router as {name="value",...},
+// sorted by name.
+func labelSet(r Row) string {
+	labels := map[string]string{"router": r.Node}
+	for k, v := range r.Keys {
+		labels[metricName("", k)] = v
+	}
+	names := make([]string, 0, len(labels))
+	for k := range labels {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	parts := make([]string, len(names))
+	for i, k := range names {
+		parts[i] = k + `="` + escapeLabel(labels[k]) + `"`
+	}
+	return "{" + strings.Join(parts, ",") + "}"
+}
+
+// lastElem returns the last element of an encoding path, as in
+// generic-counters for .../interface/latest/generic-counters.
+func lastElem(path string) string {
+	path = strings.TrimRight(path, "/")
+	if i := strings.LastIndexAny(path, "/:"); i >= 0 {
+		return path[i+1:]
+	}
+	return path
+}
+
+// metricName joins prefix and s with '_', with any character Prometheus
+// doesn't allow in names replaced by '_'.
+func metricName(prefix, s string) string {
+	var b strings.Builder
+	if prefix != "" {
+		b.WriteString(prefix)
+		b.WriteByte('_')
+	}
+	for i, c := range s {
+		switch {
+		case c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z':
+		case c >= '0' && c <= '9' && (i > 0 || prefix != ""):
+		default:
+			c = '_'
+		}
+		b.WriteRune(c)
+	}
+	return b.String()
+}
+
+func escapeLabel(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s)
+}
+
+func escapeHelp(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "\n", `\n`).Replace(s)
+}
@@ -0,0 +1,96 @@
+package mdt
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMetricName(t *testing.T) {
+	tests := []struct {
+		prefix, s, want string
+	}{
+		{prefix: "xr", s: "generic-counters", want: "xr_generic_counters"},
+		{s: "lldp_neighbor/hold_time", want: "lldp_neighbor_hold_time"},
+		// A name can't start with a digit, unless it follows the prefix.
+		{s: "5tuple", want: "_tuple"},
+		{prefix: "xr", s: "5tuple", want: "xr_5tuple"},
+		{s: "état:ok", want: "_tat_ok"},
+	}
+	for _, tt := range tests {
+		if got := metricName(tt.prefix, tt.s); got != tt.want {
+			t.Errorf("metricName(%q, %q) = %q, want %q", tt.prefix, tt.s, got, tt.want)
+		}
+	}
+}
+
+func TestLastElem(t *testing.T) {
+	tests := map[string]string{
+		"Cisco-IOS-XR-infra-statsd-oper:infra-statistics/interfaces/interface/latest/generic-counters": "generic-counters",
+		"Cisco-IOS-XR-ethernet-lldp-oper:lldp/":                                                        "lldp",
+		"plain":                                                                                        "plain",
+	}
+	for path, want := range tests {
+		if got := lastElem(path); got != want {
+			t.Errorf("lastElem(%q) = %q, want %q", path, got, want)
+		}
+	}
+}
+
+func TestLabelSet(t *testing.T) {
+	r := Row{Node: "r1", Keys: map[string]string{"interface-name": "Gi0/0", "descr": "a \"b\"\\\nc"}}
+	want := `{descr="a \"b\"\\\nc",interface_name="Gi0/0",router="r1"}`
+	if got := labelSet(r); got != want {
+		t.Errorf("labelSet() = %s, want %s", got, want)
+	}
+}
+
+func TestPrometheus(t *testing.T) {
+	p := NewPrometheus("xr", time.Minute)
+	path := "Cisco-IOS-XR-infra-statsd-oper:infra-statistics/interfaces/interface/latest/generic-counters"
+	rows := []Row{
+		{Path: path, Node: "r1", Keys: map[string]string{"interface-name": "Gi0"}, Fields: map[string]interface{}{
+			"packets-received": uint64(10),
+			"carrier-delta":    int64(-1),
+			"load":             0.5,
+			"is-up":            true,
+			"mac-address":      "00:01",
+		}},
+		{Path: path, Node: "r1", Keys: map[string]string{"interface-name": "Gi1"}, Fields: map[string]interface{}{
+			"is-up": false,
+		}},
+	}
+	if err := p.Write(rows); err != nil {
+		t.Fatal(err)
+	}
+	// A later value replaces the earlier one.
+	if err := p.Write([]Row{{Path: path, Node: "r1", Keys: map[string]string{"interface-name": "Gi0"}, Fields: map[string]interface{}{"packets-received": uint64(12)}}}); err != nil {
+		t.Fatal(err)
+	}
+	got := string(p.expose(time.Now()))
+	want := `# HELP xr_generic_counters_carrier_delta ` + path + ` carrier-delta
+# TYPE xr_generic_counters_carrier_delta untyped
+xr_generic_counters_carrier_delta{interface_name="Gi0",router="r1"} -1
+# HELP xr_generic_counters_is_up ` + path + ` is-up
+# TYPE xr_generic_counters_is_up untyped
+xr_generic_counters_is_up{interface_name="Gi0",router="r1"} 1
+xr_generic_counters_is_up{interface_name="Gi1",router="r1"} 0
+# HELP xr_generic_counters_load ` + path + ` load
+# TYPE xr_generic_counters_load untyped
+xr_generic_counters_load{interface_name="Gi0",router="r1"} 0.5
+# HELP xr_generic_counters_packets_received ` + path + ` packets-received
+# TYPE xr_generic_counters_packets_received untyped
+xr_generic_counters_packets_received{interface_name="Gi0",router="r1"} 12
+`
+	if got != want {
+		t.Errorf("expose() =\n%s\nwant\n%s", got, want)
+	}
+
+	// Series that haven't been updated for Expire go away.
+	if got := string(p.expose(time.Now().Add(2 * time.Minute))); got != "" {
+		t.Errorf("expose() after they expired =\n%s", got)
+	}
+	if strings.Contains(string(p.expose(time.Now())), "router") {
+		t.Errorf("expired series came back")
+	}
+}
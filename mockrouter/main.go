@@ -0,0 +1,147 @@
+/*
+mockrouter stands in for the gRPC services of an IOS XR router, so the
+tools can run without a lab. Point them at it with the mock inventory:
+
+	./mockrouter &
+	cd ../getconfig && ./getconfig -inv ../input/mock/inventory.json -device mock
+
+The config lives in memory, starting from -config, and is lost when it
+stops.
+*/
+package main
+
+import (
+	"context"
+	"flag"
+	"io/ioutil"
+	"log"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/nleiva/clus2019/xrmock"
+	"github.com/nleiva/clus2019/yang"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+)
+
+func main() {
+	// Address to listen on; defaults to ":57344", as a router
+	listen := flag.String("listen", ":57344", "Address to serve on")
+	// Starting config
+	config := flag.String("config", "../input/mock/config.json", "YANG JSON config to start from")
+	cli := flag.String("cli", "../input/mock/running.cfg", "CLI config to start from, for 'show running-config'")
+	oper := flag.String("oper", "", "YANG JSON operational data for GetOper")
+	// Canned answers and recordings
+	canned := flag.String("canned", "../input/mock/canned.json", "JSON file of canned show command output and action replies")
+	tdir := flag.String("telemetry", "../input/mock/telemetry", "Directory of telemetry recordings, named <subscription>.<encoding>.rec")
+	speed := flag.Float64("speed", 1, "Telemetry replay speed: 1 as recorded, 10 ten times faster, 0 without pauses")
+	loop := flag.Bool("loop", false, "Replay telemetry recordings until the client cancels")
+	// Dial-out; the mock connects to a collector, as a router configured for it
+	dial := flag.String("dialout", "", "Send a telemetry recording to this collector, tcp://host:port or grpc://host:port, as a router dialing out")
+	dsubs := flag.String("dialout-subs", "LLDP", "Subscription whose recording -dialout sends")
+	denc := flag.String("dialout-enc", "gpbkv", "Encoding of the recording -dialout sends: 'json', 'gpb' or 'gpbkv'")
+	// YANG modules to check payloads with; none by default
+	ydir := flag.String("yang", "", "Directory of YANG modules to check payloads and find list keys with")
+	// TLS; xrgrpc always dials with TLS
+	cert := flag.String("cert", "../input/mock/mock.pem", "TLS certificate; a self-signed one is written here if missing")
+	key := flag.String("key", "../input/mock/mock.key", "TLS key; written with the certificate")
+	// Credentials, as in the inventory defaults
+	user := flag.String("username", "cisco", "Username to accept; empty to accept any")
+	pass := flag.String("password", "cisco", "Password to accept")
+	verbose := flag.Bool("v", false, "Log every request")
+	flag.Parse()
+
+	schema, err := yang.Load(*ydir)
+	if err != nil {
+		log.Fatalf("could not load the YANG modules: %v", err)
+	}
+	s := &xrmock.Server{
+		Schema:    schema,
+		Telemetry: *tdir,
+		Speed:     *speed,
+		Loop:      *loop,
+		Username:  *user,
+		Password:  *pass,
+	}
+	if s.Config, err = xrmock.LoadDatastore(*config); err != nil {
+		log.Fatalf("could not load the config: %v", err)
+	}
+	s.Config.Schema = schema
+	if s.Oper, err = xrmock.LoadDatastore(*oper); err != nil {
+		log.Fatalf("could not load the operational data: %v", err)
+	}
+	if *cli != "" {
+		b, err := ioutil.ReadFile(*cli)
+		if err != nil {
+			log.Fatalf("could not read the CLI config: %v", err)
+		}
+		s.SetCLI(string(b))
+	}
+	if *canned != "" {
+		if s.Canned, err = xrmock.LoadCanned(*canned); err != nil {
+			log.Fatalf("could not load the canned answers: %v", err)
+		}
+	}
+
+	if *dial != "" {
+		mape := map[string]int64{
+			"gpb":   2,
+			"gpbkv": 3,
+			"json":  4,
+		}
+		e, ok := mape[*denc]
+		if !ok {
+			log.Fatalf("encoding option '%v' not supported", *denc)
+		}
+		go dialOut(s, *dial, *dsubs, e)
+	}
+
+	host, _, _ := net.SplitHostPort(*listen)
+	tc, err := xrmock.LoadOrCreateCert(*cert, *key, host)
+	if err != nil {
+		log.Fatalf("could not load the TLS certificate: %v", err)
+	}
+	opts := []grpc.ServerOption{grpc.Creds(credentials.NewServerTLSFromCert(&tc))}
+	if *verbose {
+		opts = append(opts, grpc.UnaryInterceptor(logUnary), grpc.StreamInterceptor(logStream))
+	}
+	lis, err := net.Listen("tcp", *listen)
+	if err != nil {
+		log.Fatalf("could not listen on %s: %v", *listen, err)
+	}
+	srv := grpc.NewServer(opts...)
+	s.Register(srv)
+	log.Printf("mock router serving on %s, certificate in %s", lis.Addr(), *cert)
+	if err = srv.Serve(lis); err != nil {
+		log.Fatalf("mock router stopped: %v", err)
+	}
+}
+
+// dialOut sends the recording to the collector at addr over and over, as
+// a router keeps streaming, dialing again a second after each session.
+func dialOut(s *xrmock.Server, addr, subs string, enc int64) {
+	for {
+		if err := s.DialOut(context.Background(), addr, subs, enc); err != nil {
+			log.Printf("dial-out to %s failed: %v", addr, err)
+		} else {
+			log.Printf("sent %s to %s", subs, addr)
+		}
+		time.Sleep(time.Second)
+	}
+}
+
+func logUnary(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	log.Printf("%s %v", method(info.FullMethod), req)
+	return handler(ctx, req)
+}
+
+func logStream(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	log.Printf("%s", method(info.FullMethod))
+	return handler(srv, ss)
+}
+
+// method drops the service from a full method name.
+func method(full string) string {
+	return full[strings.LastIndex(full, "/")+1:]
+}
@@ -0,0 +1,177 @@
+/*
+Package payload renders YANG JSON payloads from Go text/template files, with
+variables set globally, per group and per device.
+*/
+package payload
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"text/template"
+
+	"github.com/nleiva/clus2019/inventory"
+	"github.com/nleiva/clus2019/yang"
+	"github.com/pkg/errors"
+)
+
+// Vars is the content of a variables file. Device values override group
+// values, which override global ones. Groups apply in the order the device
+// lists them.
+type Vars struct {
+	Global  map[string]interface{}            `json:"global"`
+	Groups  map[string]map[string]interface{} `json:"groups"`
+	Devices map[string]map[string]interface{} `json:"devices"`
+}
+
+// LoadVars reads a variables file.
+func LoadVars(file string) (*Vars, error) {
+	b, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not read file %s", file)
+	}
+	v := new(Vars)
+	if err = json.Unmarshal(b, v); err != nil {
+		return nil, errors.Wrapf(err, "could not parse variables %s", file)
+	}
+	return v, nil
+}
+
+// For returns the template data for d: its variables plus the inventory
+// entry itself under "device", e.g. {{.device.Name}}.
+func (v *Vars) For(d inventory.Device) map[string]interface{} {
+	data := make(map[string]interface{})
+	if v != nil {
+		copyVars(data, v.Global)
+		for _, g := range d.Groups {
+			copyVars(data, v.Groups[g])
+		}
+		copyVars(data, v.Devices[d.Name])
+	}
+	data["device"] = d
+	return data
+}
+
+func copyVars(dst, src map[string]interface{}) {
+	for k, val := range src {
+		dst[k] = val
+	}
+}
+
+// IsTemplate reports whether file should be rendered before sending; by
+// convention templates end in ".tmpl".
+func IsTemplate(file string) bool {
+	return strings.HasSuffix(file, ".tmpl")
+}
+
+var funcs = template.FuncMap{
+	// json encodes a value, quoting and escaping strings.
+	"json": func(v interface{}) (string, error) {
+		b, err := json.Marshal(v)
+		return string(b), err
+	},
+}
+
+// Template is a parsed payload template.
+type Template struct {
+	name string
+	t    *template.Template
+}
+
+// Parse reads a payload template. Referencing a variable that isn't set
+// is an error when rendering.
+func Parse(file string) (*Template, error) {
+	b, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not read file %s", file)
+	}
+	name := filepath.Base(file)
+	t, err := template.New(name).Funcs(funcs).Option("missingkey=error").Parse(string(b))
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not parse template %s", file)
+	}
+	return &Template{name: name, t: t}, nil
+}
+
+// Render executes the template with data and checks the result is JSON.
+func (t *Template) Render(data map[string]interface{}) ([]byte, error) {
+	var out bytes.Buffer
+	if err := t.t.Execute(&out, data); err != nil {
+		return nil, errors.Wrapf(err, "could not render %s", t.name)
+	}
+	if err := Validate(out.Bytes()); err != nil {
+		return nil, errors.Wrapf(err, "%s rendered invalid JSON", t.name)
+	}
+	return out.Bytes(), nil
+}
+
+// Validate checks b is a JSON object, reporting the line of any syntax error.
+func Validate(b []byte) error {
+	var v map[string]interface{}
+	err := json.Unmarshal(b, &v)
+	if serr, ok := err.(*json.SyntaxError); ok {
+		line := bytes.Count(b[:serr.Offset], []byte("\n")) + 1
+		return errors.Wrapf(err, "line %d", line)
+	}
+	return err
+}
+
+// Source gives the payload to send to each device: a plain file, or a
+// template rendered with the device's variables.
+type Source struct {
+	file string
+	js   []byte
+	tmpl *Template
+	vars *Vars
+
+	schema *yang.Schema
+	op     yang.Op
+}
+
+// Open loads file as a payload source. file is treated as a template if it
+// ends in ".tmpl" or a variables file is given.
+func Open(file, varsFile string) (*Source, error) {
+	s := &Source{file: file}
+	if !IsTemplate(file) && varsFile == "" {
+		js, err := ioutil.ReadFile(file)
+		if err != nil {
+			return nil, errors.Wrapf(err, "could not read file %s", file)
+		}
+		s.js = js
+		return s, nil
+	}
+	t, err := Parse(file)
+	if err != nil {
+		return nil, err
+	}
+	s.tmpl = t
+	if varsFile != "" {
+		if s.vars, err = LoadVars(varsFile); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// Check makes For validate every payload against schema, for op. A nil
+// schema checks nothing.
+func (s *Source) Check(schema *yang.Schema, op yang.Op) {
+	s.schema, s.op = schema, op
+}
+
+// For returns the payload for d.
+func (s *Source) For(d inventory.Device) ([]byte, error) {
+	js := s.js
+	if s.tmpl != nil {
+		var err error
+		if js, err = s.tmpl.Render(s.vars.For(d)); err != nil {
+			return nil, err
+		}
+	}
+	if err := s.schema.Check(js, s.op); err != nil {
+		return nil, errors.Wrapf(err, "%s doesn't match the YANG modules", s.file)
+	}
+	return js, nil
+}
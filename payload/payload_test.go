@@ -0,0 +1,233 @@
+package payload
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/nleiva/clus2019/inventory"
+	"github.com/nleiva/clus2019/yang"
+)
+
+// writeFiles writes each name: content pair to a directory the caller
+// removes.
+func writeFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir, err := ioutil.TempDir("", "payload")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range files {
+		if err = ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+var testVars = &Vars{
+	Global: map[string]interface{}{"mtu": 1500, "domain": "example.com", "ntp": "192.0.2.123"},
+	Groups: map[string]map[string]interface{}{
+		"core": {"mtu": 9000, "site": "core"},
+		"lab":  {"mtu": 1514, "ntp": "198.51.100.123"},
+	},
+	Devices: map[string]map[string]interface{}{
+		"r2": {"mtu": 4000},
+	},
+}
+
+func TestFor(t *testing.T) {
+	tests := []struct {
+		device inventory.Device
+		want   map[string]interface{}
+	}{
+		// No groups, nothing for the device: the global values.
+		{
+			device: inventory.Device{Name: "r1"},
+			want:   map[string]interface{}{"mtu": 1500, "domain": "example.com", "ntp": "192.0.2.123"},
+		},
+		// The device over its group over the global values.
+		{
+			device: inventory.Device{Name: "r2", Groups: []string{"core"}},
+			want:   map[string]interface{}{"mtu": 4000, "domain": "example.com", "ntp": "192.0.2.123", "site": "core"},
+		},
+		// Later groups win, and unknown ones are skipped.
+		{
+			device: inventory.Device{Name: "r3", Groups: []string{"core", "none", "lab"}},
+			want:   map[string]interface{}{"mtu": 1514, "domain": "example.com", "ntp": "198.51.100.123", "site": "core"},
+		},
+		{
+			device: inventory.Device{Name: "r3", Groups: []string{"lab", "core"}},
+			want:   map[string]interface{}{"mtu": 9000, "domain": "example.com", "ntp": "198.51.100.123", "site": "core"},
+		},
+	}
+	for _, tt := range tests {
+		got := testVars.For(tt.device)
+		if d, ok := got["device"].(inventory.Device); !ok || d.Name != tt.device.Name {
+			t.Errorf("For(%s) device = %v, want the inventory entry", tt.device.Name, got["device"])
+		}
+		delete(got, "device")
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("For(%s %v) = %v, want %v", tt.device.Name, tt.device.Groups, got, tt.want)
+		}
+	}
+	// Without variables, there's only the device.
+	var none *Vars
+	if got := none.For(inventory.Device{Name: "r1"}); len(got) != 1 || got["device"] == nil {
+		t.Errorf("For() without variables = %v, want only the device", got)
+	}
+	// Nor do devices share their data.
+	testVars.For(inventory.Device{Name: "r2"})["mtu"] = 1
+	if testVars.Devices["r2"]["mtu"] != 4000 {
+		t.Errorf("For() result shares the variables: mtu = %v", testVars.Devices["r2"]["mtu"])
+	}
+}
+
+func TestRender(t *testing.T) {
+	tests := []struct {
+		name string
+		tmpl string
+		want string
+		err  string
+	}{
+		{
+			name: "variables",
+			tmpl: `{"mtu": {{.mtu}}, "host": "{{.device.Name}}.{{.domain}}"}`,
+			want: `{"mtu": 1500, "host": "r1.example.com"}`,
+		},
+		// json quotes and escapes strings, and encodes any value.
+		{
+			name: "json",
+			tmpl: `{"desc": {{json .desc}}, "list": {{json .list}}, "n": {{json .mtu}}}`,
+			want: `{"desc": "say \"hi\"\n\u003cnow\u003e", "list": ["a","b"], "n": 1500}`,
+		},
+		// A variable that isn't set is an error, not "<no value>".
+		{
+			name: "missing",
+			tmpl: `{"mtu": {{.mtu}}, "speed": {{.speed}}}`,
+			err:  `map has no entry for key "speed"`,
+		},
+		{
+			name: "missing field",
+			tmpl: `{"name": "{{.device.Site}}"}`,
+			err:  `can't evaluate field Site`,
+		},
+		{
+			name: "invalid JSON",
+			tmpl: "{\n  \"mtu\": {{.mtu}},\n}",
+			err:  "t.tmpl rendered invalid JSON: line 3",
+		},
+	}
+	data := map[string]interface{}{
+		"mtu":    1500,
+		"domain": "example.com",
+		"desc":   "say \"hi\"\n<now>",
+		"list":   []string{"a", "b"},
+		"device": inventory.Device{Name: "r1"},
+	}
+	for _, tt := range tests {
+		dir := writeFiles(t, map[string]string{"t.tmpl": tt.tmpl})
+		tmpl, err := Parse(filepath.Join(dir, "t.tmpl"))
+		os.RemoveAll(dir)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		got, err := tmpl.Render(data)
+		switch {
+		case tt.err != "":
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("%s: Render() = %s, %v, want error %s", tt.name, got, err, tt.err)
+			}
+		case err != nil:
+			t.Errorf("%s: %v", tt.name, err)
+		case string(got) != tt.want:
+			t.Errorf("%s: Render() = %s, want %s", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		js  string
+		err string
+	}{
+		{js: `{"a": [1, 2]}`},
+		{js: "{\n  \"a\": 1\n  \"b\": 2\n}", err: "line 3: invalid character '\"' after object key:value pair"},
+		{js: "{\"a\":\n", err: "unexpected end of JSON input"},
+		{js: "", err: "unexpected end of JSON input"},
+		// The payload is an object of top-level nodes.
+		{js: `["a"]`, err: "cannot unmarshal array"},
+	}
+	for _, tt := range tests {
+		err := Validate([]byte(tt.js))
+		if (err == nil) != (tt.err == "") || err != nil && !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("Validate(%q) = %v, want %q", tt.js, err, tt.err)
+		}
+	}
+}
+
+func TestSource(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"plain.json":  `{"test:user": {"name": "{{.name}}"}}`,
+		"user.tmpl":   `{"test:user": {"name": "{{.device.Name}}", "id": {{.id}}}}`,
+		"user.json":   `{"test:user": {"name": "{{.device.Name}}", "id": {{.id}}}}`,
+		"broken.tmpl": `{"test:user": {{.id}`,
+		"vars.json":   `{"global": {"id": 10}, "devices": {"big": {"id": 70000}}}`,
+		"bad.json":    `{"global": `,
+	})
+	defer os.RemoveAll(dir)
+	file := func(name string) string { return filepath.Join(dir, name) }
+	schema, err := yang.Load("../yang")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		file   string
+		vars   string
+		device string
+		want   string
+		err    string
+	}{
+		// Without ".tmpl" or variables, a file is sent as it is.
+		{name: "plain", file: "plain.json", device: "r1", want: `{"test:user": {"name": "{{.name}}"}}`},
+		{name: "template", file: "user.tmpl", vars: "vars.json", device: "r1", want: `{"test:user": {"name": "r1", "id": 10}}`},
+		// A variables file makes any file a template.
+		{name: "vars", file: "user.json", vars: "vars.json", device: "r2", want: `{"test:user": {"name": "r2", "id": 10}}`},
+		// The YANG modules don't take what the variables give this one.
+		{name: "schema", file: "user.tmpl", vars: "vars.json", device: "big", err: "user.tmpl doesn't match the YANG modules: /test:user/id: 70000 is out of range for uint16"},
+		// Without variables, a template can only use the device.
+		{name: "no vars", file: "user.tmpl", device: "r1", err: `map has no entry for key "id"`},
+		{name: "no file", file: "none.json", err: "could not read file"},
+		{name: "no template", file: "none.tmpl", err: "could not read file"},
+		{name: "broken template", file: "broken.tmpl", err: "could not parse template"},
+		{name: "no vars file", file: "user.tmpl", vars: "none.json", err: "could not read file"},
+		{name: "bad vars", file: "user.tmpl", vars: "bad.json", err: "could not parse variables"},
+	}
+	for _, tt := range tests {
+		vars := ""
+		if tt.vars != "" {
+			vars = file(tt.vars)
+		}
+		src, err := Open(file(tt.file), vars)
+		var got []byte
+		if err == nil {
+			src.Check(schema, yang.Merge)
+			got, err = src.For(inventory.Device{Name: tt.device})
+		}
+		switch {
+		case tt.err != "":
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("%s: got %s, %v, want error %s", tt.name, got, err, tt.err)
+			}
+		case err != nil:
+			t.Errorf("%s: %v", tt.name, err)
+		case string(got) != tt.want:
+			t.Errorf("%s: got %s, want %s", tt.name, got, tt.want)
+		}
+	}
+}
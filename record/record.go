@@ -0,0 +1,177 @@
+/*
+Package record reads and writes telemetry recordings: the messages of a
+subscription as they came off the wire, each with the time it was received.
+
+A recording is a sequence of frames, each an 8-byte receive time in
+nanoseconds since the Unix epoch, a 4-byte length and the message, with
+both numbers big-endian.
+*/
+package record
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"io"
+	"os"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// maxFrame bounds the length of a message, so a corrupt file fails
+// instead of allocating gigabytes.
+const maxFrame = 64 << 20
+
+// Frame is a message and when it was received.
+type Frame struct {
+	Time time.Time
+	Data []byte
+}
+
+// Writer writes frames to a recording.
+type Writer struct {
+	w *bufio.Writer
+	c io.Closer
+}
+
+// NewWriter returns a Writer that appends frames to w.
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{w: bufio.NewWriter(w)}
+}
+
+// Create returns a Writer for a new recording in file, truncating it if
+// it exists.
+func Create(file string) (*Writer, error) {
+	fd, err := os.Create(file)
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not create file %s", file)
+	}
+	return &Writer{w: bufio.NewWriter(fd), c: fd}, nil
+}
+
+// Write adds a frame. It isn't on w until Flush.
+func (w *Writer) Write(f Frame) error {
+	var hdr [12]byte
+	binary.BigEndian.PutUint64(hdr[:8], uint64(f.Time.UnixNano()))
+	binary.BigEndian.PutUint32(hdr[8:], uint32(len(f.Data)))
+	if _, err := w.w.Write(hdr[:]); err != nil {
+		return errors.Wrap(err, "could not write frame")
+	}
+	_, err := w.w.Write(f.Data)
+	return errors.Wrap(err, "could not write frame")
+}
+
+// Flush writes the buffered frames.
+func (w *Writer) Flush() error {
+	return errors.Wrap(w.w.Flush(), "could not write frames")
+}
+
+// Close flushes the frames and closes the file of a Writer from Create.
+func (w *Writer) Close() error {
+	err := w.Flush()
+	if w.c != nil {
+		if cerr := w.c.Close(); err == nil {
+			err = errors.Wrap(cerr, "could not close the recording")
+		}
+	}
+	return err
+}
+
+// Reader reads frames from a recording.
+type Reader struct {
+	r *bufio.Reader
+}
+
+// NewReader returns a Reader for the frames in r.
+func NewReader(r io.Reader) *Reader {
+	return &Reader{r: bufio.NewReader(r)}
+}
+
+// Next returns the next frame, or io.EOF after the last one.
+func (r *Reader) Next() (Frame, error) {
+	var hdr [12]byte
+	if _, err := io.ReadFull(r.r, hdr[:]); err != nil {
+		if err == io.EOF {
+			return Frame{}, io.EOF
+		}
+		return Frame{}, errors.Wrap(err, "could not read frame")
+	}
+	n := binary.BigEndian.Uint32(hdr[8:])
+	if n > maxFrame {
+		return Frame{}, errors.Errorf("frame of %d bytes is too long", n)
+	}
+	f := Frame{
+		Time: time.Unix(0, int64(binary.BigEndian.Uint64(hdr[:8]))),
+		Data: make([]byte, n),
+	}
+	if _, err := io.ReadFull(r.r, f.Data); err != nil {
+		return Frame{}, errors.Wrap(err, "could not read frame")
+	}
+	return f, nil
+}
+
+// ReadFile returns every frame in file.
+func ReadFile(file string) ([]Frame, error) {
+	fd, err := os.Open(file)
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not open file %s", file)
+	}
+	defer fd.Close()
+	var frames []Frame
+	r := NewReader(fd)
+	for {
+		f, err := r.Next()
+		if err == io.EOF {
+			return frames, nil
+		}
+		if err != nil {
+			return nil, errors.Wrapf(err, "could not read file %s", file)
+		}
+		frames = append(frames, f)
+	}
+}
+
+// Pause waits as long as passed between frames prev and next, divided by
+// speed, or until ctx is done. A speed of 0 doesn't wait.
+func Pause(ctx context.Context, prev, next Frame, speed float64) error {
+	if speed <= 0 {
+		return ctx.Err()
+	}
+	t := time.NewTimer(time.Duration(float64(next.Time.Sub(prev.Time)) / speed))
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Replay sends the messages in file on the first channel at the pace
+// they were received, scaled by speed as in Pause, and closes it after
+// the last one or when ctx is done. The channels are those of
+// xrgrpc.GetSubscription, so a recording goes through the same code as a
+// live subscription; nothing is sent on the second one, as the whole file
+// is read before Replay returns.
+func Replay(ctx context.Context, file string, speed float64) (chan []byte, chan error, error) {
+	frames, err := ReadFile(file)
+	if err != nil {
+		return nil, nil, err
+	}
+	ch, ech := make(chan []byte), make(chan error)
+	go func() {
+		defer close(ch)
+		for i, f := range frames {
+			if i > 0 && Pause(ctx, frames[i-1], f, speed) != nil {
+				return
+			}
+			select {
+			case ch <- f.Data:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return ch, ech, nil
+}
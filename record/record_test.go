@@ -0,0 +1,165 @@
+package record
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRoundTrip(t *testing.T) {
+	t0 := time.Unix(1560182400, 123)
+	frames := []Frame{
+		{Time: t0, Data: []byte("one")},
+		{Time: t0.Add(time.Second), Data: []byte{}},
+		{Time: t0.Add(2 * time.Second), Data: bytes.Repeat([]byte{0xff}, 5000)},
+	}
+	var b bytes.Buffer
+	w := NewWriter(&b)
+	for i, f := range frames {
+		if err := w.Write(f); err != nil {
+			t.Fatal(err)
+		}
+		// Small frames wait in the buffer until Flush.
+		if i == 0 && b.Len() != 0 {
+			t.Errorf("%d bytes written before Flush", b.Len())
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	// The header is the time in nanoseconds and the length, big-endian.
+	h := b.Bytes()[:12]
+	if got, want := int64(binary.BigEndian.Uint64(h)), t0.UnixNano(); got != want {
+		t.Errorf("header time is %d, want %d", got, want)
+	}
+	if got := binary.BigEndian.Uint32(h[8:]); got != 3 {
+		t.Errorf("header length is %d, want 3", got)
+	}
+
+	r := NewReader(&b)
+	for i, want := range frames {
+		got, err := r.Next()
+		if err != nil {
+			t.Fatalf("frame %d: %v", i, err)
+		}
+		if !got.Time.Equal(want.Time) || !bytes.Equal(got.Data, want.Data) {
+			t.Errorf("frame %d is %v with %d bytes, want %v with %d", i, got.Time, len(got.Data), want.Time, len(want.Data))
+		}
+	}
+	if _, err := r.Next(); err != io.EOF {
+		t.Errorf("Next() after the last frame = %v, want io.EOF", err)
+	}
+}
+
+func TestNextErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		in   []byte
+		want string
+	}{
+		{name: "short header", in: []byte{0, 0, 0}, want: "could not read frame: unexpected EOF"},
+		{name: "short data", in: []byte{0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 5, 'a'}, want: "could not read frame: unexpected EOF"},
+		{name: "too long", in: []byte{0, 0, 0, 0, 0, 0, 0, 0, 0x10, 0, 0, 0}, want: "frame of 268435456 bytes is too long"},
+	}
+	for _, tt := range tests {
+		_, err := NewReader(bytes.NewReader(tt.in)).Next()
+		if err == nil || err.Error() != tt.want {
+			t.Errorf("%s: Next() = %v, want %s", tt.name, err, tt.want)
+		}
+	}
+}
+
+func TestFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "record")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "s.rec")
+	w, err := Create(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t0 := time.Now()
+	for i := 0; i < 3; i++ {
+		if err = w.Write(Frame{Time: t0.Add(time.Duration(i) * 20 * time.Millisecond), Data: []byte{byte(i)}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err = w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	frames, err := ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(frames) != 3 {
+		t.Fatalf("ReadFile() = %d frames, want 3", len(frames))
+	}
+
+	// At speed 1 the replay takes as long as the recording, 40ms.
+	start := time.Now()
+	ch, _, err := Replay(context.Background(), file, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got [][]byte
+	for b := range ch {
+		got = append(got, b)
+	}
+	if want := [][]byte{{0}, {1}, {2}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Replay() sent %v, want %v", got, want)
+	}
+	if d := time.Since(start); d < 40*time.Millisecond {
+		t.Errorf("Replay() took %v, want at least 40ms", d)
+	}
+
+	if _, _, err = Replay(context.Background(), filepath.Join(dir, "missing.rec"), 0); err == nil || !strings.Contains(err.Error(), "could not open file") {
+		t.Errorf("Replay() of a missing file = %v", err)
+	}
+}
+
+func TestPause(t *testing.T) {
+	t0 := time.Now()
+	prev, next := Frame{Time: t0}, Frame{Time: t0.Add(time.Hour)}
+	tests := []struct {
+		name   string
+		speed  float64
+		cancel bool
+		want   error
+		max    time.Duration
+	}{
+		{name: "no pauses", speed: 0, max: 10 * time.Millisecond},
+		// An hour at 72000 times the speed is 50ms.
+		{name: "scaled", speed: 72000, max: time.Second},
+		{name: "cancelled", speed: 1, cancel: true, want: context.Canceled, max: 10 * time.Millisecond},
+	}
+	for _, tt := range tests {
+		ctx, cancel := context.WithCancel(context.Background())
+		if tt.cancel {
+			cancel()
+		}
+		start := time.Now()
+		err := Pause(ctx, prev, next, tt.speed)
+		d := time.Since(start)
+		cancel()
+		if err != tt.want {
+			t.Errorf("%s: Pause() = %v, want %v", tt.name, err, tt.want)
+		}
+		if d > tt.max {
+			t.Errorf("%s: Pause() took %v, want at most %v", tt.name, d, tt.max)
+		}
+		if tt.name == "scaled" && d < 50*time.Millisecond {
+			t.Errorf("%s: Pause() took %v, want 50ms", tt.name, d)
+		}
+	}
+}
@@ -0,0 +1,85 @@
+/*
+gRPC Client
+*/
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"time"
+
+	"github.com/nleiva/clus2019/inventory"
+	"github.com/nleiva/clus2019/yang"
+	xr "github.com/nleiva/xrgrpc"
+)
+
+func timeTrack(start time.Time) {
+	elapsed := time.Since(start)
+	log.Printf("This process took %s\n", elapsed)
+
+}
+
+func main() {
+	// To time this process
+	defer timeTrack(time.Now())
+
+	// YANG config; defaults to "yangocconfig.json"
+	ypath := flag.String("ypath", "../input/yangocconfig.json", "YANG path arguments")
+	// YANG modules to validate the payload with; defaults to "../yang"
+	var yf yang.Flags
+	yf.AddFlags(flag.CommandLine)
+	// Target devices; defaults to "router2" from "inventory.json"
+	var tg inventory.Target
+	tg.AddFlags(flag.CommandLine)
+	flag.Parse()
+
+	devices, err := tg.Devices()
+	if err != nil {
+		log.Fatalf("could not select a device, %v", err)
+	}
+
+	// Get YANG config file
+	js, err := ioutil.ReadFile(*ypath)
+	if err != nil {
+		log.Fatalf("could not read file: %v: %v\n", *ypath, err)
+	}
+	schema, err := yf.Load(log.Printf)
+	if err != nil {
+		log.Fatalf("could not load the YANG modules: %v\n", err)
+	}
+	if err = schema.Check(js, yang.Replace); err != nil {
+		log.Fatalf("%v doesn't match the YANG modules:\n%v\n", *ypath, err)
+	}
+	for _, d := range devices {
+		replaceConfig(d, string(js))
+	}
+}
+
+func replaceConfig(d inventory.Device, js string) {
+	// ID for the transaction.
+	var id int64 = 1
+
+	// Target parameters come from the inventory.
+	router, err := d.Router(xr.WithTimeout(5))
+	if err != nil {
+		log.Fatalf("could not build a router, %v", err)
+	}
+
+	// Setup a connection to the target.
+	conn, ctx, err := xr.Connect(*router)
+	if err != nil {
+		log.Fatalf("could not setup a client connection to %s, %v", router.Host, err)
+	}
+	defer conn.Close()
+
+	// Replace the config on target with 'js'
+	ri, err := xr.ReplaceConfig(ctx, conn, js, id)
+	if err != nil {
+		log.Fatalf("failed to replace config on %s: %v\n", router.Host, err)
+	}
+	fmt.Printf("\nconfig replaced on %s -> Request ID: %v, Response ID: %v\n\n", router.Host, id, ri)
+
+}
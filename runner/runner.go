@@ -0,0 +1,165 @@
+/*
+Package runner executes the same request against many routers concurrently
+and collects a per-device report, so a failing device doesn't abort the run.
+*/
+package runner
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/nleiva/clus2019/inventory"
+	xr "github.com/nleiva/xrgrpc"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc"
+)
+
+// Task is the request to run on a connected router. It returns the
+// output to report for the device.
+type Task func(ctx context.Context, conn *grpc.ClientConn, id int64) (string, error)
+
+// Result is the outcome of a Task on a device.
+type Result struct {
+	Device  string
+	Host    string
+	Output  string
+	Err     error
+	Latency time.Duration
+}
+
+// Runner runs a Task on a list of devices with at most Workers in
+// parallel. Timeout, in seconds, overrides the per-device timeout from the
+// inventory when set.
+type Runner struct {
+	Workers int
+	Timeout int
+	// Defaults are applied before the inventory values, e.g. a tool's
+	// default xr.WithTimeout.
+	Defaults []xr.RouterOption
+
+	// connect connects to a device; dial unless a test says otherwise.
+	connect func(d inventory.Device) (*grpc.ClientConn, context.Context, error)
+}
+
+// Run executes t on every device and returns the results in the same order
+// as devices.
+func (r *Runner) Run(devices []inventory.Device, t Task) []Result {
+	return r.RunEach(devices, func(inventory.Device) Task { return t })
+}
+
+// RunEach is like Run, but builds the Task for each device, for requests
+// that depend on the target.
+func (r *Runner) RunEach(devices []inventory.Device, task func(inventory.Device) Task) []Result {
+	workers := r.Workers
+	if workers < 1 {
+		workers = 1
+	}
+	results := make([]Result, len(devices))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				results[i] = r.runOne(devices[i], task(devices[i]))
+			}
+		}()
+	}
+	for i := range devices {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+	return results
+}
+
+func (r *Runner) runOne(d inventory.Device, t Task) Result {
+	start := time.Now()
+	output, err := r.exec(d, t)
+	return Result{
+		Device:  d.Name,
+		Host:    d.Addr(),
+		Output:  output,
+		Err:     err,
+		Latency: time.Since(start),
+	}
+}
+
+func (r *Runner) exec(d inventory.Device, t Task) (string, error) {
+	// ID for the transaction.
+	var id int64 = 1
+
+	connect := r.connect
+	if connect == nil {
+		connect = r.dial
+	}
+	conn, ctx, err := connect(d)
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+
+	return t(ctx, conn, id)
+}
+
+// dial connects to d with the options of the inventory and r.
+func (r *Runner) dial(d inventory.Device) (*grpc.ClientConn, context.Context, error) {
+	opts := d.Options(r.Defaults...)
+	if r.Timeout > 0 {
+		opts = append(opts, xr.WithTimeout(r.Timeout))
+	}
+	router, err := xr.BuildRouter(opts...)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "could not build a router")
+	}
+	conn, ctx, err := xr.Connect(*router)
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, "could not setup a client connection to %s", router.Host)
+	}
+	return conn, ctx, nil
+}
+
+// GetConfig returns a Task that retrieves the config for the YANG paths in js.
+func GetConfig(js string) Task {
+	return func(ctx context.Context, conn *grpc.ClientConn, id int64) (string, error) {
+		return xr.GetConfig(ctx, conn, js, id)
+	}
+}
+
+// ShowCmd returns a Task that runs the show command cli.
+func ShowCmd(cli string) Task {
+	return func(ctx context.Context, conn *grpc.ClientConn, id int64) (string, error) {
+		return xr.ShowCmdTextOutput(ctx, conn, cli, id)
+	}
+}
+
+// Failed counts the results that carry an error.
+func Failed(rs []Result) int {
+	n := 0
+	for _, r := range rs {
+		if r.Err != nil {
+			n++
+		}
+	}
+	return n
+}
+
+// Report writes a summary table with one line per device.
+func Report(w io.Writer, rs []Result) {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "DEVICE\tHOST\tSTATUS\tLATENCY\tERROR")
+	for _, r := range rs {
+		status, msg := "ok", ""
+		if r.Err != nil {
+			status, msg = "failed", r.Err.Error()
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", r.Device, r.Host, status, r.Latency.Round(time.Millisecond), msg)
+	}
+	tw.Flush()
+	fmt.Fprintf(w, "\n%d devices, %d failed\n", len(rs), Failed(rs))
+}
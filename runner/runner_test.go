@@ -0,0 +1,140 @@
+package runner
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nleiva/clus2019/inventory"
+	"google.golang.org/grpc"
+)
+
+// fakeConnect connects to any device but "down", without a server: the
+// tasks of these tests don't use the connection.
+func fakeConnect(d inventory.Device) (*grpc.ClientConn, context.Context, error) {
+	if d.Name == "down" {
+		return nil, nil, errors.New("could not setup a client connection to " + d.Host)
+	}
+	conn, err := grpc.Dial("passthrough:///"+d.Host, grpc.WithInsecure())
+	return conn, context.Background(), err
+}
+
+func devices(names ...string) []inventory.Device {
+	var out []inventory.Device
+	for i, n := range names {
+		out = append(out, inventory.Device{Name: n, Host: fmt.Sprintf("192.0.2.%d", i+1), Port: 57344})
+	}
+	return out
+}
+
+// pool runs tasks that take wait, and tracks how many run at once.
+type pool struct {
+	wait time.Duration
+	mu   sync.Mutex
+	now  int
+	max  int
+}
+
+// task returns a Task for d that reports its name, or fails for "bad".
+func (p *pool) task(d inventory.Device) Task {
+	return func(ctx context.Context, conn *grpc.ClientConn, id int64) (string, error) {
+		p.mu.Lock()
+		p.now++
+		if p.now > p.max {
+			p.max = p.now
+		}
+		p.mu.Unlock()
+		time.Sleep(p.wait)
+		p.mu.Lock()
+		p.now--
+		p.mu.Unlock()
+		if d.Name == "bad" {
+			return "", errors.New("invalid command")
+		}
+		return "output of " + d.Name, nil
+	}
+}
+
+func TestRunEach(t *testing.T) {
+	tests := []struct {
+		workers int
+		max     int
+	}{
+		{workers: 3, max: 3},
+		// More workers than devices, of which seven connect, and none,
+		// which means one.
+		{workers: 20, max: 7},
+		{workers: 0, max: 1},
+	}
+	names := []string{"r1", "r2", "bad", "r4", "down", "r6", "r7", "r8"}
+	for _, tt := range tests {
+		p := &pool{wait: 20 * time.Millisecond}
+		r := &Runner{Workers: tt.workers, connect: fakeConnect}
+		rs := r.RunEach(devices(names...), p.task)
+		if p.max != tt.max {
+			t.Errorf("%d workers: %d tasks ran at once, want %d", tt.workers, p.max, tt.max)
+		}
+		if len(rs) != len(names) {
+			t.Fatalf("%d workers: %d results, want %d", tt.workers, len(rs), len(names))
+		}
+		for i, res := range rs {
+			want := "output of " + names[i]
+			switch names[i] {
+			case "bad", "down":
+				want = ""
+			}
+			if res.Device != names[i] || res.Host != fmt.Sprintf("192.0.2.%d:57344", i+1) || res.Output != want {
+				t.Errorf("%d workers: result %d = %s %s %q, want %s %q", tt.workers, i, res.Device, res.Host, res.Output, names[i], want)
+			}
+			if failed := names[i] == "bad" || names[i] == "down"; (res.Err != nil) != failed {
+				t.Errorf("%d workers: %s failed with %v", tt.workers, names[i], res.Err)
+			}
+			// A device that's down doesn't get to run the task.
+			if names[i] != "down" && res.Latency < p.wait {
+				t.Errorf("%d workers: %s took %v, want at least %v", tt.workers, names[i], res.Latency, p.wait)
+			}
+		}
+		if n := Failed(rs); n != 2 {
+			t.Errorf("%d workers: Failed() = %d, want 2", tt.workers, n)
+		}
+	}
+}
+
+func TestRun(t *testing.T) {
+	calls := 0
+	var mu sync.Mutex
+	task := func(ctx context.Context, conn *grpc.ClientConn, id int64) (string, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		calls++
+		return "same", nil
+	}
+	r := &Runner{Workers: 2, connect: fakeConnect}
+	rs := r.Run(devices("r1", "r2", "r3"), task)
+	if calls != 3 || len(rs) != 3 || Failed(rs) != 0 {
+		t.Errorf("Run() called the task %d times for %d results, %d failed; want 3, 3, 0", calls, len(rs), Failed(rs))
+	}
+	if rs := r.Run(nil, task); len(rs) != 0 {
+		t.Errorf("Run() without devices = %v, want no results", rs)
+	}
+}
+
+func TestReport(t *testing.T) {
+	rs := []Result{
+		{Device: "r1", Host: "192.0.2.1:57344", Output: "x", Latency: 1234567 * time.Nanosecond},
+		{Device: "router-two", Host: "192.0.2.2:57344", Err: errors.New("could not setup a client connection to 192.0.2.2"), Latency: 3 * time.Second},
+	}
+	want := "DEVICE      HOST             STATUS  LATENCY  ERROR\n" +
+		"r1          192.0.2.1:57344  ok      1ms      \n" +
+		"router-two  192.0.2.2:57344  failed  3s       could not setup a client connection to 192.0.2.2\n" +
+		"\n2 devices, 1 failed\n"
+	var b bytes.Buffer
+	Report(&b, rs)
+	if b.String() != want {
+		t.Errorf("got\n%s\nwant\n%s", b.String(), want)
+	}
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/nleiva/clus2019/diff"
+)
+
+// cliNode is a line of CLI config with the lines indented under it.
+type cliNode struct {
+	text     string
+	children []*cliNode
+	// first and last are the lines the node and its children take in
+	// the running config.
+	first, last int
+	indent      int
+}
+
+// parseCLI builds the tree of a CLI config from its indentation, as
+// "show running-config" prints it. "!" lines only close blocks, and "end"
+// marks the end of the config.
+func parseCLI(lines []string) (root *cliNode, end int) {
+	root = &cliNode{first: -1, last: -1}
+	end = len(lines)
+	type level struct {
+		node   *cliNode
+		indent int
+	}
+	stack := []level{{root, -1}}
+	for i, l := range lines {
+		text := strings.TrimSpace(l)
+		switch text {
+		case "", "!":
+			continue
+		case "end":
+			end = i
+			continue
+		}
+		indent := len(l) - len(strings.TrimLeft(l, " "))
+		for len(stack) > 1 && stack[len(stack)-1].indent >= indent {
+			stack = stack[:len(stack)-1]
+		}
+		n := &cliNode{text: text, first: i, last: i, indent: indent}
+		parent := stack[len(stack)-1].node
+		parent.children = append(parent.children, n)
+		for _, lv := range stack[1:] {
+			lv.node.last = i
+		}
+		stack = append(stack, level{n, indent})
+	}
+	return root, end
+}
+
+func (n *cliNode) child(text string) *cliNode {
+	for _, c := range n.children {
+		if c.text == text {
+			return c
+		}
+	}
+	return nil
+}
+
+// mergeCLI returns the running config with the lines of cli in it, where
+// the router would show them: a line goes under the same parents it has
+// in cli, after their last child, or at the end of the config for a new
+// top-level one. Lines already there are left as they are, and "no" lines
+// remove the line they negate, with the ones under it and its "!".
+func mergeCLI(running, cli string) string {
+	lines := diff.SplitLines(running)
+	root, end := parseCLI(lines)
+	// The root takes new top-level lines before "end", after the
+	// closing "!" of the last block.
+	root.last = end - 1
+	change, _ := parseCLI(diff.SplitLines(cli))
+
+	insert := make(map[int][]string)
+	removed := make(map[int]bool)
+	var merge func(parent *cliNode, depth int, cs []*cliNode)
+	merge = func(parent *cliNode, depth int, cs []*cliNode) {
+		for _, c := range cs {
+			if strings.HasPrefix(c.text, "no ") {
+				if old := parent.child(strings.TrimPrefix(c.text, "no ")); old != nil {
+					for i := old.first; i <= old.last; i++ {
+						removed[i] = true
+					}
+					// So does the "!" that closes it.
+					if next := old.last + 1; next < len(lines) && lines[next] == strings.Repeat(" ", old.indent)+"!" {
+						removed[next] = true
+					}
+					continue
+				}
+			}
+			if old := parent.child(c.text); old != nil {
+				merge(old, depth+1, c.children)
+				continue
+			}
+			insert[parent.last] = append(insert[parent.last], render(c, depth)...)
+		}
+	}
+	merge(root, 0, change.children)
+
+	out := insert[-1]
+	for i, l := range lines {
+		if !removed[i] {
+			out = append(out, l)
+		}
+		out = append(out, insert[i]...)
+	}
+	return strings.Join(out, "\n") + "\n"
+}
+
+// render prints a new node and its children, indented one space a level,
+// with the "!" that closes a top-level line or a block.
+func render(n *cliNode, depth int) []string {
+	indent := strings.Repeat(" ", depth)
+	out := []string{indent + n.text}
+	for _, c := range n.children {
+		out = append(out, render(c, depth+1)...)
+	}
+	if depth == 0 || len(n.children) > 0 {
+		out = append(out, indent+"!")
+	}
+	return out
+}
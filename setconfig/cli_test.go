@@ -0,0 +1,142 @@
+package main
+
+import (
+	"testing"
+)
+
+const running = `hostname mock
+!
+interface Loopback0
+ description lo0
+ ipv4 address 10.0.0.1 255.255.255.255
+!
+router static
+ address-family ipv4 unicast
+  0.0.0.0/0 10.0.0.254
+ !
+!
+end
+`
+
+func TestMergeCLI(t *testing.T) {
+	tests := []struct {
+		name    string
+		running string
+		cli     string
+		want    string
+	}{
+		{
+			name:    "new top-level",
+			running: running,
+			cli:     "interface Loopback9\n description e2e",
+			want: `hostname mock
+!
+interface Loopback0
+ description lo0
+ ipv4 address 10.0.0.1 255.255.255.255
+!
+router static
+ address-family ipv4 unicast
+  0.0.0.0/0 10.0.0.254
+ !
+!
+interface Loopback9
+ description e2e
+!
+end
+`,
+		},
+		{
+			name:    "under an existing line",
+			running: running,
+			cli:     "interface Loopback0\n shutdown",
+			want: `hostname mock
+!
+interface Loopback0
+ description lo0
+ ipv4 address 10.0.0.1 255.255.255.255
+ shutdown
+!
+router static
+ address-family ipv4 unicast
+  0.0.0.0/0 10.0.0.254
+ !
+!
+end
+`,
+		},
+		{
+			name:    "nested",
+			running: running,
+			cli:     "router static\n address-family ipv4 unicast\n  10.0.0.0/8 10.0.0.253",
+			want: `hostname mock
+!
+interface Loopback0
+ description lo0
+ ipv4 address 10.0.0.1 255.255.255.255
+!
+router static
+ address-family ipv4 unicast
+  0.0.0.0/0 10.0.0.254
+  10.0.0.0/8 10.0.0.253
+ !
+!
+end
+`,
+		},
+		{
+			name:    "already there",
+			running: running,
+			cli:     "interface Loopback0\n description lo0",
+			want:    running,
+		},
+		{
+			name:    "no block",
+			running: running,
+			cli:     "no interface Loopback0",
+			want: `hostname mock
+!
+router static
+ address-family ipv4 unicast
+  0.0.0.0/0 10.0.0.254
+ !
+!
+end
+`,
+		},
+		{
+			name:    "no line",
+			running: running,
+			cli:     "interface Loopback0\n no description lo0",
+			want: `hostname mock
+!
+interface Loopback0
+ ipv4 address 10.0.0.1 255.255.255.255
+!
+router static
+ address-family ipv4 unicast
+  0.0.0.0/0 10.0.0.254
+ !
+!
+end
+`,
+		},
+		{
+			name:    "empty running config",
+			running: "",
+			cli:     "hostname r1",
+			want:    "hostname r1\n!\n",
+		},
+		{
+			name:    "without end",
+			running: "hostname mock\n",
+			cli:     "interface Loopback9 description e2e",
+			want:    "hostname mock\ninterface Loopback9 description e2e\n!\n",
+		},
+	}
+	for _, tt := range tests {
+		if got := mergeCLI(tt.running, tt.cli); got != tt.want {
+			t.Errorf("%s: mergeCLI() =\n%s\nwant\n%s", tt.name, got, tt.want)
+		}
+	}
+}
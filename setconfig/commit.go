@@ -0,0 +1,425 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/nleiva/clus2019/backup"
+	"github.com/nleiva/clus2019/diff"
+	"github.com/nleiva/clus2019/inventory"
+	"github.com/nleiva/clus2019/yang"
+	xr "github.com/nleiva/xrgrpc"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc"
+)
+
+// workflow runs the candidate config subcommands against a single device.
+type workflow struct {
+	dev inventory.Device
+	jnl *journal
+}
+
+// session is a connection to the device.
+type session struct {
+	host string
+	conn *grpc.ClientConn
+	ctx  context.Context
+}
+
+func (w *workflow) connect() (*session, error) {
+	router, err := w.dev.Router(xr.WithTimeout(30))
+	if err != nil {
+		return nil, err
+	}
+	conn, ctx, err := xr.Connect(*router)
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not setup a client connection to %s", router.Host)
+	}
+	return &session{host: router.Host, conn: conn, ctx: ctx}, nil
+}
+
+func (s *session) close() { s.conn.Close() }
+
+// running returns the running config as CLI text.
+func (s *session) running(id int64) (string, error) {
+	out, err := xr.ShowCmdTextOutput(s.ctx, s.conn, "show running-config", id)
+	if err != nil {
+		return "", errors.Wrapf(err, "could not get the running config from %s", s.host)
+	}
+	return backup.CleanConfig(out), nil
+}
+
+// apply sends a staged change to the device, which commits it right away:
+// there's no candidate config left to commit, with a label or not.
+func (s *session) apply(c change, id int64) error {
+	var err error
+	switch {
+	case c.CLI != "":
+		err = xr.CLIConfig(s.ctx, s.conn, c.CLI, id)
+	case c.Op == "replace":
+		_, err = xr.ReplaceConfig(s.ctx, s.conn, c.YANG, id)
+	default:
+		_, err = xr.MergeConfig(s.ctx, s.conn, c.YANG, id)
+	}
+	return errors.Wrapf(err, "failed to config %s", s.host)
+}
+
+// restore replaces the whole running config with cfg.
+func (s *session) restore(cfg string, id int64) error {
+	err := xr.CommitReplace(s.ctx, s.conn, cfg, "", id)
+	return errors.Wrapf(err, "failed to restore the config on %s", s.host)
+}
+
+func printDiff(before, after string) {
+	if !diff.Unified(os.Stdout, diff.SplitLines(before), diff.SplitLines(after), 3) {
+		fmt.Println("no changes")
+	}
+}
+
+func prettyprint(b []byte) ([]byte, error) {
+	var out bytes.Buffer
+	err := json.Indent(&out, b, "", "  ")
+	return out.Bytes(), err
+}
+
+// stage saves a change and shows what it will do.
+func (w *workflow) stage(args []string) error {
+	fs := flag.NewFlagSet("stage", flag.ExitOnError)
+	cli := fs.String("cli", "", "CLI config to stage")
+	ypath := fs.String("ypath", "", "YANG config file to stage")
+	op := fs.String("op", "merge", "YANG operation: 'merge' or 'replace'")
+	var yf yang.Flags
+	yf.AddFlags(fs)
+	fs.Parse(args)
+
+	c := change{CLI: *cli, Op: *op, Time: time.Now()}
+	switch {
+	case (*cli == "") == (*ypath == ""):
+		return errors.New("stage needs one of -cli or -ypath")
+	case *op != "merge" && *op != "replace":
+		return errors.Errorf("operation '%v' not supported", *op)
+	case *ypath != "":
+		js, err := ioutil.ReadFile(*ypath)
+		if err != nil {
+			return errors.Wrapf(err, "could not read file %s", *ypath)
+		}
+		schema, err := yf.Load(log.Printf)
+		if err != nil {
+			return errors.Wrap(err, "could not load the YANG modules")
+		}
+		yop := yang.Merge
+		if *op == "replace" {
+			yop = yang.Replace
+		}
+		if err = schema.Check(js, yop); err != nil {
+			return errors.Wrapf(err, "%s doesn't match the YANG modules", *ypath)
+		}
+		c.YANG = string(js)
+	}
+
+	fmt.Printf("\nchange staged for %s\n\n", w.dev.Name)
+	var err error
+	switch {
+	case c.CLI != "":
+		err = w.previewCLI(c.CLI)
+	case c.Op == "merge":
+		err = w.previewMerge(c.YANG)
+	default:
+		err = w.previewReplace(c.YANG)
+	}
+	if err != nil {
+		return err
+	}
+	return w.jnl.stage(c)
+}
+
+// previewCLI shows the running config with the CLI lines added, as the
+// router would show them, against the running config.
+func (w *workflow) previewCLI(cli string) error {
+	// ID for the transaction.
+	var id int64 = 1
+
+	s, err := w.connect()
+	if err != nil {
+		return err
+	}
+	defer s.close()
+
+	before, err := s.running(id)
+	if err != nil {
+		return err
+	}
+	printDiff(before, mergeCLI(before, cli))
+	return nil
+}
+
+// previewMerge shows the leaves a merge adds or changes in the running
+// config. A merge leaves alone what the file doesn't mention, so nothing
+// is removed.
+func (w *workflow) previewMerge(js string) error {
+	out, err := w.runningYANG(js)
+	if err != nil {
+		return err
+	}
+	cs, err := diff.JSON([]byte(out), []byte(js), diff.Options{})
+	if err != nil {
+		return err
+	}
+	if !diff.Print(os.Stdout, cs) {
+		fmt.Println("no changes")
+	}
+	return nil
+}
+
+// previewReplace shows the diff of the subtrees in the file against their
+// running config, which is exact, as a replace overwrites them.
+func (w *workflow) previewReplace(js string) error {
+	out, err := w.runningYANG(js)
+	if err != nil {
+		return err
+	}
+	// GetConfig wraps the subtrees in a "data" object, and returns
+	// nothing when none of them are configured.
+	var running struct {
+		Data json.RawMessage `json:"data"`
+	}
+	if strings.TrimSpace(out) == "" {
+		out = "{}"
+	}
+	if err = json.Unmarshal([]byte(out), &running); err != nil {
+		return errors.Wrap(err, "could not parse the running config")
+	}
+	if running.Data == nil {
+		running.Data = json.RawMessage("{}")
+	}
+	before, err := prettyprint(running.Data)
+	if err != nil {
+		return errors.Wrap(err, "could not pretty-print the running config")
+	}
+	after, err := prettyprint([]byte(js))
+	if err != nil {
+		return errors.Wrap(err, "could not pretty-print the YANG config")
+	}
+	printDiff(string(before), string(after))
+	return nil
+}
+
+// runningYANG gets the running config of the subtrees in js.
+func (w *workflow) runningYANG(js string) (string, error) {
+	// ID for the transaction.
+	var id int64 = 1
+
+	paths, err := diff.Paths([]byte(js))
+	if err != nil {
+		return "", err
+	}
+	s, err := w.connect()
+	if err != nil {
+		return "", err
+	}
+	defer s.close()
+
+	out, err := xr.GetConfig(s.ctx, s.conn, paths, id)
+	return out, errors.Wrapf(err, "could not get the config from %s", s.host)
+}
+
+// commit applies the staged change and records it in the journal with its
+// label and comment; the router doesn't get them. With -confirmed, the change is rolled back unless it's
+// confirmed before the timer expires. The timer runs in this process, not
+// on the router: if the process dies, or the change cuts it off from the
+// router, the change stays.
+func (w *workflow) commit(args []string) error {
+	fs := flag.NewFlagSet("commit", flag.ExitOnError)
+	label := fs.String("label", "", "Commit label, kept in the journal")
+	comment := fs.String("comment", "", "Commit comment, kept in the journal")
+	confirmed := fs.Int("confirmed", 0, "Seconds to wait for a confirmation before rolling back, while this process runs; 0 disables it")
+	fs.Parse(args)
+
+	// ID for the transaction.
+	var id int64 = 1
+
+	c, err := w.jnl.staged()
+	if err != nil {
+		return err
+	}
+	s, err := w.connect()
+	if err != nil {
+		return err
+	}
+	defer s.close()
+
+	before, err := s.running(id)
+	if err != nil {
+		return err
+	}
+	if err = s.apply(c, id); err != nil {
+		return err
+	}
+	e := entry{Time: time.Now(), Label: *label, Comment: *comment, Status: statusCommitted, Change: c}
+	if *confirmed > 0 {
+		e.Status = statusPending
+	}
+	e, err = w.jnl.add(e, before)
+	if err != nil {
+		return err
+	}
+	if err = w.jnl.discard(); err != nil {
+		return err
+	}
+
+	after, err := s.running(id)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("\ncommit %d applied to %s\n\n", e.ID, s.host)
+	printDiff(before, after)
+
+	if *confirmed == 0 {
+		return nil
+	}
+	if w.waitConfirm(time.Duration(*confirmed) * time.Second) {
+		e.Status = statusConfirmed
+		fmt.Printf("\ncommit %d confirmed\n\n", e.ID)
+		return w.jnl.update(e)
+	}
+
+	// The session deadline may have passed while waiting, so use a new one.
+	fmt.Printf("\ncommit %d not confirmed, rolling back\n", e.ID)
+	r, err := w.connect()
+	if err != nil {
+		return err
+	}
+	defer r.close()
+	if err = r.restore(before, id); err != nil {
+		return err
+	}
+	e.Status = statusRolledBack
+	fmt.Printf("\ncommit %d rolled back on %s\n\n", e.ID, r.host)
+	return w.jnl.update(e)
+}
+
+// waitConfirm waits for "confirm" on stdin or a 'setconfig confirm' from
+// another shell.
+func (w *workflow) waitConfirm(d time.Duration) bool {
+	os.Remove(w.jnl.confirmFile())
+	fmt.Printf("\ntype 'confirm' or run 'setconfig -device %s confirm' within %v to keep the change\n", w.dev.Name, d)
+
+	typed := make(chan bool, 1)
+	go func() {
+		sc := bufio.NewScanner(os.Stdin)
+		for sc.Scan() {
+			if strings.TrimSpace(sc.Text()) == "confirm" {
+				typed <- true
+				return
+			}
+		}
+	}()
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	tick := time.NewTicker(500 * time.Millisecond)
+	defer tick.Stop()
+	for {
+		select {
+		case <-typed:
+			return true
+		case <-tick.C:
+			if _, err := os.Stat(w.jnl.confirmFile()); err == nil {
+				os.Remove(w.jnl.confirmFile())
+				return true
+			}
+		case <-timer.C:
+			return false
+		}
+	}
+}
+
+// confirm keeps a change committed with -confirmed in another shell.
+func (w *workflow) confirm(args []string) error {
+	err := ioutil.WriteFile(w.jnl.confirmFile(), []byte(time.Now().Format(time.RFC3339)), 0644)
+	if err != nil {
+		return errors.Wrap(err, "could not confirm the commit")
+	}
+	fmt.Printf("\nconfirmation sent for %s\n\n", w.dev.Name)
+	return nil
+}
+
+// rollback restores the running config as it was before commit -to.
+func (w *workflow) rollback(args []string) error {
+	fs := flag.NewFlagSet("rollback", flag.ExitOnError)
+	to := fs.Int("to", 0, "Commit ID to roll back")
+	fs.Parse(args)
+
+	// ID for the transaction.
+	var id int64 = 1
+
+	if *to == 0 {
+		return errors.New("rollback needs a commit ID in -to")
+	}
+	target, err := w.jnl.before(*to)
+	if err != nil {
+		return err
+	}
+	s, err := w.connect()
+	if err != nil {
+		return err
+	}
+	defer s.close()
+
+	before, err := s.running(id)
+	if err != nil {
+		return err
+	}
+	if err = s.restore(target, id); err != nil {
+		return err
+	}
+	e := entry{
+		Time:    time.Now(),
+		Comment: "rollback of commit " + strconv.Itoa(*to),
+		Status:  statusCommitted,
+	}
+	if e, err = w.jnl.add(e, before); err != nil {
+		return err
+	}
+	if old, err := w.jnl.entry(*to); err == nil {
+		old.Status = statusRolledBack
+		w.jnl.update(old)
+	}
+	fmt.Printf("\ncommit %d rolled back on %s as commit %d\n\n", *to, s.host, e.ID)
+	printDiff(before, target)
+	return nil
+}
+
+// discard drops the staged change.
+func (w *workflow) discard(args []string) error {
+	return w.jnl.discard()
+}
+
+// history lists the commits in the journal.
+func (w *workflow) history(args []string) error {
+	ids, err := w.jnl.ids()
+	if err != nil {
+		return err
+	}
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tTIME\tSTATUS\tLABEL\tCOMMENT")
+	for _, id := range ids {
+		e, err := w.jnl.entry(id)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%s\t%s\n", e.ID, e.Time.Format(time.RFC3339), e.Status, e.Label, e.Comment)
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Commit status values kept in the journal.
+const (
+	statusCommitted  = "committed"
+	statusPending    = "pending confirmation"
+	statusConfirmed  = "confirmed"
+	statusRolledBack = "rolled back"
+)
+
+// change is a config change waiting to be committed.
+type change struct {
+	CLI  string `json:"cli,omitempty"`
+	YANG string `json:"yang,omitempty"`
+	// Op is "merge" or "replace" for YANG changes.
+	Op   string    `json:"op,omitempty"`
+	Time time.Time `json:"time"`
+}
+
+// entry records a commit. The running config before the commit is
+// stored next to it, so the commit can be rolled back.
+type entry struct {
+	ID      int       `json:"id"`
+	Time    time.Time `json:"time"`
+	Label   string    `json:"label,omitempty"`
+	Comment string    `json:"comment,omitempty"`
+	Status  string    `json:"status"`
+	Change  change    `json:"change"`
+}
+
+// journal keeps the staged change and commit history of a device on disk,
+// one directory per device.
+type journal struct {
+	dir string
+}
+
+func openJournal(root, device string) (*journal, error) {
+	dir := filepath.Join(root, device)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, errors.Wrapf(err, "could not create journal directory %s", dir)
+	}
+	return &journal{dir: dir}, nil
+}
+
+func (j *journal) stagedFile() string  { return filepath.Join(j.dir, "staged.json") }
+func (j *journal) confirmFile() string { return filepath.Join(j.dir, "confirm") }
+
+func (j *journal) entryFile(id int) string {
+	return filepath.Join(j.dir, strconv.Itoa(id)+".json")
+}
+
+func (j *journal) configFile(id int) string {
+	return filepath.Join(j.dir, strconv.Itoa(id)+".cfg")
+}
+
+func writeJSON(file string, v interface{}) error {
+	b, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "could not marshall into JSON")
+	}
+	return errors.Wrapf(ioutil.WriteFile(file, b, 0644), "could not write file %s", file)
+}
+
+func readJSON(file string, v interface{}) error {
+	b, err := ioutil.ReadFile(file)
+	if err != nil {
+		return errors.Wrapf(err, "could not read file %s", file)
+	}
+	return errors.Wrapf(json.Unmarshal(b, v), "could not parse %s", file)
+}
+
+func (j *journal) stage(c change) error {
+	return writeJSON(j.stagedFile(), c)
+}
+
+func (j *journal) staged() (change, error) {
+	var c change
+	if _, err := os.Stat(j.stagedFile()); os.IsNotExist(err) {
+		return c, errors.New("there is no staged change, run 'stage' first")
+	}
+	err := readJSON(j.stagedFile(), &c)
+	return c, err
+}
+
+func (j *journal) discard() error {
+	err := os.Remove(j.stagedFile())
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+// ids returns the commit IDs in the journal, oldest first.
+func (j *journal) ids() ([]int, error) {
+	fs, err := ioutil.ReadDir(j.dir)
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not read journal directory %s", j.dir)
+	}
+	var ids []int
+	for _, f := range fs {
+		name := f.Name()
+		if !strings.HasSuffix(name, ".json") {
+			continue
+		}
+		id, err := strconv.Atoi(strings.TrimSuffix(name, ".json"))
+		if err != nil {
+			continue
+		}
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	return ids, nil
+}
+
+// add stores a new commit entry with the config it replaced and returns it
+// with its assigned ID.
+func (j *journal) add(e entry, before string) (entry, error) {
+	ids, err := j.ids()
+	if err != nil {
+		return e, err
+	}
+	e.ID = 1
+	if len(ids) > 0 {
+		e.ID = ids[len(ids)-1] + 1
+	}
+	if err = ioutil.WriteFile(j.configFile(e.ID), []byte(before), 0644); err != nil {
+		return e, errors.Wrapf(err, "could not write file %s", j.configFile(e.ID))
+	}
+	return e, writeJSON(j.entryFile(e.ID), e)
+}
+
+func (j *journal) update(e entry) error {
+	return writeJSON(j.entryFile(e.ID), e)
+}
+
+func (j *journal) entry(id int) (entry, error) {
+	var e entry
+	err := readJSON(j.entryFile(id), &e)
+	return e, err
+}
+
+// before returns the running config saved before commit id was applied.
+func (j *journal) before(id int) (string, error) {
+	b, err := ioutil.ReadFile(j.configFile(id))
+	if err != nil {
+		return "", errors.Wrapf(err, "could not read the config saved for commit %d", id)
+	}
+	return string(b), nil
+}
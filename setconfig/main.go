@@ -6,6 +6,7 @@ import (
 	"log"
 	"time"
 
+	"github.com/nleiva/clus2019/inventory"
 	xr "github.com/nleiva/xrgrpc"
 )
 
@@ -20,21 +21,62 @@ func main() {
 
 	// CLI config to apply; defaults to "interface lo1 desc test"
 	cli := flag.String("cli", "interface lo1 desc test", "Config to apply")
+	// Commit journal for the candidate config workflow
+	jdir := flag.String("journal", "../journal", "Directory to keep staged changes and commits")
+	// Target devices; defaults to "router2" from "inventory.json"
+	var tg inventory.Target
+	tg.AddFlags(flag.CommandLine)
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: setconfig [flags] [stage|commit|confirm|rollback|discard|log] [command flags]\n")
+		flag.PrintDefaults()
+	}
 	flag.Parse()
 
+	// Without a subcommand, apply and commit right away.
+	if flag.NArg() == 0 {
+		devices, err := tg.Devices()
+		if err != nil {
+			log.Fatalf("could not select a device, %v", err)
+		}
+		for _, d := range devices {
+			setConfig(d, *cli)
+		}
+		return
+	}
+
+	d, err := tg.One()
+	if err != nil {
+		log.Fatalf("could not select a device, %v", err)
+	}
+	jnl, err := openJournal(*jdir, d.Name)
+	if err != nil {
+		log.Fatalf("could not open the journal, %v", err)
+	}
+	w := &workflow{dev: d, jnl: jnl}
+	cmds := map[string]func([]string) error{
+		"stage":    w.stage,
+		"commit":   w.commit,
+		"confirm":  w.confirm,
+		"rollback": w.rollback,
+		"discard":  w.discard,
+		"log":      w.history,
+	}
+	run, ok := cmds[flag.Arg(0)]
+	if !ok {
+		flag.Usage()
+		log.Fatalf("unknown command %q", flag.Arg(0))
+	}
+	if err = run(flag.Args()[1:]); err != nil {
+		log.Fatalf("%s failed on %s, %v", flag.Arg(0), d.Name, err)
+	}
+}
+
+func setConfig(d inventory.Device, cli string) {
 	// ID for the transaction.
 	var id int64 = 1
 
-	// Manually specify target parameters.
-	router, err := xr.BuildRouter(
-		xr.WithUsername("cisco"),
-		xr.WithPassword("cisco"),
-		//xr.WithHost("[2001:420:2cff:1204::5502:1]:57344"),
-		//xr.WithCert("../input/certificate/router1.pem"),
-		xr.WithHost("[2001:420:2cff:1204::5502:2]:57344"),
-		xr.WithCert("../input/certificate/router2.pem"),
-		xr.WithTimeout(5),
-	)
+	// Target parameters come from the inventory.
+	router, err := d.Router(xr.WithTimeout(5))
 	if err != nil {
 		log.Fatalf("could not build a router, %v", err)
 	}
@@ -47,7 +89,7 @@ func main() {
 	defer conn.Close()
 
 	// Apply 'cli' config to target
-	err = xr.CLIConfig(ctx, conn, *cli, id)
+	err = xr.CLIConfig(ctx, conn, cli, id)
 	if err != nil {
 		log.Fatalf("failed to config %s, %v", router.Host, err)
 	}
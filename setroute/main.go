@@ -5,6 +5,7 @@ import (
 	"log"
 	"time"
 
+	"github.com/nleiva/clus2019/inventory"
 	xr "github.com/nleiva/xrgrpc"
 )
 
@@ -21,21 +22,26 @@ func main() {
 	pfx := flag.String("pfx", "2001:db8::/32", "IPv6 prefix to setup")
 	// IPv6 next-hop to setup; defaults to "2001:db8:cafe::1"
 	nh := flag.String("nh", "2001:db8:cafe::1", "IPv6 next-hop to setup")
+	// Target devices; defaults to "router2" from "inventory.json"
+	var tg inventory.Target
+	tg.AddFlags(flag.CommandLine)
 	flag.Parse()
 
+	devices, err := tg.Devices()
+	if err != nil {
+		log.Fatalf("could not select a device, %v", err)
+	}
+	for _, d := range devices {
+		setRoute(d, *pfx, *nh)
+	}
+}
+
+func setRoute(d inventory.Device, pfx, nh string) {
 	// Admin Distance
 	var admdis uint32 = 2
 
-	// Manually specify target parameters.
-	router, err := xr.BuildRouter(
-		xr.WithUsername("cisco"),
-		xr.WithPassword("cisco"),
-		//xr.WithHost("[2001:420:2cff:1204::5502:1]:57344"),
-		//xr.WithCert("../input/certificate/router1.pem"),
-		xr.WithHost("[2001:420:2cff:1204::5502:2]:57344"),
-		xr.WithCert("../input/certificate/router2.pem"),
-		xr.WithTimeout(5),
-	)
+	// Target parameters come from the inventory.
+	router, err := d.Router(xr.WithTimeout(5))
 	if err != nil {
 		log.Fatalf("could not build a router, %v", err)
 	}
@@ -64,7 +70,7 @@ func main() {
 		log.Fatalf("Failed to send VRF Operation EOF to %s, %v", router.Host, err)
 	}
 	// Route Add Operation (= 1),
-	err = xr.SetRoute(conn, 1, *pfx, admdis, *nh)
+	err = xr.SetRoute(conn, 1, pfx, admdis, nh)
 	if err != nil {
 		log.Fatalf("Failed to set Route on %s, %v", router.Host, err)
 	}
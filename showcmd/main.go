@@ -4,8 +4,11 @@ import (
 	"flag"
 	"fmt"
 	"log"
+	"os"
 	"time"
 
+	"github.com/nleiva/clus2019/inventory"
+	"github.com/nleiva/clus2019/runner"
 	xr "github.com/nleiva/xrgrpc"
 )
 
@@ -15,42 +18,48 @@ func timeTrack(start time.Time) {
 }
 
 func main() {
-	// To time this process
-	defer timeTrack(time.Now())
-
 	// CLI to issue; defaults to "show grpc status"
 	cli := flag.String("cli", "show grpc status", "Command to execute")
+	// Number of devices to query in parallel; defaults to 10
+	workers := flag.Int("workers", 10, "Devices to query concurrently")
+	// Per-device timeout in seconds; defaults to the inventory value or 5
+	timeout := flag.Int("timeout", 0, "Per-device timeout in seconds")
+	// Target devices; defaults to "router2" from "inventory.json"
+	var tg inventory.Target
+	tg.AddFlags(flag.CommandLine)
 	flag.Parse()
 
-	// ID for the transaction.
-	var id int64 = 1
-	var output string
-
-	// Manually specify target parameters.
-	router, err := xr.BuildRouter(
-		xr.WithUsername("cisco"),
-		xr.WithPassword("cisco"),
-		//xr.WithHost("[2001:420:2cff:1204::5502:1]:57344"),
-		//xr.WithCert("../input/certificate/router1.pem"),
-		xr.WithHost("[2001:420:2cff:1204::5502:2]:57344"),
-		xr.WithCert("../input/certificate/router2.pem"),
-		xr.WithTimeout(5),
-	)
-	if err != nil {
-		log.Fatalf("could not build a router, %v", err)
+	if code := run(tg, *cli, *workers, *timeout); code != 0 {
+		os.Exit(code)
 	}
+}
+
+func run(tg inventory.Target, cli string, workers, timeout int) int {
+	// To time this process
+	defer timeTrack(time.Now())
 
-	// Setup a connection to the target.
-	conn, ctx, err := xr.Connect(*router)
+	devices, err := tg.Devices()
 	if err != nil {
-		log.Fatalf("could not setup a client connection to %s, %v", router.Host, err)
+		log.Fatalf("could not select a device, %v", err)
 	}
-	defer conn.Close()
 
-	// Return show command output based on encoding selected
-	output, err = xr.ShowCmdTextOutput(ctx, conn, *cli, id)
-	if err != nil {
-		log.Fatalf("couldn't get the cli output: %v\n", err)
+	r := runner.Runner{
+		Workers:  workers,
+		Timeout:  timeout,
+		Defaults: []xr.RouterOption{xr.WithTimeout(5)},
+	}
+	results := r.Run(devices, runner.ShowCmd(cli))
+	for _, res := range results {
+		if res.Err != nil {
+			continue
+		}
+		fmt.Printf("\noutput from %s\n %s\n", res.Host, res.Output)
+	}
+	fmt.Println()
+	runner.Report(os.Stdout, results)
+
+	if runner.Failed(results) > 0 {
+		return 1
 	}
-	fmt.Printf("\noutput from %s\n %s\n", router.Host, output)
+	return 0
 }
@@ -0,0 +1,120 @@
+package sink
+
+import (
+	"bufio"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// NATS publishes messages to a NATS server, with the topic as the subject,
+// speaking the text protocol of the server itself. Each message is
+// followed by a PING, and Publish waits for the PONG, so the server has
+// every message Publish returned nil for.
+type NATS struct {
+	// Addr is the host:port of the server.
+	Addr string
+	// Timeout bounds connecting and each exchange with the server.
+	Timeout time.Duration
+
+	mu   sync.Mutex
+	conn net.Conn
+	r    *bufio.Reader
+}
+
+// NewNATS returns a NATS for the server at addr. It connects on the first
+// Publish, and again after a failure, so a server that's down for a while
+// doesn't stop anything.
+func NewNATS(addr string) *NATS {
+	return &NATS{Addr: addr, Timeout: 5 * time.Second}
+}
+
+// Publish implements Sink. The topic can't be empty or have whitespace,
+// which would break up the PUB line.
+func (n *NATS) Publish(topic string, msg []byte) error {
+	if topic == "" || strings.ContainsAny(topic, " \t\r\n") {
+		return errors.Errorf("invalid NATS subject %q", topic)
+	}
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.conn == nil {
+		if err := n.connect(); err != nil {
+			return err
+		}
+	}
+	err := n.publish(topic, msg)
+	if err != nil {
+		n.conn.Close()
+		n.conn = nil
+		return errors.Wrapf(err, "could not publish to %s", n.Addr)
+	}
+	return nil
+}
+
+// connect reads the INFO of the server, and says who we are.
+func (n *NATS) connect() error {
+	conn, err := net.DialTimeout("tcp", n.Addr, n.Timeout)
+	if err != nil {
+		return errors.Wrapf(err, "could not connect to %s", n.Addr)
+	}
+	n.conn, n.r = conn, bufio.NewReader(conn)
+	conn.SetDeadline(time.Now().Add(n.Timeout))
+	line, err := n.r.ReadString('\n')
+	if err == nil && !strings.HasPrefix(line, "INFO ") {
+		err = errors.Errorf("expected INFO, got %q", strings.TrimSpace(line))
+	}
+	if err == nil {
+		_, err = conn.Write([]byte(`CONNECT {"verbose":false,"pedantic":false,"name":"clus2019"}` + "\r\n"))
+	}
+	if err != nil {
+		conn.Close()
+		n.conn = nil
+		return errors.Wrapf(err, "could not connect to %s", n.Addr)
+	}
+	return nil
+}
+
+func (n *NATS) publish(topic string, msg []byte) error {
+	n.conn.SetDeadline(time.Now().Add(n.Timeout))
+	var b []byte
+	b = append(b, "PUB "+topic+" "+strconv.Itoa(len(msg))+"\r\n"...)
+	b = append(b, msg...)
+	b = append(b, "\r\nPING\r\n"...)
+	if _, err := n.conn.Write(b); err != nil {
+		return err
+	}
+	for {
+		line, err := n.r.ReadString('\n')
+		if err != nil {
+			return err
+		}
+		line = strings.TrimSpace(line)
+		switch {
+		case line == "PONG":
+			return nil
+		case line == "PING":
+			if _, err = n.conn.Write([]byte("PONG\r\n")); err != nil {
+				return err
+			}
+		case strings.HasPrefix(line, "-ERR"):
+			return errors.New(strings.TrimSpace(strings.TrimPrefix(line, "-ERR")))
+		}
+		// +OK and INFO updates need nothing.
+	}
+}
+
+// Close closes the connection to the server.
+func (n *NATS) Close() error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.conn == nil {
+		return nil
+	}
+	err := n.conn.Close()
+	n.conn = nil
+	return errors.Wrap(err, "could not close the connection")
+}
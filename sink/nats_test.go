@@ -0,0 +1,272 @@
+package sink
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// broker is a NATS server stand-in. It greets with info, keeps what's
+// published and answers the PING after each PUB with what reply returns
+// for it, counted from 1: "" for a PONG, "close" to hang up.
+type broker struct {
+	info  string
+	reply func(n int) string
+
+	ln      net.Listener
+	mu      sync.Mutex
+	open    []net.Conn
+	conns   int
+	connect []string
+	pubs    []string
+	pongs   int
+}
+
+// start starts b on addr, any free port if empty, for the caller to stop.
+func start(t *testing.T, b *broker, addr string) *broker {
+	t.Helper()
+	if addr == "" {
+		addr = "127.0.0.1:0"
+	}
+	if b.info == "" {
+		b.info = `INFO {"server_id":"test","version":"1.4.1","max_payload":1048576}`
+	}
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b.ln = ln
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			b.mu.Lock()
+			b.conns++
+			b.open = append(b.open, conn)
+			b.mu.Unlock()
+			go b.serve(conn)
+		}
+	}()
+	return b
+}
+
+// stop stops the server and drops its connections.
+func (b *broker) stop() {
+	b.ln.Close()
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, c := range b.open {
+		c.Close()
+	}
+}
+
+func (b *broker) serve(conn net.Conn) {
+	defer conn.Close()
+	fmt.Fprintf(conn, "%s\r\n", b.info)
+	r := bufio.NewReader(conn)
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		f := strings.Fields(line)
+		switch {
+		case len(f) == 0:
+		case f[0] == "CONNECT":
+			b.mu.Lock()
+			b.connect = append(b.connect, strings.TrimSpace(strings.TrimPrefix(line, "CONNECT")))
+			b.mu.Unlock()
+		case f[0] == "PONG":
+			b.mu.Lock()
+			b.pongs++
+			b.mu.Unlock()
+		case f[0] == "PUB" && len(f) == 3:
+			size, err := strconv.Atoi(f[2])
+			if err != nil {
+				return
+			}
+			msg := make([]byte, size+2)
+			if _, err = io.ReadFull(r, msg); err != nil {
+				return
+			}
+			// The PING that follows.
+			if _, err = r.ReadString('\n'); err != nil {
+				return
+			}
+			b.mu.Lock()
+			b.pubs = append(b.pubs, f[1]+" "+string(msg[:size]))
+			n := len(b.pubs)
+			b.mu.Unlock()
+			reply := ""
+			if b.reply != nil {
+				reply = b.reply(n)
+			}
+			switch reply {
+			case "close":
+				return
+			case "":
+				reply = "PONG"
+			}
+			fmt.Fprintf(conn, "%s\r\n", reply)
+		default:
+			fmt.Fprintf(conn, "-ERR 'Unknown Protocol Operation'\r\n")
+		}
+	}
+}
+
+// published returns the "subject message" of each PUB so far, and the
+// number of connections.
+func (b *broker) published() ([]string, int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]string(nil), b.pubs...), b.conns
+}
+
+func testNATS(addr string) *NATS {
+	n := NewNATS(addr)
+	n.Timeout = 2 * time.Second
+	return n
+}
+
+func TestNATS(t *testing.T) {
+	// Servers send +OK and PINGs of their own too.
+	b := start(t, &broker{reply: func(n int) string {
+		if n == 2 {
+			return "+OK\r\nPING\r\nPONG"
+		}
+		return ""
+	}}, "")
+	defer b.stop()
+	n := testNATS(b.ln.Addr().String())
+	defer n.Close()
+
+	for _, m := range []string{`{"a":1}`, `{"a":2}`, "{\r\n}"} {
+		if err := n.Publish("telemetry.lldp", []byte(m)); err != nil {
+			t.Fatalf("Publish(%q) = %v", m, err)
+		}
+	}
+	got, conns := b.published()
+	want := []string{`telemetry.lldp {"a":1}`, `telemetry.lldp {"a":2}`, "telemetry.lldp {\r\n}"}
+	if !reflect.DeepEqual(got, want) || conns != 1 {
+		t.Errorf("published %q over %d connections, want %q over 1", got, conns, want)
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if len(b.connect) != 1 || !strings.Contains(b.connect[0], `"verbose":false`) {
+		t.Errorf("CONNECT %q, want one, not verbose", b.connect)
+	}
+	if b.pongs != 1 {
+		t.Errorf("the server's PING got %d PONGs, want 1", b.pongs)
+	}
+}
+
+func TestNATSErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		broker *broker
+		want   string
+	}{
+		{
+			name:   "not NATS",
+			broker: &broker{info: "220 smtp.example.com ESMTP"},
+			want:   `expected INFO, got "220 smtp.example.com ESMTP"`,
+		},
+		{
+			name:   "error",
+			broker: &broker{reply: func(int) string { return "-ERR 'Maximum Payload Violation'" }},
+			want:   "'Maximum Payload Violation'",
+		},
+		{
+			name:   "hang up",
+			broker: &broker{reply: func(int) string { return "close" }},
+			want:   "EOF",
+		},
+	}
+	for _, tt := range tests {
+		b := start(t, tt.broker, "")
+		n := testNATS(b.ln.Addr().String())
+		err := n.Publish("t", []byte("{}"))
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: Publish() = %v, want %s", tt.name, err, tt.want)
+		}
+		// The failed connection is dropped, and the next Publish makes a
+		// new one.
+		n.Publish("t", []byte("{}"))
+		if _, conns := b.published(); conns != 2 {
+			t.Errorf("%s: %d connections, want 2", tt.name, conns)
+		}
+		n.Close()
+		b.stop()
+	}
+}
+
+func TestNATSReconnect(t *testing.T) {
+	// The first connection goes away after a message.
+	b := start(t, &broker{reply: func(n int) string {
+		if n == 2 {
+			return "close"
+		}
+		return ""
+	}}, "")
+	addr := b.ln.Addr().String()
+	n := testNATS(addr)
+	defer n.Close()
+
+	tests := []struct {
+		msg string
+		ok  bool
+	}{
+		{msg: "1", ok: true},
+		{msg: "2", ok: false},
+		{msg: "3", ok: true},
+	}
+	for _, tt := range tests {
+		if err := n.Publish("t", []byte(tt.msg)); (err == nil) != tt.ok {
+			t.Errorf("Publish(%s) = %v, want ok %v", tt.msg, err, tt.ok)
+		}
+	}
+	got, conns := b.published()
+	if want := []string{"t 1", "t 2", "t 3"}; !reflect.DeepEqual(got, want) || conns != 2 {
+		t.Errorf("published %q over %d connections, want %q over 2", got, conns, want)
+	}
+
+	// While the server is down, Publish fails; once it's back, it works.
+	b.stop()
+	if err := n.Publish("t", []byte("4")); err == nil {
+		t.Error("Publish() to a server that's down succeeded")
+	}
+	b = start(t, &broker{}, addr)
+	defer b.stop()
+	if err := n.Publish("t", []byte("5")); err != nil {
+		t.Errorf("Publish() once the server is back = %v", err)
+	}
+	if got, _ := b.published(); !reflect.DeepEqual(got, []string{"t 5"}) {
+		t.Errorf("published %q once the server is back, want [t 5]", got)
+	}
+}
+
+func TestNATSSubject(t *testing.T) {
+	b := start(t, &broker{}, "")
+	defer b.stop()
+	n := testNATS(b.ln.Addr().String())
+	defer n.Close()
+
+	for _, topic := range []string{"", "a b", "a\tb", "a\r\nPUB b 2", "a\n"} {
+		err := n.Publish(topic, []byte("{}"))
+		if want := fmt.Sprintf("invalid NATS subject %q", topic); err == nil || err.Error() != want {
+			t.Errorf("Publish(%q) = %v, want %s", topic, err, want)
+		}
+	}
+	if got, conns := b.published(); len(got) != 0 || conns != 0 {
+		t.Errorf("published %q over %d connections, want nothing", got, conns)
+	}
+}
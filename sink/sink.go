@@ -0,0 +1,151 @@
+/*
+Package sink publishes decoded telemetry, as JSON, to a topic per encoding
+path: on stdout or a file, to an HTTP webhook, or to a NATS message bus. A
+Spool in front of a sink keeps what it can't take on disk until it's back.
+*/
+package sink
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Sink takes the messages of a telemetry session.
+type Sink interface {
+	// Publish sends msg, a JSON document, to topic. It returns once the
+	// sink has it, or with the reason it doesn't.
+	Publish(topic string, msg []byte) error
+	Close() error
+}
+
+// Open returns the sink for dest: "-" for stdout, an http:// or https://
+// URL for a webhook, a nats://host:port URL for a NATS server, or else a
+// file, which is appended to.
+func Open(dest string) (Sink, error) {
+	switch {
+	case dest == "-":
+		return NewWriter(os.Stdout), nil
+	case strings.HasPrefix(dest, "http://") || strings.HasPrefix(dest, "https://"):
+		return NewWebhook(dest), nil
+	case strings.HasPrefix(dest, "nats://"):
+		return NewNATS(strings.TrimPrefix(dest, "nats://")), nil
+	}
+	f, err := os.OpenFile(dest, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not open file %s", dest)
+	}
+	w := NewWriter(f)
+	w.c = f
+	return w, nil
+}
+
+// Topic names the topic of an encoding path, with prefix in front:
+// the elements of the path separated by dots, and any character other than
+// letters, digits, '-' and '_' replaced by '_', so it suits NATS subjects
+// and Kafka topics alike.
+//
+//	telemetry.Cisco-IOS-XR-ethernet-lldp-oper.lldp.nodes.node.neighbors.details.detail
+func Topic(prefix, path string) string {
+	var b strings.Builder
+	if prefix != "" {
+		b.WriteString(prefix)
+		b.WriteByte('.')
+	}
+	for _, c := range strings.Trim(path, "/") {
+		switch {
+		case c == ':' || c == '/':
+			c = '.'
+		case c == '-' || c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9':
+		default:
+			c = '_'
+		}
+		b.WriteRune(c)
+	}
+	return b.String()
+}
+
+// entry is a message with its topic, as a Writer writes them and a Spool
+// keeps them.
+type entry struct {
+	Topic   string          `json:"topic"`
+	Message json.RawMessage `json:"message"`
+}
+
+// Writer writes messages a line each, as {"topic":...,"message":...}.
+type Writer struct {
+	mu sync.Mutex
+	w  io.Writer
+	c  io.Closer
+}
+
+// NewWriter returns a Writer that writes to w.
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{w: w}
+}
+
+// Publish implements Sink.
+func (w *Writer) Publish(topic string, msg []byte) error {
+	b, err := json.Marshal(entry{Topic: topic, Message: msg})
+	if err != nil {
+		return errors.Wrap(err, "could not marshal the message")
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	_, err = w.w.Write(append(b, '\n'))
+	return errors.Wrap(err, "could not write the message")
+}
+
+// Close closes the file of a Writer from Open.
+func (w *Writer) Close() error {
+	if w.c == nil {
+		return nil
+	}
+	return errors.Wrap(w.c.Close(), "could not close the file")
+}
+
+// Webhook posts each message to a URL. "{topic}" in the URL stands for
+// the topic, which is in the X-Topic header as well.
+type Webhook struct {
+	URL    string
+	client *http.Client
+}
+
+// NewWebhook returns a Webhook that posts to url.
+func NewWebhook(url string) *Webhook {
+	return &Webhook{URL: url, client: &http.Client{Timeout: 10 * time.Second}}
+}
+
+// Publish implements Sink. Anything but a 2xx answer is a failure.
+func (h *Webhook) Publish(topic string, msg []byte) error {
+	url := strings.Replace(h.URL, "{topic}", topic, -1)
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(msg))
+	if err != nil {
+		return errors.Wrap(err, "could not build the request")
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Topic", topic)
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return errors.Wrapf(err, "could not post to %s", url)
+	}
+	defer resp.Body.Close()
+	body, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 512))
+	if resp.StatusCode/100 != 2 {
+		return errors.Errorf("could not post to %s: %s: %s", url, resp.Status, bytes.TrimSpace(body))
+	}
+	return nil
+}
+
+// Close implements Sink.
+func (h *Webhook) Close() error {
+	return nil
+}
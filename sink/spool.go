@@ -0,0 +1,211 @@
+package sink
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// spoolFile is the file of a Spool in its directory.
+const spoolFile = "pending.jsonl"
+
+// maxWait caps the time between attempts to reach a sink that's down.
+const maxWait = time.Minute
+
+// Spool delivers messages to a sink at least once. What the sink doesn't
+// take is appended to a file, a line per message as a Writer writes them,
+// and sent ahead of newer messages once the sink takes them again, in
+// order, from this run or an earlier one. A message may be sent twice if
+// the sink fails after taking it, but isn't lost.
+type Spool struct {
+	sink Sink
+	file string
+
+	mu      sync.Mutex
+	pending []entry
+	// next is when to try the sink again after a failure, and wait the
+	// pause before it.
+	next time.Time
+	wait time.Duration
+}
+
+// NewSpool returns a Spool for s that keeps messages in dir, picking up
+// the ones an earlier run left there.
+func NewSpool(dir string, s Sink) (*Spool, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, errors.Wrapf(err, "could not create the spool %s", dir)
+	}
+	sp := &Spool{sink: s, file: filepath.Join(dir, spoolFile)}
+	f, err := os.Open(sp.file)
+	if os.IsNotExist(err) {
+		return sp, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "could not open the spool")
+	}
+	defer f.Close()
+	sc := bufio.NewScanner(f)
+	sc.Buffer(nil, 64<<20)
+	for sc.Scan() {
+		var e entry
+		if err = json.Unmarshal(sc.Bytes(), &e); err != nil {
+			// A line cut short by a crash; the ones before it are fine.
+			break
+		}
+		sp.pending = append(sp.pending, e)
+	}
+	if err = sc.Err(); err != nil {
+		return nil, errors.Wrap(err, "could not read the spool")
+	}
+	return sp, nil
+}
+
+// Pending returns how many messages are waiting for the sink.
+func (sp *Spool) Pending() int {
+	sp.mu.Lock()
+	defer sp.mu.Unlock()
+	return len(sp.pending)
+}
+
+// Publish implements Sink. It returns nil once the message is with the
+// sink or on disk, and an error only if neither took it.
+func (sp *Spool) Publish(topic string, msg []byte) error {
+	sp.mu.Lock()
+	defer sp.mu.Unlock()
+	if time.Now().After(sp.next) && sp.drain() == nil {
+		err := sp.sink.Publish(topic, msg)
+		if err == nil {
+			return nil
+		}
+		sp.failed()
+	}
+	return sp.add(entry{Topic: topic, Message: msg})
+}
+
+// Close tries once more to send what's pending, whatever the time, and
+// closes the sink. What's left stays on disk for the next run.
+func (sp *Spool) Close() error {
+	sp.mu.Lock()
+	defer sp.mu.Unlock()
+	err := sp.drain()
+	if err != nil {
+		err = errors.Wrapf(err, "%d messages left in %s", len(sp.pending), sp.file)
+	}
+	if cerr := sp.sink.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// drain sends the pending messages. The ones sent are removed from the
+// file even if a later one fails, so they aren't sent again.
+func (sp *Spool) drain() error {
+	if len(sp.pending) == 0 {
+		return nil
+	}
+	sent := 0
+	var err error
+	for _, e := range sp.pending {
+		if err = sp.sink.Publish(e.Topic, e.Message); err != nil {
+			sp.failed()
+			break
+		}
+		sent++
+	}
+	if sent == 0 {
+		return err
+	}
+	sp.pending = sp.pending[sent:]
+	if werr := sp.rewrite(); err == nil {
+		err = werr
+	}
+	if err == nil {
+		sp.wait = 0
+	}
+	return err
+}
+
+// failed puts off the next attempt, twice as long as the last one up to
+// maxWait.
+func (sp *Spool) failed() {
+	switch {
+	case sp.wait == 0:
+		sp.wait = time.Second
+	case sp.wait < maxWait:
+		sp.wait *= 2
+	}
+	if sp.wait > maxWait {
+		sp.wait = maxWait
+	}
+	sp.next = time.Now().Add(sp.wait)
+}
+
+// add appends e to the file, and syncs it.
+func (sp *Spool) add(e entry) error {
+	b, err := json.Marshal(e)
+	if err != nil {
+		return errors.Wrap(err, "could not marshal the message")
+	}
+	f, err := os.OpenFile(sp.file, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return errors.Wrap(err, "could not open the spool")
+	}
+	_, err = f.Write(append(b, '\n'))
+	if err == nil {
+		err = f.Sync()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return errors.Wrap(err, "could not spool the message")
+	}
+	sp.pending = append(sp.pending, e)
+	return nil
+}
+
+// rewrite replaces the file with the pending messages, or removes it if
+// there are none.
+func (sp *Spool) rewrite() error {
+	if len(sp.pending) == 0 {
+		err := os.Remove(sp.file)
+		if os.IsNotExist(err) {
+			err = nil
+		}
+		return errors.Wrap(err, "could not empty the spool")
+	}
+	var b bytes.Buffer
+	for _, e := range sp.pending {
+		js, err := json.Marshal(e)
+		if err != nil {
+			return errors.Wrap(err, "could not marshal the message")
+		}
+		b.Write(append(js, '\n'))
+	}
+	tmp := sp.file + ".tmp"
+	if err := writeSync(tmp, b.Bytes()); err != nil {
+		return errors.Wrap(err, "could not rewrite the spool")
+	}
+	return errors.Wrap(os.Rename(tmp, sp.file), "could not rewrite the spool")
+}
+
+func writeSync(file string, b []byte) error {
+	f, err := os.Create(file)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(b)
+	if err == nil {
+		err = f.Sync()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
@@ -0,0 +1,165 @@
+package sink
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testSink takes messages until it's down, or until it has taken up.
+type testSink struct {
+	down  bool
+	up    int
+	calls int
+	got   []string
+}
+
+func (s *testSink) Publish(topic string, msg []byte) error {
+	s.calls++
+	if s.down || s.up > 0 && len(s.got) >= s.up {
+		return errors.New("down")
+	}
+	s.got = append(s.got, topic+" "+string(msg))
+	return nil
+}
+
+func (s *testSink) Close() error {
+	return nil
+}
+
+// testSpool returns a Spool for s in a directory the caller removes.
+func testSpool(t *testing.T, s Sink) (*Spool, string) {
+	t.Helper()
+	dir, err := ioutil.TempDir("", "spool")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sp, err := NewSpool(dir, s)
+	if err != nil {
+		os.RemoveAll(dir)
+		t.Fatal(err)
+	}
+	return sp, dir
+}
+
+// lines returns the messages spooled in dir.
+func lines(t *testing.T, dir string) []string {
+	t.Helper()
+	b, err := ioutil.ReadFile(filepath.Join(dir, spoolFile))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return strings.Split(strings.TrimSpace(string(b)), "\n")
+}
+
+func TestSpool(t *testing.T) {
+	s := &testSink{down: true}
+	sp, dir := testSpool(t, s)
+	defer os.RemoveAll(dir)
+
+	for _, msg := range []string{"1", "2"} {
+		if err := sp.Publish("t", []byte(msg)); err != nil {
+			t.Fatalf("Publish() = %v, want the message on disk", err)
+		}
+	}
+	// The second message didn't try the sink, which failed a moment ago.
+	if s.calls != 1 {
+		t.Errorf("the sink was tried %d times, want 1", s.calls)
+	}
+	if got, want := lines(t, dir), []string{`{"topic":"t","message":1}`, `{"topic":"t","message":2}`}; !reflect.DeepEqual(got, want) {
+		t.Errorf("spooled %q, want %q", got, want)
+	}
+
+	// Once it's back, the spooled messages go ahead of the new one.
+	s.down, sp.next = false, time.Time{}
+	if err := sp.Publish("t", []byte("3")); err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"t 1", "t 2", "t 3"}; !reflect.DeepEqual(s.got, want) {
+		t.Errorf("sink got %q, want %q", s.got, want)
+	}
+	if sp.Pending() != 0 || lines(t, dir) != nil {
+		t.Errorf("%d messages still pending, %q on disk", sp.Pending(), lines(t, dir))
+	}
+	if err := sp.Close(); err != nil {
+		t.Errorf("Close() = %v", err)
+	}
+}
+
+func TestSpoolPartial(t *testing.T) {
+	s := &testSink{down: true}
+	sp, dir := testSpool(t, s)
+	defer os.RemoveAll(dir)
+	for _, msg := range []string{"1", "2", "3"} {
+		if err := sp.Publish("t", []byte(msg)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// The sink takes one message and fails again: that one leaves the
+	// file, so it isn't sent twice.
+	s.down, s.up = false, 1
+	err := sp.Close()
+	if err == nil || !strings.Contains(err.Error(), "2 messages left in") {
+		t.Errorf("Close() = %v, want 2 messages left", err)
+	}
+	if got, want := lines(t, dir), []string{`{"topic":"t","message":2}`, `{"topic":"t","message":3}`}; !reflect.DeepEqual(got, want) {
+		t.Errorf("spooled %q, want %q", got, want)
+	}
+}
+
+func TestSpoolRestart(t *testing.T) {
+	dir, err := ioutil.TempDir("", "spool")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	// An earlier run crashed while writing the last line.
+	left := `{"topic":"a","message":{"n":1}}` + "\n" + `{"topic":"b","message":[2]}` + "\n" + `{"topic":"c","mess`
+	if err = ioutil.WriteFile(filepath.Join(dir, spoolFile), []byte(left), 0644); err != nil {
+		t.Fatal(err)
+	}
+	s := &testSink{}
+	sp, err := NewSpool(dir, s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sp.Pending() != 2 {
+		t.Errorf("Pending() = %d, want 2", sp.Pending())
+	}
+	if err = sp.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{`a {"n":1}`, "b [2]"}; !reflect.DeepEqual(s.got, want) {
+		t.Errorf("sink got %q, want %q", s.got, want)
+	}
+	if got := lines(t, dir); got != nil {
+		t.Errorf("%q left on disk", got)
+	}
+}
+
+func TestSpoolBackoff(t *testing.T) {
+	sp := &Spool{}
+	var got []time.Duration
+	for i := 0; i < 9; i++ {
+		sp.failed()
+		got = append(got, sp.wait)
+	}
+	want := []time.Duration{1, 2, 4, 8, 16, 32, 60, 60, 60}
+	for i := range want {
+		want[i] *= time.Second
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("waits %v, want %v", got, want)
+	}
+	if d := time.Until(sp.next); d <= 0 || d > maxWait {
+		t.Errorf("next attempt in %v, want within %v", d, maxWait)
+	}
+}
@@ -0,0 +1,364 @@
+/*
+Package stream is the plumbing the telemetry commands share: where the
+messages come from, a subscription to a router, a recording or routers
+dialing out, and where they go other than the command's printer, a
+recording, InfluxDB or a sink.
+*/
+package stream
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	proto "github.com/golang/protobuf/proto"
+	"github.com/nleiva/clus2019/dialout"
+	"github.com/nleiva/clus2019/gpb"
+	"github.com/nleiva/clus2019/inventory"
+	"github.com/nleiva/clus2019/mdt"
+	"github.com/nleiva/clus2019/record"
+	"github.com/nleiva/clus2019/sink"
+	xr "github.com/nleiva/xrgrpc"
+	"github.com/nleiva/xrgrpc/proto/telemetry"
+	"github.com/pkg/errors"
+)
+
+// encodings are the subscription encodings, by name.
+var encodings = map[string]int64{
+	"gpb":   2,
+	"gpbkv": 3,
+	"json":  4,
+}
+
+// Flags pick the source and the outputs of a Session.
+type Flags struct {
+	Subs    string
+	Enc     string
+	Record  string
+	Replay  string
+	Speed   float64
+	Dialout string
+	Influx  string
+	Publish string
+	Topic   string
+	Spool   string
+	Proto   string
+	Target  inventory.Target
+
+	// proto says -proto was registered, so it's worth mentioning.
+	proto bool
+}
+
+// AddFlags registers the flags of the source, -record and -influx on fs,
+// with enc as the default encoding.
+func (f *Flags) AddFlags(fs *flag.FlagSet, enc string) {
+	// Subs options; LLDP, we will add some more
+	fs.StringVar(&f.Subs, "subs", "LLDP", "Telemetry Subscription")
+	fs.StringVar(&f.Enc, "enc", enc, "Encoding: 'json', 'gpb' or 'gpbkv'")
+	// Recording options; messages come from the router unless -replay is set
+	fs.StringVar(&f.Record, "record", "", "Save the messages received to this file")
+	fs.StringVar(&f.Replay, "replay", "", "Decode the messages saved in this file instead of subscribing")
+	fs.Float64Var(&f.Speed, "speed", 1, "Pace of -replay: 1 as recorded, 10 ten times faster, 0 without pauses")
+	// Routers configured for dial-out connect to these addresses instead
+	fs.StringVar(&f.Dialout, "dialout", "", "Listen for dial-out sessions on these comma-separated tcp://host:port or grpc://host:port addresses instead of subscribing")
+	// Rows go to InfluxDB instead of being printed when -influx is set,
+	// as well as to -publish
+	fs.StringVar(&f.Influx, "influx", "", "Write the rows in InfluxDB line protocol to this file, '-' for stdout, or an http:// write URL")
+	// Target device; defaults to "router2" from "inventory.json"
+	f.Target.AddFlags(fs)
+}
+
+// AddPublishFlags registers -publish, -topic and -spool on fs.
+func (f *Flags) AddPublishFlags(fs *flag.FlagSet) {
+	// Decoded messages go to a sink instead of being printed when -publish is set
+	fs.StringVar(&f.Publish, "publish", "", "Publish the decoded JSON to this file, '-' for stdout, an http:// webhook or a nats://host:port server")
+	fs.StringVar(&f.Topic, "topic", "telemetry", "Prefix of the topic of each encoding path")
+	fs.StringVar(&f.Spool, "spool", "", "Keep what the -publish sink doesn't take in this directory until it does")
+}
+
+// AddProtoFlag registers -proto on fs.
+func (f *Flags) AddProtoFlag(fs *flag.FlagSet) {
+	// Messages of the encoding paths xrgrpc doesn't have
+	fs.StringVar(&f.Proto, "proto", "", "Directory of .proto files for more encoding paths")
+	f.proto = true
+}
+
+// Session is a stream of telemetry messages and the outputs they go to.
+type Session struct {
+	// From is where the messages come from: a router, a recording or the
+	// dial-out listeners.
+	From string
+	// Payloads, if set, returns what -publish sends for a message, in
+	// place of the message as JSON.
+	Payloads func(m *telemetry.Telemetry) [][]byte
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	ch     chan []byte
+	ech    chan error
+	// timeout is the session timeout of a router, in seconds.
+	timeout int
+	conn    io.Closer
+
+	rec    *record.Writer
+	influx *mdt.Influx
+	pub    sink.Sink
+	topic  string
+
+	logf func(format string, args ...interface{})
+	// proto says there's -proto to load more messages with.
+	proto bool
+	// unknown are the encoding paths without messages reported so far.
+	unknown map[string]bool
+}
+
+// Open loads the messages in -proto, starts the source the flags pick and
+// opens the outputs. logf is told about dial-out sessions and about the
+// messages that can't be written or published, which don't end the
+// session.
+func (f *Flags) Open(logf func(format string, args ...interface{})) (*Session, error) {
+	e, ok := encodings[f.Enc]
+	if !ok {
+		return nil, errors.Errorf("encoding option '%v' not supported", f.Enc)
+	}
+	if f.Proto != "" {
+		n, err := gpb.Default.Load(f.Proto)
+		if err != nil {
+			return nil, errors.Wrap(err, "could not load the messages of more encoding paths")
+		}
+		logf("loaded the messages of %d encoding paths from %s", n, f.Proto)
+	}
+	s := &Session{From: f.Replay, topic: f.Topic, logf: logf, proto: f.proto, unknown: make(map[string]bool)}
+	err := s.start(f, e)
+	if err == nil {
+		err = s.open(f)
+	}
+	if err != nil {
+		s.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+// start starts the source.
+func (s *Session) start(f *Flags, enc int64) error {
+	s.ctx, s.cancel = context.WithCancel(context.Background())
+	var err error
+	switch {
+	case f.Replay != "":
+		s.ch, s.ech, err = record.Replay(s.ctx, f.Replay, f.Speed)
+		return errors.Wrap(err, "could not replay the messages")
+	case f.Dialout != "":
+		// The routers pick the subscription and encoding of a dial-out.
+		s.ch, s.ech, err = dialout.Listen(s.ctx, strings.Split(f.Dialout, ","), s.logf)
+		if err != nil {
+			return errors.Wrap(err, "could not listen for dial-out sessions")
+		}
+		s.From = "dial-out on " + f.Dialout
+		s.logf("listening for dial-out sessions on %s", f.Dialout)
+		return nil
+	}
+	// Target parameters come from the inventory.
+	d, err := f.Target.One()
+	if err != nil {
+		return errors.Wrap(err, "could not select a device")
+	}
+	router, err := d.Router(xr.WithTimeout(60))
+	if err != nil {
+		return errors.Wrap(err, "could not build a router")
+	}
+	conn, ctx, err := xr.Connect(*router)
+	if err != nil {
+		return errors.Wrapf(err, "could not setup a client connection to %s", router.Host)
+	}
+	s.conn = conn
+	s.From, s.timeout = router.Host, router.Timeout
+	s.cancel()
+	s.ctx, s.cancel = context.WithCancel(ctx)
+
+	// ID for the transaction.
+	var id int64 = 1
+	s.ch, s.ech, err = xr.GetSubscription(s.ctx, conn, f.Subs, id, enc)
+	return errors.Wrap(err, "could not setup Telemetry Subscription")
+}
+
+// open opens the outputs.
+func (s *Session) open(f *Flags) error {
+	var err error
+	if f.Record != "" {
+		if s.rec, err = record.Create(f.Record); err != nil {
+			return errors.Wrap(err, "could not record the messages")
+		}
+	}
+	if f.Influx != "" {
+		if s.influx, err = mdt.OpenInflux(f.Influx); err != nil {
+			return errors.Wrap(err, "could not write to InfluxDB")
+		}
+	}
+	if f.Publish != "" {
+		pub, err := sink.Open(f.Publish)
+		if err != nil {
+			return errors.Wrap(err, "could not open the sink")
+		}
+		if f.Spool != "" {
+			spool, err := sink.NewSpool(f.Spool, pub)
+			if err != nil {
+				pub.Close()
+				return errors.Wrap(err, "could not open the spool")
+			}
+			pub = spool
+		}
+		s.pub = pub
+	}
+	return nil
+}
+
+// Close ends the session and closes the outputs, flushing what they hold.
+func (s *Session) Close() error {
+	if s.cancel != nil {
+		s.cancel()
+	}
+	var errs []string
+	if s.rec != nil {
+		if err := s.rec.Close(); err != nil {
+			errs = append(errs, "could not close the recording: "+err.Error())
+		}
+	}
+	if s.influx != nil {
+		if err := s.influx.Close(); err != nil {
+			errs = append(errs, "could not write to InfluxDB: "+err.Error())
+		}
+	}
+	if s.pub != nil {
+		if err := s.pub.Close(); err != nil {
+			errs = append(errs, "could not close the sink: "+err.Error())
+		}
+	}
+	if s.conn != nil {
+		s.conn.Close()
+	}
+	if errs != nil {
+		return errors.New(strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// Run records each message, then writes it to InfluxDB and publishes it,
+// or passes it to print if there's neither output, until the source ends
+// or the process is interrupted. A message that doesn't unmarshal is
+// logged and skipped.
+func (s *Session) Run(print func(m *telemetry.Telemetry) error) error {
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, os.Interrupt)
+	defer signal.Stop(c)
+	go s.watch(c)
+
+	for b := range s.ch {
+		if s.rec != nil {
+			if err := s.save(b); err != nil {
+				return err
+			}
+		}
+		m := new(telemetry.Telemetry)
+		if err := proto.Unmarshal(b, m); err != nil {
+			s.logf("could not unmarshall the message: %v", err)
+			continue
+		}
+		if s.influx != nil {
+			s.write(m)
+		}
+		if s.pub != nil {
+			s.publish(m)
+		}
+		if s.influx == nil && s.pub == nil {
+			if err := print(m); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// watch reports why the session ends, and ends it on an interrupt.
+func (s *Session) watch(c chan os.Signal) {
+	select {
+	case <-c:
+		s.logf("manually cancelled the session to %v", s.From)
+		s.cancel()
+	case <-s.ctx.Done():
+		// Timeout: "context deadline exceeded". A replay or dial-out has
+		// no deadline, and ends with "context canceled".
+		if err := s.ctx.Err(); s.timeout > 0 && err == context.DeadlineExceeded {
+			s.logf("gRPC session timed out after %v seconds: %v", s.timeout, err)
+		}
+	case err := <-s.ech:
+		s.logf("gRPC session to %v failed: %v", s.From, err)
+	}
+}
+
+// save writes a message to the recording as it's received, flushing it
+// so an interrupted session keeps every message before it.
+func (s *Session) save(b []byte) error {
+	err := s.rec.Write(record.Frame{Time: time.Now(), Data: b})
+	if err == nil {
+		err = s.rec.Flush()
+	}
+	return errors.Wrap(err, "could not record the message")
+}
+
+// write sends the rows of a message to InfluxDB; a failure is logged
+// rather than ending the session.
+func (s *Session) write(m *telemetry.Telemetry) {
+	rows, err := mdt.Flatten(m, gpb.Default)
+	if errors.Cause(err) == gpb.ErrUnknownPath {
+		s.Unknown(m.GetEncodingPath())
+		return
+	}
+	if err == nil {
+		err = s.influx.Write(rows)
+	}
+	if err != nil {
+		s.logf("could not write the message to InfluxDB: %v", err)
+	}
+}
+
+// publish sends the payloads of a message to the sink, under the topic of
+// its encoding path; a failure is logged rather than ending the session.
+func (s *Session) publish(m *telemetry.Telemetry) {
+	var payloads [][]byte
+	if s.Payloads != nil {
+		payloads = s.Payloads(m)
+	} else {
+		b, err := json.Marshal(m)
+		if err != nil {
+			s.logf("could not marshall into JSON: %v", err)
+			return
+		}
+		payloads = [][]byte{b}
+	}
+	topic := sink.Topic(s.topic, m.GetEncodingPath())
+	for _, b := range payloads {
+		if err := s.pub.Publish(topic, b); err != nil {
+			s.logf("could not publish the message: %v", err)
+		}
+	}
+}
+
+// Unknown reports an encoding path without messages, once rather than for
+// every message.
+func (s *Session) Unknown(path string) {
+	if s.unknown[path] {
+		return
+	}
+	s.unknown[path] = true
+	if s.proto {
+		s.logf("no messages for path %v, load its .proto file with -proto", path)
+		return
+	}
+	s.logf("no messages for path %v", path)
+}
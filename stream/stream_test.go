@@ -0,0 +1,107 @@
+package stream
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	proto "github.com/golang/protobuf/proto"
+	"github.com/nleiva/clus2019/record"
+	"github.com/nleiva/xrgrpc/proto/telemetry"
+)
+
+func TestRun(t *testing.T) {
+	dir, err := ioutil.TempDir("", "stream")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	good, err := proto.Marshal(&telemetry.Telemetry{EncodingPath: "p"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	file := filepath.Join(dir, "session.rec")
+	w, err := record.Create(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, b := range [][]byte{good, {0xff}, good} {
+		if err = w.Write(record.Frame{Time: time.Now(), Data: b}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err = w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		flags  Flags
+		shown  int
+		logged []string
+	}{
+		{
+			// A message that doesn't unmarshal doesn't end the session.
+			name:   "print",
+			flags:  Flags{Enc: "gpbkv", Replay: file},
+			shown:  2,
+			logged: []string{"could not unmarshall the message"},
+		},
+		{
+			// -influx and -publish both get the messages, and nothing is
+			// printed.
+			name:  "outputs",
+			flags: Flags{Enc: "gpbkv", Replay: file, Influx: filepath.Join(dir, "lines"), Publish: filepath.Join(dir, "published"), Topic: "t"},
+		},
+	}
+	for _, tt := range tests {
+		var logged []string
+		logf := func(format string, args ...interface{}) {
+			logged = append(logged, fmt.Sprintf(format, args...))
+		}
+		s, err := tt.flags.Open(logf)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		shown := 0
+		err = s.Run(func(*telemetry.Telemetry) error {
+			shown++
+			return nil
+		})
+		if cerr := s.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+		}
+		if shown != tt.shown {
+			t.Errorf("%s: %d messages printed, want %d", tt.name, shown, tt.shown)
+		}
+		for _, want := range tt.logged {
+			if !strings.Contains(strings.Join(logged, "\n"), want) {
+				t.Errorf("%s: logged %q, want %q", tt.name, logged, want)
+			}
+		}
+	}
+
+	// The messages have no rows for InfluxDB, but the sink got them.
+	b, err := ioutil.ReadFile(filepath.Join(dir, "published"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := strings.Count(string(b), `"topic":"t.p"`); n != 2 {
+		t.Errorf("%d messages published, want 2\n%s", n, b)
+	}
+}
+
+func TestOpenEncoding(t *testing.T) {
+	f := Flags{Enc: "xml", Replay: "any.rec"}
+	if _, err := f.Open(func(string, ...interface{}) {}); err == nil || !strings.Contains(err.Error(), "not supported") {
+		t.Errorf("Open() = %v, want an unsupported encoding", err)
+	}
+}
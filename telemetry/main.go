@@ -2,17 +2,14 @@ package main
 
 import (
 	"bytes"
-	"context"
 	"encoding/json"
 	"flag"
 	"fmt"
 	"log"
-	"os"
-	"os/signal"
 
-	proto "github.com/golang/protobuf/proto"
-	xr "github.com/nleiva/xrgrpc"
+	"github.com/nleiva/clus2019/stream"
 	"github.com/nleiva/xrgrpc/proto/telemetry"
+	"github.com/pkg/errors"
 )
 
 func prettyprint(b []byte) ([]byte, error) {
@@ -22,97 +19,43 @@ func prettyprint(b []byte) ([]byte, error) {
 }
 
 func main() {
-	// Subs options; LLDP, we will add some more
-	p := flag.String("subs", "LLDP", "Telemetry Subscription")
-	// Encoding option; defaults to GPBKV (only one supported in this example)
-	enc := flag.String("enc", "gpbkv", "Encoding: 'json', 'gpb' or 'gpbkv'")
+	// Source and outputs; a GPBKV subscription to "router2" by default
+	var f stream.Flags
+	f.AddFlags(flag.CommandLine, "gpbkv")
+	f.AddPublishFlags(flag.CommandLine)
 	flag.Parse()
 
-	mape := map[string]int64{
-		"gpb":   2,
-		"gpbkv": 3,
-		"json":  4,
+	if err := run(&f); err != nil {
+		log.Fatal(err)
 	}
-	e, ok := mape[*enc]
-	if !ok {
-		log.Fatalf("encoding option '%v' not supported", *enc)
-	}
-
-	// ID for the transaction.
-	var id int64 = 1
-
-	// Manually specify target parameters.
-	router, err := xr.BuildRouter(
-		xr.WithUsername("cisco"),
-		xr.WithPassword("cisco"),
-		//xr.WithHost("[2001:420:2cff:1204::5502:1]:57344"),
-		//xr.WithCert("../input/certificate/router1.pem"),
-		xr.WithHost("[2001:420:2cff:1204::5502:2]:57344"),
-		xr.WithCert("../input/certificate/router2.pem"),
-		xr.WithTimeout(60),
-	)
-	if err != nil {
-		log.Fatalf("could not build a router, %v", err)
-	}
-
-	// Setup a connection to the target.
-	conn, ctx, err := xr.Connect(*router)
-	if err != nil {
-		log.Fatalf("could not setup a client connection to %s, %v", router.Host, err)
-	}
-	defer conn.Close()
-
-	ctx, cancel := context.WithCancel(ctx)
-	defer cancel()
+}
 
-	ch, ech, err := xr.GetSubscription(ctx, conn, *p, id, e)
+// run prints the messages as JSON, so the deferred Close flushes the
+// outputs before main exits.
+func run(f *stream.Flags) error {
+	s, err := f.Open(log.Printf)
 	if err != nil {
-		log.Fatalf("could not setup Telemetry Subscription: %v\n", err)
+		return err
 	}
-
-	c := make(chan os.Signal, 1)
-	// If no signals are provided, all incoming signals will be relayed to c.
-	// Otherwise, just the provided signals will. E.g.: signal.Notify(c, os.Interrupt, syscall.SIGTERM)
-	signal.Notify(c, os.Interrupt)
 	defer func() {
-		signal.Stop(c)
-		cancel()
-	}()
-
-	go func() {
-		select {
-		case <-c:
-			fmt.Printf("\nmanually cancelled the session to %v\n\n", router.Host)
-			cancel()
-			return
-		case <-ctx.Done():
-			// Timeout: "context deadline exceeded"
-			err = ctx.Err()
-			fmt.Printf("\ngRPC session timed out after %v seconds: %v\n\n", router.Timeout, err.Error())
-			return
-		case err = <-ech:
-			// Session canceled: "context canceled"
-			fmt.Printf("\ngRPC session to %v failed: %v\n\n", router.Host, err.Error())
-			return
+		if err := s.Close(); err != nil {
+			log.Println(err)
 		}
 	}()
+	return s.Run(show)
+}
 
-	for tele := range ch {
-		message := new(telemetry.Telemetry)
-		err := proto.Unmarshal(tele, message)
-		if err != nil {
-			log.Fatalf("could not unmarshall the message: %v\n", err)
-		}
-		fmt.Printf("Time %v, Path: %v\n", message.GetMsgTimestamp(), message.GetEncodingPath())
+func show(message *telemetry.Telemetry) error {
+	fmt.Printf("Time %v, Path: %v\n", message.GetMsgTimestamp(), message.GetEncodingPath())
 
-		b, err := json.Marshal(message)
-		if err != nil {
-			log.Fatalf("could not marshall into JSON: %v\n", err)
-		}
-		bjs, err := prettyprint(b)
-		if err != nil {
-			log.Fatalf("could not pretty-print the message: %v\n", err)
-		}
-		fmt.Println(string(bjs))
+	b, err := json.Marshal(message)
+	if err != nil {
+		return errors.Wrap(err, "could not marshall into JSON")
+	}
+	bjs, err := prettyprint(b)
+	if err != nil {
+		return errors.Wrap(err, "could not pretty-print the message")
 	}
+	fmt.Println(string(bjs))
+	return nil
 }
@@ -2,19 +2,14 @@ package main
 
 import (
 	"bytes"
-	"context"
 	"encoding/json"
 	"flag"
 	"fmt"
 	"log"
-	"os"
-	"os/signal"
-	"reflect"
 
-	"github.com/golang/protobuf/proto"
-	xr "github.com/nleiva/xrgrpc"
+	"github.com/nleiva/clus2019/gpb"
+	"github.com/nleiva/clus2019/stream"
 	"github.com/nleiva/xrgrpc/proto/telemetry"
-	lldp "github.com/nleiva/xrgrpc/proto/telemetry/lldp65x"
 	"github.com/pkg/errors"
 )
 
@@ -25,116 +20,71 @@ func prettyprint(b []byte) ([]byte, error) {
 }
 
 func main() {
-	// Subs options; LLDP, we will add some more
-	p := flag.String("subs", "LLDP", "Telemetry Subscription")
-	// Encoding option; defaults to GPB (only one supported in this example)
-	enc := flag.String("enc", "gpb", "Encoding: 'json', 'gpb' or 'gpbkv'")
+	// Source and outputs; a GPB subscription to "router2" by default
+	var f stream.Flags
+	f.AddFlags(flag.CommandLine, "gpb")
+	f.AddPublishFlags(flag.CommandLine)
+	f.AddProtoFlag(flag.CommandLine)
 	flag.Parse()
 
-	mape := map[string]int64{
-		"gpb":   2,
-		"gpbkv": 3,
-		"json":  4,
+	if err := run(&f); err != nil {
+		log.Fatal(err)
 	}
-	e, ok := mape[*enc]
-	if !ok {
-		log.Fatalf("encoding option '%v' not supported", *enc)
-	}
-
-	// ID for the transaction.
-	var id int64 = 1
-
-	// Manually specify target parameters.
-	router, err := xr.BuildRouter(
-		xr.WithUsername("cisco"),
-		xr.WithPassword("cisco"),
-		//xr.WithHost("[2001:420:2cff:1204::5502:1]:57344"),
-		//xr.WithCert("../input/certificate/router1.pem"),
-		xr.WithHost("[2001:420:2cff:1204::5502:2]:57344"),
-		xr.WithCert("../input/certificate/router2.pem"),
-		xr.WithTimeout(60),
-	)
-	if err != nil {
-		log.Fatalf("could not build a router, %v", err)
-	}
-
-	// Setup a connection to the target.
-	conn, ctx, err := xr.Connect(*router)
-	if err != nil {
-		log.Fatalf("could not setup a client connection to %s, %v", router.Host, err)
-	}
-	defer conn.Close()
-
-	ctx, cancel := context.WithCancel(ctx)
-	defer cancel()
+}
 
-	ch, ech, err := xr.GetSubscription(ctx, conn, *p, id, e)
+// run prints the decoded rows of the messages, so the deferred Close
+// flushes the outputs before main exits.
+func run(f *stream.Flags) error {
+	s, err := f.Open(log.Printf)
 	if err != nil {
-		log.Fatalf("could not setup Telemetry Subscription: %v\n", err)
+		return err
 	}
-	c := make(chan os.Signal, 1)
-	// If no signals are provided, all incoming signals will be relayed to c.
-	// Otherwise, just the provided signals will. E.g.: signal.Notify(c, os.Interrupt, syscall.SIGTERM)
-	signal.Notify(c, os.Interrupt)
 	defer func() {
-		signal.Stop(c)
-		cancel()
-	}()
-
-	go func() {
-		select {
-		case <-c:
-			fmt.Printf("\nmanually cancelled the session to %v\n\n", router.Host)
-			cancel()
-			return
-		case <-ctx.Done():
-			// Timeout: "context deadline exceeded"
-			err = ctx.Err()
-			fmt.Printf("\ngRPC session timed out after %v seconds: %v\n\n", router.Timeout, err.Error())
-			return
-		case err = <-ech:
-			// Session canceled: "context canceled"
-			fmt.Printf("\ngRPC session to %v failed: %v\n\n", router.Host, err.Error())
-			return
+		if err := s.Close(); err != nil {
+			log.Println(err)
 		}
 	}()
+	s.Payloads = func(m *telemetry.Telemetry) [][]byte {
+		return rows(s, m)
+	}
+	return s.Run(func(m *telemetry.Telemetry) error {
+		return show(s, m)
+	})
+}
+
+func show(s *stream.Session, message *telemetry.Telemetry) error {
+	e := message.GetEncodingPath()
+	t := message.GetMsgTimestamp()
+	fmt.Printf("Time %v, Path: %v\n", t, e)
 
-	for tele := range ch {
-		message := new(telemetry.Telemetry)
-		err := proto.Unmarshal(tele, message)
+	msgs, ok := gpb.Default.Lookup(e)
+	if !ok {
+		s.Unknown(e)
+		return nil
+	}
+	for _, row := range message.GetDataGpb().GetRow() {
+		// Keys
+		output, err := decode(row.GetKeys(), msgs.Keys)
 		if err != nil {
-			log.Fatalf("could not unmarshall the message: %v\n", err)
+			return errors.Wrap(err, "could not decode Keys")
 		}
-		e := message.GetEncodingPath()
-		t := message.GetMsgTimestamp()
-		fmt.Printf("Time %v, Path: %v\n", t, e)
-
-		encPath := EncodingPath(e)
-		for _, row := range message.GetDataGpb().GetRow() {
-			// Keys
-			msgStr := reflect.New(path2msg[encPath][protoKeys])
-			output, err := decode(row.GetKeys(), msgStr.Interface().(proto.Message))
-			if err != nil {
-				log.Fatalf("could decode Keys: %v\n", err)
-			}
-			fmt.Printf("Decoded Keys:\n%v\n", output)
-			// Content
-			msgStr = reflect.New(path2msg[encPath][protoContent])
-			output, err = decode(row.GetContent(), msgStr.Interface().(proto.Message))
-			if err != nil {
-				log.Fatalf("could not decode Content: %v\n", err)
-			}
-			fmt.Printf("Decoded Content:\n%v\n", output)
+		fmt.Printf("Decoded Keys:\n%v\n", output)
+		// Content
+		output, err = decode(row.GetContent(), msgs.Content)
+		if err != nil {
+			return errors.Wrap(err, "could not decode Content")
 		}
+		fmt.Printf("Decoded Content:\n%v\n", output)
 	}
+	return nil
 }
 
-func decode(bk []byte, m proto.Message) (string, error) {
-	err := proto.Unmarshal(bk, m)
+func decode(bk []byte, m gpb.Message) (string, error) {
+	v, err := m.Decode(bk)
 	if err != nil {
-		return "", errors.Wrap(err, "could not unmarshall the message")
+		return "", err
 	}
-	b, err := json.Marshal(m)
+	b, err := json.Marshal(v)
 	if err != nil {
 		return "", errors.Wrap(err, "could not marshall into JSON")
 	}
@@ -145,16 +95,48 @@ func decode(bk []byte, m proto.Message) (string, error) {
 	return string(b), err
 }
 
-const (
-	protoKeys    = 0
-	protoContent = 1
-)
-
-// EncodingPath is the path as reported by GetEncodingPath()
-type EncodingPath string
+// row is what's published for a row of a message.
+type row struct {
+	Node         string      `json:"node_id_str"`
+	Subscription string      `json:"subscription_id_str"`
+	Path         string      `json:"encoding_path"`
+	Timestamp    uint64      `json:"msg_timestamp"`
+	Keys         interface{} `json:"keys"`
+	Content      interface{} `json:"content"`
+}
 
-var path2msg = map[EncodingPath][]reflect.Type{
-	"Cisco-IOS-XR-ethernet-lldp-oper:lldp/nodes/node/neighbors/details/detail": []reflect.Type{
-		reflect.TypeOf((*lldp.LldpNeighborEntry_KEYS)(nil)).Elem(),
-		reflect.TypeOf((*lldp.LldpNeighborEntry)(nil)).Elem()},
+// rows decodes each row of a message for the sink; a row that doesn't
+// decode is logged and left out, and a path without messages reported
+// once.
+func rows(s *stream.Session, m *telemetry.Telemetry) [][]byte {
+	e := m.GetEncodingPath()
+	msgs, ok := gpb.Default.Lookup(e)
+	if !ok {
+		s.Unknown(e)
+		return nil
+	}
+	var out [][]byte
+	for _, gr := range m.GetDataGpb().GetRow() {
+		r := row{
+			Node:         m.GetNodeIdStr(),
+			Subscription: m.GetSubscriptionIdStr(),
+			Path:         e,
+			Timestamp:    m.GetMsgTimestamp(),
+		}
+		var err error
+		if r.Keys, err = msgs.Keys.Decode(gr.GetKeys()); err == nil {
+			r.Content, err = msgs.Content.Decode(gr.GetContent())
+		}
+		if err != nil {
+			log.Printf("could not decode a row: %v\n", err)
+			continue
+		}
+		b, err := json.Marshal(r)
+		if err != nil {
+			log.Printf("could not marshall a row into JSON: %v\n", err)
+			continue
+		}
+		out = append(out, b)
+	}
+	return out
 }
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"bytes"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/nleiva/clus2019/record"
+	"github.com/nleiva/xrgrpc/proto/telemetry"
+)
+
+// captured returns the fields printMessage writes for the first message
+// recorded in file, without the header.
+func captured(t *testing.T, file string) string {
+	t.Helper()
+	frames, err := record.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return fields(t, frames[0].Data)
+}
+
+// fields returns the fields printMessage writes for a message as sent on
+// the wire, without the header.
+func fields(t *testing.T, b []byte) string {
+	t.Helper()
+	m := new(telemetry.Telemetry)
+	if err := proto.Unmarshal(b, m); err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	printMessage(&out, m)
+	lines := strings.SplitN(out.String(), "\n", 4)
+	if len(lines) < 4 || !strings.HasSuffix(lines[1], "Path: "+m.GetEncodingPath()) {
+		t.Fatalf("no header in %q", out.String())
+	}
+	return lines[3]
+}
+
+func TestCounters(t *testing.T) {
+	// Every value type, bytes as a MAC, IPv4 and IPv6 address and hex,
+	// and a field with both a value and children.
+	want := `  interface-name: HundredGigE0/0/0/0
+  packets-received: 1000
+  bytes-received: 128000
+  input-drops: 0
+  carrier-delta: -1
+  clock-offset: -250
+  input-load: 0.125
+  output-load: 0.25
+  is-up: true
+  mac-address: 00:8a:96:46:6c:d9
+  ip-address: 203.0.113.1
+  ipv6-address: 2001:db8::1
+  checksum: 0xbeef
+  state: im-state-up
+   transitions: 2
+   last-change: 3600
+`
+	if got := captured(t, "../input/mock/telemetry/COUNTERS.gpbkv.rec"); got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestLLDP(t *testing.T) {
+	got := captured(t, "../input/mock/telemetry/LLDP.gpbkv.rec")
+	// Keys and content are a level down from the entry, the address two
+	// more; empty strings still show.
+	for _, want := range []string{
+		"\n  device-id: router1\n   receiving-interface-name: HundredGigE0/0/0/0\n",
+		"\n   platform: \n       address-type: ipv4\n       ipv4-address: 198.51.100.1\n",
+		"\n    system-name: router3\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("no %q in\n%s", want, got)
+		}
+	}
+}
+
+func TestUnknownType(t *testing.T) {
+	// A field whose value is of a type from a newer telemetry.proto,
+	// number 13, with a child.
+	child := []byte{0x12, 5, 'c', 'h', 'i', 'l', 'd', 0x38, 5}
+	field := append([]byte{0x12, 6, 'f', 'u', 't', 'u', 'r', 'e', 0x68, 1, 0x7a, byte(len(child))}, child...)
+	msg := append([]byte{0x32, 1, 'p', 0x5a, byte(len(field))}, field...)
+	if got, want := fields(t, msg), " child: 5\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestValue(t *testing.T) {
+	tests := []struct {
+		field *telemetry.TelemetryField
+		want  string
+	}{
+		{&telemetry.TelemetryField{ValueByType: &telemetry.TelemetryField_StringValue{StringValue: "up"}}, "up"},
+		{&telemetry.TelemetryField{ValueByType: &telemetry.TelemetryField_BoolValue{BoolValue: false}}, "false"},
+		{&telemetry.TelemetryField{ValueByType: &telemetry.TelemetryField_Uint32Value{Uint32Value: math.MaxUint32}}, "4294967295"},
+		{&telemetry.TelemetryField{ValueByType: &telemetry.TelemetryField_Uint64Value{Uint64Value: math.MaxUint64}}, "18446744073709551615"},
+		{&telemetry.TelemetryField{ValueByType: &telemetry.TelemetryField_Sint32Value{Sint32Value: math.MinInt32}}, "-2147483648"},
+		{&telemetry.TelemetryField{ValueByType: &telemetry.TelemetryField_Sint64Value{Sint64Value: math.MinInt64}}, "-9223372036854775808"},
+		// Floats keep the digits of their own precision.
+		{&telemetry.TelemetryField{ValueByType: &telemetry.TelemetryField_FloatValue{FloatValue: 0.1}}, "0.1"},
+		{&telemetry.TelemetryField{ValueByType: &telemetry.TelemetryField_DoubleValue{DoubleValue: 1e21}}, "1e+21"},
+		// Bytes are an address only when the name says so.
+		{&telemetry.TelemetryField{Name: "next-hop", ValueByType: &telemetry.TelemetryField_BytesValue{BytesValue: []byte{10, 0, 0, 1}}}, "10.0.0.1"},
+		{&telemetry.TelemetryField{Name: "mac", ValueByType: &telemetry.TelemetryField_BytesValue{BytesValue: []byte{0, 1, 2, 3, 4, 5}}}, "00:01:02:03:04:05"},
+		{&telemetry.TelemetryField{Name: "serial", ValueByType: &telemetry.TelemetryField_BytesValue{BytesValue: []byte{10, 0, 0, 1}}}, "0x0a000001"},
+		{&telemetry.TelemetryField{Name: "address", ValueByType: &telemetry.TelemetryField_BytesValue{BytesValue: []byte{1, 2, 3}}}, "0x010203"},
+		{&telemetry.TelemetryField{Name: "address", ValueByType: &telemetry.TelemetryField_BytesValue{}}, ""},
+		{&telemetry.TelemetryField{}, "(value of unknown type <nil>)"},
+	}
+	for _, tt := range tests {
+		if got := value(tt.field); got != tt.want {
+			t.Errorf("value(%v) = %q, want %q", tt.field, got, tt.want)
+		}
+	}
+}
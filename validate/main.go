@@ -0,0 +1,138 @@
+/*
+validate checks YANG JSON payloads against the YANG modules in a directory,
+without a router: unknown nodes, type mismatches, missing list keys and
+missing mandatory leaves.
+*/
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/nleiva/clus2019/inventory"
+	"github.com/nleiva/clus2019/payload"
+	"github.com/nleiva/clus2019/yang"
+)
+
+// Exit codes, as in diffconfig.
+const (
+	exitValid   = 0
+	exitInvalid = 1
+	exitTrouble = 2
+)
+
+func main() {
+	// YANG modules; defaults to "../yang"
+	ydir := flag.String("yang", "../yang", "Directory of YANG modules, searched recursively")
+	// YANG config; defaults to "yangocconfig.json"
+	ypath := flag.String("ypath", "../input/yangocconfig.json", "YANG config file or template (*.tmpl); ignored if files are given as arguments")
+	// Template variables, e.g. "vars.json"
+	vars := flag.String("vars", "", "Variables file to render templates with")
+	// Operation the payload is for
+	op := flag.String("op", "merge", "Operation the payload is for: 'merge', 'replace' or 'delete'")
+	// Fail on modules that aren't loaded
+	strict := flag.Bool("strict", false, "Report nodes from modules that aren't loaded as errors")
+	// Show what the modules use that couldn't be resolved
+	verbose := flag.Bool("v", false, "Show the parts of the YANG modules that can't be checked")
+	// Target devices to render templates for; defaults to "router2" from "inventory.json"
+	var tg inventory.Target
+	tg.AddFlags(flag.CommandLine)
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: validate [flags] [file...]\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	files := flag.Args()
+	if len(files) == 0 {
+		files = []string{*ypath}
+	}
+	yop, err := yang.ParseOp(*op)
+	if err != nil {
+		log.Fatal(err)
+	}
+	os.Exit(run(*ydir, files, *vars, yop, *strict, *verbose, tg))
+}
+
+func run(ydir string, files []string, vars string, op yang.Op, strict, verbose bool, tg inventory.Target) int {
+	schema, err := yang.Load(ydir)
+	if err != nil {
+		log.Printf("could not load the YANG modules: %v\n", err)
+		return exitTrouble
+	}
+	if schema == nil {
+		log.Printf("no YANG modules to validate with\n")
+		return exitTrouble
+	}
+	if verbose {
+		for _, w := range schema.Warnings {
+			fmt.Printf("warning: %s\n", w)
+		}
+	}
+
+	code := exitValid
+	for _, f := range files {
+		src, err := payload.Open(f, vars)
+		if err != nil {
+			log.Printf("could not load the payload: %v\n", err)
+			code = exitTrouble
+			continue
+		}
+		// Templates render differently per device; plain files only once.
+		devices := []inventory.Device{{}}
+		if payload.IsTemplate(f) || vars != "" {
+			if devices, err = tg.Devices(); err != nil {
+				log.Printf("could not select a device, %v", err)
+				return exitTrouble
+			}
+		}
+		for _, d := range devices {
+			name := f
+			if d.Name != "" {
+				name = f + " for " + d.Name
+			}
+			js, err := src.For(d)
+			if err != nil {
+				log.Printf("could not render %s: %v\n", name, err)
+				code = exitTrouble
+				continue
+			}
+			ps, err := schema.Validate(js, op)
+			if err != nil {
+				fmt.Printf("%s: %v\n", name, err)
+				code = worst(code, exitInvalid)
+				continue
+			}
+			if report(name, ps, strict) {
+				code = worst(code, exitInvalid)
+			}
+		}
+	}
+	return code
+}
+
+// report prints the problems with a payload and says if it's invalid.
+func report(name string, ps []yang.Problem, strict bool) bool {
+	invalid := false
+	for _, p := range ps {
+		if p.Unchecked && !strict {
+			fmt.Printf("%s: not checked: %v\n", name, p)
+			continue
+		}
+		fmt.Printf("%s: %v\n", name, p)
+		invalid = true
+	}
+	if !invalid {
+		fmt.Printf("%s: valid\n", name)
+	}
+	return invalid
+}
+
+func worst(a, b int) int {
+	if b > a {
+		return b
+	}
+	return a
+}
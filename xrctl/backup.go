@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"time"
+
+	"github.com/nleiva/clus2019/backup"
+	"github.com/nleiva/clus2019/inventory"
+	"github.com/nleiva/clus2019/runner"
+	xr "github.com/nleiva/xrgrpc"
+	"google.golang.org/grpc"
+)
+
+func init() {
+	register(command{"backup", "Save timestamped config snapshots", backupConfig})
+}
+
+func backupConfig(g *globals, args []string) error {
+	fs := flag.NewFlagSet("backup", flag.ExitOnError)
+	dir := fs.String("dir", "../backups", "Directory to store the snapshots in")
+	// YANG path arguments; defaults to every model in "yangocpaths.json"
+	ypath := fs.String("ypath", "../input/yangocpaths.json", "YANG path arguments; empty skips the YANG snapshot")
+	cli := fs.Bool("cli", false, "Also save the CLI running config")
+	keep := fs.Int("keep", 30, "Snapshots to keep per device and kind; 0 keeps all")
+	maxAge := fs.Duration("max-age", 0, "Remove snapshots older than this, e.g. 720h; 0 disables it")
+	fs.Parse(args)
+
+	var paths string
+	if *ypath != "" {
+		js, err := ioutil.ReadFile(*ypath)
+		if err != nil {
+			return fmt.Errorf("could not read file: %v: %v", *ypath, err)
+		}
+		paths = string(js)
+	}
+	if paths == "" && !*cli {
+		return fmt.Errorf("nothing to back up, set -ypath or -cli")
+	}
+	store := &backup.Store{Dir: *dir}
+	ret := backup.Retention{Keep: *keep, MaxAge: *maxAge}
+
+	return g.fanOutEach(func(d inventory.Device) runner.Task {
+		return func(ctx context.Context, conn *grpc.ClientConn, id int64) (string, error) {
+			now := time.Now()
+			var report []string
+			save := func(kind string, data string) error {
+				snap, isNew, err := store.Save(d.Name, kind, []byte(data), now)
+				if err != nil {
+					return err
+				}
+				if isNew {
+					report = append(report, "saved "+snap.File)
+				} else {
+					report = append(report, "unchanged since "+snap.File)
+				}
+				removed, err := store.Prune(d.Name, kind, ret, now)
+				for _, r := range removed {
+					report = append(report, "pruned "+r.File)
+				}
+				return err
+			}
+
+			if paths != "" {
+				out, err := xr.GetConfig(ctx, conn, paths, id)
+				if err != nil {
+					return "", fmt.Errorf("could not get the config, %v", err)
+				}
+				if err = save(backup.JSON, out); err != nil {
+					return "", err
+				}
+			}
+			if *cli {
+				out, err := xr.ShowCmdTextOutput(ctx, conn, "show running-config", id)
+				if err != nil {
+					return "", fmt.Errorf("could not get the running config, %v", err)
+				}
+				if err = save(backup.CLI, out); err != nil {
+					return "", err
+				}
+			}
+			return strings.Join(report, "\n "), nil
+		}
+	}, "backup of", 30)
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+
+	"github.com/nleiva/clus2019/inventory"
+	"github.com/nleiva/clus2019/payload"
+	"github.com/nleiva/clus2019/runner"
+	"github.com/nleiva/clus2019/yang"
+	xr "github.com/nleiva/xrgrpc"
+	"google.golang.org/grpc"
+)
+
+func init() {
+	register(command{"get", "Get the config for a set of YANG paths", get})
+	register(command{"set", "Apply CLI config", set})
+	register(command{"merge", "Merge a YANG JSON config", yangConfig("merge", "merged on", "../input/yangocconfig.json", xr.MergeConfig, yang.Merge)})
+	register(command{"replace", "Replace the config with a YANG JSON document", yangConfig("replace", "replaced on", "../input/yangocconfig.json", xr.ReplaceConfig, yang.Replace)})
+	register(command{"delete", "Delete the config in a YANG JSON document", yangConfig("delete", "deleted on", "../input/yangdelocconfig.json", xr.DeleteConfig, yang.Delete)})
+}
+
+func get(g *globals, args []string) error {
+	fs := flag.NewFlagSet("get", flag.ExitOnError)
+	// YANG path arguments; defaults to "yangocpaths.json"
+	ypath := fs.String("ypath", "../input/yangocpaths.json", "YANG path arguments")
+	fs.Parse(args)
+
+	js, err := ioutil.ReadFile(*ypath)
+	if err != nil {
+		return fmt.Errorf("could not read file: %v: %v", *ypath, err)
+	}
+	return g.fanOut(func(ctx context.Context, conn *grpc.ClientConn, id int64) (string, error) {
+		return xr.GetConfig(ctx, conn, string(js), id)
+	}, "config from", 5)
+}
+
+func set(g *globals, args []string) error {
+	fs := flag.NewFlagSet("set", flag.ExitOnError)
+	// CLI config to apply; defaults to "interface lo1 desc test"
+	cli := fs.String("cli", "interface lo1 desc test", "Config to apply")
+	fs.Parse(args)
+
+	return g.fanOut(func(ctx context.Context, conn *grpc.ClientConn, id int64) (string, error) {
+		return "", xr.CLIConfig(ctx, conn, *cli, id)
+	}, "config applied to", 5)
+}
+
+// yangOp is the signature shared by xr.MergeConfig, xr.ReplaceConfig and
+// xr.DeleteConfig.
+type yangOp func(ctx context.Context, conn *grpc.ClientConn, js string, id int64) (int64, error)
+
+// yangConfig builds a subcommand that sends the file in -ypath with op.
+// The file may be a template, rendered per device with -vars.
+func yangConfig(name, verb, def string, op yangOp, check yang.Op) func(g *globals, args []string) error {
+	return func(g *globals, args []string) error {
+		fs := flag.NewFlagSet(name, flag.ExitOnError)
+		// YANG config file
+		ypath := fs.String("ypath", def, "YANG config file or template (*.tmpl)")
+		vars := fs.String("vars", "", "Variables file to render -ypath with")
+		dryRun := fs.Bool("dry-run", false, "Show the payload for each device without sending it")
+		var yf yang.Flags
+		yf.AddFlags(fs)
+		fs.Parse(args)
+
+		src, err := payload.Open(*ypath, *vars)
+		if err != nil {
+			return err
+		}
+		schema, err := yf.Load(log.Printf)
+		if err != nil {
+			return err
+		}
+		src.Check(schema, check)
+		if *dryRun {
+			ds, err := g.devices()
+			if err != nil {
+				return err
+			}
+			for _, d := range ds {
+				js, err := src.For(d)
+				if err != nil {
+					return fmt.Errorf("could not render the payload for %s: %v", d.Name, err)
+				}
+				fmt.Printf("\npayload for %s\n%s\n", d.Name, js)
+			}
+			return nil
+		}
+		return g.fanOutEach(func(d inventory.Device) runner.Task {
+			return func(ctx context.Context, conn *grpc.ClientConn, id int64) (string, error) {
+				js, err := src.For(d)
+				if err != nil {
+					return "", err
+				}
+				ri, err := op(ctx, conn, string(js), id)
+				if err != nil {
+					return "", err
+				}
+				return fmt.Sprintf("Request ID: %v, Response ID: %v", id, ri), nil
+			}
+		}, "config "+verb, 5)
+	}
+}
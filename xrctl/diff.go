@@ -0,0 +1,45 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/nleiva/clus2019/diff"
+	"github.com/nleiva/clus2019/runner"
+	xr "github.com/nleiva/xrgrpc"
+)
+
+func init() {
+	register(command{"diff", "Compare the running config with an intended YANG JSON file", diffConfig})
+}
+
+func diffConfig(g *globals, args []string) error {
+	fs := flag.NewFlagSet("diff", flag.ExitOnError)
+	// Intended YANG config; defaults to "yangocconfig.json"
+	ypath := fs.String("ypath", "../input/yangocconfig.json", "Intended YANG config")
+	strict := fs.Bool("strict", false, "Also report config only present on the router")
+	keys := fs.String("keys", strings.Join(diff.DefaultKeys, ","), "List keys to match entries by, in order of preference")
+	fs.Parse(args)
+
+	intended, err := ioutil.ReadFile(*ypath)
+	if err != nil {
+		return fmt.Errorf("could not read file: %v: %v", *ypath, err)
+	}
+	ds, err := g.devices()
+	if err != nil {
+		return err
+	}
+	opts := diff.Options{Keys: strings.Split(*keys, ","), Strict: *strict}
+	r := runner.Runner{
+		Workers:  g.workers,
+		Defaults: []xr.RouterOption{xr.WithTimeout(5)},
+	}
+	d, err := diff.Devices(os.Stdout, &r, ds, intended, opts)
+	if err != nil {
+		return err
+	}
+	return d.Err()
+}
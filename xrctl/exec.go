@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io/ioutil"
+
+	xr "github.com/nleiva/xrgrpc"
+	"google.golang.org/grpc"
+)
+
+func init() {
+	register(command{"show", "Run a show command", show})
+	register(command{"action", "Trigger a YANG action, e.g. ping", action})
+	register(command{"route", "Set an IPv6 route through the service layer API", route})
+}
+
+func show(g *globals, args []string) error {
+	fs := flag.NewFlagSet("show", flag.ExitOnError)
+	// CLI to issue; defaults to "show grpc status"
+	cli := fs.String("cli", "show grpc status", "Command to execute")
+	fs.Parse(args)
+
+	return g.fanOut(func(ctx context.Context, conn *grpc.ClientConn, id int64) (string, error) {
+		return xr.ShowCmdTextOutput(ctx, conn, *cli, id)
+	}, "output from", 5)
+}
+
+func action(g *globals, args []string) error {
+	fs := flag.NewFlagSet("action", flag.ExitOnError)
+	// Action to issue; defaults to "ping6.json"
+	act := fs.String("act", "../input/action/ping6.json", "Action to execute")
+	fs.Parse(args)
+
+	file, err := ioutil.ReadFile(*act)
+	if err != nil {
+		return fmt.Errorf("could not read file: %v: %v", *act, err)
+	}
+	return g.fanOut(func(ctx context.Context, conn *grpc.ClientConn, id int64) (string, error) {
+		return xr.ActionJSON(ctx, conn, string(file), id)
+	}, "output from", 20)
+}
+
+func route(g *globals, args []string) error {
+	fs := flag.NewFlagSet("route", flag.ExitOnError)
+	// IPv6 prefix to setup; defaults to "2001:db8::/32"
+	pfx := fs.String("pfx", "2001:db8::/32", "IPv6 prefix to setup")
+	// IPv6 next-hop to setup; defaults to "2001:db8:cafe::1"
+	nh := fs.String("nh", "2001:db8:cafe::1", "IPv6 next-hop to setup")
+	// Admin Distance
+	admdis := fs.Uint("ad", 2, "Administrative distance")
+	fs.Parse(args)
+
+	return g.fanOut(func(_ context.Context, conn *grpc.ClientConn, _ int64) (string, error) {
+		// CSCva95005: Return SL_NOT_CONNECTED when the init session is killed from the Client.
+		if err := xr.ClientInit(conn); err != nil {
+			return "", fmt.Errorf("failed to initialize connection, %v", err)
+		}
+		// VRF Register Operation (= 1),
+		if err := xr.VRFOperation(conn, 1, uint32(*admdis)); err != nil {
+			return "", fmt.Errorf("failed to register the VRF Operation, %v", err)
+		}
+		// VRF EOF Operation (= 3),
+		if err := xr.VRFOperation(conn, 3, uint32(*admdis)); err != nil {
+			return "", fmt.Errorf("failed to send VRF Operation EOF, %v", err)
+		}
+		// Route Add Operation (= 1),
+		if err := xr.SetRoute(conn, 1, *pfx, uint32(*admdis), *nh); err != nil {
+			return "", fmt.Errorf("failed to set Route, %v", err)
+		}
+		return fmt.Sprintf("%s via %s", *pfx, *nh), nil
+	}, "route set on", 5)
+}
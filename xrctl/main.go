@@ -0,0 +1,208 @@
+/*
+xrctl bundles the demo operations in a single binary. Global flags select the
+targets and how to reach them; the subcommand selects the operation:
+
+	xrctl [global flags] <command> [command flags]
+*/
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/nleiva/clus2019/inventory"
+	"github.com/nleiva/clus2019/runner"
+	xr "github.com/nleiva/xrgrpc"
+)
+
+// command is an xrctl subcommand. run parses its own flags from args.
+type command struct {
+	name  string
+	usage string
+	run   func(g *globals, args []string) error
+}
+
+// commands holds every subcommand, indexed by name.
+var commands = map[string]command{}
+
+func register(c command) {
+	commands[c.name] = c
+}
+
+// globals are the flags shared by every subcommand.
+type globals struct {
+	inventory.Target
+	host    string
+	user    string
+	pass    string
+	cert    string
+	timeout int
+	workers int
+	format  string
+}
+
+func (g *globals) addFlags(fs *flag.FlagSet) {
+	g.Target.AddFlags(fs)
+	fs.StringVar(&g.host, "host", "", "Target address, e.g. [2001:db8::1]:57344; bypasses the inventory")
+	fs.StringVar(&g.user, "user", "", "Username; overrides the inventory")
+	fs.StringVar(&g.pass, "pass", "", "Password; overrides the inventory")
+	fs.StringVar(&g.cert, "cert", "", "TLS certificate file; overrides the inventory")
+	fs.IntVar(&g.timeout, "timeout", 0, "Per-device timeout in seconds; overrides the inventory")
+	fs.IntVar(&g.workers, "workers", 10, "Devices to run against concurrently")
+	fs.StringVar(&g.format, "o", "text", "Output format: 'text' or 'json'")
+}
+
+// devices returns the selected targets with the flag overrides applied.
+func (g *globals) devices() ([]inventory.Device, error) {
+	var ds []inventory.Device
+	if g.host != "" {
+		ds = []inventory.Device{{Name: g.host, Host: g.host, Port: inventory.DefaultPort}}
+	} else {
+		var err error
+		ds, err = g.Devices()
+		if err != nil {
+			return nil, err
+		}
+	}
+	for i := range ds {
+		if g.user != "" {
+			ds[i].Username = g.user
+		}
+		if g.pass != "" {
+			ds[i].Password = g.pass
+		}
+		if g.cert != "" {
+			ds[i].Cert = g.cert
+		}
+		if g.timeout != 0 {
+			ds[i].Timeout = g.timeout
+		}
+	}
+	return ds, nil
+}
+
+// one returns the single selected target, for streaming commands.
+func (g *globals) one() (inventory.Device, error) {
+	ds, err := g.devices()
+	if err != nil {
+		return inventory.Device{}, err
+	}
+	if len(ds) != 1 {
+		return inventory.Device{}, fmt.Errorf("this command needs exactly one device, %d selected", len(ds))
+	}
+	return ds[0], nil
+}
+
+// fanOut runs t on the selected devices and prints the results. verb
+// introduces each successful output in text mode, e.g. "config from".
+func (g *globals) fanOut(t runner.Task, verb string, timeout int) error {
+	return g.fanOutEach(func(inventory.Device) runner.Task { return t }, verb, timeout)
+}
+
+// fanOutEach is like fanOut, but builds the Task for each device.
+func (g *globals) fanOutEach(task func(inventory.Device) runner.Task, verb string, timeout int) error {
+	ds, err := g.devices()
+	if err != nil {
+		return err
+	}
+	r := runner.Runner{
+		Workers:  g.workers,
+		Defaults: []xr.RouterOption{xr.WithTimeout(timeout)},
+	}
+	results := r.RunEach(ds, task)
+	if err = g.print(results, verb); err != nil {
+		return err
+	}
+	if n := runner.Failed(results); n > 0 {
+		return fmt.Errorf("%d of %d devices failed", n, len(results))
+	}
+	return nil
+}
+
+type jsonResult struct {
+	Device    string `json:"device"`
+	Host      string `json:"host"`
+	Output    string `json:"output,omitempty"`
+	Error     string `json:"error,omitempty"`
+	LatencyMs int64  `json:"latency_ms"`
+}
+
+func (g *globals) print(results []runner.Result, verb string) error {
+	switch g.format {
+	case "json":
+		out := make([]jsonResult, 0, len(results))
+		for _, r := range results {
+			jr := jsonResult{
+				Device:    r.Device,
+				Host:      r.Host,
+				Output:    r.Output,
+				LatencyMs: int64(r.Latency / time.Millisecond),
+			}
+			if r.Err != nil {
+				jr.Error = r.Err.Error()
+			}
+			out = append(out, jr)
+		}
+		b, err := json.MarshalIndent(out, "", "  ")
+		if err != nil {
+			return fmt.Errorf("could not marshall into JSON: %v", err)
+		}
+		fmt.Println(string(b))
+	case "text":
+		for _, r := range results {
+			if r.Err != nil {
+				continue
+			}
+			fmt.Printf("\n%s %s\n %s\n", verb, r.Host, r.Output)
+		}
+		fmt.Println()
+		runner.Report(os.Stdout, results)
+	default:
+		return fmt.Errorf("output format '%v' not supported", g.format)
+	}
+	return nil
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "Usage: xrctl [global flags] <command> [command flags]\n\nCommands:\n")
+	names := make([]string, 0, len(commands))
+	for n := range commands {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	for _, n := range names {
+		fmt.Fprintf(os.Stderr, "  %-10s %s\n", n, commands[n].usage)
+	}
+	fmt.Fprintf(os.Stderr, "\nGlobal flags:\n")
+	flag.PrintDefaults()
+}
+
+func main() {
+	var g globals
+	g.addFlags(flag.CommandLine)
+	flag.Usage = usage
+	flag.Parse()
+
+	if flag.NArg() == 0 {
+		usage()
+		os.Exit(2)
+	}
+	c, ok := commands[flag.Arg(0)]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n", flag.Arg(0))
+		usage()
+		os.Exit(2)
+	}
+
+	start := time.Now()
+	err := c.run(&g, flag.Args()[1:])
+	log.Printf("This process took %s\n", time.Since(start))
+	if err != nil {
+		log.Fatalf("%s: %v", c.name, err)
+	}
+}
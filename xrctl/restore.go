@@ -0,0 +1,167 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/nleiva/clus2019/backup"
+	"github.com/nleiva/clus2019/diff"
+	xr "github.com/nleiva/xrgrpc"
+	"google.golang.org/grpc"
+)
+
+func init() {
+	register(command{"restore", "Restore a backup snapshot, showing the changes first", restore})
+}
+
+func restore(g *globals, args []string) error {
+	fs := flag.NewFlagSet("restore", flag.ExitOnError)
+	dir := fs.String("dir", "../backups", "Directory the snapshots are stored in")
+	file := fs.String("snapshot", "", "Snapshot file to restore; defaults to the latest JSON snapshot of the device")
+	yes := fs.Bool("yes", false, "Apply without asking for confirmation")
+	fs.Parse(args)
+
+	// ID for the transaction.
+	var id int64 = 1
+
+	d, err := g.one()
+	if err != nil {
+		return err
+	}
+	if *file == "" {
+		store := &backup.Store{Dir: *dir}
+		snap, err := store.Latest(d.Name, backup.JSON)
+		if err != nil {
+			return err
+		}
+		*file = snap.File
+	}
+	data, err := ioutil.ReadFile(*file)
+	if err != nil {
+		return fmt.Errorf("could not read file: %v: %v", *file, err)
+	}
+
+	router, err := d.Router(xr.WithTimeout(30))
+	if err != nil {
+		return err
+	}
+	// connect starts a session, with the deadline of the router's
+	// timeout from then on.
+	connect := func() (*grpc.ClientConn, context.Context, error) {
+		conn, ctx, err := xr.Connect(*router)
+		if err != nil {
+			return nil, nil, fmt.Errorf("could not setup a client connection to %s, %v", router.Host, err)
+		}
+		return conn, ctx, nil
+	}
+	conn, ctx, err := connect()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if conn != nil {
+			conn.Close()
+		}
+	}()
+
+	// plan prints what the restore changes and reports whether there is
+	// anything to do.
+	var plan func(ctx context.Context, conn *grpc.ClientConn) (bool, error)
+	var apply func(ctx context.Context, conn *grpc.ClientConn) error
+	if filepath.Ext(*file) == "."+backup.CLI {
+		plan = func(ctx context.Context, conn *grpc.ClientConn) (bool, error) {
+			out, err := xr.ShowCmdTextOutput(ctx, conn, "show running-config", id)
+			if err != nil {
+				return false, fmt.Errorf("could not get the running config from %s, %v", router.Host, err)
+			}
+			running := diff.SplitLines(backup.CleanConfig(out))
+			return diff.Unified(os.Stdout, running, diff.SplitLines(string(data)), 3), nil
+		}
+		apply = func(ctx context.Context, conn *grpc.ClientConn) error {
+			return xr.CommitReplace(ctx, conn, string(data), "", id)
+		}
+	} else {
+		// Snapshots keep the "data" object GetConfig wraps the config in.
+		doc, err := diff.Decode(data)
+		if err != nil {
+			return fmt.Errorf("could not parse %s, %v", *file, err)
+		}
+		js, err := json.Marshal(doc)
+		if err != nil {
+			return fmt.Errorf("could not marshall into JSON: %v", err)
+		}
+		paths, err := diff.Paths(js)
+		if err != nil {
+			return err
+		}
+		plan = func(ctx context.Context, conn *grpc.ClientConn) (bool, error) {
+			out, err := xr.GetConfig(ctx, conn, paths, id)
+			if err != nil {
+				return false, fmt.Errorf("could not get the config from %s, %v", router.Host, err)
+			}
+			cs, err := diff.JSON([]byte(out), js, diff.Options{Strict: true})
+			if err != nil {
+				return false, err
+			}
+			if len(cs) > 0 {
+				fmt.Printf("%s\n", diff.Summary(cs))
+			}
+			return diff.Print(os.Stdout, cs), nil
+		}
+		apply = func(ctx context.Context, conn *grpc.ClientConn) error {
+			_, err := xr.ReplaceConfig(ctx, conn, string(js), id)
+			return err
+		}
+	}
+
+	fmt.Printf("\nrestore plan for %s from %s\n\n", router.Host, *file)
+	changed, err := plan(ctx, conn)
+	if err != nil {
+		return err
+	}
+	if !changed {
+		fmt.Printf("\nnothing to restore, the running config matches the snapshot\n\n")
+		return nil
+	}
+	if !*yes {
+		if !ask("\napply these changes?") {
+			fmt.Printf("\nrestore cancelled\n\n")
+			return nil
+		}
+		// The session deadline may have passed while waiting, so use a
+		// new one.
+		conn.Close()
+		if conn, ctx, err = connect(); err != nil {
+			return err
+		}
+	}
+	if err = apply(ctx, conn); err != nil {
+		return fmt.Errorf("failed to restore the config on %s, %v", router.Host, err)
+	}
+	fmt.Printf("\nconfig restored on %s\n\nremaining differences:\n", router.Host)
+	if changed, err = plan(ctx, conn); err != nil {
+		return err
+	}
+	if !changed {
+		fmt.Printf("none\n\n")
+	}
+	return nil
+}
+
+// ask prompts for a yes/no answer on stdin.
+func ask(question string) bool {
+	fmt.Printf("%s [y/N] ", question)
+	answer, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil {
+		return false
+	}
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+
+	proto "github.com/golang/protobuf/proto"
+	xr "github.com/nleiva/xrgrpc"
+	"github.com/nleiva/xrgrpc/proto/telemetry"
+)
+
+func init() {
+	register(command{"subscribe", "Stream a telemetry subscription", subscribe})
+}
+
+func prettyprint(b []byte) ([]byte, error) {
+	var out bytes.Buffer
+	err := json.Indent(&out, b, "", "  ")
+	return out.Bytes(), err
+}
+
+func subscribe(g *globals, args []string) error {
+	fs := flag.NewFlagSet("subscribe", flag.ExitOnError)
+	// Subs options; LLDP, we will add some more
+	p := fs.String("subs", "LLDP", "Telemetry Subscription")
+	// Encoding option; defaults to GPBKV
+	enc := fs.String("enc", "gpbkv", "Encoding: 'json', 'gpb' or 'gpbkv'")
+	fs.Parse(args)
+
+	mape := map[string]int64{
+		"gpb":   2,
+		"gpbkv": 3,
+		"json":  4,
+	}
+	e, ok := mape[*enc]
+	if !ok {
+		return fmt.Errorf("encoding option '%v' not supported", *enc)
+	}
+
+	// ID for the transaction.
+	var id int64 = 1
+
+	d, err := g.one()
+	if err != nil {
+		return err
+	}
+	router, err := d.Router(xr.WithTimeout(60))
+	if err != nil {
+		return err
+	}
+	conn, ctx, err := xr.Connect(*router)
+	if err != nil {
+		return fmt.Errorf("could not setup a client connection to %s, %v", router.Host, err)
+	}
+	defer conn.Close()
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	ch, ech, err := xr.GetSubscription(ctx, conn, *p, id, e)
+	if err != nil {
+		return fmt.Errorf("could not setup Telemetry Subscription: %v", err)
+	}
+
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, os.Interrupt)
+	defer signal.Stop(c)
+
+	go func() {
+		select {
+		case <-c:
+			fmt.Fprintf(os.Stderr, "\nmanually cancelled the session to %v\n\n", router.Host)
+			cancel()
+		case <-ctx.Done():
+			// Timeout: "context deadline exceeded", rather than the end of
+			// the command.
+			if err := ctx.Err(); err == context.DeadlineExceeded {
+				fmt.Fprintf(os.Stderr, "\ngRPC session timed out after %v seconds: %v\n\n", router.Timeout, err)
+			}
+		case err := <-ech:
+			// Session canceled: "context canceled"
+			fmt.Fprintf(os.Stderr, "\ngRPC session to %v failed: %v\n\n", router.Host, err)
+			cancel()
+		}
+	}()
+
+	for tele := range ch {
+		message := new(telemetry.Telemetry)
+		if err := proto.Unmarshal(tele, message); err != nil {
+			return fmt.Errorf("could not unmarshall the message: %v", err)
+		}
+		b, err := json.Marshal(message)
+		if err != nil {
+			return fmt.Errorf("could not marshall into JSON: %v", err)
+		}
+		if g.format == "json" {
+			fmt.Println(string(b))
+			continue
+		}
+		fmt.Printf("Time %v, Path: %v\n", message.GetMsgTimestamp(), message.GetEncodingPath())
+		bjs, err := prettyprint(b)
+		if err != nil {
+			return fmt.Errorf("could not pretty-print the message: %v", err)
+		}
+		fmt.Println(string(bjs))
+	}
+	return nil
+}
@@ -0,0 +1,467 @@
+package xrmock
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/nleiva/clus2019/yang"
+	"github.com/pkg/errors"
+)
+
+// guessKeys are tried, in order, as the key of a list the YANG modules
+// don't describe. Most OpenConfig and IOS XR lists are keyed by one of them.
+var guessKeys = []string{"name", "index", "id", "ip", "interface-name", "prefix", "key"}
+
+// Datastore holds a YANG JSON document, RFC 7951 style, that the config
+// operations of a router read and change.
+type Datastore struct {
+	// Schema, when set, gives the keys of lists; without it, or for lists
+	// of modules it doesn't have, they're guessed from guessKeys.
+	Schema *yang.Schema
+
+	mu   sync.Mutex
+	data map[string]interface{}
+}
+
+// NewDatastore returns a Datastore holding js, or an empty one for an
+// empty js.
+func NewDatastore(js []byte) (*Datastore, error) {
+	d := &Datastore{data: make(map[string]interface{})}
+	if len(bytes.TrimSpace(js)) == 0 {
+		return d, nil
+	}
+	doc, err := decode(js)
+	if err != nil {
+		return nil, err
+	}
+	d.data = doc
+	return d, nil
+}
+
+// LoadDatastore returns a Datastore holding the document in file, or an
+// empty one if file is "".
+func LoadDatastore(file string) (*Datastore, error) {
+	if file == "" {
+		return NewDatastore(nil)
+	}
+	b, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not read file %s", file)
+	}
+	d, err := NewDatastore(b)
+	return d, errors.Wrapf(err, "could not load %s", file)
+}
+
+func decode(js []byte) (map[string]interface{}, error) {
+	d := json.NewDecoder(bytes.NewReader(js))
+	d.UseNumber()
+	var doc map[string]interface{}
+	if err := d.Decode(&doc); err != nil {
+		return nil, errors.Wrap(err, "could not parse the JSON payload")
+	}
+	return doc, nil
+}
+
+func encode(v interface{}) string {
+	b, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		// Only what decode made gets here.
+		panic(err)
+	}
+	return string(b)
+}
+
+// JSON returns the whole document.
+func (d *Datastore) JSON() string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return encode(d.data)
+}
+
+// Get returns the parts of the document selected by the paths in js, in
+// the format GetConfig takes: a node with a null, [null] or {} value
+// selects all of it, objects select their members, and list entries pick
+// the entries whose leaves match theirs. Nothing selected gives "".
+func (d *Datastore) Get(js string) (string, error) {
+	req, err := decode([]byte(js))
+	if err != nil {
+		return "", err
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	out := filter(d.data, req)
+	if out == nil {
+		return "", nil
+	}
+	return encode(out), nil
+}
+
+// all says whether a request selects a whole node.
+func all(req interface{}) bool {
+	switch r := req.(type) {
+	case nil:
+		return true
+	case []interface{}:
+		return len(r) == 0 || len(r) == 1 && r[0] == nil
+	case map[string]interface{}:
+		return len(r) == 0
+	}
+	return false
+}
+
+// filter returns what req selects of v, or nil.
+func filter(v, req interface{}) interface{} {
+	if all(req) {
+		return v
+	}
+	switch r := req.(type) {
+	case map[string]interface{}:
+		obj, ok := v.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		out := make(map[string]interface{})
+		for k, sub := range r {
+			c, ok := obj[k]
+			if !ok {
+				continue
+			}
+			if f := filter(c, sub); f != nil {
+				out[k] = f
+			}
+		}
+		if len(out) == 0 {
+			return nil
+		}
+		return out
+	case []interface{}:
+		list, ok := v.([]interface{})
+		if !ok {
+			return nil
+		}
+		var out []interface{}
+		for _, e := range list {
+			entry, ok := e.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			for _, want := range r {
+				if f := filterEntry(entry, want); f != nil {
+					out = append(out, f)
+					break
+				}
+			}
+		}
+		if len(out) == 0 {
+			return nil
+		}
+		return out
+	}
+	// A leaf with a value selects the leaf.
+	return v
+}
+
+// filterEntry returns what want selects of a list entry: its leaves have
+// to match, and what's left selects inside the entry.
+func filterEntry(entry map[string]interface{}, want interface{}) interface{} {
+	w, ok := want.(map[string]interface{})
+	if !ok || len(w) == 0 {
+		return entry
+	}
+	sub := make(map[string]interface{})
+	for k, v := range w {
+		if scalar(v) {
+			if !equal(entry[k], v) {
+				return nil
+			}
+			continue
+		}
+		sub[k] = v
+	}
+	if len(sub) == 0 {
+		return entry
+	}
+	out, _ := filter(entry, sub).(map[string]interface{})
+	if out == nil {
+		return nil
+	}
+	for k, v := range w {
+		if scalar(v) {
+			out[k] = entry[k]
+		}
+	}
+	return out
+}
+
+func scalar(v interface{}) bool {
+	switch v.(type) {
+	case map[string]interface{}, []interface{}, nil:
+		return false
+	}
+	return true
+}
+
+func equal(a, b interface{}) bool {
+	return reflect.DeepEqual(a, b)
+}
+
+// Merge merges the document in js into the one held: objects member by
+// member, lists entry by entry, by key.
+func (d *Datastore) Merge(js string) error {
+	doc, err := decode([]byte(js))
+	if err != nil {
+		return err
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.merge(d.data, doc, d.top)
+	return nil
+}
+
+// Replace replaces the top-level nodes in js with the ones given.
+func (d *Datastore) Replace(js string) error {
+	doc, err := decode([]byte(js))
+	if err != nil {
+		return err
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for k, v := range doc {
+		d.data[k] = v
+	}
+	return nil
+}
+
+// ReplaceAll makes js the whole document, as a commit replace does.
+func (d *Datastore) ReplaceAll(js string) error {
+	doc, err := decode([]byte(js))
+	if err != nil {
+		return err
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.data = doc
+	return nil
+}
+
+// Delete removes what js names: nodes with an empty value whole, leaves
+// whatever their value, and list entries given by their keys alone.
+// Deleting what isn't there isn't an error.
+func (d *Datastore) Delete(js string) error {
+	doc, err := decode([]byte(js))
+	if err != nil {
+		return err
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.delete(d.data, doc, d.top)
+	return nil
+}
+
+// schemaNode finds the schema node of member k, below n or at the top for
+// a nil n, if there's a schema to look in.
+type schemaNode func(k string) (*yang.Node, schemaNode)
+
+// top looks up top-level members, which carry their module name.
+func (d *Datastore) top(k string) (*yang.Node, schemaNode) {
+	mod, name := split(k, "")
+	if d.Schema == nil || mod == "" {
+		return nil, nil
+	}
+	m := d.Schema.Module(mod)
+	if m == nil {
+		return nil, nil
+	}
+	for _, n := range m.Nodes() {
+		if n.Name == name {
+			return n, below(n)
+		}
+	}
+	return nil, nil
+}
+
+// below looks up the members of n, which only carry a module name when it
+// isn't n's.
+func below(n *yang.Node) schemaNode {
+	return func(k string) (*yang.Node, schemaNode) {
+		mod, name := split(k, n.Module)
+		c := n.Child(mod, name)
+		if c == nil {
+			return nil, nil
+		}
+		return c, below(c)
+	}
+}
+
+func split(k, def string) (string, string) {
+	if i := strings.Index(k, ":"); i >= 0 {
+		return k[:i], k[i+1:]
+	}
+	return def, k
+}
+
+func (d *Datastore) merge(dst, src map[string]interface{}, look schemaNode) {
+	for k, v := range src {
+		var n *yang.Node
+		var next schemaNode
+		if look != nil {
+			n, next = look(k)
+		}
+		switch sv := v.(type) {
+		case map[string]interface{}:
+			if dv, ok := dst[k].(map[string]interface{}); ok {
+				d.merge(dv, sv, next)
+				continue
+			}
+		case []interface{}:
+			if dv, ok := dst[k].([]interface{}); ok {
+				dst[k] = d.mergeList(dv, sv, n, next)
+				continue
+			}
+		}
+		dst[k] = v
+	}
+}
+
+func (d *Datastore) mergeList(dst, src []interface{}, n *yang.Node, next schemaNode) []interface{} {
+	for _, e := range src {
+		se, ok := e.(map[string]interface{})
+		if !ok {
+			// Leaf-lists add the values they don't have.
+			if !contains(dst, e) {
+				dst = append(dst, e)
+			}
+			continue
+		}
+		if i := find(dst, se, keys(n, se)); i >= 0 {
+			d.merge(dst[i].(map[string]interface{}), se, next)
+			continue
+		}
+		dst = append(dst, se)
+	}
+	return dst
+}
+
+func (d *Datastore) delete(dst, src map[string]interface{}, look schemaNode) {
+	for k, v := range src {
+		var n *yang.Node
+		var next schemaNode
+		if look != nil {
+			n, next = look(k)
+		}
+		switch sv := v.(type) {
+		case map[string]interface{}:
+			if dv, ok := dst[k].(map[string]interface{}); ok && len(sv) > 0 {
+				d.delete(dv, sv, next)
+				continue
+			}
+		case []interface{}:
+			if dv, ok := dst[k].([]interface{}); ok && !all(sv) {
+				dst[k] = d.deleteList(dv, sv, n, next)
+				continue
+			}
+		}
+		delete(dst, k)
+	}
+}
+
+func (d *Datastore) deleteList(dst, src []interface{}, n *yang.Node, next schemaNode) []interface{} {
+	for _, e := range src {
+		se, ok := e.(map[string]interface{})
+		if !ok {
+			for i, v := range dst {
+				if equal(v, e) {
+					dst = append(dst[:i], dst[i+1:]...)
+					break
+				}
+			}
+			continue
+		}
+		ks := keys(n, se)
+		i := find(dst, se, ks)
+		if i < 0 {
+			continue
+		}
+		rest := make(map[string]interface{})
+		for k, v := range se {
+			if !containsString(ks, k) {
+				rest[k] = v
+			}
+		}
+		if len(rest) == 0 {
+			dst = append(dst[:i], dst[i+1:]...)
+			continue
+		}
+		d.delete(dst[i].(map[string]interface{}), rest, next)
+	}
+	return dst
+}
+
+// keys returns the key leaves of list n, or a guess from an entry.
+func keys(n *yang.Node, entry map[string]interface{}) []string {
+	if n != nil && len(n.Keys) > 0 {
+		return n.Keys
+	}
+	for _, k := range guessKeys {
+		if _, ok := entry[k]; ok {
+			return []string{k}
+		}
+	}
+	// Without a key, an entry only matches an identical one, by its
+	// leaves.
+	var ks []string
+	for k, v := range entry {
+		if scalar(v) {
+			ks = append(ks, k)
+		}
+	}
+	sort.Strings(ks)
+	return ks
+}
+
+// find returns the index of the entry of list with the same keys as e.
+func find(list []interface{}, e map[string]interface{}, ks []string) int {
+	if len(ks) == 0 {
+		return -1
+	}
+	for i, v := range list {
+		entry, ok := v.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		match := true
+		for _, k := range ks {
+			if !equal(entry[k], e[k]) {
+				match = false
+				break
+			}
+		}
+		if match {
+			return i
+		}
+	}
+	return -1
+}
+
+func contains(list []interface{}, v interface{}) bool {
+	for _, e := range list {
+		if equal(e, v) {
+			return true
+		}
+	}
+	return false
+}
+
+func containsString(list []string, s string) bool {
+	for _, e := range list {
+		if e == s {
+			return true
+		}
+	}
+	return false
+}
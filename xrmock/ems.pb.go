@@ -0,0 +1,704 @@
+package xrmock
+
+import (
+	proto "github.com/golang/protobuf/proto"
+)
+
+// The messages of the IOS XR ems_grpc.proto, laid out as protoc-gen-go lays
+// them out so the proto package can marshal them from the struct tags. Only
+// the fields the xrgrpc client sets or reads are declared; the rest are
+// kept in XXX_unrecognized. They aren't registered with the proto package:
+// the names belong to xrgrpc's own copy, which may be in the same binary.
+
+// CommitResult is the outcome of a commit.
+type CommitResult int32
+
+const (
+	CommitResult_CHANGE    CommitResult = 0
+	CommitResult_NO_CHANGE CommitResult = 1
+	CommitResult_FAIL      CommitResult = 2
+)
+
+var CommitResult_name = map[int32]string{
+	0: "CHANGE",
+	1: "NO_CHANGE",
+	2: "FAIL",
+}
+
+var CommitResult_value = map[string]int32{
+	"CHANGE":    0,
+	"NO_CHANGE": 1,
+	"FAIL":      2,
+}
+
+func (x CommitResult) String() string {
+	return proto.EnumName(CommitResult_name, int32(x))
+}
+
+type ConfigGetArgs struct {
+	ReqId                int64    `protobuf:"varint,1,opt,name=ReqId,proto3" json:"ReqId,omitempty"`
+	Yangpathjson         string   `protobuf:"bytes,2,opt,name=yangpathjson,proto3" json:"yangpathjson,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ConfigGetArgs) Reset()         { *m = ConfigGetArgs{} }
+func (m *ConfigGetArgs) String() string { return proto.CompactTextString(m) }
+func (*ConfigGetArgs) ProtoMessage()    {}
+
+func (m *ConfigGetArgs) GetReqId() int64 {
+	if m != nil {
+		return m.ReqId
+	}
+	return 0
+}
+
+func (m *ConfigGetArgs) GetYangpathjson() string {
+	if m != nil {
+		return m.Yangpathjson
+	}
+	return ""
+}
+
+type ConfigGetReply struct {
+	ResReqId             int64    `protobuf:"varint,1,opt,name=ResReqId,proto3" json:"ResReqId,omitempty"`
+	Yangjson             string   `protobuf:"bytes,2,opt,name=yangjson,proto3" json:"yangjson,omitempty"`
+	Errors               string   `protobuf:"bytes,3,opt,name=errors,proto3" json:"errors,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ConfigGetReply) Reset()         { *m = ConfigGetReply{} }
+func (m *ConfigGetReply) String() string { return proto.CompactTextString(m) }
+func (*ConfigGetReply) ProtoMessage()    {}
+
+func (m *ConfigGetReply) GetResReqId() int64 {
+	if m != nil {
+		return m.ResReqId
+	}
+	return 0
+}
+
+func (m *ConfigGetReply) GetYangjson() string {
+	if m != nil {
+		return m.Yangjson
+	}
+	return ""
+}
+
+func (m *ConfigGetReply) GetErrors() string {
+	if m != nil {
+		return m.Errors
+	}
+	return ""
+}
+
+type GetOperArgs struct {
+	ReqId                int64    `protobuf:"varint,1,opt,name=ReqId,proto3" json:"ReqId,omitempty"`
+	Yangpathjson         string   `protobuf:"bytes,2,opt,name=yangpathjson,proto3" json:"yangpathjson,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *GetOperArgs) Reset()         { *m = GetOperArgs{} }
+func (m *GetOperArgs) String() string { return proto.CompactTextString(m) }
+func (*GetOperArgs) ProtoMessage()    {}
+
+func (m *GetOperArgs) GetReqId() int64 {
+	if m != nil {
+		return m.ReqId
+	}
+	return 0
+}
+
+func (m *GetOperArgs) GetYangpathjson() string {
+	if m != nil {
+		return m.Yangpathjson
+	}
+	return ""
+}
+
+type GetOperReply struct {
+	ResReqId             int64    `protobuf:"varint,1,opt,name=ResReqId,proto3" json:"ResReqId,omitempty"`
+	Yangjson             string   `protobuf:"bytes,2,opt,name=yangjson,proto3" json:"yangjson,omitempty"`
+	Errors               string   `protobuf:"bytes,3,opt,name=errors,proto3" json:"errors,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *GetOperReply) Reset()         { *m = GetOperReply{} }
+func (m *GetOperReply) String() string { return proto.CompactTextString(m) }
+func (*GetOperReply) ProtoMessage()    {}
+
+func (m *GetOperReply) GetResReqId() int64 {
+	if m != nil {
+		return m.ResReqId
+	}
+	return 0
+}
+
+func (m *GetOperReply) GetYangjson() string {
+	if m != nil {
+		return m.Yangjson
+	}
+	return ""
+}
+
+func (m *GetOperReply) GetErrors() string {
+	if m != nil {
+		return m.Errors
+	}
+	return ""
+}
+
+type ConfigArgs struct {
+	ReqId                int64    `protobuf:"varint,1,opt,name=ReqId,proto3" json:"ReqId,omitempty"`
+	Yangjson             string   `protobuf:"bytes,2,opt,name=yangjson,proto3" json:"yangjson,omitempty"`
+	Confirmed            bool     `protobuf:"varint,3,opt,name=Confirmed,proto3" json:"Confirmed,omitempty"`
+	ConfirmTimeout       uint32   `protobuf:"varint,4,opt,name=ConfirmTimeout,proto3" json:"ConfirmTimeout,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ConfigArgs) Reset()         { *m = ConfigArgs{} }
+func (m *ConfigArgs) String() string { return proto.CompactTextString(m) }
+func (*ConfigArgs) ProtoMessage()    {}
+
+func (m *ConfigArgs) GetReqId() int64 {
+	if m != nil {
+		return m.ReqId
+	}
+	return 0
+}
+
+func (m *ConfigArgs) GetYangjson() string {
+	if m != nil {
+		return m.Yangjson
+	}
+	return ""
+}
+
+func (m *ConfigArgs) GetConfirmed() bool {
+	if m != nil {
+		return m.Confirmed
+	}
+	return false
+}
+
+func (m *ConfigArgs) GetConfirmTimeout() uint32 {
+	if m != nil {
+		return m.ConfirmTimeout
+	}
+	return 0
+}
+
+type ConfigReply struct {
+	ResReqId             int64    `protobuf:"varint,1,opt,name=ResReqId,proto3" json:"ResReqId,omitempty"`
+	Errors               string   `protobuf:"bytes,2,opt,name=errors,proto3" json:"errors,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ConfigReply) Reset()         { *m = ConfigReply{} }
+func (m *ConfigReply) String() string { return proto.CompactTextString(m) }
+func (*ConfigReply) ProtoMessage()    {}
+
+func (m *ConfigReply) GetResReqId() int64 {
+	if m != nil {
+		return m.ResReqId
+	}
+	return 0
+}
+
+func (m *ConfigReply) GetErrors() string {
+	if m != nil {
+		return m.Errors
+	}
+	return ""
+}
+
+type CliConfigArgs struct {
+	ReqId                int64    `protobuf:"varint,1,opt,name=ReqId,proto3" json:"ReqId,omitempty"`
+	Cli                  string   `protobuf:"bytes,2,opt,name=cli,proto3" json:"cli,omitempty"`
+	Confirmed            bool     `protobuf:"varint,3,opt,name=Confirmed,proto3" json:"Confirmed,omitempty"`
+	ConfirmTimeout       uint32   `protobuf:"varint,4,opt,name=ConfirmTimeout,proto3" json:"ConfirmTimeout,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *CliConfigArgs) Reset()         { *m = CliConfigArgs{} }
+func (m *CliConfigArgs) String() string { return proto.CompactTextString(m) }
+func (*CliConfigArgs) ProtoMessage()    {}
+
+func (m *CliConfigArgs) GetReqId() int64 {
+	if m != nil {
+		return m.ReqId
+	}
+	return 0
+}
+
+func (m *CliConfigArgs) GetCli() string {
+	if m != nil {
+		return m.Cli
+	}
+	return ""
+}
+
+func (m *CliConfigArgs) GetConfirmed() bool {
+	if m != nil {
+		return m.Confirmed
+	}
+	return false
+}
+
+func (m *CliConfigArgs) GetConfirmTimeout() uint32 {
+	if m != nil {
+		return m.ConfirmTimeout
+	}
+	return 0
+}
+
+type CliConfigReply struct {
+	ResReqId             int64    `protobuf:"varint,1,opt,name=ResReqId,proto3" json:"ResReqId,omitempty"`
+	Errors               string   `protobuf:"bytes,2,opt,name=errors,proto3" json:"errors,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *CliConfigReply) Reset()         { *m = CliConfigReply{} }
+func (m *CliConfigReply) String() string { return proto.CompactTextString(m) }
+func (*CliConfigReply) ProtoMessage()    {}
+
+func (m *CliConfigReply) GetResReqId() int64 {
+	if m != nil {
+		return m.ResReqId
+	}
+	return 0
+}
+
+func (m *CliConfigReply) GetErrors() string {
+	if m != nil {
+		return m.Errors
+	}
+	return ""
+}
+
+type CommitReplaceArgs struct {
+	ReqId                int64    `protobuf:"varint,1,opt,name=ReqId,proto3" json:"ReqId,omitempty"`
+	Cli                  string   `protobuf:"bytes,2,opt,name=cli,proto3" json:"cli,omitempty"`
+	Yangjson             string   `protobuf:"bytes,3,opt,name=yangjson,proto3" json:"yangjson,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *CommitReplaceArgs) Reset()         { *m = CommitReplaceArgs{} }
+func (m *CommitReplaceArgs) String() string { return proto.CompactTextString(m) }
+func (*CommitReplaceArgs) ProtoMessage()    {}
+
+func (m *CommitReplaceArgs) GetReqId() int64 {
+	if m != nil {
+		return m.ReqId
+	}
+	return 0
+}
+
+func (m *CommitReplaceArgs) GetCli() string {
+	if m != nil {
+		return m.Cli
+	}
+	return ""
+}
+
+func (m *CommitReplaceArgs) GetYangjson() string {
+	if m != nil {
+		return m.Yangjson
+	}
+	return ""
+}
+
+type CommitReplaceReply struct {
+	ResReqId             int64    `protobuf:"varint,1,opt,name=ResReqId,proto3" json:"ResReqId,omitempty"`
+	Errors               string   `protobuf:"bytes,2,opt,name=errors,proto3" json:"errors,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *CommitReplaceReply) Reset()         { *m = CommitReplaceReply{} }
+func (m *CommitReplaceReply) String() string { return proto.CompactTextString(m) }
+func (*CommitReplaceReply) ProtoMessage()    {}
+
+func (m *CommitReplaceReply) GetResReqId() int64 {
+	if m != nil {
+		return m.ResReqId
+	}
+	return 0
+}
+
+func (m *CommitReplaceReply) GetErrors() string {
+	if m != nil {
+		return m.Errors
+	}
+	return ""
+}
+
+type CommitMsg struct {
+	Label                string   `protobuf:"bytes,1,opt,name=label,proto3" json:"label,omitempty"`
+	Comment              string   `protobuf:"bytes,2,opt,name=comment,proto3" json:"comment,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *CommitMsg) Reset()         { *m = CommitMsg{} }
+func (m *CommitMsg) String() string { return proto.CompactTextString(m) }
+func (*CommitMsg) ProtoMessage()    {}
+
+func (m *CommitMsg) GetLabel() string {
+	if m != nil {
+		return m.Label
+	}
+	return ""
+}
+
+func (m *CommitMsg) GetComment() string {
+	if m != nil {
+		return m.Comment
+	}
+	return ""
+}
+
+type CommitArgs struct {
+	Msg                  *CommitMsg `protobuf:"bytes,1,opt,name=msg,proto3" json:"msg,omitempty"`
+	ReqId                int64      `protobuf:"varint,2,opt,name=ReqId,proto3" json:"ReqId,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}   `json:"-"`
+	XXX_unrecognized     []byte     `json:"-"`
+	XXX_sizecache        int32      `json:"-"`
+}
+
+func (m *CommitArgs) Reset()         { *m = CommitArgs{} }
+func (m *CommitArgs) String() string { return proto.CompactTextString(m) }
+func (*CommitArgs) ProtoMessage()    {}
+
+func (m *CommitArgs) GetMsg() *CommitMsg {
+	if m != nil {
+		return m.Msg
+	}
+	return nil
+}
+
+func (m *CommitArgs) GetReqId() int64 {
+	if m != nil {
+		return m.ReqId
+	}
+	return 0
+}
+
+type CommitReply struct {
+	Result               CommitResult `protobuf:"varint,1,opt,name=result,proto3,enum=IOSXRExtensibleManagabilityService.CommitResult" json:"result,omitempty"`
+	ResReqId             int64        `protobuf:"varint,2,opt,name=ResReqId,proto3" json:"ResReqId,omitempty"`
+	Errors               string       `protobuf:"bytes,3,opt,name=errors,proto3" json:"errors,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}     `json:"-"`
+	XXX_unrecognized     []byte       `json:"-"`
+	XXX_sizecache        int32        `json:"-"`
+}
+
+func (m *CommitReply) Reset()         { *m = CommitReply{} }
+func (m *CommitReply) String() string { return proto.CompactTextString(m) }
+func (*CommitReply) ProtoMessage()    {}
+
+func (m *CommitReply) GetResult() CommitResult {
+	if m != nil {
+		return m.Result
+	}
+	return CommitResult_CHANGE
+}
+
+func (m *CommitReply) GetResReqId() int64 {
+	if m != nil {
+		return m.ResReqId
+	}
+	return 0
+}
+
+func (m *CommitReply) GetErrors() string {
+	if m != nil {
+		return m.Errors
+	}
+	return ""
+}
+
+type DiscardChangesArgs struct {
+	ReqId                int64    `protobuf:"varint,1,opt,name=ReqId,proto3" json:"ReqId,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *DiscardChangesArgs) Reset()         { *m = DiscardChangesArgs{} }
+func (m *DiscardChangesArgs) String() string { return proto.CompactTextString(m) }
+func (*DiscardChangesArgs) ProtoMessage()    {}
+
+func (m *DiscardChangesArgs) GetReqId() int64 {
+	if m != nil {
+		return m.ReqId
+	}
+	return 0
+}
+
+type DiscardChangesReply struct {
+	ResReqId             int64    `protobuf:"varint,1,opt,name=ResReqId,proto3" json:"ResReqId,omitempty"`
+	Errors               string   `protobuf:"bytes,2,opt,name=errors,proto3" json:"errors,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *DiscardChangesReply) Reset()         { *m = DiscardChangesReply{} }
+func (m *DiscardChangesReply) String() string { return proto.CompactTextString(m) }
+func (*DiscardChangesReply) ProtoMessage()    {}
+
+func (m *DiscardChangesReply) GetResReqId() int64 {
+	if m != nil {
+		return m.ResReqId
+	}
+	return 0
+}
+
+func (m *DiscardChangesReply) GetErrors() string {
+	if m != nil {
+		return m.Errors
+	}
+	return ""
+}
+
+type ShowCmdArgs struct {
+	ReqId                int64    `protobuf:"varint,1,opt,name=ReqId,proto3" json:"ReqId,omitempty"`
+	Cli                  string   `protobuf:"bytes,2,opt,name=cli,proto3" json:"cli,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ShowCmdArgs) Reset()         { *m = ShowCmdArgs{} }
+func (m *ShowCmdArgs) String() string { return proto.CompactTextString(m) }
+func (*ShowCmdArgs) ProtoMessage()    {}
+
+func (m *ShowCmdArgs) GetReqId() int64 {
+	if m != nil {
+		return m.ReqId
+	}
+	return 0
+}
+
+func (m *ShowCmdArgs) GetCli() string {
+	if m != nil {
+		return m.Cli
+	}
+	return ""
+}
+
+type ShowCmdTextReply struct {
+	ResReqId             int64    `protobuf:"varint,1,opt,name=ResReqId,proto3" json:"ResReqId,omitempty"`
+	Output               string   `protobuf:"bytes,2,opt,name=output,proto3" json:"output,omitempty"`
+	Errors               string   `protobuf:"bytes,3,opt,name=errors,proto3" json:"errors,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ShowCmdTextReply) Reset()         { *m = ShowCmdTextReply{} }
+func (m *ShowCmdTextReply) String() string { return proto.CompactTextString(m) }
+func (*ShowCmdTextReply) ProtoMessage()    {}
+
+func (m *ShowCmdTextReply) GetResReqId() int64 {
+	if m != nil {
+		return m.ResReqId
+	}
+	return 0
+}
+
+func (m *ShowCmdTextReply) GetOutput() string {
+	if m != nil {
+		return m.Output
+	}
+	return ""
+}
+
+func (m *ShowCmdTextReply) GetErrors() string {
+	if m != nil {
+		return m.Errors
+	}
+	return ""
+}
+
+type ShowCmdJSONReply struct {
+	ResReqId             int64    `protobuf:"varint,1,opt,name=ResReqId,proto3" json:"ResReqId,omitempty"`
+	Jsonoutput           string   `protobuf:"bytes,2,opt,name=jsonoutput,proto3" json:"jsonoutput,omitempty"`
+	Errors               string   `protobuf:"bytes,3,opt,name=errors,proto3" json:"errors,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ShowCmdJSONReply) Reset()         { *m = ShowCmdJSONReply{} }
+func (m *ShowCmdJSONReply) String() string { return proto.CompactTextString(m) }
+func (*ShowCmdJSONReply) ProtoMessage()    {}
+
+func (m *ShowCmdJSONReply) GetResReqId() int64 {
+	if m != nil {
+		return m.ResReqId
+	}
+	return 0
+}
+
+func (m *ShowCmdJSONReply) GetJsonoutput() string {
+	if m != nil {
+		return m.Jsonoutput
+	}
+	return ""
+}
+
+func (m *ShowCmdJSONReply) GetErrors() string {
+	if m != nil {
+		return m.Errors
+	}
+	return ""
+}
+
+type CreateSubsArgs struct {
+	ReqId                int64    `protobuf:"varint,1,opt,name=ReqId,proto3" json:"ReqId,omitempty"`
+	Encode               int64    `protobuf:"varint,2,opt,name=encode,proto3" json:"encode,omitempty"`
+	Subidstr             string   `protobuf:"bytes,3,opt,name=subidstr,proto3" json:"subidstr,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *CreateSubsArgs) Reset()         { *m = CreateSubsArgs{} }
+func (m *CreateSubsArgs) String() string { return proto.CompactTextString(m) }
+func (*CreateSubsArgs) ProtoMessage()    {}
+
+func (m *CreateSubsArgs) GetReqId() int64 {
+	if m != nil {
+		return m.ReqId
+	}
+	return 0
+}
+
+func (m *CreateSubsArgs) GetEncode() int64 {
+	if m != nil {
+		return m.Encode
+	}
+	return 0
+}
+
+func (m *CreateSubsArgs) GetSubidstr() string {
+	if m != nil {
+		return m.Subidstr
+	}
+	return ""
+}
+
+type CreateSubsReply struct {
+	ResReqId             int64    `protobuf:"varint,1,opt,name=ResReqId,proto3" json:"ResReqId,omitempty"`
+	Data                 []byte   `protobuf:"bytes,2,opt,name=data,proto3" json:"data,omitempty"`
+	Errors               string   `protobuf:"bytes,3,opt,name=errors,proto3" json:"errors,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *CreateSubsReply) Reset()         { *m = CreateSubsReply{} }
+func (m *CreateSubsReply) String() string { return proto.CompactTextString(m) }
+func (*CreateSubsReply) ProtoMessage()    {}
+
+func (m *CreateSubsReply) GetResReqId() int64 {
+	if m != nil {
+		return m.ResReqId
+	}
+	return 0
+}
+
+func (m *CreateSubsReply) GetData() []byte {
+	if m != nil {
+		return m.Data
+	}
+	return nil
+}
+
+func (m *CreateSubsReply) GetErrors() string {
+	if m != nil {
+		return m.Errors
+	}
+	return ""
+}
+
+type ActionJSONArgs struct {
+	ReqId                int64    `protobuf:"varint,1,opt,name=ReqId,proto3" json:"ReqId,omitempty"`
+	Yangpathjson         string   `protobuf:"bytes,2,opt,name=yangpathjson,proto3" json:"yangpathjson,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ActionJSONArgs) Reset()         { *m = ActionJSONArgs{} }
+func (m *ActionJSONArgs) String() string { return proto.CompactTextString(m) }
+func (*ActionJSONArgs) ProtoMessage()    {}
+
+func (m *ActionJSONArgs) GetReqId() int64 {
+	if m != nil {
+		return m.ReqId
+	}
+	return 0
+}
+
+func (m *ActionJSONArgs) GetYangpathjson() string {
+	if m != nil {
+		return m.Yangpathjson
+	}
+	return ""
+}
+
+type ActionJSONReply struct {
+	ResReqId             int64    `protobuf:"varint,1,opt,name=ResReqId,proto3" json:"ResReqId,omitempty"`
+	Yangjson             string   `protobuf:"bytes,2,opt,name=yangjson,proto3" json:"yangjson,omitempty"`
+	Errors               string   `protobuf:"bytes,3,opt,name=errors,proto3" json:"errors,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ActionJSONReply) Reset()         { *m = ActionJSONReply{} }
+func (m *ActionJSONReply) String() string { return proto.CompactTextString(m) }
+func (*ActionJSONReply) ProtoMessage()    {}
+
+func (m *ActionJSONReply) GetResReqId() int64 {
+	if m != nil {
+		return m.ResReqId
+	}
+	return 0
+}
+
+func (m *ActionJSONReply) GetYangjson() string {
+	if m != nil {
+		return m.Yangjson
+	}
+	return ""
+}
+
+func (m *ActionJSONReply) GetErrors() string {
+	if m != nil {
+		return m.Errors
+	}
+	return ""
+}
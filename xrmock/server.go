@@ -0,0 +1,400 @@
+/*
+Package xrmock is a stand-in for the gRPC services of an IOS XR router, so
+the tools in this repo can be developed and tested without a lab. It serves
+GetConfig, MergeConfig, DeleteConfig, ReplaceConfig and CommitReplace from
+a JSON datastore, GetOper from another one, canned output for show commands
+and actions, and telemetry subscriptions replayed from recordings.
+*/
+package xrmock
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/nleiva/clus2019/dialout"
+	"github.com/nleiva/clus2019/record"
+	"github.com/nleiva/clus2019/yang"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// Encodings of a subscription, as xrgrpc numbers them, name the recording
+// to replay.
+var encodings = map[int64]string{
+	2: "gpb",
+	3: "gpbkv",
+	4: "json",
+}
+
+// Canned holds the answers of the requests the mock can't work out.
+type Canned struct {
+	// Show maps show commands to their text output.
+	Show map[string]string `json:"show"`
+	// ShowJSON maps show commands to their JSON output.
+	ShowJSON map[string]json.RawMessage `json:"show-json"`
+	// Actions maps the top-level node of an action, as in
+	// "Cisco-IOS-XR-ping-act:ping", to its reply.
+	Actions map[string]json.RawMessage `json:"actions"`
+}
+
+// LoadCanned reads canned answers from a JSON file.
+func LoadCanned(file string) (*Canned, error) {
+	b, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not read file %s", file)
+	}
+	c := new(Canned)
+	if err = json.Unmarshal(b, c); err != nil {
+		return nil, errors.Wrapf(err, "could not parse %s", file)
+	}
+	// Commands are looked up with their spaces squeezed.
+	show := make(map[string]string, len(c.Show))
+	for cmd, out := range c.Show {
+		show[command(cmd)] = out
+	}
+	c.Show = show
+	showJSON := make(map[string]json.RawMessage, len(c.ShowJSON))
+	for cmd, out := range c.ShowJSON {
+		showJSON[command(cmd)] = out
+	}
+	c.ShowJSON = showJSON
+	return c, nil
+}
+
+func command(cli string) string {
+	return strings.Join(strings.Fields(cli), " ")
+}
+
+// Server implements the gRPCConfigOper and gRPCExec services.
+type Server struct {
+	// Config is the running config; nil is an empty one.
+	Config *Datastore
+	// Oper is the operational data GetOper reads; nil is an empty one.
+	Oper *Datastore
+	// Schema, when set, checks payloads before they change Config.
+	Schema *yang.Schema
+	// Canned answers show commands and actions; nil knows none.
+	Canned *Canned
+	// Telemetry is the directory of recordings to replay, named
+	// <subscription>.<encoding>.rec, as in LLDP.gpbkv.rec.
+	Telemetry string
+	// Speed scales the pace of the recordings: 1 replays them as
+	// recorded, 10 ten times faster, and 0 without pauses.
+	Speed float64
+	// Loop replays recordings until the client cancels, rather than
+	// ending the stream after the last message.
+	Loop bool
+	// Username and Password, when set, have to come with every request.
+	Username string
+	Password string
+
+	mu sync.Mutex
+	// cli is the running config in CLI form, for CliConfig and
+	// "show running-config".
+	cli string
+}
+
+// Register registers s for both services with srv.
+func (s *Server) Register(srv *grpc.Server) {
+	if s.Config == nil {
+		s.Config, _ = NewDatastore(nil)
+	}
+	if s.Oper == nil {
+		s.Oper, _ = NewDatastore(nil)
+	}
+	RegisterGRPCConfigOperServer(srv, s)
+	RegisterGRPCExecServer(srv, s)
+}
+
+// SetCLI sets the running config in CLI form.
+func (s *Server) SetCLI(cli string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.cli = cli
+}
+
+// CLI returns the running config in CLI form.
+func (s *Server) CLI() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.cli
+}
+
+// auth checks the credentials xrgrpc sends as metadata.
+func (s *Server) auth(ctx context.Context) error {
+	if s.Username == "" {
+		return nil
+	}
+	md, _ := metadata.FromIncomingContext(ctx)
+	if first(md["username"]) != s.Username || first(md["password"]) != s.Password {
+		return status.Error(codes.Unauthenticated, "invalid username or password")
+	}
+	return nil
+}
+
+func first(vs []string) string {
+	if len(vs) == 0 {
+		return ""
+	}
+	return vs[0]
+}
+
+// rpcError formats err as the errors field of a router's reply.
+func rpcError(err error) string {
+	if err == nil {
+		return ""
+	}
+	return encode(map[string]interface{}{
+		"cisco-grpc:errors": map[string]interface{}{
+			"error": []interface{}{
+				map[string]interface{}{
+					"error-type":     "application",
+					"error-tag":      "operation-failed",
+					"error-severity": "error",
+					"error-message":  err.Error(),
+				},
+			},
+		},
+	})
+}
+
+// GetConfig returns the parts of the running config asked for.
+func (s *Server) GetConfig(in *ConfigGetArgs, stream GRPCConfigOper_GetConfigServer) error {
+	if err := s.auth(stream.Context()); err != nil {
+		return err
+	}
+	out, err := s.Config.Get(in.Yangpathjson)
+	return stream.Send(&ConfigGetReply{ResReqId: in.ReqId, Yangjson: out, Errors: rpcError(err)})
+}
+
+// GetOper returns the parts of the operational data asked for.
+func (s *Server) GetOper(in *GetOperArgs, stream GRPCConfigOper_GetOperServer) error {
+	if err := s.auth(stream.Context()); err != nil {
+		return err
+	}
+	out, err := s.Oper.Get(in.Yangpathjson)
+	return stream.Send(&GetOperReply{ResReqId: in.ReqId, Yangjson: out, Errors: rpcError(err)})
+}
+
+// change applies a YANG operation to the running config, once the payload
+// passes the Schema.
+func (s *Server) change(ctx context.Context, in *ConfigArgs, op yang.Op, apply func(string) error) (*ConfigReply, error) {
+	if err := s.auth(ctx); err != nil {
+		return nil, err
+	}
+	err := s.Schema.Check([]byte(in.Yangjson), op)
+	if err == nil {
+		err = apply(in.Yangjson)
+	}
+	return &ConfigReply{ResReqId: in.ReqId, Errors: rpcError(err)}, nil
+}
+
+// MergeConfig merges the payload into the running config.
+func (s *Server) MergeConfig(ctx context.Context, in *ConfigArgs) (*ConfigReply, error) {
+	return s.change(ctx, in, yang.Merge, s.Config.Merge)
+}
+
+// DeleteConfig deletes the payload from the running config.
+func (s *Server) DeleteConfig(ctx context.Context, in *ConfigArgs) (*ConfigReply, error) {
+	return s.change(ctx, in, yang.Delete, s.Config.Delete)
+}
+
+// ReplaceConfig replaces the top-level nodes of the payload.
+func (s *Server) ReplaceConfig(ctx context.Context, in *ConfigArgs) (*ConfigReply, error) {
+	return s.change(ctx, in, yang.Replace, s.Config.Replace)
+}
+
+// CliConfig adds the CLI lines to the running config in CLI form. They
+// aren't parsed, so they don't show in the YANG datastore.
+func (s *Server) CliConfig(ctx context.Context, in *CliConfigArgs) (*CliConfigReply, error) {
+	if err := s.auth(ctx); err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.cli != "" && !strings.HasSuffix(s.cli, "\n") {
+		s.cli += "\n"
+	}
+	s.cli += in.Cli
+	return &CliConfigReply{ResReqId: in.ReqId}, nil
+}
+
+// CommitReplace makes the payload the whole running config: the YANG one
+// for JSON, the CLI one for CLI.
+func (s *Server) CommitReplace(ctx context.Context, in *CommitReplaceArgs) (*CommitReplaceReply, error) {
+	if err := s.auth(ctx); err != nil {
+		return nil, err
+	}
+	var err error
+	if in.Yangjson != "" {
+		if err = s.Schema.Check([]byte(in.Yangjson), yang.Replace); err == nil {
+			err = s.Config.ReplaceAll(in.Yangjson)
+		}
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if in.Cli != "" && err == nil {
+		s.cli = in.Cli
+	}
+	return &CommitReplaceReply{ResReqId: in.ReqId, Errors: rpcError(err)}, nil
+}
+
+// CommitConfig has nothing to commit: as on the router, changes commit as
+// they're applied.
+func (s *Server) CommitConfig(ctx context.Context, in *CommitArgs) (*CommitReply, error) {
+	if err := s.auth(ctx); err != nil {
+		return nil, err
+	}
+	return &CommitReply{Result: CommitResult_NO_CHANGE, ResReqId: in.ReqId}, nil
+}
+
+// ConfigDiscardChanges has nothing to discard.
+func (s *Server) ConfigDiscardChanges(ctx context.Context, in *DiscardChangesArgs) (*DiscardChangesReply, error) {
+	if err := s.auth(ctx); err != nil {
+		return nil, err
+	}
+	return &DiscardChangesReply{ResReqId: in.ReqId}, nil
+}
+
+// ShowCmdTextOutput returns the canned output of a show command, or the
+// CLI running config for "show running-config".
+func (s *Server) ShowCmdTextOutput(in *ShowCmdArgs, stream GRPCExec_ShowCmdTextOutputServer) error {
+	if err := s.auth(stream.Context()); err != nil {
+		return err
+	}
+	cmd := command(in.Cli)
+	reply := &ShowCmdTextReply{ResReqId: in.ReqId}
+	if s.Canned != nil && s.Canned.Show[cmd] != "" {
+		reply.Output = s.Canned.Show[cmd]
+	} else if cmd == "show running-config" || cmd == "show run" {
+		s.mu.Lock()
+		reply.Output = s.cli
+		s.mu.Unlock()
+	} else {
+		reply.Errors = rpcError(errors.Errorf("no canned output for %q", cmd))
+	}
+	return stream.Send(reply)
+}
+
+// ShowCmdJSONOutput returns the canned JSON output of a show command.
+func (s *Server) ShowCmdJSONOutput(in *ShowCmdArgs, stream GRPCExec_ShowCmdJSONOutputServer) error {
+	if err := s.auth(stream.Context()); err != nil {
+		return err
+	}
+	cmd := command(in.Cli)
+	reply := &ShowCmdJSONReply{ResReqId: in.ReqId}
+	if s.Canned != nil && s.Canned.ShowJSON[cmd] != nil {
+		reply.Jsonoutput = string(s.Canned.ShowJSON[cmd])
+	} else {
+		reply.Errors = rpcError(errors.Errorf("no canned JSON output for %q", cmd))
+	}
+	return stream.Send(reply)
+}
+
+// ActionJSON returns the canned reply of the action in the payload.
+func (s *Server) ActionJSON(in *ActionJSONArgs, stream GRPCExec_ActionJSONServer) error {
+	if err := s.auth(stream.Context()); err != nil {
+		return err
+	}
+	reply := &ActionJSONReply{ResReqId: in.ReqId}
+	doc, err := decode([]byte(in.Yangpathjson))
+	if err != nil {
+		reply.Errors = rpcError(err)
+		return stream.Send(reply)
+	}
+	names := make([]string, 0, len(doc))
+	for k := range doc {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	if len(names) != 1 {
+		reply.Errors = rpcError(errors.Errorf("expected one action, got %d", len(names)))
+		return stream.Send(reply)
+	}
+	if s.Canned != nil && s.Canned.Actions[names[0]] != nil {
+		reply.Yangjson = string(s.Canned.Actions[names[0]])
+	} else {
+		reply.Errors = rpcError(errors.Errorf("no canned reply for action %s", names[0]))
+	}
+	return stream.Send(reply)
+}
+
+// CreateSubs replays the recording of the subscription, in the encoding
+// asked for.
+func (s *Server) CreateSubs(in *CreateSubsArgs, stream GRPCConfigOper_CreateSubsServer) error {
+	ctx := stream.Context()
+	if err := s.auth(ctx); err != nil {
+		return err
+	}
+	enc, ok := encodings[in.Encode]
+	if !ok {
+		return stream.Send(&CreateSubsReply{ResReqId: in.ReqId, Errors: rpcError(errors.Errorf("encoding %d not supported", in.Encode))})
+	}
+	file := filepath.Join(s.Telemetry, in.Subidstr+"."+enc+".rec")
+	frames, err := record.ReadFile(file)
+	if err != nil {
+		if os.IsNotExist(errors.Cause(err)) {
+			err = errors.Errorf("subscription %s has no %s recording", in.Subidstr, enc)
+		}
+		return stream.Send(&CreateSubsReply{ResReqId: in.ReqId, Errors: rpcError(err)})
+	}
+	for {
+		for i, f := range frames {
+			if i > 0 {
+				if err = record.Pause(ctx, frames[i-1], f, s.Speed); err != nil {
+					return err
+				}
+			}
+			if err = stream.Send(&CreateSubsReply{ResReqId: in.ReqId, Data: f.Data}); err != nil {
+				return err
+			}
+		}
+		if !s.Loop || len(frames) == 0 {
+			return nil
+		}
+	}
+}
+
+// DialOut sends the recording of subscription subs in encoding enc to the
+// collector at addr, tcp://host:port or grpc://host:port, as a router
+// configured for dial-out would, paced and looped as subscriptions are.
+func (s *Server) DialOut(ctx context.Context, addr, subs string, enc int64) error {
+	name, ok := encodings[enc]
+	if !ok {
+		return errors.Errorf("encoding %d not supported", enc)
+	}
+	frames, err := record.ReadFile(filepath.Join(s.Telemetry, subs+"."+name+".rec"))
+	if err != nil {
+		return err
+	}
+	conn, err := dialout.Dial(ctx, addr)
+	if err != nil {
+		return err
+	}
+	for {
+		for i, f := range frames {
+			if i > 0 {
+				if err = record.Pause(ctx, frames[i-1], f, s.Speed); err != nil {
+					conn.Close()
+					return err
+				}
+			}
+			if err = conn.Send(f.Data); err != nil {
+				conn.Close()
+				return err
+			}
+		}
+		if !s.Loop || len(frames) == 0 {
+			return conn.Close()
+		}
+	}
+}
@@ -0,0 +1,676 @@
+package xrmock
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+)
+
+// The gRPCConfigOper and gRPCExec services of the IOS XR ems_grpc.proto, as
+// protoc-gen-go's grpc plugin would declare them.
+
+// GRPCConfigOperClient is the client API for the gRPCConfigOper service.
+type GRPCConfigOperClient interface {
+	GetConfig(ctx context.Context, in *ConfigGetArgs, opts ...grpc.CallOption) (GRPCConfigOper_GetConfigClient, error)
+	MergeConfig(ctx context.Context, in *ConfigArgs, opts ...grpc.CallOption) (*ConfigReply, error)
+	DeleteConfig(ctx context.Context, in *ConfigArgs, opts ...grpc.CallOption) (*ConfigReply, error)
+	ReplaceConfig(ctx context.Context, in *ConfigArgs, opts ...grpc.CallOption) (*ConfigReply, error)
+	CliConfig(ctx context.Context, in *CliConfigArgs, opts ...grpc.CallOption) (*CliConfigReply, error)
+	CommitReplace(ctx context.Context, in *CommitReplaceArgs, opts ...grpc.CallOption) (*CommitReplaceReply, error)
+	CommitConfig(ctx context.Context, in *CommitArgs, opts ...grpc.CallOption) (*CommitReply, error)
+	ConfigDiscardChanges(ctx context.Context, in *DiscardChangesArgs, opts ...grpc.CallOption) (*DiscardChangesReply, error)
+	GetOper(ctx context.Context, in *GetOperArgs, opts ...grpc.CallOption) (GRPCConfigOper_GetOperClient, error)
+	CreateSubs(ctx context.Context, in *CreateSubsArgs, opts ...grpc.CallOption) (GRPCConfigOper_CreateSubsClient, error)
+}
+
+type gRPCConfigOperClient struct {
+	cc *grpc.ClientConn
+}
+
+// NewGRPCConfigOperClient returns a client for the gRPCConfigOper service on cc.
+func NewGRPCConfigOperClient(cc *grpc.ClientConn) GRPCConfigOperClient {
+	return &gRPCConfigOperClient{cc}
+}
+
+func (c *gRPCConfigOperClient) GetConfig(ctx context.Context, in *ConfigGetArgs, opts ...grpc.CallOption) (GRPCConfigOper_GetConfigClient, error) {
+	stream, err := c.cc.NewStream(ctx, &_GRPCConfigOper_serviceDesc.Streams[0], "/IOSXRExtensibleManagabilityService.gRPCConfigOper/GetConfig", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &gRPCConfigOperGetConfigClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// GRPCConfigOper_GetConfigClient receives the replies streamed by GetConfig.
+type GRPCConfigOper_GetConfigClient interface {
+	Recv() (*ConfigGetReply, error)
+	grpc.ClientStream
+}
+
+type gRPCConfigOperGetConfigClient struct {
+	grpc.ClientStream
+}
+
+func (x *gRPCConfigOperGetConfigClient) Recv() (*ConfigGetReply, error) {
+	m := new(ConfigGetReply)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *gRPCConfigOperClient) MergeConfig(ctx context.Context, in *ConfigArgs, opts ...grpc.CallOption) (*ConfigReply, error) {
+	out := new(ConfigReply)
+	err := c.cc.Invoke(ctx, "/IOSXRExtensibleManagabilityService.gRPCConfigOper/MergeConfig", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *gRPCConfigOperClient) DeleteConfig(ctx context.Context, in *ConfigArgs, opts ...grpc.CallOption) (*ConfigReply, error) {
+	out := new(ConfigReply)
+	err := c.cc.Invoke(ctx, "/IOSXRExtensibleManagabilityService.gRPCConfigOper/DeleteConfig", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *gRPCConfigOperClient) ReplaceConfig(ctx context.Context, in *ConfigArgs, opts ...grpc.CallOption) (*ConfigReply, error) {
+	out := new(ConfigReply)
+	err := c.cc.Invoke(ctx, "/IOSXRExtensibleManagabilityService.gRPCConfigOper/ReplaceConfig", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *gRPCConfigOperClient) CliConfig(ctx context.Context, in *CliConfigArgs, opts ...grpc.CallOption) (*CliConfigReply, error) {
+	out := new(CliConfigReply)
+	err := c.cc.Invoke(ctx, "/IOSXRExtensibleManagabilityService.gRPCConfigOper/CliConfig", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *gRPCConfigOperClient) CommitReplace(ctx context.Context, in *CommitReplaceArgs, opts ...grpc.CallOption) (*CommitReplaceReply, error) {
+	out := new(CommitReplaceReply)
+	err := c.cc.Invoke(ctx, "/IOSXRExtensibleManagabilityService.gRPCConfigOper/CommitReplace", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *gRPCConfigOperClient) CommitConfig(ctx context.Context, in *CommitArgs, opts ...grpc.CallOption) (*CommitReply, error) {
+	out := new(CommitReply)
+	err := c.cc.Invoke(ctx, "/IOSXRExtensibleManagabilityService.gRPCConfigOper/CommitConfig", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *gRPCConfigOperClient) ConfigDiscardChanges(ctx context.Context, in *DiscardChangesArgs, opts ...grpc.CallOption) (*DiscardChangesReply, error) {
+	out := new(DiscardChangesReply)
+	err := c.cc.Invoke(ctx, "/IOSXRExtensibleManagabilityService.gRPCConfigOper/ConfigDiscardChanges", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *gRPCConfigOperClient) GetOper(ctx context.Context, in *GetOperArgs, opts ...grpc.CallOption) (GRPCConfigOper_GetOperClient, error) {
+	stream, err := c.cc.NewStream(ctx, &_GRPCConfigOper_serviceDesc.Streams[1], "/IOSXRExtensibleManagabilityService.gRPCConfigOper/GetOper", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &gRPCConfigOperGetOperClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// GRPCConfigOper_GetOperClient receives the replies streamed by GetOper.
+type GRPCConfigOper_GetOperClient interface {
+	Recv() (*GetOperReply, error)
+	grpc.ClientStream
+}
+
+type gRPCConfigOperGetOperClient struct {
+	grpc.ClientStream
+}
+
+func (x *gRPCConfigOperGetOperClient) Recv() (*GetOperReply, error) {
+	m := new(GetOperReply)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *gRPCConfigOperClient) CreateSubs(ctx context.Context, in *CreateSubsArgs, opts ...grpc.CallOption) (GRPCConfigOper_CreateSubsClient, error) {
+	stream, err := c.cc.NewStream(ctx, &_GRPCConfigOper_serviceDesc.Streams[2], "/IOSXRExtensibleManagabilityService.gRPCConfigOper/CreateSubs", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &gRPCConfigOperCreateSubsClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// GRPCConfigOper_CreateSubsClient receives the replies streamed by CreateSubs.
+type GRPCConfigOper_CreateSubsClient interface {
+	Recv() (*CreateSubsReply, error)
+	grpc.ClientStream
+}
+
+type gRPCConfigOperCreateSubsClient struct {
+	grpc.ClientStream
+}
+
+func (x *gRPCConfigOperCreateSubsClient) Recv() (*CreateSubsReply, error) {
+	m := new(CreateSubsReply)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// GRPCConfigOperServer is the server API for the gRPCConfigOper service.
+type GRPCConfigOperServer interface {
+	GetConfig(*ConfigGetArgs, GRPCConfigOper_GetConfigServer) error
+	MergeConfig(context.Context, *ConfigArgs) (*ConfigReply, error)
+	DeleteConfig(context.Context, *ConfigArgs) (*ConfigReply, error)
+	ReplaceConfig(context.Context, *ConfigArgs) (*ConfigReply, error)
+	CliConfig(context.Context, *CliConfigArgs) (*CliConfigReply, error)
+	CommitReplace(context.Context, *CommitReplaceArgs) (*CommitReplaceReply, error)
+	CommitConfig(context.Context, *CommitArgs) (*CommitReply, error)
+	ConfigDiscardChanges(context.Context, *DiscardChangesArgs) (*DiscardChangesReply, error)
+	GetOper(*GetOperArgs, GRPCConfigOper_GetOperServer) error
+	CreateSubs(*CreateSubsArgs, GRPCConfigOper_CreateSubsServer) error
+}
+
+// RegisterGRPCConfigOperServer registers srv with s.
+func RegisterGRPCConfigOperServer(s *grpc.Server, srv GRPCConfigOperServer) {
+	s.RegisterService(&_GRPCConfigOper_serviceDesc, srv)
+}
+
+func _GRPCConfigOper_GetConfig_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(ConfigGetArgs)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(GRPCConfigOperServer).GetConfig(m, &gRPCConfigOperGetConfigServer{stream})
+}
+
+// GRPCConfigOper_GetConfigServer sends the replies of GetConfig.
+type GRPCConfigOper_GetConfigServer interface {
+	Send(*ConfigGetReply) error
+	grpc.ServerStream
+}
+
+type gRPCConfigOperGetConfigServer struct {
+	grpc.ServerStream
+}
+
+func (x *gRPCConfigOperGetConfigServer) Send(m *ConfigGetReply) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _GRPCConfigOper_MergeConfig_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ConfigArgs)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GRPCConfigOperServer).MergeConfig(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/IOSXRExtensibleManagabilityService.gRPCConfigOper/MergeConfig",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GRPCConfigOperServer).MergeConfig(ctx, req.(*ConfigArgs))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _GRPCConfigOper_DeleteConfig_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ConfigArgs)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GRPCConfigOperServer).DeleteConfig(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/IOSXRExtensibleManagabilityService.gRPCConfigOper/DeleteConfig",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GRPCConfigOperServer).DeleteConfig(ctx, req.(*ConfigArgs))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _GRPCConfigOper_ReplaceConfig_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ConfigArgs)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GRPCConfigOperServer).ReplaceConfig(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/IOSXRExtensibleManagabilityService.gRPCConfigOper/ReplaceConfig",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GRPCConfigOperServer).ReplaceConfig(ctx, req.(*ConfigArgs))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _GRPCConfigOper_CliConfig_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CliConfigArgs)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GRPCConfigOperServer).CliConfig(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/IOSXRExtensibleManagabilityService.gRPCConfigOper/CliConfig",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GRPCConfigOperServer).CliConfig(ctx, req.(*CliConfigArgs))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _GRPCConfigOper_CommitReplace_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CommitReplaceArgs)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GRPCConfigOperServer).CommitReplace(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/IOSXRExtensibleManagabilityService.gRPCConfigOper/CommitReplace",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GRPCConfigOperServer).CommitReplace(ctx, req.(*CommitReplaceArgs))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _GRPCConfigOper_CommitConfig_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CommitArgs)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GRPCConfigOperServer).CommitConfig(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/IOSXRExtensibleManagabilityService.gRPCConfigOper/CommitConfig",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GRPCConfigOperServer).CommitConfig(ctx, req.(*CommitArgs))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _GRPCConfigOper_ConfigDiscardChanges_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DiscardChangesArgs)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GRPCConfigOperServer).ConfigDiscardChanges(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/IOSXRExtensibleManagabilityService.gRPCConfigOper/ConfigDiscardChanges",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GRPCConfigOperServer).ConfigDiscardChanges(ctx, req.(*DiscardChangesArgs))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _GRPCConfigOper_GetOper_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(GetOperArgs)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(GRPCConfigOperServer).GetOper(m, &gRPCConfigOperGetOperServer{stream})
+}
+
+// GRPCConfigOper_GetOperServer sends the replies of GetOper.
+type GRPCConfigOper_GetOperServer interface {
+	Send(*GetOperReply) error
+	grpc.ServerStream
+}
+
+type gRPCConfigOperGetOperServer struct {
+	grpc.ServerStream
+}
+
+func (x *gRPCConfigOperGetOperServer) Send(m *GetOperReply) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _GRPCConfigOper_CreateSubs_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(CreateSubsArgs)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(GRPCConfigOperServer).CreateSubs(m, &gRPCConfigOperCreateSubsServer{stream})
+}
+
+// GRPCConfigOper_CreateSubsServer sends the replies of CreateSubs.
+type GRPCConfigOper_CreateSubsServer interface {
+	Send(*CreateSubsReply) error
+	grpc.ServerStream
+}
+
+type gRPCConfigOperCreateSubsServer struct {
+	grpc.ServerStream
+}
+
+func (x *gRPCConfigOperCreateSubsServer) Send(m *CreateSubsReply) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+var _GRPCConfigOper_serviceDesc = grpc.ServiceDesc{
+	ServiceName: "IOSXRExtensibleManagabilityService.gRPCConfigOper",
+	HandlerType: (*GRPCConfigOperServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "MergeConfig",
+			Handler:    _GRPCConfigOper_MergeConfig_Handler,
+		},
+		{
+			MethodName: "DeleteConfig",
+			Handler:    _GRPCConfigOper_DeleteConfig_Handler,
+		},
+		{
+			MethodName: "ReplaceConfig",
+			Handler:    _GRPCConfigOper_ReplaceConfig_Handler,
+		},
+		{
+			MethodName: "CliConfig",
+			Handler:    _GRPCConfigOper_CliConfig_Handler,
+		},
+		{
+			MethodName: "CommitReplace",
+			Handler:    _GRPCConfigOper_CommitReplace_Handler,
+		},
+		{
+			MethodName: "CommitConfig",
+			Handler:    _GRPCConfigOper_CommitConfig_Handler,
+		},
+		{
+			MethodName: "ConfigDiscardChanges",
+			Handler:    _GRPCConfigOper_ConfigDiscardChanges_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "GetConfig",
+			Handler:       _GRPCConfigOper_GetConfig_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "GetOper",
+			Handler:       _GRPCConfigOper_GetOper_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "CreateSubs",
+			Handler:       _GRPCConfigOper_CreateSubs_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "ems_grpc.proto",
+}
+
+// GRPCExecClient is the client API for the gRPCExec service.
+type GRPCExecClient interface {
+	ShowCmdTextOutput(ctx context.Context, in *ShowCmdArgs, opts ...grpc.CallOption) (GRPCExec_ShowCmdTextOutputClient, error)
+	ShowCmdJSONOutput(ctx context.Context, in *ShowCmdArgs, opts ...grpc.CallOption) (GRPCExec_ShowCmdJSONOutputClient, error)
+	ActionJSON(ctx context.Context, in *ActionJSONArgs, opts ...grpc.CallOption) (GRPCExec_ActionJSONClient, error)
+}
+
+type gRPCExecClient struct {
+	cc *grpc.ClientConn
+}
+
+// NewGRPCExecClient returns a client for the gRPCExec service on cc.
+func NewGRPCExecClient(cc *grpc.ClientConn) GRPCExecClient {
+	return &gRPCExecClient{cc}
+}
+
+func (c *gRPCExecClient) ShowCmdTextOutput(ctx context.Context, in *ShowCmdArgs, opts ...grpc.CallOption) (GRPCExec_ShowCmdTextOutputClient, error) {
+	stream, err := c.cc.NewStream(ctx, &_GRPCExec_serviceDesc.Streams[0], "/IOSXRExtensibleManagabilityService.gRPCExec/ShowCmdTextOutput", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &gRPCExecShowCmdTextOutputClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// GRPCExec_ShowCmdTextOutputClient receives the replies streamed by ShowCmdTextOutput.
+type GRPCExec_ShowCmdTextOutputClient interface {
+	Recv() (*ShowCmdTextReply, error)
+	grpc.ClientStream
+}
+
+type gRPCExecShowCmdTextOutputClient struct {
+	grpc.ClientStream
+}
+
+func (x *gRPCExecShowCmdTextOutputClient) Recv() (*ShowCmdTextReply, error) {
+	m := new(ShowCmdTextReply)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *gRPCExecClient) ShowCmdJSONOutput(ctx context.Context, in *ShowCmdArgs, opts ...grpc.CallOption) (GRPCExec_ShowCmdJSONOutputClient, error) {
+	stream, err := c.cc.NewStream(ctx, &_GRPCExec_serviceDesc.Streams[1], "/IOSXRExtensibleManagabilityService.gRPCExec/ShowCmdJSONOutput", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &gRPCExecShowCmdJSONOutputClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// GRPCExec_ShowCmdJSONOutputClient receives the replies streamed by ShowCmdJSONOutput.
+type GRPCExec_ShowCmdJSONOutputClient interface {
+	Recv() (*ShowCmdJSONReply, error)
+	grpc.ClientStream
+}
+
+type gRPCExecShowCmdJSONOutputClient struct {
+	grpc.ClientStream
+}
+
+func (x *gRPCExecShowCmdJSONOutputClient) Recv() (*ShowCmdJSONReply, error) {
+	m := new(ShowCmdJSONReply)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *gRPCExecClient) ActionJSON(ctx context.Context, in *ActionJSONArgs, opts ...grpc.CallOption) (GRPCExec_ActionJSONClient, error) {
+	stream, err := c.cc.NewStream(ctx, &_GRPCExec_serviceDesc.Streams[2], "/IOSXRExtensibleManagabilityService.gRPCExec/ActionJSON", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &gRPCExecActionJSONClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// GRPCExec_ActionJSONClient receives the replies streamed by ActionJSON.
+type GRPCExec_ActionJSONClient interface {
+	Recv() (*ActionJSONReply, error)
+	grpc.ClientStream
+}
+
+type gRPCExecActionJSONClient struct {
+	grpc.ClientStream
+}
+
+func (x *gRPCExecActionJSONClient) Recv() (*ActionJSONReply, error) {
+	m := new(ActionJSONReply)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// GRPCExecServer is the server API for the gRPCExec service.
+type GRPCExecServer interface {
+	ShowCmdTextOutput(*ShowCmdArgs, GRPCExec_ShowCmdTextOutputServer) error
+	ShowCmdJSONOutput(*ShowCmdArgs, GRPCExec_ShowCmdJSONOutputServer) error
+	ActionJSON(*ActionJSONArgs, GRPCExec_ActionJSONServer) error
+}
+
+// RegisterGRPCExecServer registers srv with s.
+func RegisterGRPCExecServer(s *grpc.Server, srv GRPCExecServer) {
+	s.RegisterService(&_GRPCExec_serviceDesc, srv)
+}
+
+func _GRPCExec_ShowCmdTextOutput_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(ShowCmdArgs)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(GRPCExecServer).ShowCmdTextOutput(m, &gRPCExecShowCmdTextOutputServer{stream})
+}
+
+// GRPCExec_ShowCmdTextOutputServer sends the replies of ShowCmdTextOutput.
+type GRPCExec_ShowCmdTextOutputServer interface {
+	Send(*ShowCmdTextReply) error
+	grpc.ServerStream
+}
+
+type gRPCExecShowCmdTextOutputServer struct {
+	grpc.ServerStream
+}
+
+func (x *gRPCExecShowCmdTextOutputServer) Send(m *ShowCmdTextReply) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _GRPCExec_ShowCmdJSONOutput_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(ShowCmdArgs)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(GRPCExecServer).ShowCmdJSONOutput(m, &gRPCExecShowCmdJSONOutputServer{stream})
+}
+
+// GRPCExec_ShowCmdJSONOutputServer sends the replies of ShowCmdJSONOutput.
+type GRPCExec_ShowCmdJSONOutputServer interface {
+	Send(*ShowCmdJSONReply) error
+	grpc.ServerStream
+}
+
+type gRPCExecShowCmdJSONOutputServer struct {
+	grpc.ServerStream
+}
+
+func (x *gRPCExecShowCmdJSONOutputServer) Send(m *ShowCmdJSONReply) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _GRPCExec_ActionJSON_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(ActionJSONArgs)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(GRPCExecServer).ActionJSON(m, &gRPCExecActionJSONServer{stream})
+}
+
+// GRPCExec_ActionJSONServer sends the replies of ActionJSON.
+type GRPCExec_ActionJSONServer interface {
+	Send(*ActionJSONReply) error
+	grpc.ServerStream
+}
+
+type gRPCExecActionJSONServer struct {
+	grpc.ServerStream
+}
+
+func (x *gRPCExecActionJSONServer) Send(m *ActionJSONReply) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+var _GRPCExec_serviceDesc = grpc.ServiceDesc{
+	ServiceName: "IOSXRExtensibleManagabilityService.gRPCExec",
+	HandlerType: (*GRPCExecServer)(nil),
+	Methods:     []grpc.MethodDesc{},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "ShowCmdTextOutput",
+			Handler:       _GRPCExec_ShowCmdTextOutput_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "ShowCmdJSONOutput",
+			Handler:       _GRPCExec_ShowCmdJSONOutput_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "ActionJSON",
+			Handler:       _GRPCExec_ActionJSON_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "ems_grpc.proto",
+}
@@ -0,0 +1,84 @@
+package xrmock
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"os"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Domain is the name xrgrpc checks the router's certificate against.
+const Domain = "ems.cisco.com"
+
+// SelfSigned returns a PEM certificate and key for Domain, localhost and
+// hosts, which may be names or addresses. The certificate signs itself, so
+// clients can trust it as the CA, as they do a router's ems.pem.
+func SelfSigned(hosts ...string) (certPEM, keyPEM []byte, err error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "could not generate a key")
+	}
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 62))
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "could not generate a serial number")
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: Domain, Organization: []string{"xrmock"}},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().AddDate(10, 0, 0),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		DNSNames:              []string{Domain, "localhost"},
+		IPAddresses:           []net.IP{net.IPv4(127, 0, 0, 1), net.IPv6loopback},
+	}
+	for _, h := range hosts {
+		if ip := net.ParseIP(h); ip != nil {
+			tmpl.IPAddresses = append(tmpl.IPAddresses, ip)
+		} else if h != "" {
+			tmpl.DNSNames = append(tmpl.DNSNames, h)
+		}
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "could not create the certificate")
+	}
+	kb, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "could not encode the key")
+	}
+	certPEM = pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM = pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: kb})
+	return certPEM, keyPEM, nil
+}
+
+// LoadOrCreateCert loads the certificate in certFile and keyFile, first
+// writing a SelfSigned one for hosts there if certFile doesn't exist.
+func LoadOrCreateCert(certFile, keyFile string, hosts ...string) (tls.Certificate, error) {
+	if _, err := os.Stat(certFile); os.IsNotExist(err) {
+		c, k, err := SelfSigned(hosts...)
+		if err != nil {
+			return tls.Certificate{}, err
+		}
+		if err = ioutil.WriteFile(keyFile, k, 0600); err != nil {
+			return tls.Certificate{}, errors.Wrapf(err, "could not write file %s", keyFile)
+		}
+		if err = ioutil.WriteFile(certFile, c, 0644); err != nil {
+			return tls.Certificate{}, errors.Wrapf(err, "could not write file %s", certFile)
+		}
+	}
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	return cert, errors.Wrapf(err, "could not load %s and %s", certFile, keyFile)
+}
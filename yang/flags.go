@@ -0,0 +1,26 @@
+package yang
+
+import "flag"
+
+// Flags are the flags of the commands that check a payload before they
+// send it.
+type Flags struct {
+	Dir    string
+	Strict bool
+}
+
+// AddFlags registers the -yang and -strict flags on fs.
+func (f *Flags) AddFlags(fs *flag.FlagSet) {
+	fs.StringVar(&f.Dir, "yang", "../yang", "Directory of YANG modules to validate the payload with; empty to skip")
+	fs.BoolVar(&f.Strict, "strict", false, "Fail on nodes from modules that aren't in -yang, instead of warning about them")
+}
+
+// Load loads the modules in Dir. Check then warns about modules that
+// aren't loaded with warn, or fails on them if Strict is set.
+func (f *Flags) Load(warn func(format string, args ...interface{})) (*Schema, error) {
+	s, err := Load(f.Dir)
+	if s != nil {
+		s.Strict, s.Warn = f.Strict, warn
+	}
+	return s, err
+}
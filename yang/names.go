@@ -0,0 +1,59 @@
+package yang
+
+import "strings"
+
+// initialisms are spelled in capitals, as Go does.
+var initialisms = map[string]string{
+	"id": "ID", "ip": "IP", "ipv4": "IPv4", "ipv6": "IPv6", "mtu": "MTU",
+	"mac": "MAC", "vrf": "VRF", "bgp": "BGP", "url": "URL", "uuid": "UUID",
+}
+
+// Camel turns a YANG identifier into a Go type or proto message name:
+// "prefix-length" becomes "PrefixLength", and "interface-id" becomes
+// "InterfaceID".
+func Camel(s string) string {
+	var b strings.Builder
+	for _, part := range strings.FieldsFunc(s, separator) {
+		if up, ok := initialisms[strings.ToLower(part)]; ok {
+			b.WriteString(up)
+			continue
+		}
+		b.WriteString(strings.ToUpper(part[:1]) + part[1:])
+	}
+	out := b.String()
+	if out == "" || out[0] >= '0' && out[0] <= '9' {
+		out = "X" + out
+	}
+	return out
+}
+
+// Snake turns a YANG identifier into a proto field or package name:
+// "prefix-length" becomes "prefix_length".
+func Snake(s string) string {
+	out := strings.Join(strings.FieldsFunc(s, separator), "_")
+	if out == "" || out[0] >= '0' && out[0] <= '9' {
+		out = "x_" + out
+	}
+	return out
+}
+
+func separator(r rune) bool {
+	return r == '-' || r == '_' || r == '.' || r == ':' || r == ' ' || r == '/'
+}
+
+// DataNodes returns ns as they appear in data: choices and cases are
+// replaced by their contents, and state data is dropped unless state is
+// set.
+func DataNodes(ns []*Node, state bool) []*Node {
+	var out []*Node
+	for _, n := range ns {
+		switch {
+		case !n.Config && !state:
+		case n.Kind == Choice || n.Kind == Case:
+			out = append(out, DataNodes(n.Children, state)...)
+		default:
+			out = append(out, n)
+		}
+	}
+	return out
+}
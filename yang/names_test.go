@@ -0,0 +1,26 @@
+package yang
+
+import "testing"
+
+func TestNames(t *testing.T) {
+	tests := []struct {
+		in, camel, snake string
+	}{
+		{"name", "Name", "name"},
+		{"prefix-length", "PrefixLength", "prefix_length"},
+		{"interface-id", "InterfaceID", "interface_id"},
+		{"ipv6", "IPv6", "ipv6"},
+		{"openconfig-if-ip:ipv4", "OpenconfigIfIPIPv4", "openconfig_if_ip_ipv4"},
+		{"host_name", "HostName", "host_name"},
+		{"802.1q", "X8021q", "x_802_1q"},
+		{"", "X", "x_"},
+	}
+	for _, tt := range tests {
+		if got := Camel(tt.in); got != tt.camel {
+			t.Errorf("Camel(%q) = %q, want %q", tt.in, got, tt.camel)
+		}
+		if got := Snake(tt.in); got != tt.snake {
+			t.Errorf("Snake(%q) = %q, want %q", tt.in, got, tt.snake)
+		}
+	}
+}
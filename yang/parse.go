@@ -0,0 +1,257 @@
+package yang
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Statement is a YANG statement: a keyword, an optional argument and its
+// substatements.
+type Statement struct {
+	Keyword string
+	Arg     string
+	Subs    []*Statement
+	Parent  *Statement
+	// File and Line locate the statement, for error messages.
+	File string
+	Line int
+
+	// f is the module or submodule the statement is in, set when loading.
+	f *file
+}
+
+// sub returns the first substatement with keyword, or nil.
+func (s *Statement) sub(keyword string) *Statement {
+	for _, c := range s.Subs {
+		if c.Keyword == keyword {
+			return c
+		}
+	}
+	return nil
+}
+
+// arg returns the argument of the first substatement with keyword, or "".
+func (s *Statement) arg(keyword string) string {
+	if c := s.sub(keyword); c != nil {
+		return c.Arg
+	}
+	return ""
+}
+
+func (s *Statement) walk(fn func(*Statement)) {
+	fn(s)
+	for _, c := range s.Subs {
+		c.walk(fn)
+	}
+}
+
+// Parse reads the statements in a YANG file; name is only used in errors.
+func Parse(name string, in []byte) ([]*Statement, error) {
+	l := &lexer{file: name, in: in, line: 1}
+	return l.statements(nil)
+}
+
+// lexer splits YANG into tokens: '{', '}', ';' and strings, quoted or not.
+type lexer struct {
+	file string
+	in   []byte
+	pos  int
+	line int
+}
+
+// token is a lexed token; quoted strings are never taken for punctuation.
+type token struct {
+	text   string
+	quoted bool
+	line   int
+}
+
+func (t token) is(punct string) bool {
+	return !t.quoted && t.text == punct
+}
+
+func (l *lexer) errorf(line int, format string, args ...interface{}) error {
+	return errors.Errorf("%s:%d: %s", l.file, line, fmt.Sprintf(format, args...))
+}
+
+// statements reads statements up to the '}' closing parent, or the end of
+// the input at the top level.
+func (l *lexer) statements(parent *Statement) ([]*Statement, error) {
+	var out []*Statement
+	for {
+		t, eof, err := l.next()
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case eof && parent != nil:
+			return nil, l.errorf(parent.Line, "missing '}' for %s", parent.Keyword)
+		case eof:
+			return out, nil
+		case t.is("}") && parent == nil:
+			return nil, l.errorf(t.line, "unexpected '}'")
+		case t.is("}"):
+			return out, nil
+		case t.quoted || t.is("{") || t.is(";"):
+			return nil, l.errorf(t.line, "expected a keyword, got %q", t.text)
+		}
+		s := &Statement{Keyword: t.text, Parent: parent, File: l.file, Line: t.line}
+		t, eof, err = l.next()
+		if err != nil {
+			return nil, err
+		}
+		if !eof && !t.is(";") && !t.is("{") {
+			s.Arg = t.text
+			t, eof, err = l.next()
+			if err != nil {
+				return nil, err
+			}
+		}
+		switch {
+		case eof:
+			return nil, l.errorf(s.Line, "missing ';' after %s", s.Keyword)
+		case t.is("{"):
+			if s.Subs, err = l.statements(s); err != nil {
+				return nil, err
+			}
+		case !t.is(";"):
+			return nil, l.errorf(t.line, "expected ';' or '{' after %s, got %q", s.Keyword, t.text)
+		}
+		out = append(out, s)
+	}
+}
+
+// skip moves past whitespace and comments.
+func (l *lexer) skip() error {
+	for l.pos < len(l.in) {
+		c := l.in[l.pos]
+		switch {
+		case c == '\n':
+			l.line++
+			l.pos++
+		case c == ' ' || c == '\t' || c == '\r':
+			l.pos++
+		case bytes.HasPrefix(l.in[l.pos:], []byte("//")):
+			for l.pos < len(l.in) && l.in[l.pos] != '\n' {
+				l.pos++
+			}
+		case bytes.HasPrefix(l.in[l.pos:], []byte("/*")):
+			end := bytes.Index(l.in[l.pos+2:], []byte("*/"))
+			if end < 0 {
+				return l.errorf(l.line, "unterminated comment")
+			}
+			l.line += bytes.Count(l.in[l.pos:l.pos+2+end], []byte("\n"))
+			l.pos += end + 4
+		default:
+			return nil
+		}
+	}
+	return nil
+}
+
+// next returns the next token. Quoted strings joined with '+' are returned
+// as one.
+func (l *lexer) next() (token, bool, error) {
+	if err := l.skip(); err != nil {
+		return token{}, false, err
+	}
+	if l.pos >= len(l.in) {
+		return token{}, true, nil
+	}
+	t := token{line: l.line}
+	switch c := l.in[l.pos]; c {
+	case '{', '}', ';':
+		l.pos++
+		t.text = string(c)
+		return t, false, nil
+	case '"', '\'':
+		var b strings.Builder
+		for {
+			s, err := l.quoted()
+			if err != nil {
+				return token{}, false, err
+			}
+			b.WriteString(s)
+			// Look ahead for "+" and another quoted string.
+			pos, line := l.pos, l.line
+			if err = l.skip(); err != nil {
+				return token{}, false, err
+			}
+			if l.pos+1 < len(l.in) && l.in[l.pos] == '+' {
+				l.pos++
+				if err = l.skip(); err != nil {
+					return token{}, false, err
+				}
+				if l.pos < len(l.in) && (l.in[l.pos] == '"' || l.in[l.pos] == '\'') {
+					continue
+				}
+			}
+			l.pos, l.line = pos, line
+			break
+		}
+		t.text, t.quoted = b.String(), true
+		return t, false, nil
+	}
+	start := l.pos
+	for l.pos < len(l.in) {
+		c := l.in[l.pos]
+		if c == ' ' || c == '\t' || c == '\r' || c == '\n' || c == ';' || c == '{' || c == '}' ||
+			bytes.HasPrefix(l.in[l.pos:], []byte("//")) || bytes.HasPrefix(l.in[l.pos:], []byte("/*")) {
+			break
+		}
+		l.pos++
+	}
+	t.text = string(l.in[start:l.pos])
+	return t, false, nil
+}
+
+// quoted reads a single or double quoted string. As RFC 7950 section 6.1.3
+// says, double quoted strings lose the indentation of continuation lines up
+// to the column after the opening quote, and trailing whitespace before a
+// line break.
+func (l *lexer) quoted() (string, error) {
+	q := l.in[l.pos]
+	line := l.line
+	col := l.pos - (bytes.LastIndexByte(l.in[:l.pos], '\n') + 1) + 1
+	l.pos++
+	var b strings.Builder
+	for l.pos < len(l.in) {
+		c := l.in[l.pos]
+		l.pos++
+		switch {
+		case c == q:
+			return b.String(), nil
+		case c == '\\' && q == '"' && l.pos < len(l.in):
+			e := l.in[l.pos]
+			l.pos++
+			switch e {
+			case 'n':
+				b.WriteByte('\n')
+			case 't':
+				b.WriteByte('\t')
+			case '"', '\\':
+				b.WriteByte(e)
+			default:
+				b.WriteByte('\\')
+				b.WriteByte(e)
+			}
+		case c == '\n':
+			l.line++
+			if q == '"' {
+				s := strings.TrimRight(b.String(), " \t")
+				b.Reset()
+				b.WriteString(s)
+				for i := 0; i < col && l.pos < len(l.in) && (l.in[l.pos] == ' ' || l.in[l.pos] == '\t'); i++ {
+					l.pos++
+				}
+			}
+			b.WriteByte('\n')
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return "", l.errorf(line, "unterminated string")
+}
@@ -0,0 +1,113 @@
+package yang
+
+import (
+	"strings"
+	"testing"
+)
+
+// flat writes statements as "keyword arg" lines, indented by depth.
+func flat(ss []*Statement, depth int, b *strings.Builder) {
+	for _, s := range ss {
+		b.WriteString(strings.Repeat(" ", depth) + s.Keyword)
+		if s.Arg != "" {
+			b.WriteString(" " + s.Arg)
+		}
+		b.WriteString("\n")
+		flat(s.Subs, depth+1, b)
+	}
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{name: "empty"},
+		{
+			name: "nested",
+			in:   "module m { prefix m; container c { leaf l { type string; } } }",
+			want: "module m\n prefix m\n container c\n  leaf l\n   type string\n",
+		},
+		{
+			name: "comments",
+			in:   "// a comment\nleaf l { /* type int8; */ type string; } // trailing",
+			want: "leaf l\n type string\n",
+		},
+		{
+			// Quoted strings aren't punctuation, and '+' joins them.
+			name: "quoted",
+			in:   `pattern "[a-z]{1,3};" + '\d+';`,
+			want: "pattern [a-z]{1,3};\\d+\n",
+		},
+		{
+			name: "escapes",
+			in:   `description "a \"b\"\tc\\";`,
+			want: "description a \"b\"\tc\\\n",
+		},
+		{
+			// Continuation lines lose the indentation up to the column
+			// after the quote, and trailing spaces.
+			name: "indentation",
+			in:   "description \"one  \n             two\n               three\";",
+			want: "description one\ntwo\n  three\n",
+		},
+		{
+			name: "single quotes",
+			in:   "description 'no \\n escapes\n  here';",
+			want: "description no \\n escapes\n  here\n",
+		},
+		{
+			name: "unquoted",
+			in:   "range 1..10|20;\nkey \"a b\";",
+			want: "range 1..10|20\nkey a b\n",
+		},
+	}
+	for _, tt := range tests {
+		ss, err := Parse("t.yang", []byte(tt.in))
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		var b strings.Builder
+		flat(ss, 0, &b)
+		if b.String() != tt.want {
+			t.Errorf("%s: Parse() = %q, want %q", tt.name, b.String(), tt.want)
+		}
+	}
+}
+
+func TestParseLines(t *testing.T) {
+	ss, err := Parse("t.yang", []byte("module m {\n  /* two\n  lines */\n  leaf l {\n    description \"a\n b\";\n    type string;\n  }\n}"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	l := ss[0].Subs[0]
+	if l.Line != 4 || l.File != "t.yang" || l.Parent != ss[0] {
+		t.Errorf("leaf at %s:%d with parent %v, want t.yang:4 in the module", l.File, l.Line, l.Parent)
+	}
+	if typ := l.sub("type"); typ == nil || typ.Line != 7 {
+		t.Errorf("type at %v, want line 7", typ)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{in: "module m {\n leaf l;", want: "t.yang:1: missing '}' for module"},
+		{in: "leaf l;\n}", want: "t.yang:2: unexpected '}'"},
+		{in: "leaf l { \"type\" string; }", want: `t.yang:1: expected a keyword, got "type"`},
+		{in: "leaf l\n", want: "t.yang:1: missing ';' after leaf"},
+		{in: "leaf l m;", want: `t.yang:1: expected ';' or '{' after leaf, got "m"`},
+		{in: "leaf l;\n/* open", want: "t.yang:2: unterminated comment"},
+		{in: "\ndescription \"open;", want: "t.yang:2: unterminated string"},
+	}
+	for _, tt := range tests {
+		_, err := Parse("t.yang", []byte(tt.in))
+		if err == nil || err.Error() != tt.want {
+			t.Errorf("Parse(%q) = %v, want %s", tt.in, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,593 @@
+/*
+Package yang loads YANG modules and checks RFC 7951 JSON payloads against
+them, without a router. It understands the part of YANG the OpenConfig and
+IOS XR models use for config: containers, lists, leaves, choices, groupings,
+augments, typedefs and identities. Features, "when" and "must" are ignored,
+and of the deviations only "not-supported" is applied.
+*/
+package yang
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Kind is the kind of a schema node.
+type Kind int
+
+// Schema node kinds.
+const (
+	Container Kind = iota
+	List
+	Leaf
+	LeafList
+	Choice
+	Case
+	AnyData
+)
+
+var kindNames = []string{"container", "list", "leaf", "leaf-list", "choice", "case", "anydata"}
+
+var kinds = map[string]Kind{
+	"container": Container,
+	"list":      List,
+	"leaf":      Leaf,
+	"leaf-list": LeafList,
+	"choice":    Choice,
+	"case":      Case,
+	"anydata":   AnyData,
+	"anyxml":    AnyData,
+}
+
+func (k Kind) String() string {
+	if k < 0 || int(k) >= len(kindNames) {
+		return fmt.Sprintf("Kind(%d)", int(k))
+	}
+	return kindNames[k]
+}
+
+// Node is a schema node, with the groupings it uses expanded and the
+// augments that target it applied.
+type Node struct {
+	Name string
+	// Module is the module whose namespace the node is in.
+	Module      string
+	Kind        Kind
+	Description string
+	// Config is false for state data.
+	Config    bool
+	Mandatory bool
+	Presence  bool
+	// Keys are the key leaves of a list.
+	Keys []string
+	// Unique lists the sets of leaves, by descendant path, that no two
+	// entries of a list may share.
+	Unique [][]string
+	// Type is the type of a leaf or leaf-list.
+	Type     *Type
+	Default  string
+	Children []*Node
+	Parent   *Node
+}
+
+// Child returns the child of n with name in module, looking through
+// choices and cases, as a JSON member name would.
+func (n *Node) Child(module, name string) *Node {
+	for _, c := range n.Children {
+		if c.Kind == Choice || c.Kind == Case {
+			if d := c.Child(module, name); d != nil {
+				return d
+			}
+			continue
+		}
+		if c.Name == name && c.Module == module {
+			return c
+		}
+	}
+	return nil
+}
+
+// Path returns the schema path of n, e.g. "/test:user/name".
+func (n *Node) Path() string {
+	var parts []string
+	for c := n; c != nil && c.Name != ""; c = c.Parent {
+		if c.Kind == Choice || c.Kind == Case {
+			continue
+		}
+		p := c.Name
+		if c.Parent == nil || c.Parent.Name == "" || c.Parent.Module != c.Module {
+			p = c.Module + ":" + p
+		}
+		parts = append([]string{p}, parts...)
+	}
+	return "/" + strings.Join(parts, "/")
+}
+
+// step returns the child of n a schema node identifier step names,
+// choices and cases included. Unprefixed names match any module, since
+// grouping contents take the namespace of the module that uses them.
+func (n *Node) step(module, name string) *Node {
+	var any *Node
+	for _, c := range n.Children {
+		if c.Name != name {
+			continue
+		}
+		if c.Module == module {
+			return c
+		}
+		if any == nil {
+			any = c
+		}
+	}
+	if module == "" {
+		return any
+	}
+	return nil
+}
+
+// Module is a loaded YANG module, submodules included.
+type Module struct {
+	Name        string
+	Prefix      string
+	Namespace   string
+	Revision    string
+	Description string
+
+	// root holds the top-level data nodes.
+	root  *Node
+	files []*file
+}
+
+// Nodes returns the top-level data nodes of m.
+func (m *Module) Nodes() []*Node {
+	return m.root.Children
+}
+
+// file is a module or submodule file.
+type file struct {
+	stmt *Statement
+	// module is the module the file is, or belongs to.
+	module string
+	prefix string
+	// imports maps prefixes to module names.
+	imports map[string]string
+}
+
+// Schema is a set of modules.
+type Schema struct {
+	modules map[string]*Module
+	// identities maps "module:identity" to the qualified names of its bases.
+	identities map[string][]string
+	// Warnings lists what couldn't be resolved while loading, such as
+	// types from modules that weren't found. Those parts aren't checked.
+	Warnings []string
+	// Strict makes Check fail on nodes from modules that aren't loaded.
+	Strict bool
+	// Warn, if set, is what Check tells about the modules a payload uses
+	// that aren't loaded, when Strict isn't set.
+	Warn func(format string, args ...interface{})
+}
+
+// Module returns the module called name, or nil.
+func (s *Schema) Module(name string) *Module {
+	if s == nil {
+		return nil
+	}
+	return s.modules[name]
+}
+
+// Modules returns the loaded modules, sorted by name.
+func (s *Schema) Modules() []*Module {
+	if s == nil {
+		return nil
+	}
+	out := make([]*Module, 0, len(s.modules))
+	for _, m := range s.modules {
+		out = append(out, m)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+func (s *Schema) warnf(format string, args ...interface{}) {
+	s.Warnings = append(s.Warnings, fmt.Sprintf(format, args...))
+}
+
+// Load reads every *.yang file under dirs. Empty directory names are
+// ignored; with none left Load returns a nil Schema, which accepts any
+// payload.
+func Load(dirs ...string) (*Schema, error) {
+	var files []string
+	for _, dir := range dirs {
+		if dir == "" {
+			continue
+		}
+		err := filepath.Walk(dir, func(path string, fi os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if !fi.IsDir() && strings.HasSuffix(path, ".yang") {
+				files = append(files, path)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, errors.Wrapf(err, "could not list YANG modules in %s", dir)
+		}
+	}
+	if files == nil {
+		for _, dir := range dirs {
+			if dir != "" {
+				return nil, errors.Errorf("no YANG modules in %s", strings.Join(dirs, ", "))
+			}
+		}
+		return nil, nil
+	}
+	var stmts []*Statement
+	for _, f := range files {
+		b, err := ioutil.ReadFile(f)
+		if err != nil {
+			return nil, errors.Wrapf(err, "could not read file %s", f)
+		}
+		ss, err := Parse(f, b)
+		if err != nil {
+			return nil, err
+		}
+		stmts = append(stmts, ss...)
+	}
+	return build(stmts)
+}
+
+// build makes a Schema out of module and submodule statements.
+func build(stmts []*Statement) (*Schema, error) {
+	s := &Schema{modules: make(map[string]*Module), identities: make(map[string][]string)}
+	var subs []*Statement
+	for _, st := range stmts {
+		switch st.Keyword {
+		case "module":
+			if old := s.modules[st.Arg]; old != nil && revision(old.files[0].stmt) >= revision(st) {
+				continue
+			}
+			f := &file{stmt: st, module: st.Arg, prefix: st.arg("prefix")}
+			s.modules[st.Arg] = &Module{
+				Name:        st.Arg,
+				Prefix:      f.prefix,
+				Namespace:   st.arg("namespace"),
+				Revision:    revision(st),
+				Description: st.arg("description"),
+				root:        &Node{Config: true},
+				files:       []*file{f},
+			}
+		case "submodule":
+			subs = append(subs, st)
+		default:
+			return nil, errors.Errorf("%s:%d: expected a module or submodule, got %s", st.File, st.Line, st.Keyword)
+		}
+	}
+	for _, st := range subs {
+		bt := st.sub("belongs-to")
+		if bt == nil {
+			return nil, errors.Errorf("%s:%d: submodule %s has no belongs-to", st.File, st.Line, st.Arg)
+		}
+		m := s.modules[bt.Arg]
+		if m == nil {
+			s.warnf("submodule %s belongs to %s, which isn't loaded", st.Arg, bt.Arg)
+			continue
+		}
+		m.files = append(m.files, &file{stmt: st, module: m.Name, prefix: bt.arg("prefix")})
+	}
+	for _, m := range s.modules {
+		for _, f := range m.files {
+			f.imports = map[string]string{f.prefix: f.module}
+			for _, im := range f.stmt.Subs {
+				if im.Keyword == "import" {
+					f.imports[im.arg("prefix")] = im.Arg
+				}
+			}
+			f.stmt.walk(func(st *Statement) { st.f = f })
+		}
+	}
+
+	b := &builder{s: s}
+	for _, m := range s.Modules() {
+		for _, f := range m.files {
+			for _, st := range f.stmt.Subs {
+				if st.Keyword == "identity" {
+					b.identity(st)
+				}
+			}
+		}
+	}
+	for _, m := range s.Modules() {
+		for _, f := range m.files {
+			b.children(m.root, f.stmt.Subs, m.Name)
+		}
+	}
+	b.augments()
+	b.deviations()
+	for _, m := range s.Modules() {
+		walk(m.root, b.leafrefs)
+	}
+	return s, nil
+}
+
+// revision returns the latest revision date of a module; they sort as
+// strings.
+func revision(st *Statement) string {
+	var rev string
+	for _, r := range st.Subs {
+		if r.Keyword == "revision" && r.Arg > rev {
+			rev = r.Arg
+		}
+	}
+	return rev
+}
+
+func walk(n *Node, fn func(*Node)) {
+	fn(n)
+	for _, c := range n.Children {
+		walk(c, fn)
+	}
+}
+
+// builder turns statements into nodes.
+type builder struct {
+	s *Schema
+	// pending are the augments whose target doesn't exist yet.
+	pending []*Statement
+}
+
+func (b *builder) warnf(st *Statement, format string, args ...interface{}) {
+	b.s.warnf("%s:%d: %s", st.File, st.Line, fmt.Sprintf(format, args...))
+}
+
+// qualify splits a "prefix:name" reference made in st's file into module
+// and name. An unknown prefix gives an empty module.
+func qualify(st *Statement, ref string) (string, string) {
+	i := strings.IndexByte(ref, ':')
+	if i < 0 {
+		return st.f.module, ref
+	}
+	return st.f.imports[ref[:i]], ref[i+1:]
+}
+
+// definition finds the typedef or grouping called ref, visible from st:
+// first in the enclosing statements, then at the top of the module it
+// refers to.
+func (b *builder) definition(st *Statement, keyword, ref string) *Statement {
+	mod, name := qualify(st, ref)
+	if mod == st.f.module {
+		for p := st.Parent; p != nil; p = p.Parent {
+			for _, d := range p.Subs {
+				if d.Keyword == keyword && d.Arg == name {
+					return d
+				}
+			}
+		}
+	}
+	m := b.s.modules[mod]
+	if m == nil {
+		return nil
+	}
+	for _, f := range m.files {
+		for _, d := range f.stmt.Subs {
+			if d.Keyword == keyword && d.Arg == name {
+				return d
+			}
+		}
+	}
+	return nil
+}
+
+func (b *builder) identity(st *Statement) {
+	id := st.f.module + ":" + st.Arg
+	var bases []string
+	for _, base := range st.Subs {
+		if base.Keyword == "base" {
+			mod, name := qualify(base, base.Arg)
+			bases = append(bases, mod+":"+name)
+		}
+	}
+	b.s.identities[id] = bases
+}
+
+// children adds the data nodes in stmts to parent, in the namespace of
+// module.
+func (b *builder) children(parent *Node, stmts []*Statement, module string) {
+	for _, st := range stmts {
+		if _, ok := kinds[st.Keyword]; ok {
+			parent.Children = append(parent.Children, b.node(parent, st, module))
+			continue
+		}
+		if st.Keyword != "uses" {
+			continue
+		}
+		g := b.definition(st, "grouping", st.Arg)
+		if g == nil {
+			b.warnf(st, "grouping %s not found", st.Arg)
+			continue
+		}
+		b.children(parent, g.Subs, module)
+		for _, sub := range st.Subs {
+			switch sub.Keyword {
+			case "refine":
+				if n := b.descendant(parent, sub); n != nil {
+					refine(n, sub)
+				}
+			case "augment":
+				if n := b.descendant(parent, sub); n != nil {
+					b.children(n, sub.Subs, module)
+				}
+			}
+		}
+	}
+}
+
+func (b *builder) node(parent *Node, st *Statement, module string) *Node {
+	n := &Node{
+		Name:        st.Arg,
+		Module:      module,
+		Kind:        kinds[st.Keyword],
+		Description: st.arg("description"),
+		Config:      parent.Config,
+		Parent:      parent,
+		Default:     st.arg("default"),
+	}
+	refine(n, st)
+	switch n.Kind {
+	case List:
+		for _, k := range strings.Fields(st.arg("key")) {
+			if i := strings.IndexByte(k, ':'); i >= 0 {
+				k = k[i+1:]
+			}
+			n.Keys = append(n.Keys, k)
+		}
+		for _, u := range st.Subs {
+			if u.Keyword == "unique" {
+				n.Unique = append(n.Unique, strings.Fields(u.Arg))
+			}
+		}
+	case Leaf, LeafList:
+		if t := st.sub("type"); t != nil {
+			n.Type = b.typ(t, 0)
+		}
+	case Choice:
+		// Data nodes right under a choice are shorthand for a case each.
+		for _, c := range st.Subs {
+			k, ok := kinds[c.Keyword]
+			switch {
+			case !ok:
+			case k == Case:
+				n.Children = append(n.Children, b.node(n, c, module))
+			default:
+				cs := &Node{Name: c.Arg, Module: module, Kind: Case, Config: n.Config, Parent: n}
+				cs.Children = []*Node{b.node(cs, c, module)}
+				n.Children = append(n.Children, cs)
+			}
+		}
+		return n
+	}
+	b.children(n, st.Subs, module)
+	return n
+}
+
+// refine applies the properties in st, a data definition or a refine, to n.
+func refine(n *Node, st *Statement) {
+	for _, sub := range st.Subs {
+		switch sub.Keyword {
+		case "config":
+			n.Config = sub.Arg != "false"
+			walk(n, func(c *Node) {
+				if !n.Config {
+					c.Config = false
+				}
+			})
+		case "mandatory":
+			n.Mandatory = sub.Arg == "true"
+		case "presence":
+			n.Presence = true
+		case "description":
+			n.Description = sub.Arg
+		case "default":
+			n.Default = sub.Arg
+		}
+	}
+}
+
+// descendant resolves the schema node identifier in st's argument,
+// relative to n for a descendant path or from the module roots for an
+// absolute one.
+func (b *builder) descendant(n *Node, st *Statement) *Node {
+	path := st.Arg
+	if strings.HasPrefix(path, "/") {
+		n = nil
+	}
+	for _, step := range strings.Split(strings.Trim(path, "/"), "/") {
+		mod, name := "", step
+		if strings.IndexByte(step, ':') >= 0 {
+			mod, name = qualify(st, step)
+		}
+		if n == nil {
+			if mod == "" {
+				mod = st.f.module
+			}
+			m := b.s.modules[mod]
+			if m == nil {
+				return nil
+			}
+			n = m.root
+		}
+		if n = n.step(mod, name); n == nil {
+			return nil
+		}
+	}
+	return n
+}
+
+// augments applies the top-level augments, in as many passes as it takes
+// for augments of augmented nodes.
+func (b *builder) augments() {
+	for _, m := range b.s.Modules() {
+		for _, f := range m.files {
+			for _, st := range f.stmt.Subs {
+				if st.Keyword == "augment" {
+					b.pending = append(b.pending, st)
+				}
+			}
+		}
+	}
+	for len(b.pending) > 0 {
+		var left []*Statement
+		for _, st := range b.pending {
+			n := b.descendant(nil, st)
+			if n == nil {
+				left = append(left, st)
+				continue
+			}
+			b.children(n, st.Subs, st.f.module)
+		}
+		if len(left) == len(b.pending) {
+			for _, st := range left {
+				b.warnf(st, "augment target %s not found", st.Arg)
+			}
+			break
+		}
+		b.pending = left
+	}
+}
+
+// deviations removes the nodes deviated as not supported.
+func (b *builder) deviations() {
+	for _, m := range b.s.Modules() {
+		for _, f := range m.files {
+			for _, st := range f.stmt.Subs {
+				if st.Keyword != "deviation" {
+					continue
+				}
+				for _, d := range st.Subs {
+					if d.Keyword != "deviate" || d.Arg != "not-supported" {
+						continue
+					}
+					n := b.descendant(nil, st)
+					if n == nil {
+						b.warnf(st, "deviation target %s not found", st.Arg)
+						continue
+					}
+					p := n.Parent
+					for i, c := range p.Children {
+						if c == n {
+							p.Children = append(p.Children[:i:i], p.Children[i+1:]...)
+							break
+						}
+					}
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,286 @@
+package yang
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// testModules exercise what build resolves: typedefs, groupings, refines,
+// identities, choices, leafrefs, a submodule, an augment and a deviation
+// from another module.
+var testModules = map[string]string{
+	"a.yang": `module a {
+    namespace "urn:a";
+    prefix a;
+    include a-sub;
+
+    revision 2019-06-01;
+
+    identity transport;
+    identity tcp { base transport; }
+    identity udp { base a:transport; }
+
+    typedef percent {
+        type uint8 { range "0..100"; }
+    }
+    typedef small-percent {
+        type percent { range "0..10"; }
+    }
+    typedef mode {
+        type enumeration {
+            enum off;
+            enum on { value 5; }
+            enum auto;
+        }
+    }
+
+    grouping endpoint {
+        leaf host { type string; }
+        leaf port { type uint16; }
+    }
+
+    container top {
+        uses endpoint {
+            refine port { mandatory true; description "Port to use"; }
+        }
+        leaf load { type small-percent; }
+        leaf mode { type mode; }
+        leaf on-only {
+            type mode { enum on; }
+        }
+        leaf proto { type identityref { base transport; } }
+        leaf ratio { type decimal64 { fraction-digits 2; range "0..1"; } }
+        leaf name { type string { pattern "[a-z]+"; length "1..8"; } }
+        leaf flags { type bits { bit up; bit down; } }
+        leaf enabled { type boolean; }
+        leaf marker { type empty; }
+        leaf addr {
+            type union {
+                type uint32;
+                type string { pattern "\d+\.\d+\.\d+\.\d+"; }
+            }
+        }
+        leaf dropped { type string; }
+        list server {
+            key "host";
+            uses endpoint;
+        }
+        leaf primary {
+            type leafref { path "../server/host"; }
+        }
+        choice transport {
+            case tcp { leaf window { type uint32; } }
+            leaf datagram { type boolean; }
+        }
+    }
+}`,
+	"a-sub.yang": `submodule a-sub {
+    belongs-to a { prefix a; }
+    container extra {
+        leaf note { type string; }
+    }
+}`,
+	// An older revision of a, which loses to the one above.
+	"a-old.yang": `module a {
+    namespace "urn:a";
+    prefix a;
+    revision 2018-01-01;
+    container old;
+}`,
+	"b.yang": `module b {
+    namespace "urn:b";
+    prefix b;
+    import a { prefix x; }
+
+    augment "/x:top" {
+        leaf tag { type string; }
+    }
+    deviation "/x:top/x:dropped" {
+        deviate not-supported;
+    }
+    augment "/x:missing" {
+        leaf lost { type string; }
+    }
+    container c {
+        leaf t { type x:percent; }
+        leaf u { type x:unknown; }
+        uses x:nowhere;
+    }
+}`,
+}
+
+// testDir writes testModules to a directory the caller removes.
+func testDir(t *testing.T) string {
+	t.Helper()
+	dir, err := ioutil.TempDir("", "yang")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, src := range testModules {
+		if err = ioutil.WriteFile(filepath.Join(dir, name), []byte(src), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+// node returns the node at a path of names, from the top of module m.
+func node(t *testing.T, s *Schema, m string, names ...string) *Node {
+	t.Helper()
+	n := s.Module(m).root
+	for _, name := range names {
+		c := n.step("", name)
+		if c == nil {
+			t.Fatalf("%s has no child %s", n.Path(), name)
+		}
+		n = c
+	}
+	return n
+}
+
+func TestLoad(t *testing.T) {
+	dir := testDir(t)
+	defer os.RemoveAll(dir)
+	s, err := Load(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var names []string
+	for _, m := range s.Modules() {
+		names = append(names, m.Name+"@"+m.Revision)
+	}
+	if want := []string{"a@2019-06-01", "b@"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("Modules() = %v, want %v", names, want)
+	}
+	var top []string
+	for _, n := range s.Module("a").Nodes() {
+		top = append(top, n.Path())
+	}
+	if want := []string{"/a:top", "/a:extra"}; !reflect.DeepEqual(top, want) {
+		t.Errorf("top-level nodes of a are %v, want %v", top, want)
+	}
+
+	port := node(t, s, "a", "top", "port")
+	if !port.Mandatory || port.Description != "Port to use" || port.Type.Kind != "uint16" {
+		t.Errorf("refined port = %+v", port)
+	}
+	if n := node(t, s, "a", "top", "server", "port"); n.Mandatory {
+		t.Errorf("refine leaked into the other use of the grouping")
+	}
+	if tag := node(t, s, "a", "top", "tag"); tag.Module != "b" || tag.Path() != "/a:top/b:tag" {
+		t.Errorf("augmented leaf %s is in module %s", tag.Path(), tag.Module)
+	}
+	if n := s.Module("a").root.Child("a", "top").Child("a", "dropped"); n != nil {
+		t.Errorf("deviated %s is still there", n.Path())
+	}
+	if n := s.Module("a").root.Child("a", "top").Child("a", "window"); n == nil || n.Path() != "/a:top/window" {
+		t.Errorf("Child() doesn't see through the choice: %v", n)
+	}
+	if n := s.Module("a").root.Child("a", "top").Child("a", "datagram"); n == nil || n.Path() != "/a:top/datagram" {
+		t.Errorf("Child() doesn't find a leaf straight under the choice: %v", n)
+	}
+	primary := node(t, s, "a", "top", "primary")
+	if primary.Type.Target != node(t, s, "a", "top", "server", "host") {
+		t.Errorf("leafref %s points to %v", primary.Type.Path, primary.Type.Target)
+	}
+
+	mode := node(t, s, "a", "top", "mode").Type
+	if mode.Kind != "enumeration" || mode.Name != "mode" || !reflect.DeepEqual(mode.Enums, []string{"off", "on", "auto"}) || !reflect.DeepEqual(mode.Values, []int{0, 5, 6}) {
+		t.Errorf("mode = %+v", mode)
+	}
+	if on := node(t, s, "a", "top", "on-only").Type; !reflect.DeepEqual(on.Enums, []string{"on"}) || !reflect.DeepEqual(on.Values, []int{5}) {
+		t.Errorf("restricted enumeration = %v %v, want [on] [5]", on.Enums, on.Values)
+	}
+
+	for _, w := range []string{
+		"augment target /x:missing not found",
+		"type x:unknown not found",
+		"grouping x:nowhere not found",
+	} {
+		if !strings.Contains(strings.Join(s.Warnings, "\n"), w) {
+			t.Errorf("Warnings = %q, want %q", s.Warnings, w)
+		}
+	}
+}
+
+func TestLoadDirs(t *testing.T) {
+	if s, err := Load("", ""); s != nil || err != nil {
+		t.Errorf(`Load("") = %v, %v, want nil`, s, err)
+	}
+	dir, err := ioutil.TempDir("", "yang")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	if _, err = Load(dir); err == nil || !strings.Contains(err.Error(), "no YANG modules in") {
+		t.Errorf("Load() of an empty directory = %v", err)
+	}
+	if err = ioutil.WriteFile(filepath.Join(dir, "bad.yang"), []byte("module bad {"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = Load(dir); err == nil || !strings.Contains(err.Error(), "bad.yang:1: missing '}' for module") {
+		t.Errorf("Load() of a bad module = %v", err)
+	}
+}
+
+func TestTypes(t *testing.T) {
+	dir := testDir(t)
+	defer os.RemoveAll(dir)
+	s, err := Load(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		leaf string
+		val  string
+		want string
+	}{
+		{leaf: "load", val: `10`},
+		// Both ranges on the way to uint8 apply.
+		{leaf: "load", val: `50`, want: "50 is out of range for small-percent, a uint8"},
+		{leaf: "load", val: `1.5`, want: "expected an integer (small-percent, a uint8), got 1.5"},
+		{leaf: "mode", val: `"auto"`},
+		{leaf: "mode", val: `"fast"`, want: `expected one of off, on, auto (mode, a enumeration), got string "fast"`},
+		{leaf: "on-only", val: `"off"`, want: `expected one of on (mode, a enumeration), got string "off"`},
+		{leaf: "proto", val: `"a:tcp"`},
+		{leaf: "proto", val: `"udp"`},
+		{leaf: "proto", val: `"a:quic"`, want: "unknown identity a:quic"},
+		{leaf: "ratio", val: `"0.25"`},
+		{leaf: "ratio", val: `"0.125"`, want: "0.125 has more than 2 fraction digits"},
+		{leaf: "ratio", val: `"1.5"`, want: "1.5 is out of range for decimal64"},
+		{leaf: "name", val: `"abc"`},
+		{leaf: "name", val: `"ABC"`, want: `"ABC" doesn't match the pattern of string`},
+		{leaf: "name", val: `"abcdefghi"`, want: `"abcdefghi" has the wrong length for string`},
+		{leaf: "flags", val: `"up down"`},
+		{leaf: "flags", val: `"sideways"`, want: "unknown bit sideways, expected some of up, down"},
+		{leaf: "enabled", val: `"true"`, want: `expected true or false, got string "true"`},
+		{leaf: "marker", val: `[null]`},
+		{leaf: "marker", val: `true`, want: "expected [null] for an empty leaf, got boolean true"},
+		{leaf: "addr", val: `167772161`},
+		{leaf: "addr", val: `"10.0.0.1"`},
+		{leaf: "addr", val: `"ten"`, want: `string "ten" doesn't match any type of union`},
+	}
+	for _, tt := range tests {
+		js := `{"a:top": {"port": 1, "` + tt.leaf + `": ` + tt.val + `}}`
+		ps, err := s.Validate([]byte(js), Merge)
+		if err != nil {
+			t.Errorf("%s = %s: %v", tt.leaf, tt.val, err)
+			continue
+		}
+		var got []string
+		for _, p := range ps {
+			got = append(got, p.Msg)
+		}
+		switch {
+		case tt.want == "" && got != nil:
+			t.Errorf("%s = %s: %q, want no problems", tt.leaf, tt.val, got)
+		case tt.want != "" && (len(got) != 1 || !strings.HasPrefix(got[0], tt.want)):
+			t.Errorf("%s = %s: %q, want %q", tt.leaf, tt.val, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,286 @@
+package yang
+
+import (
+	"math/big"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Type is a leaf type with its typedefs resolved down to a built-in type.
+type Type struct {
+	// Kind is the built-in type, e.g. "uint16" or "enumeration". It's
+	// empty if the type couldn't be resolved, and then any value goes.
+	Kind string
+	// Name is the type as written, e.g. "oc-inet:ipv4-address".
+	Name string
+	// Enums are the names of an enumeration, Bits those of a bits type.
+	Enums []string
+	Bits  []string
+	// Values are the values of Enums: the value statement if there is one,
+	// or one more than the highest value before it, as RFC 7950 assigns
+	// them.
+	Values []int
+	// Bases are the "module:identity" bases of an identityref.
+	Bases []string
+	// Union holds the member types of a union.
+	Union []*Type
+	// Path is the path of a leafref; Target is the node it points to.
+	Path           string
+	Target         *Node
+	FractionDigits int
+
+	// Every restriction on the way from the built-in type applies.
+	ranges   [][]interval
+	lengths  [][]interval
+	patterns []pattern
+
+	// path is the path statement, for resolving the prefixes in Path.
+	path *Statement
+}
+
+// interval is a range or length part, e.g. "1..10", "min..max" or "5".
+type interval struct {
+	lo, hi string
+}
+
+type pattern struct {
+	re     *regexp.Regexp
+	invert bool
+}
+
+var builtin = map[string]bool{
+	"binary": true, "bits": true, "boolean": true, "decimal64": true,
+	"empty": true, "enumeration": true, "identityref": true,
+	"instance-identifier": true, "leafref": true, "string": true, "union": true,
+	"int8": true, "int16": true, "int32": true, "int64": true,
+	"uint8": true, "uint16": true, "uint32": true, "uint64": true,
+}
+
+// maxDepth bounds typedef chains, in case of loops.
+const maxDepth = 32
+
+// typ resolves a type statement.
+func (b *builder) typ(st *Statement, depth int) *Type {
+	var t *Type
+	switch {
+	case builtin[st.Arg]:
+		t = &Type{Kind: st.Arg}
+	case depth > maxDepth:
+		b.warnf(st, "type %s: typedefs nest too deep", st.Arg)
+		t = &Type{}
+	default:
+		td := b.definition(st, "typedef", st.Arg)
+		if td == nil || td.sub("type") == nil {
+			b.warnf(st, "type %s not found", st.Arg)
+			t = &Type{}
+			break
+		}
+		base := b.typ(td.sub("type"), depth+1)
+		// Copy, so restrictions here don't leak into other users.
+		c := *base
+		c.ranges = append([][]interval(nil), base.ranges...)
+		c.lengths = append([][]interval(nil), base.lengths...)
+		c.patterns = append([]pattern(nil), base.patterns...)
+		t = &c
+	}
+	t.Name = st.Arg
+	var enums, bits []string
+	var values []int
+	next := 0
+	for _, sub := range st.Subs {
+		switch sub.Keyword {
+		case "range":
+			t.ranges = append(t.ranges, intervals(sub.Arg))
+		case "length":
+			t.lengths = append(t.lengths, intervals(sub.Arg))
+		case "pattern":
+			// YANG patterns are XML Schema regular expressions, which are
+			// implicitly anchored. Most translate as they are; the rest
+			// aren't checked.
+			re, err := regexp.Compile("^(?:" + sub.Arg + ")$")
+			if err != nil {
+				b.warnf(sub, "pattern not checked: %v", err)
+				continue
+			}
+			t.patterns = append(t.patterns, pattern{re: re, invert: sub.arg("modifier") == "invert-match"})
+		case "enum":
+			v := next
+			if arg := sub.arg("value"); arg != "" {
+				v, _ = strconv.Atoi(arg)
+			} else {
+				// A restated enum keeps its value from the base type.
+				for i, e := range t.Enums {
+					if e == sub.Arg {
+						v = t.Values[i]
+					}
+				}
+			}
+			if v >= next {
+				next = v + 1
+			}
+			enums, values = append(enums, sub.Arg), append(values, v)
+		case "bit":
+			bits = append(bits, sub.Arg)
+		case "base":
+			mod, name := qualify(sub, sub.Arg)
+			t.Bases = append(t.Bases, mod+":"+name)
+		case "fraction-digits":
+			t.FractionDigits, _ = strconv.Atoi(sub.Arg)
+		case "path":
+			t.Path, t.path = sub.Arg, sub
+		case "type":
+			t.Union = append(t.Union, b.typ(sub, depth+1))
+		}
+	}
+	// Restating enums or bits in a derived type restricts them.
+	if enums != nil {
+		t.Enums, t.Values = enums, values
+	}
+	if bits != nil {
+		t.Bits = bits
+	}
+	return t
+}
+
+func intervals(arg string) []interval {
+	var out []interval
+	for _, part := range strings.Split(arg, "|") {
+		part = strings.TrimSpace(part)
+		if i := strings.Index(part, ".."); i >= 0 {
+			out = append(out, interval{strings.TrimSpace(part[:i]), strings.TrimSpace(part[i+2:])})
+			continue
+		}
+		out = append(out, interval{part, part})
+	}
+	return out
+}
+
+// leafrefs points the leafref types of n to their targets.
+func (b *builder) leafrefs(n *Node) {
+	var resolve func(t *Type)
+	resolve = func(t *Type) {
+		if t == nil {
+			return
+		}
+		for _, u := range t.Union {
+			resolve(u)
+		}
+		if t.Kind != "leafref" || t.path == nil || t.Target != nil {
+			return
+		}
+		if t.Target = b.leafref(n, t); t.Target == nil {
+			b.warnf(t.path, "leafref target %s not found", t.Path)
+		}
+	}
+	resolve(n.Type)
+}
+
+// leafref follows the path of t from n. Predicates are dropped, since only
+// the type of the target matters.
+func (b *builder) leafref(n *Node, t *Type) *Node {
+	path := predicate.ReplaceAllString(t.Path, "")
+	path = strings.Join(strings.Fields(path), "")
+	cur := n
+	if strings.HasPrefix(path, "/") {
+		cur = nil
+	}
+	for _, step := range strings.Split(strings.Trim(path, "/"), "/") {
+		if step == "" {
+			continue
+		}
+		if step == ".." {
+			if cur == nil {
+				return nil
+			}
+			cur = cur.Parent
+			for cur != nil && (cur.Kind == Choice || cur.Kind == Case) {
+				cur = cur.Parent
+			}
+			if cur == nil {
+				return nil
+			}
+			continue
+		}
+		mod, name := qualify(t.path, step)
+		if cur == nil {
+			m := b.s.modules[mod]
+			if m == nil {
+				return nil
+			}
+			cur = m.root
+		}
+		next := cur.Child(mod, name)
+		if next == nil {
+			// Steps in groupings belong to whoever uses them.
+			next = cur.Child(cur.Module, name)
+		}
+		if cur = next; cur == nil {
+			return nil
+		}
+	}
+	return cur
+}
+
+var predicate = regexp.MustCompile(`\[[^\]]*\]`)
+
+// bounds of the integer types.
+var bounds = map[string][2]string{
+	"int8":   {"-128", "127"},
+	"int16":  {"-32768", "32767"},
+	"int32":  {"-2147483648", "2147483647"},
+	"int64":  {"-9223372036854775808", "9223372036854775807"},
+	"uint8":  {"0", "255"},
+	"uint16": {"0", "65535"},
+	"uint32": {"0", "4294967295"},
+	"uint64": {"0", "18446744073709551615"},
+}
+
+// lengthBounds are the bounds of string and binary lengths.
+var lengthBounds = [2]string{"0", "18446744073709551615"}
+
+// within reports whether x falls in every list of intervals, with min and
+// max standing for the bounds of the type.
+func within(x *big.Rat, lists [][]interval, min, max string) bool {
+	for _, list := range lists {
+		ok := false
+		for _, iv := range list {
+			lo, hi := rat(iv.lo, min, max), rat(iv.hi, min, max)
+			if lo == nil || hi == nil {
+				// Not a bound we understand; don't fail the value on it.
+				ok = true
+				break
+			}
+			if x.Cmp(lo) >= 0 && x.Cmp(hi) <= 0 {
+				ok = true
+				break
+			}
+		}
+		if !ok {
+			return false
+		}
+	}
+	return true
+}
+
+func rat(s, min, max string) *big.Rat {
+	switch s {
+	case "min":
+		s = min
+	case "max":
+		s = max
+	}
+	r, ok := new(big.Rat).SetString(s)
+	if !ok {
+		return nil
+	}
+	return r
+}
+
+// decimalBounds returns the bounds of a decimal64 with fd fraction digits.
+func decimalBounds(fd int) (string, string) {
+	scale := new(big.Rat).SetFrac(big.NewInt(1), new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(fd)), nil))
+	lo, _ := new(big.Rat).SetString(bounds["int64"][0])
+	hi, _ := new(big.Rat).SetString(bounds["int64"][1])
+	return lo.Mul(lo, scale).FloatString(fd), hi.Mul(hi, scale).FloatString(fd)
+}
@@ -0,0 +1,42 @@
+// Code generated by yanggo from test. DO NOT EDIT.
+
+package user
+
+import "encoding/json"
+
+// Root holds the top-level nodes; marshalled to JSON it is a payload
+// for MergeConfig, ReplaceConfig or DeleteConfig.
+type Root struct {
+	User *User `json:"test:user,omitempty"`
+}
+
+// JSON returns r as indented RFC 7951 JSON.
+func (r *Root) JSON() (string, error) {
+	b, err := json.MarshalIndent(r, "", "  ")
+	return string(b), err
+}
+
+// User is the container /test:user.
+type User struct {
+	Name  *string `json:"name,omitempty"`
+	Email *string `json:"email,omitempty"`
+	ID    *uint16 `json:"id,omitempty"`
+}
+
+// GetOrCreateUser returns user, creating it if needed.
+func (r *Root) GetOrCreateUser() *User {
+	if r.User == nil {
+		r.User = new(User)
+	}
+	return r.User
+}
+
+// String returns a pointer to v, for setting string leaves.
+func String(v string) *string {
+	return &v
+}
+
+// Uint16 returns a pointer to v, for setting uint16 leaves.
+func Uint16(v uint16) *uint16 {
+	return &v
+}
@@ -0,0 +1,467 @@
+package yang
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/pkg/errors"
+)
+
+// Op is the operation a payload is for. It decides which checks apply:
+// only a replace has to carry the mandatory leaves, as a merge may add to
+// what the router has and a delete only identifies what it removes.
+type Op int
+
+// Operations.
+const (
+	Merge Op = iota
+	Replace
+	Delete
+)
+
+// ParseOp returns the Op called s: "merge", "replace" or "delete".
+func ParseOp(s string) (Op, error) {
+	switch s {
+	case "merge":
+		return Merge, nil
+	case "replace":
+		return Replace, nil
+	case "delete":
+		return Delete, nil
+	}
+	return 0, errors.Errorf("operation '%v' not supported", s)
+}
+
+// Problem is something wrong with a payload, at Path.
+type Problem struct {
+	Path string
+	Msg  string
+	// Unchecked is set when the problem is only that the payload uses a
+	// module that isn't loaded, so that part wasn't checked.
+	Unchecked bool
+}
+
+func (p Problem) Error() string {
+	return p.Path + ": " + p.Msg
+}
+
+// Errors are the problems that make a payload invalid.
+type Errors []Problem
+
+func (e Errors) Error() string {
+	msgs := make([]string, len(e))
+	for i, p := range e {
+		msgs[i] = p.Error()
+	}
+	return strings.Join(msgs, "\n")
+}
+
+// Validate checks a JSON payload for op and returns the problems found,
+// sorted by path. The error is only for payloads that aren't JSON.
+func (s *Schema) Validate(js []byte, op Op) ([]Problem, error) {
+	d := json.NewDecoder(bytes.NewReader(js))
+	d.UseNumber()
+	var doc map[string]interface{}
+	if err := d.Decode(&doc); err != nil {
+		return nil, errors.Wrap(err, "could not parse the payload")
+	}
+	if s == nil {
+		return nil, nil
+	}
+	v := &validator{s: s, op: op}
+	for _, k := range sortedKeys(doc) {
+		path := "/" + k
+		mod, name := split(k)
+		if mod == "" {
+			v.problem(path, "top-level names must have a module prefix, as in \"module:%s\"", k)
+			continue
+		}
+		m := s.modules[mod]
+		if m == nil {
+			v.ps = append(v.ps, Problem{Path: path, Msg: fmt.Sprintf("module %s isn't loaded", mod), Unchecked: true})
+			continue
+		}
+		n := m.root.Child(mod, name)
+		if n == nil {
+			v.problem(path, "module %s has no top-level node %s", mod, name)
+			continue
+		}
+		v.node(n, doc[k], path)
+	}
+	sort.SliceStable(v.ps, func(i, j int) bool { return v.ps[i].Path < v.ps[j].Path })
+	return v.ps, nil
+}
+
+// Check is Validate for callers that only care whether the payload can be
+// sent: it returns the problems as Errors, if any. Nodes from modules that
+// aren't loaded aren't checked; they are errors with Strict, and otherwise
+// go to Warn, once per module.
+func (s *Schema) Check(js []byte, op Op) error {
+	if s == nil {
+		return nil
+	}
+	ps, err := s.Validate(js, op)
+	if err != nil {
+		return err
+	}
+	var errs Errors
+	warned := make(map[string]bool)
+	for _, p := range ps {
+		switch {
+		case !p.Unchecked || s.Strict:
+			errs = append(errs, p)
+		case s.Warn != nil && !warned[p.Msg]:
+			warned[p.Msg] = true
+			s.Warn("%s, so %s wasn't checked", p.Msg, p.Path)
+		}
+	}
+	if errs == nil {
+		return nil
+	}
+	return errs
+}
+
+type validator struct {
+	s  *Schema
+	op Op
+	ps []Problem
+}
+
+func (v *validator) problem(path, format string, args ...interface{}) {
+	v.ps = append(v.ps, Problem{Path: path, Msg: fmt.Sprintf(format, args...)})
+}
+
+// split splits a JSON member name into module and name.
+func split(k string) (string, string) {
+	if i := strings.IndexByte(k, ':'); i >= 0 {
+		return k[:i], k[i+1:]
+	}
+	return "", k
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// node checks val, the value of n at path.
+func (v *validator) node(n *Node, val interface{}, path string) {
+	if !n.Config {
+		v.problem(path, "%s is state data and can't be configured", n.Name)
+		return
+	}
+	switch n.Kind {
+	case Container:
+		obj, ok := val.(map[string]interface{})
+		if !ok {
+			v.problem(path, "expected an object for container %s, got %s", n.Name, describe(val))
+			return
+		}
+		v.object(n, obj, path)
+	case List:
+		entries, ok := val.([]interface{})
+		if !ok {
+			v.problem(path, "expected an array for list %s, got %s", n.Name, describe(val))
+			return
+		}
+		// Entries may repeat a key; the router merges them.
+		for i, e := range entries {
+			obj, ok := e.(map[string]interface{})
+			if !ok {
+				v.problem(fmt.Sprintf("%s[%d]", path, i), "expected an object for a %s entry, got %s", n.Name, describe(e))
+				continue
+			}
+			v.object(n, obj, v.entry(n, obj, path, i))
+		}
+	case Leaf:
+		v.leaf(n, n.Type, val, path)
+	case LeafList:
+		vals, ok := val.([]interface{})
+		if !ok {
+			v.problem(path, "expected an array for leaf-list %s, got %s", n.Name, describe(val))
+			return
+		}
+		for i, e := range vals {
+			v.leaf(n, n.Type, e, fmt.Sprintf("%s[%d]", path, i))
+		}
+	}
+}
+
+// entry checks the keys of a list entry and returns its path, as in
+// "interface[name=Loopback0]".
+func (v *validator) entry(n *Node, obj map[string]interface{}, path string, i int) string {
+	var preds, missing []string
+	for _, k := range n.Keys {
+		val, ok := obj[k]
+		if !ok {
+			missing = append(missing, k)
+			continue
+		}
+		preds = append(preds, fmt.Sprintf("[%s=%v]", k, val))
+	}
+	if missing != nil || len(preds) == 0 {
+		epath := fmt.Sprintf("%s[%d]", path, i)
+		if missing != nil {
+			v.problem(epath, "missing list key %s", strings.Join(missing, ", "))
+		}
+		return epath
+	}
+	return path + strings.Join(preds, "")
+}
+
+// object checks the members of a container or list entry and, for a
+// replace, that the mandatory nodes under it are there.
+func (v *validator) object(n *Node, obj map[string]interface{}, path string) {
+	for _, k := range sortedKeys(obj) {
+		mod, name := split(k)
+		if mod == "" {
+			mod = n.Module
+		}
+		c := n.Child(mod, name)
+		if c == nil {
+			if mod != n.Module && v.s.modules[mod] == nil {
+				v.ps = append(v.ps, Problem{Path: path + "/" + k, Msg: fmt.Sprintf("module %s isn't loaded", mod), Unchecked: true})
+				continue
+			}
+			v.problem(path+"/"+k, "%s has no node %s", n.Name, k)
+			continue
+		}
+		v.node(c, obj[k], path+"/"+k)
+	}
+	if v.op == Replace {
+		v.mandatory(n, obj, path)
+	}
+}
+
+// mandatory reports the mandatory leaves missing from obj, including those
+// in containers that exist because their parent does. Mandatory nodes
+// inside choices are only so when their case is chosen, and aren't checked.
+func (v *validator) mandatory(n *Node, obj map[string]interface{}, path string) {
+	for _, c := range n.Children {
+		if !c.Config || c.Kind == Choice {
+			continue
+		}
+		k := c.Name
+		if c.Module != n.Module {
+			k = c.Module + ":" + c.Name
+		}
+		if _, ok := obj[k]; ok {
+			continue
+		}
+		switch {
+		case c.Mandatory:
+			v.problem(path+"/"+k, "missing mandatory %s %s", c.Kind, c.Name)
+		case c.Kind == Container && !c.Presence:
+			v.mandatory(c, nil, path+"/"+k)
+		}
+	}
+}
+
+func (v *validator) leaf(n *Node, t *Type, val interface{}, path string) {
+	if t == nil {
+		return
+	}
+	if msg := v.check(n, t, val, 0); msg != "" {
+		v.problem(path, "%s", msg)
+	}
+}
+
+// check returns what's wrong with val as a value of t, or "".
+func (v *validator) check(n *Node, t *Type, val interface{}, depth int) string {
+	switch t.Kind {
+	case "":
+		return ""
+	case "int8", "int16", "int32", "uint8", "uint16", "uint32", "int64", "uint64":
+		s, ok := val.(json.Number)
+		// RFC 7951 encodes 64-bit integers as strings; take both.
+		if str, isStr := val.(string); isStr && (t.Kind == "int64" || t.Kind == "uint64") {
+			s, ok = json.Number(str), true
+		}
+		if !ok {
+			return fmt.Sprintf("expected a number (%s), got %s", typeName(t), describe(val))
+		}
+		x, ok := new(big.Int).SetString(string(s), 10)
+		if !ok {
+			return fmt.Sprintf("expected an integer (%s), got %s", typeName(t), s)
+		}
+		b := bounds[t.Kind]
+		r := new(big.Rat).SetInt(x)
+		if !within(r, [][]interval{{{b[0], b[1]}}}, b[0], b[1]) || !within(r, t.ranges, b[0], b[1]) {
+			return fmt.Sprintf("%s is out of range for %s", s, typeName(t))
+		}
+	case "decimal64":
+		var s string
+		switch x := val.(type) {
+		case json.Number:
+			s = string(x)
+		case string:
+			s = x
+		default:
+			return fmt.Sprintf("expected a decimal number (%s), got %s", typeName(t), describe(val))
+		}
+		r, ok := new(big.Rat).SetString(s)
+		if !ok {
+			return fmt.Sprintf("expected a decimal number (%s), got %q", typeName(t), s)
+		}
+		if i := strings.IndexByte(s, '.'); i >= 0 && len(s)-i-1 > t.FractionDigits {
+			return fmt.Sprintf("%s has more than %d fraction digits", s, t.FractionDigits)
+		}
+		lo, hi := decimalBounds(t.FractionDigits)
+		if !within(r, t.ranges, lo, hi) {
+			return fmt.Sprintf("%s is out of range for %s", s, typeName(t))
+		}
+	case "string":
+		s, ok := val.(string)
+		if !ok {
+			return fmt.Sprintf("expected a string (%s), got %s", typeName(t), describe(val))
+		}
+		l := new(big.Rat).SetInt64(int64(utf8.RuneCountInString(s)))
+		if !within(l, t.lengths, lengthBounds[0], lengthBounds[1]) {
+			return fmt.Sprintf("%q has the wrong length for %s", s, typeName(t))
+		}
+		for _, p := range t.patterns {
+			if p.re.MatchString(s) == p.invert {
+				return fmt.Sprintf("%q doesn't match the pattern of %s", s, typeName(t))
+			}
+		}
+	case "boolean":
+		if _, ok := val.(bool); !ok {
+			return fmt.Sprintf("expected true or false, got %s", describe(val))
+		}
+	case "empty":
+		if a, ok := val.([]interface{}); !ok || len(a) != 1 || a[0] != nil {
+			return fmt.Sprintf("expected [null] for an empty leaf, got %s", describe(val))
+		}
+	case "enumeration":
+		s, ok := val.(string)
+		if !ok || !contains(t.Enums, s) {
+			return fmt.Sprintf("expected one of %s (%s), got %s", strings.Join(t.Enums, ", "), typeName(t), describe(val))
+		}
+	case "bits":
+		s, ok := val.(string)
+		if !ok {
+			return fmt.Sprintf("expected a string of bits (%s), got %s", typeName(t), describe(val))
+		}
+		for _, bit := range strings.Fields(s) {
+			if !contains(t.Bits, bit) {
+				return fmt.Sprintf("unknown bit %s, expected some of %s", bit, strings.Join(t.Bits, ", "))
+			}
+		}
+	case "binary":
+		s, ok := val.(string)
+		if !ok {
+			return fmt.Sprintf("expected base64 data, got %s", describe(val))
+		}
+		b, err := base64.StdEncoding.DecodeString(s)
+		if err != nil {
+			return fmt.Sprintf("expected base64 data, got %q", s)
+		}
+		if !within(new(big.Rat).SetInt64(int64(len(b))), t.lengths, lengthBounds[0], lengthBounds[1]) {
+			return fmt.Sprintf("binary value has the wrong length for %s", typeName(t))
+		}
+	case "identityref":
+		s, ok := val.(string)
+		if !ok {
+			return fmt.Sprintf("expected an identity (%s), got %s", typeName(t), describe(val))
+		}
+		mod, name := split(s)
+		if mod == "" {
+			mod = n.Module
+		}
+		if v.s.modules[mod] == nil {
+			// Identities often come from modules that aren't loaded.
+			return ""
+		}
+		id := mod + ":" + name
+		if _, ok := v.s.identities[id]; !ok {
+			return fmt.Sprintf("unknown identity %s", s)
+		}
+		for _, base := range t.Bases {
+			if !v.derives(id, base, 0) {
+				return fmt.Sprintf("identity %s isn't derived from %s", s, base)
+			}
+		}
+	case "union":
+		var msgs []string
+		for _, u := range t.Union {
+			msg := v.check(n, u, val, depth+1)
+			if msg == "" {
+				return ""
+			}
+			msgs = append(msgs, msg)
+		}
+		if len(msgs) > 0 {
+			return fmt.Sprintf("%s doesn't match any type of %s: %s", describe(val), typeName(t), strings.Join(msgs, "; "))
+		}
+	case "leafref":
+		if t.Target != nil && t.Target.Type != nil && depth < maxDepth {
+			return v.check(t.Target, t.Target.Type, val, depth+1)
+		}
+	case "instance-identifier":
+		if _, ok := val.(string); !ok {
+			return fmt.Sprintf("expected an instance identifier, got %s", describe(val))
+		}
+	}
+	return ""
+}
+
+// derives reports whether identity id is base or derived from it.
+func (v *validator) derives(id, base string, depth int) bool {
+	if id == base {
+		return true
+	}
+	if depth > maxDepth {
+		return false
+	}
+	for _, b := range v.s.identities[id] {
+		if v.derives(b, base, depth+1) {
+			return true
+		}
+	}
+	return false
+}
+
+func contains(list []string, s string) bool {
+	for _, l := range list {
+		if l == s {
+			return true
+		}
+	}
+	return false
+}
+
+func typeName(t *Type) string {
+	if t.Name != "" && t.Name != t.Kind {
+		return t.Name + ", a " + t.Kind
+	}
+	return t.Kind
+}
+
+// describe says what a JSON value is, for error messages.
+func describe(val interface{}) string {
+	switch x := val.(type) {
+	case nil:
+		return "null"
+	case string:
+		return fmt.Sprintf("string %q", x)
+	case json.Number:
+		return "number " + string(x)
+	case bool:
+		return fmt.Sprintf("boolean %v", x)
+	case []interface{}:
+		return "an array"
+	case map[string]interface{}:
+		return "an object"
+	}
+	return fmt.Sprintf("%v", val)
+}
@@ -0,0 +1,176 @@
+package yang
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+const testModule = `module t {
+    namespace "urn:t";
+    prefix t;
+
+    container system {
+        leaf hostname {
+            type string {
+                length "1..16";
+            }
+            mandatory true;
+        }
+        leaf mtu {
+            type uint16 {
+                range "64..9000";
+            }
+        }
+        list server {
+            key "address";
+            leaf address {
+                type string;
+            }
+            leaf port {
+                type uint16;
+                mandatory true;
+            }
+        }
+        leaf uptime {
+            type uint32;
+            config false;
+        }
+    }
+}`
+
+func testSchema(t *testing.T) *Schema {
+	t.Helper()
+	stmts, err := Parse("t.yang", []byte(testModule))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := build(stmts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestValidate(t *testing.T) {
+	s := testSchema(t)
+	tests := []struct {
+		name string
+		js   string
+		op   Op
+		want []string
+	}{
+		{name: "merge", js: `{"t:system": {"mtu": 1500}}`, op: Merge},
+		{name: "replace", js: `{"t:system": {"hostname": "r1", "mtu": 1500}}`, op: Replace},
+		{name: "delete", js: `{"t:system": {"server": [{"address": "10.0.0.1"}]}}`, op: Delete},
+		{
+			name: "replace mandatory",
+			js:   `{"t:system": {"mtu": 1500, "server": [{"address": "10.0.0.1"}]}}`,
+			op:   Replace,
+			want: []string{
+				"/t:system/hostname: missing mandatory leaf hostname",
+				"/t:system/server[address=10.0.0.1]/port: missing mandatory leaf port",
+			},
+		},
+		{
+			name: "merge list entry",
+			js:   `{"t:system": {"server": [{"address": "10.0.0.1"}]}}`,
+			op:   Merge,
+		},
+		{
+			name: "range",
+			js:   `{"t:system": {"mtu": 10}}`,
+			op:   Merge,
+			want: []string{"/t:system/mtu: 10 is out of range for uint16"},
+		},
+		{
+			name: "length",
+			js:   `{"t:system": {"hostname": ""}}`,
+			op:   Merge,
+			want: []string{`/t:system/hostname: "" has the wrong length for string`},
+		},
+		{
+			name: "type",
+			js:   `{"t:system": {"mtu": "1500"}}`,
+			op:   Merge,
+			want: []string{`/t:system/mtu: expected a number (uint16), got string "1500"`},
+		},
+		{
+			name: "unknown node",
+			js:   `{"t:system": {"domain": "example.com"}}`,
+			op:   Merge,
+			want: []string{"/t:system/domain: system has no node domain"},
+		},
+		{
+			name: "state",
+			js:   `{"t:system": {"uptime": 1}}`,
+			op:   Merge,
+			want: []string{"/t:system/uptime: uptime is state data and can't be configured"},
+		},
+		{
+			name: "list key",
+			js:   `{"t:system": {"server": [{"port": 53}]}}`,
+			op:   Merge,
+			want: []string{"/t:system/server[0]: missing list key address"},
+		},
+		{
+			name: "no prefix",
+			js:   `{"system": {}}`,
+			op:   Merge,
+			want: []string{`/system: top-level names must have a module prefix, as in "module:system"`},
+		},
+		{
+			name: "unloaded",
+			js:   `{"u:system": {}}`,
+			op:   Merge,
+			want: []string{"/u:system: module u isn't loaded"},
+		},
+	}
+	for _, tt := range tests {
+		ps, err := s.Validate([]byte(tt.js), tt.op)
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		var got []string
+		for _, p := range ps {
+			got = append(got, p.Error())
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: got %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestCheckUnloaded(t *testing.T) {
+	js := []byte(`{"t:system": {"mtu": 1500}, "u:a": {}, "u:b": {}}`)
+	tests := []struct {
+		strict bool
+		err    bool
+		warn   []string
+	}{
+		// One warning for module u, however many nodes use it.
+		{warn: []string{"module u isn't loaded, so /u:a wasn't checked"}},
+		{strict: true, err: true},
+	}
+	for _, tt := range tests {
+		s := testSchema(t)
+		var warn []string
+		s.Strict = tt.strict
+		s.Warn = func(format string, args ...interface{}) {
+			warn = append(warn, fmt.Sprintf(format, args...))
+		}
+		err := s.Check(js, Merge)
+		if (err != nil) != tt.err {
+			t.Errorf("strict %v: Check() = %v, want an error: %v", tt.strict, err, tt.err)
+		}
+		if !reflect.DeepEqual(warn, tt.warn) {
+			t.Errorf("strict %v: warned %q, want %q", tt.strict, warn, tt.warn)
+		}
+	}
+
+	var s *Schema
+	if err := s.Check([]byte(`{"u:a": {}}`), Replace); err != nil {
+		t.Errorf("nil Schema: Check() = %v, want nil", err)
+	}
+}
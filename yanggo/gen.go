@@ -0,0 +1,325 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"go/token"
+	"sort"
+	"strings"
+
+	"github.com/nleiva/clus2019/yang"
+)
+
+// generator writes Go types for the data nodes of a set of modules.
+type generator struct {
+	pkg   string
+	state bool
+	out   bytes.Buffer
+	// names are the type names taken so far.
+	names map[string]bool
+	// ptrs are the scalar types that need a pointer helper, e.g. "uint16".
+	ptrs  map[string]bool
+	empty bool
+}
+
+func newGenerator(pkg string, state bool) *generator {
+	return &generator{pkg: pkg, state: state, names: map[string]bool{"Root": true, "Empty": true}, ptrs: make(map[string]bool)}
+}
+
+func (g *generator) printf(format string, args ...interface{}) {
+	fmt.Fprintf(&g.out, format, args...)
+}
+
+// generate returns the gofmt'ed source for the modules.
+func (g *generator) generate(mods []*yang.Module) ([]byte, error) {
+	var names []string
+	var top []*yang.Node
+	for _, m := range mods {
+		names = append(names, m.Name)
+		top = append(top, yang.DataNodes(m.Nodes(), g.state)...)
+	}
+	g.printf("// Code generated by yanggo from %s. DO NOT EDIT.\n\n", strings.Join(names, ", "))
+	g.printf("package %s\n\n", g.pkg)
+	g.printf("import \"encoding/json\"\n\n")
+
+	fields := g.fields(top, "")
+	g.printf("// Root holds the top-level nodes; marshalled to JSON it is a payload\n")
+	g.printf("// for MergeConfig, ReplaceConfig or DeleteConfig.\n")
+	g.printf("type Root struct {\n")
+	for _, f := range fields {
+		g.printf("\t%s %s `json:\"%s,omitempty\"`\n", f.name, f.typ, f.tag)
+	}
+	g.printf("}\n\n")
+	g.printf("// JSON returns r as indented RFC 7951 JSON.\n")
+	g.printf("func (r *Root) JSON() (string, error) {\n")
+	g.printf("\tb, err := json.MarshalIndent(r, \"\", \"  \")\n")
+	g.printf("\treturn string(b), err\n}\n\n")
+	for _, f := range fields {
+		g.node(f, "Root", "r")
+	}
+	if g.empty {
+		g.printf("// Empty is the value of a leaf of type empty, set or not.\n")
+		g.printf("type Empty bool\n\n")
+		g.printf("// MarshalJSON encodes a set leaf as [null], as RFC 7951 says.\n")
+		g.printf("func (e Empty) MarshalJSON() ([]byte, error) {\n\treturn []byte(\"[null]\"), nil\n}\n\n")
+		g.printf("// UnmarshalJSON sets e.\n")
+		g.printf("func (e *Empty) UnmarshalJSON([]byte) error {\n\t*e = true\n\treturn nil\n}\n\n")
+	}
+	ptrs := make([]string, 0, len(g.ptrs))
+	for t := range g.ptrs {
+		ptrs = append(ptrs, t)
+	}
+	sort.Strings(ptrs)
+	for _, t := range ptrs {
+		fn := strings.Title(t)
+		g.printf("// %s returns a pointer to v, for setting %s leaves.\n", fn, t)
+		g.printf("func %s(v %s) *%s {\n\treturn &v\n}\n\n", fn, t, t)
+	}
+
+	src, err := format.Source(g.out.Bytes())
+	if err != nil {
+		return g.out.Bytes(), fmt.Errorf("could not format the generated code: %v", err)
+	}
+	return src, nil
+}
+
+// field is a struct field for a schema node.
+type field struct {
+	n    *yang.Node
+	name string
+	typ  string
+	tag  string
+	// def is the Go type the field refers to, for containers and lists.
+	def string
+}
+
+// fields builds the fields for ns, children of a type called owner.
+func (g *generator) fields(ns []*yang.Node, owner string) []field {
+	var out []field
+	used := make(map[string]bool)
+	for _, n := range ns {
+		f := field{n: n, name: yang.Camel(n.Name), tag: n.Name}
+		if n.Parent == nil || n.Parent.Name == "" || parentModule(n) != n.Module {
+			f.tag = n.Module + ":" + n.Name
+		}
+		if used[f.name] {
+			f.name += yang.Camel(n.Module)
+		}
+		used[f.name] = true
+		switch n.Kind {
+		case yang.Container:
+			f.def = g.typeName(owner + yang.Camel(n.Name))
+			f.typ = "*" + f.def
+		case yang.List:
+			f.def = g.typeName(owner + yang.Camel(n.Name))
+			f.typ = "[]*" + f.def
+		case yang.Leaf:
+			f.typ = g.leafType(owner+f.name, n, n.Type, true)
+		case yang.LeafList:
+			f.typ = "[]" + g.leafType(owner+f.name, n, n.Type, false)
+		default:
+			f.typ = "json.RawMessage"
+		}
+		if t := n.Type; t != nil && quoted(t) && strings.HasPrefix(f.typ, "*") {
+			f.tag += ",string"
+		}
+		out = append(out, f)
+	}
+	return out
+}
+
+// parentModule returns the module of the closest data node above n.
+func parentModule(n *yang.Node) string {
+	p := n.Parent
+	for p != nil && (p.Kind == yang.Choice || p.Kind == yang.Case) {
+		p = p.Parent
+	}
+	if p == nil {
+		return ""
+	}
+	return p.Module
+}
+
+// quoted reports whether RFC 7951 encodes values of t as strings.
+func quoted(t *yang.Type) bool {
+	switch t.Kind {
+	case "int64", "uint64", "decimal64":
+		return true
+	case "leafref":
+		return t.Target != nil && t.Target.Type != nil && quoted(t.Target.Type)
+	}
+	return false
+}
+
+// typeName returns a unique type name based on name.
+func (g *generator) typeName(name string) string {
+	n := name
+	for i := 2; g.names[n]; i++ {
+		n = fmt.Sprintf("%s%d", name, i)
+	}
+	g.names[n] = true
+	return n
+}
+
+// leafType returns the Go type for values of t. Scalars are pointers in
+// leaves, so unset ones are left out of the JSON.
+func (g *generator) leafType(name string, n *yang.Node, t *yang.Type, ptr bool) string {
+	base := g.scalar(name, n, t, 0)
+	switch {
+	case !ptr, base == "[]byte", base == "interface{}", base == "Empty", g.names[base]:
+		return base
+	}
+	g.ptrs[base] = true
+	return "*" + base
+}
+
+// scalar maps t to a Go type; enumerations get a type of their own, called
+// name.
+func (g *generator) scalar(name string, n *yang.Node, t *yang.Type, depth int) string {
+	switch t.Kind {
+	case "int8", "int16", "int32", "int64", "uint8", "uint16", "uint32", "uint64":
+		return t.Kind
+	case "decimal64":
+		return "float64"
+	case "boolean":
+		return "bool"
+	case "binary":
+		return "[]byte"
+	case "empty":
+		g.empty = true
+		return "Empty"
+	case "string", "bits", "identityref", "instance-identifier":
+		return "string"
+	case "enumeration":
+		return g.enum(name, t)
+	case "leafref":
+		if t.Target != nil && t.Target.Type != nil && depth < 8 {
+			return g.scalar(name, t.Target, t.Target.Type, depth+1)
+		}
+		return "string"
+	case "union":
+		var same string
+		for _, u := range t.Union {
+			// Enumerations in unions stay plain strings, not types of their own.
+			s := "string"
+			if u.Kind != "enumeration" {
+				s = g.scalar(name, n, u, depth+1)
+			}
+			if same != "" && s != same {
+				return "interface{}"
+			}
+			same = s
+		}
+		if same != "" {
+			return same
+		}
+	}
+	return "interface{}"
+}
+
+// enum declares a string type for an enumeration, with a constant per
+// value.
+func (g *generator) enum(name string, t *yang.Type) string {
+	name = g.typeName(name)
+	g.printf("// %s is an enumeration (%s).\n", name, t.Name)
+	g.printf("type %s string\n\n", name)
+	g.printf("// Values of %s.\n", name)
+	g.printf("const (\n")
+	for _, e := range t.Enums {
+		g.printf("\t%s%s %s = %q\n", name, yang.Camel(e), name, e)
+	}
+	g.printf(")\n\n")
+	return name
+}
+
+// node declares the struct for a container or list field of parent, its
+// builder method and the structs below.
+func (g *generator) node(f field, parent, recv string) {
+	if f.def == "" {
+		return
+	}
+	n := f.n
+	fields := g.fields(yang.DataNodes(n.Children, g.state), f.def)
+	g.printf("%s", comment(f.def, n))
+	g.printf("type %s struct {\n", f.def)
+	for _, c := range fields {
+		g.printf("\t%s %s `json:\"%s,omitempty\"`\n", c.name, c.typ, c.tag)
+	}
+	g.printf("}\n\n")
+	g.builder(f, fields, parent, recv)
+	for _, c := range fields {
+		g.node(c, f.def, "s")
+	}
+}
+
+// builder adds to parent a GetOrCreate method for a container, or a New
+// method for a list, so payloads can be built without spelling out every
+// level.
+func (g *generator) builder(f field, fields []field, parent, recv string) {
+	if f.n.Kind == yang.Container {
+		g.printf("// GetOrCreate%s returns %s, creating it if needed.\n", f.name, f.n.Name)
+		g.printf("func (%s *%s) GetOrCreate%s() *%s {\n", recv, parent, f.name, f.def)
+		g.printf("\tif %s.%s == nil {\n\t\t%s.%s = new(%s)\n\t}\n", recv, f.name, recv, f.name, f.def)
+		g.printf("\treturn %s.%s\n}\n\n", recv, f.name)
+		return
+	}
+	var params, sets []string
+	for _, k := range f.n.Keys {
+		for _, c := range fields {
+			if c.n.Name != k || c.n.Kind != yang.Leaf {
+				continue
+			}
+			p := lowerCamel(k)
+			params = append(params, p+" "+strings.TrimPrefix(c.typ, "*"))
+			if strings.HasPrefix(c.typ, "*") {
+				p = "&" + p
+			}
+			sets = append(sets, c.name+": "+p)
+		}
+	}
+	g.printf("// New%s appends an entry to the %s list and returns it.\n", f.name, f.n.Name)
+	g.printf("func (%s *%s) New%s(%s) *%s {\n", recv, parent, f.name, strings.Join(params, ", "), f.def)
+	g.printf("\te := &%s{%s}\n", f.def, strings.Join(sets, ", "))
+	g.printf("\t%s.%s = append(%s.%s, e)\n", recv, f.name, recv, f.name)
+	g.printf("\treturn e\n}\n\n")
+}
+
+func comment(typ string, n *yang.Node) string {
+	desc := strings.Join(strings.Fields(n.Description), " ")
+	line := fmt.Sprintf("// %s is the %s %s.", typ, n.Kind, n.Path())
+	if desc != "" {
+		line += " " + desc
+	}
+	return wrap(line, 76) + "\n"
+}
+
+// wrap breaks a comment line into lines of at most width characters.
+func wrap(line string, width int) string {
+	words := strings.Fields(strings.TrimPrefix(line, "// "))
+	var lines []string
+	cur := "//"
+	for _, w := range words {
+		if len(cur)+1+len(w) > width && cur != "//" {
+			lines = append(lines, cur)
+			cur = "//"
+		}
+		cur += " " + w
+	}
+	return strings.Join(append(lines, cur), "\n")
+}
+
+// lowerCamel is yang.Camel for parameter names.
+func lowerCamel(s string) string {
+	c := yang.Camel(s)
+	// A lone initialism is all lower case, as in "id".
+	if strings.EqualFold(c, s) {
+		c = strings.ToLower(c)
+	} else {
+		c = strings.ToLower(c[:1]) + c[1:]
+	}
+	if token.Lookup(c).IsKeyword() {
+		c += "_"
+	}
+	return c
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"io/ioutil"
+	"testing"
+
+	"github.com/nleiva/clus2019/yang"
+)
+
+var update = flag.Bool("update", false, "Rewrite the golden files with the generated code")
+
+func TestGolden(t *testing.T) {
+	tests := []struct {
+		dir    string
+		module string
+		pkg    string
+		golden string
+	}{
+		// The checked-in structs for user.yang are what yanggo generates.
+		{dir: "../yang", module: "test", pkg: "user", golden: "../yang/user/user.go"},
+		// Lists with one key and two, typedefs, enumerations, empty and
+		// uint64 leaves, leaf-lists, leafrefs and state data.
+		{dir: "testdata", module: "fixture", pkg: "fixture", golden: "testdata/fixture.go"},
+	}
+	for _, tt := range tests {
+		schema, err := yang.Load(tt.dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		m := schema.Module(tt.module)
+		if m == nil {
+			t.Fatalf("module %s isn't in %s", tt.module, tt.dir)
+		}
+		got, err := newGenerator(tt.pkg, false).generate([]*yang.Module{m})
+		if err != nil {
+			t.Fatalf("%s: %v", tt.module, err)
+		}
+		if *update {
+			if err = ioutil.WriteFile(tt.golden, got, 0644); err != nil {
+				t.Fatal(err)
+			}
+			continue
+		}
+		want, err := ioutil.ReadFile(tt.golden)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, want) {
+			t.Errorf("%s: the generated code differs from %s, at line %d; rerun yanggo, or go test -update", tt.module, tt.golden, diffLine(got, want))
+		}
+	}
+}
+
+// diffLine returns the first line where a and b differ, from 1.
+func diffLine(a, b []byte) int {
+	la, lb := bytes.Split(a, []byte("\n")), bytes.Split(b, []byte("\n"))
+	for i := range la {
+		if i >= len(lb) || !bytes.Equal(la[i], lb[i]) {
+			return i + 1
+		}
+	}
+	return len(la) + 1
+}
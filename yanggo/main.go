@@ -0,0 +1,68 @@
+/*
+yanggo generates Go structs from YANG modules. Marshalled to JSON they give
+RFC 7951 payloads, so config can be built in code and checked by the
+compiler instead of edited by hand in JSON files.
+*/
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/nleiva/clus2019/yang"
+)
+
+func main() {
+	// YANG modules; defaults to "../yang"
+	ydir := flag.String("yang", "../yang", "Directory of YANG modules, searched recursively")
+	// Modules to generate structs for
+	modules := flag.String("module", "", "Comma-separated modules to generate, e.g. 'openconfig-interfaces'; all by default")
+	// Generated package
+	pkg := flag.String("pkg", "main", "Package name of the generated code")
+	// Output file
+	out := flag.String("o", "", "File to write; stdout by default")
+	// Include state data
+	state := flag.Bool("state", false, "Also generate fields for state (config false) data")
+	flag.Parse()
+
+	schema, err := yang.Load(*ydir)
+	if err != nil {
+		log.Fatalf("could not load the YANG modules: %v", err)
+	}
+	if schema == nil {
+		log.Fatalf("no YANG modules to generate from")
+	}
+	var mods []*yang.Module
+	if *modules == "" {
+		for _, m := range schema.Modules() {
+			if len(m.Nodes()) > 0 {
+				mods = append(mods, m)
+			}
+		}
+	} else {
+		for _, name := range strings.Split(*modules, ",") {
+			m := schema.Module(name)
+			if m == nil {
+				log.Fatalf("module %s isn't in %s", name, *ydir)
+			}
+			mods = append(mods, m)
+		}
+	}
+
+	src, err := newGenerator(*pkg, *state).generate(mods)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if *out == "" {
+		fmt.Print(string(src))
+		return
+	}
+	if err = ioutil.WriteFile(*out, src, 0644); err != nil {
+		log.Fatalf("could not write file %s: %v", *out, err)
+	}
+	fmt.Fprintf(os.Stderr, "wrote %s\n", *out)
+}
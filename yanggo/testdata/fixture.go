@@ -0,0 +1,119 @@
+// Code generated by yanggo from fixture. DO NOT EDIT.
+
+package fixture
+
+import "encoding/json"
+
+// Root holds the top-level nodes; marshalled to JSON it is a payload
+// for MergeConfig, ReplaceConfig or DeleteConfig.
+type Root struct {
+	System    *System      `json:"fixture:system,omitempty"`
+	Interface []*Interface `json:"fixture:interface,omitempty"`
+}
+
+// JSON returns r as indented RFC 7951 JSON.
+func (r *Root) JSON() (string, error) {
+	b, err := json.MarshalIndent(r, "", "  ")
+	return string(b), err
+}
+
+// SystemMode is an enumeration (mode).
+type SystemMode string
+
+// Values of SystemMode.
+const (
+	SystemModeAuto   SystemMode = "auto"
+	SystemModeManual SystemMode = "manual"
+)
+
+// System is the container /fixture:system. Settings of the system, with a
+// description long enough to wrap the comment of its struct.
+type System struct {
+	Hostname *string         `json:"hostname,omitempty"`
+	Mode     SystemMode      `json:"mode,omitempty"`
+	Load     *uint8          `json:"load,omitempty"`
+	Uptime   *uint64         `json:"uptime,string,omitempty"`
+	Debug    Empty           `json:"debug,omitempty"`
+	Dns      []string        `json:"dns,omitempty"`
+	Server   []*SystemServer `json:"server,omitempty"`
+}
+
+// GetOrCreateSystem returns system, creating it if needed.
+func (r *Root) GetOrCreateSystem() *System {
+	if r.System == nil {
+		r.System = new(System)
+	}
+	return r.System
+}
+
+// SystemServer is the list /fixture:system/server.
+type SystemServer struct {
+	Name   *string `json:"name,omitempty"`
+	Port   *uint16 `json:"port,omitempty"`
+	Weight *uint8  `json:"weight,omitempty"`
+	Backup *string `json:"backup,omitempty"`
+}
+
+// NewServer appends an entry to the server list and returns it.
+func (s *System) NewServer(name string, port uint16) *SystemServer {
+	e := &SystemServer{Name: &name, Port: &port}
+	s.Server = append(s.Server, e)
+	return e
+}
+
+// InterfaceMode is an enumeration (mode).
+type InterfaceMode string
+
+// Values of InterfaceMode.
+const (
+	InterfaceModeAuto   InterfaceMode = "auto"
+	InterfaceModeManual InterfaceMode = "manual"
+)
+
+// Interface is the list /fixture:interface.
+type Interface struct {
+	Name *string       `json:"name,omitempty"`
+	MTU  *uint16       `json:"mtu,omitempty"`
+	Mode InterfaceMode `json:"mode,omitempty"`
+}
+
+// NewInterface appends an entry to the interface list and returns it.
+func (r *Root) NewInterface(name string) *Interface {
+	e := &Interface{Name: &name}
+	r.Interface = append(r.Interface, e)
+	return e
+}
+
+// Empty is the value of a leaf of type empty, set or not.
+type Empty bool
+
+// MarshalJSON encodes a set leaf as [null], as RFC 7951 says.
+func (e Empty) MarshalJSON() ([]byte, error) {
+	return []byte("[null]"), nil
+}
+
+// UnmarshalJSON sets e.
+func (e *Empty) UnmarshalJSON([]byte) error {
+	*e = true
+	return nil
+}
+
+// String returns a pointer to v, for setting string leaves.
+func String(v string) *string {
+	return &v
+}
+
+// Uint16 returns a pointer to v, for setting uint16 leaves.
+func Uint16(v uint16) *uint16 {
+	return &v
+}
+
+// Uint64 returns a pointer to v, for setting uint64 leaves.
+func Uint64(v uint64) *uint64 {
+	return &v
+}
+
+// Uint8 returns a pointer to v, for setting uint8 leaves.
+func Uint8(v uint8) *uint8 {
+	return &v
+}
@@ -0,0 +1,314 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/nleiva/clus2019/gpb"
+	"github.com/nleiva/clus2019/yang"
+)
+
+// translator writes proto3 definitions for the data nodes of a module.
+type translator struct {
+	state bool
+	// crud adds Create, Update, Delete and Watch RPCs for top-level lists,
+	// and for top-level containers with a key leaf.
+	crud bool
+	// key identifies the entries of a top-level container, of which the
+	// service keeps many.
+	key string
+	// prev holds the messages of the previous .proto, by full name, whose
+	// field numbers are kept.
+	prev map[string]*gpb.Descriptor
+	out  bytes.Buffer
+}
+
+// keep has the messages of f keep their field numbers.
+func (t *translator) keep(f *gpb.File) {
+	t.prev = make(map[string]*gpb.Descriptor)
+	for _, m := range f.Messages {
+		t.prev[m.FullName] = m
+	}
+}
+
+func (t *translator) printf(indent int, format string, args ...interface{}) {
+	t.out.WriteString(strings.Repeat("  ", indent))
+	fmt.Fprintf(&t.out, format, args...)
+}
+
+// translate returns the .proto file for m, with a service that looks the
+// top-level nodes up by each leaf in lookup and streams them all.
+func (t *translator) translate(m *yang.Module, service string, lookup []string) ([]byte, error) {
+	top := t.dataNodes(m.Nodes())
+	if len(top) == 0 {
+		return nil, fmt.Errorf("module %s has no data nodes", m.Name)
+	}
+	t.printf(0, "// Code generated by yangproto from %s. DO NOT EDIT.\n\n", m.Name)
+	t.printf(0, "syntax = \"proto3\";\n\n")
+	pkg := yang.Snake(m.Name)
+	t.printf(0, "package %s;\n", pkg)
+
+	if service != "" {
+		var rpcs, reqs bytes.Buffer
+		for _, n := range top {
+			// Name the RPCs after the node only if there's a choice to make.
+			suffix := ""
+			if len(top) > 1 {
+				suffix = yang.Camel(n.Name)
+			}
+			msg := yang.Camel(n.Name)
+			for _, l := range lookup {
+				leaf := child(n, l)
+				if leaf == nil {
+					continue
+				}
+				name := "Get" + suffix + "By" + yang.Camel(l)
+				fmt.Fprintf(&rpcs, "  rpc %s (%sRequest) returns (%s);\n", name, name, msg)
+				fmt.Fprintf(&reqs, "\nmessage %sRequest {\n  %s %s = 1;\n}\n", name, t.scalar(leaf.Type, 0), yang.Snake(l))
+			}
+			name := "GetAll" + suffix
+			fmt.Fprintf(&rpcs, "  rpc %s (%sRequest) returns (stream %s);\n", name, name, msg)
+			fmt.Fprintf(&reqs, "\nmessage %sRequest {}\n", name)
+			if keys := t.keys(n); t.crud && len(keys) > 0 {
+				t.writes(&rpcs, &reqs, n, suffix, keys)
+			}
+		}
+		t.printf(0, "\nservice %s {\n", service)
+		t.out.Write(rpcs.Bytes())
+		t.printf(0, "}\n")
+		t.out.Write(reqs.Bytes())
+	}
+	for _, n := range top {
+		t.out.WriteString("\n")
+		t.message(n, pkg, 0)
+	}
+	return t.out.Bytes(), nil
+}
+
+// keys returns the leaves that identify the entries of top-level node n:
+// the keys of a list, or key for a container that has it.
+func (t *translator) keys(n *yang.Node) []string {
+	switch {
+	case n.Kind == yang.List:
+		return n.Keys
+	case n.Kind == yang.Container && t.key != "" && child(n, t.key) != nil:
+		return []string{t.key}
+	}
+	return nil
+}
+
+// writes declares the RPCs that change the entries of n, identified by
+// keys, and one that streams the changes.
+func (t *translator) writes(rpcs, reqs *bytes.Buffer, n *yang.Node, suffix string, keys []string) {
+	msg := yang.Camel(n.Name)
+	fmt.Fprintf(rpcs, "  rpc Create%s (%s) returns (%s);\n", suffix, msg, msg)
+	fmt.Fprintf(rpcs, "  rpc Update%s (%s) returns (%s);\n", suffix, msg, msg)
+	fmt.Fprintf(rpcs, "  rpc Delete%s (Delete%sRequest) returns (%s);\n", suffix, suffix, msg)
+	fmt.Fprintf(rpcs, "  rpc Watch%s (stream Watch%sRequest) returns (stream %sEvent);\n", suffix, suffix, msg)
+
+	var fields bytes.Buffer
+	for i, k := range keys {
+		fmt.Fprintf(&fields, "  %s %s = %d;\n", t.scalar(keyType(n, k), 0), yang.Snake(k), i+1)
+	}
+	fmt.Fprintf(reqs, "\nmessage Delete%sRequest {\n%s}\n", suffix, fields.String())
+	fmt.Fprintf(reqs, "\nmessage Watch%sRequest {\n%s}\n", suffix, fields.String())
+	fmt.Fprintf(reqs, "\nmessage %sEvent {\n", msg)
+	fmt.Fprintf(reqs, "  enum Type {\n")
+	for i, e := range []string{"UNSPECIFIED", "CREATED", "UPDATED", "DELETED"} {
+		fmt.Fprintf(reqs, "    TYPE_%s = %d;\n", e, i)
+	}
+	fmt.Fprintf(reqs, "  }\n")
+	fmt.Fprintf(reqs, "  Type type = 1;\n")
+	fmt.Fprintf(reqs, "  %s %s = 2;\n", msg, yang.Snake(n.Name))
+	fmt.Fprintf(reqs, "}\n")
+}
+
+// keyType returns the type of key leaf k of n.
+func keyType(n *yang.Node, k string) *yang.Type {
+	if c := child(n, k); c != nil {
+		return c.Type
+	}
+	return nil
+}
+
+// child returns the leaf of n called name, for lookups.
+func child(n *yang.Node, name string) *yang.Node {
+	c := n.Child(n.Module, name)
+	if c == nil || c.Kind != yang.Leaf {
+		return nil
+	}
+	return c
+}
+
+// dataNodes returns the data nodes in ns that have a proto3 form; anydata
+// has none.
+func (t *translator) dataNodes(ns []*yang.Node) []*yang.Node {
+	var out []*yang.Node
+	for _, n := range yang.DataNodes(ns, t.state) {
+		if n.Kind != yang.AnyData {
+			out = append(out, n)
+		}
+	}
+	return out
+}
+
+// message writes the message for a container or list entry in scope; the
+// containers, lists and enumerations below it are nested inside.
+func (t *translator) message(n *yang.Node, scope string, indent int) {
+	comment(&t.out, indent, n.Description)
+	name := yang.Camel(n.Name)
+	t.printf(indent, "message %s {\n", name)
+	full := scope + "." + name
+	children := t.dataNodes(n.Children)
+	names := make([]string, len(children))
+	for i, c := range children {
+		names[i] = yang.Snake(c.Name)
+		switch {
+		case c.Kind == yang.Container || c.Kind == yang.List:
+			t.message(c, full, indent+1)
+		case c.Type != nil && c.Type.Kind == "enumeration":
+			t.enum(c, indent+1)
+		}
+	}
+	numbers, reserved := t.numbers(full, names)
+	if len(reserved) > 0 {
+		var rs []string
+		for _, r := range reserved {
+			if r[0] == r[1] {
+				rs = append(rs, fmt.Sprint(r[0]))
+			} else {
+				rs = append(rs, fmt.Sprintf("%d to %d", r[0], r[1]))
+			}
+		}
+		t.printf(indent+1, "reserved %s;\n", strings.Join(rs, ", "))
+	}
+	for i, c := range children {
+		var typ string
+		switch c.Kind {
+		case yang.Container:
+			typ = yang.Camel(c.Name)
+		case yang.List:
+			typ = "repeated " + yang.Camel(c.Name)
+		case yang.Leaf:
+			typ = t.leafType(c)
+		case yang.LeafList:
+			typ = "repeated " + t.leafType(c)
+		}
+		t.printf(indent+1, "%s %s = %d;\n", typ, names[i], numbers[i])
+	}
+	t.printf(indent, "}\n")
+}
+
+// numbers returns the field numbers of the fields called names in message
+// full. A field in the previous .proto keeps its number and a new one
+// takes the next after the highest used or reserved there, so regenerated
+// messages stay wire compatible. The numbers of fields gone are reserved,
+// along with those reserved before, so they aren't used again.
+func (t *translator) numbers(full string, names []string) ([]int, [][2]int) {
+	old := make(map[string]int)
+	next := 1
+	var reserved [][2]int
+	if m := t.prev[full]; m != nil {
+		for _, f := range m.Fields {
+			old[f.Name] = f.Number
+			if f.Number >= next {
+				next = f.Number + 1
+			}
+		}
+		for _, r := range m.Reserved {
+			reserved = append(reserved, r)
+			if r[1] >= next {
+				next = r[1] + 1
+			}
+		}
+	}
+	out := make([]int, len(names))
+	for i, name := range names {
+		if n, ok := old[name]; ok {
+			out[i] = n
+			delete(old, name)
+			continue
+		}
+		out[i] = next
+		next++
+	}
+	for _, n := range old {
+		reserved = append(reserved, [2]int{n, n})
+	}
+	sort.Slice(reserved, func(i, j int) bool { return reserved[i][0] < reserved[j][0] })
+	return out, reserved
+}
+
+func (t *translator) leafType(n *yang.Node) string {
+	if n.Type != nil && n.Type.Kind == "enumeration" {
+		return yang.Camel(n.Name)
+	}
+	return t.scalar(n.Type, 0)
+}
+
+// enum writes an enumeration leaf as a nested enum. proto3 needs the
+// first value to be zero, which stands for unset, so the YANG values from
+// zero up move up by one; negative ones stay as they are.
+func (t *translator) enum(n *yang.Node, indent int) {
+	prefix := strings.ToUpper(yang.Snake(n.Name)) + "_"
+	t.printf(indent, "enum %s {\n", yang.Camel(n.Name))
+	t.printf(indent+1, "%sUNSPECIFIED = 0;\n", prefix)
+	for i, e := range n.Type.Enums {
+		v := n.Type.Values[i]
+		if v >= 0 {
+			v++
+		}
+		t.printf(indent+1, "%s%s = %d;\n", prefix, strings.ToUpper(yang.Snake(e)), v)
+	}
+	t.printf(indent, "}\n")
+}
+
+// scalar maps a YANG type to a proto3 scalar type. Integers narrower than
+// 32 bits don't exist in proto3 and widen.
+func (t *translator) scalar(ty *yang.Type, depth int) string {
+	if ty == nil {
+		return "string"
+	}
+	switch ty.Kind {
+	case "int8", "int16", "int32":
+		return "int32"
+	case "int64":
+		return "int64"
+	case "uint8", "uint16", "uint32":
+		return "uint32"
+	case "uint64":
+		return "uint64"
+	case "decimal64":
+		return "double"
+	case "boolean", "empty":
+		return "bool"
+	case "binary":
+		return "bytes"
+	case "leafref":
+		if ty.Target != nil && ty.Target.Type != nil && depth < 8 {
+			return t.scalar(ty.Target.Type, depth+1)
+		}
+	}
+	// Strings, enumerations used by reference, identities, bits and
+	// unions all travel as text.
+	return "string"
+}
+
+func comment(w *bytes.Buffer, indent int, desc string) {
+	desc = strings.Join(strings.Fields(desc), " ")
+	if desc == "" {
+		return
+	}
+	pad := strings.Repeat("  ", indent)
+	line := pad + "//"
+	for _, word := range strings.Fields(desc) {
+		if len(line)+1+len(word) > 78 && line != pad+"//" {
+			w.WriteString(line + "\n")
+			line = pad + "//"
+		}
+		line += " " + word
+	}
+	w.WriteString(line + "\n")
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	"github.com/nleiva/clus2019/gpb"
+	"github.com/nleiva/clus2019/yang"
+)
+
+// translate returns the messages yangproto writes for module t, keeping
+// the field numbers of prev, if any.
+func translate(t *testing.T, module string, prev []byte) []byte {
+	t.Helper()
+	dir, err := ioutil.TempDir("", "yangproto")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	if err = ioutil.WriteFile(filepath.Join(dir, "t.yang"), []byte(module), 0644); err != nil {
+		t.Fatal(err)
+	}
+	s, err := yang.Load(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr := &translator{}
+	if prev != nil {
+		f, err := gpb.Parse("prev.proto", prev)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tr.keep(f)
+	}
+	src, err := tr.translate(s.Module("t"), "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return src
+}
+
+// numbers returns the field and enum value numbers in a .proto file, by
+// name.
+func numbers(src []byte) map[string]string {
+	out := make(map[string]string)
+	for _, m := range regexp.MustCompile(`(?m)(\w+) = (-?\d+);$`).FindAllSubmatch(src, -1) {
+		out[string(m[1])] = string(m[2])
+	}
+	return out
+}
+
+func TestNumbers(t *testing.T) {
+	before := `module t {
+    namespace "urn:t";
+    prefix t;
+    container system {
+        leaf hostname { type string; }
+        leaf location { type string; }
+        leaf mtu { type uint16; }
+        leaf mode {
+            type enumeration {
+                enum auto;
+                enum manual { value 5; }
+            }
+        }
+    }
+}`
+	// A new leaf first, one gone, the others swapped and a new enum.
+	after := `module t {
+    namespace "urn:t";
+    prefix t;
+    container system {
+        leaf domain { type string; }
+        leaf mtu { type uint16; }
+        leaf hostname { type string; }
+        leaf mode {
+            type enumeration {
+                enum manual { value 5; }
+                enum auto { value 0; }
+                enum off;
+            }
+        }
+        leaf contact { type string; }
+    }
+}`
+	old := translate(t, before, nil)
+	cur := translate(t, after, old)
+	tests := []struct {
+		src  []byte
+		want map[string]string
+	}{
+		// Fields are numbered in order.
+		{src: old, want: map[string]string{"hostname": "1", "location": "2", "mtu": "3", "mode": "4"}},
+		// They keep their numbers and new ones take the next free.
+		{
+			src: cur,
+			want: map[string]string{
+				"domain":           "5",
+				"mtu":              "3",
+				"hostname":         "1",
+				"mode":             "4",
+				"contact":          "6",
+				"MODE_UNSPECIFIED": "0",
+				"MODE_AUTO":        "1",
+				"MODE_MANUAL":      "6",
+				"MODE_OFF":         "7",
+			},
+		},
+		// Reserved numbers aren't used again.
+		{src: translate(t, before, cur), want: map[string]string{"hostname": "1", "location": "7", "mtu": "3", "mode": "4"}},
+	}
+	for i, tt := range tests {
+		got := numbers(tt.src)
+		for name, want := range tt.want {
+			if got[name] != want {
+				t.Errorf("%d: %s = %s, want %s", i, name, got[name], want)
+			}
+		}
+	}
+	if !bytes.Contains(cur, []byte("  reserved 2;\n")) {
+		t.Errorf("number 2 of location isn't reserved:\n%s", cur)
+	}
+}
@@ -0,0 +1,80 @@
+/*
+yangproto translates a YANG module into proto3 messages and a gRPC service
+skeleton: containers become messages, lists repeated messages and leaves
+the closest proto3 scalar, e.g. uint16 becomes uint32. Fields are
+numbered in order, but keep the numbers they have in the previous version.
+*/
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/nleiva/clus2019/gpb"
+	"github.com/nleiva/clus2019/yang"
+)
+
+func main() {
+	// YANG modules; defaults to "../yang"
+	ydir := flag.String("yang", "../yang", "Directory of YANG modules, searched recursively")
+	// Module to translate
+	module := flag.String("module", "test", "Module to translate")
+	// Service to declare
+	service := flag.String("service", "gUMI", "Name of the gRPC service; empty for messages only")
+	// Leaves to look the top-level nodes up by
+	lookup := flag.String("lookup", "id,name", "Comma-separated leaves to add a GetBy RPC for")
+	// Output file
+	out := flag.String("o", "", "File to write; stdout by default")
+	// Earlier version, for the field numbers
+	prev := flag.String("previous", "", "Earlier .proto whose field numbers to keep; defaults to the -o file, if there is one")
+	// Add write RPCs
+	crud := flag.Bool("crud", false, "Add Create, Update, Delete and Watch RPCs for top-level lists, and containers with a -key leaf")
+	// Leaf that tells top-level containers apart
+	key := flag.String("key", "name", "Leaf that identifies the entries of a top-level container, for -crud")
+	// Include state data
+	state := flag.Bool("state", false, "Also translate state (config false) data")
+	flag.Parse()
+
+	schema, err := yang.Load(*ydir)
+	if err != nil {
+		log.Fatalf("could not load the YANG modules: %v", err)
+	}
+	m := schema.Module(*module)
+	if m == nil {
+		log.Fatalf("module %s isn't in %s", *module, *ydir)
+	}
+	var leaves []string
+	if *lookup != "" {
+		leaves = strings.Split(*lookup, ",")
+	}
+
+	t := &translator{state: *state, crud: *crud, key: *key}
+	if *prev == "" && *out != "" {
+		if _, err = os.Stat(*out); err == nil {
+			*prev = *out
+		}
+	}
+	if *prev != "" {
+		f, err := gpb.ParseFile(*prev)
+		if err != nil {
+			log.Fatalf("could not read the previous messages: %v", err)
+		}
+		t.keep(f)
+	}
+	src, err := t.translate(m, *service, leaves)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if *out == "" {
+		fmt.Print(string(src))
+		return
+	}
+	if err = ioutil.WriteFile(*out, src, 0644); err != nil {
+		log.Fatalf("could not write file %s: %v", *out, err)
+	}
+	fmt.Fprintf(os.Stderr, "wrote %s\n", *out)
+}